@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -23,7 +24,9 @@ import (
 	"insightiq/backend/internal/models"
 	"insightiq/backend/internal/repository"
 	"insightiq/backend/internal/schema"
+	"insightiq/backend/internal/secrets"
 	"insightiq/backend/internal/services"
+	"insightiq/backend/internal/timeexpr"
 	"insightiq/backend/internal/vectorstore"
 )
 
@@ -129,7 +132,35 @@ func main() {
 		os.Exit(1)
 	}
 
-	connectorService := services.NewConnectorService(connectorRepo, logger)
+	// Uploaded datasets for file connectors are kept on local disk
+	fileStore := connectors.NewFileStore(getEnvOrDefault("FILE_UPLOAD_DIR", "./data/uploads"), logger)
+
+	// Drivers implement each connector type; register in-house data sources here
+	drivers := connectors.NewDefaultRegistry(fileStore, logger)
+
+	connectorService := services.NewConnectorService(connectorRepo, drivers, logger)
+	connectorService.SetFileStore(fileStore)
+
+	// Connector secrets are encrypted at rest when keys are configured
+	keyring, err := secrets.LoadKeyring(os.Getenv("CONNECTOR_ENCRYPTION_KEYS"), os.Getenv("CONNECTOR_ENCRYPTION_KEY_FILE"))
+	if err != nil {
+		logger.Error("Failed to load connector encryption keys", "error", err)
+		os.Exit(1)
+	}
+	if keyring != nil {
+		connectorService.SetKeyring(keyring)
+		logger.Info("Connector secrets encryption enabled", "key_id", keyring.PrimaryKeyID())
+	} else {
+		logger.Warn("CONNECTOR_ENCRYPTION_KEYS not set - connector secrets are stored unencrypted")
+	}
+
+	// User-maintained metric and dimension definitions of connectors
+	semanticRepo := repository.NewSemanticRepository(db)
+	if err := semanticRepo.CreateTables(ctx); err != nil {
+		logger.Error("Failed to create semantic definition tables", "error", err)
+		os.Exit(1)
+	}
+	semanticService := services.NewSemanticService(semanticRepo, connectorService, logger)
 
 	// Initialize authentication
 	userRepo := repository.NewUserRepository(db)
@@ -156,6 +187,21 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Initialize conversation session repository
+	conversationRepo := repository.NewConversationRepository(db)
+	if err := conversationRepo.CreateTables(ctx); err != nil {
+		logger.Error("Failed to create conversation tables", "error", err)
+		os.Exit(1)
+	}
+
+	planRepo := repository.NewPlanRepository(db)
+	if err := planRepo.CreateTables(ctx); err != nil {
+		logger.Error("Failed to create plan tables", "error", err)
+		os.Exit(1)
+	}
+	planService := services.NewPlanService(planRepo, logger)
+	planService.SweepAbandoned(ctx)
+
 	// Create initial admin user if it doesn't exist
 	go func() {
 		adminEmail := getEnvOrDefault("ADMIN_EMAIL", "admin@insightiq.local")
@@ -195,7 +241,12 @@ func main() {
 	connectorAdapter := &ConnectorServiceAdapter{connectorService: connectorService}
 
 	// Initialize schema scanner and analyzer for dynamic contexts
-	scannerService := schema.NewScannerService(connectorAdapter, logger)
+	scannerService := schema.NewScannerService(connectorAdapter, drivers, logger)
+	scannerService.SetDefinitionSource(semanticService)
+
+	// dbt artifacts document connectors with the project's models, tests and metrics
+	dbtImporter := schema.NewDbtImporter(getEnvOrDefault("DBT_ARTIFACTS_DIR", "./data/dbt"), logger)
+	scannerService.SetDocumentationSource(dbtImporter)
 	analyzerService := schema.NewAnalyzerService(scannerService, ollamaConn, logger)
 	domainGenerator := schema.NewDomainGeneratorService(analyzerService, vectorStore, embeddingService, logger)
 
@@ -228,8 +279,50 @@ func main() {
 	// Initialize agent manager
 	agentManager := agent.NewManager(logger)
 
+	// Keep agent task results for a while, optionally in a store shared by all replicas
+	resultTTL := agent.DefaultResultTTL
+	if raw := os.Getenv("AGENT_RESULT_TTL"); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil && parsed > 0 {
+			resultTTL = parsed
+		} else {
+			logger.Warn("Invalid AGENT_RESULT_TTL, using default", "value", raw, "error", err)
+		}
+	}
+	maxResults := agent.DefaultMaxResults
+	if raw := os.Getenv("AGENT_MAX_RESULTS"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 {
+			maxResults = parsed
+		} else {
+			logger.Warn("Invalid AGENT_MAX_RESULTS, using default", "value", raw, "error", err)
+		}
+	}
+	agentManager.SetResultRetention(resultTTL, maxResults)
+
+	switch resultStore := getEnvOrDefault("AGENT_RESULT_STORE", "memory"); resultStore {
+	case "memory":
+	case "redis":
+		if redisCache != nil {
+			agentManager.SetResultStore(agent.NewCacheResultStore(redisCache))
+		} else {
+			logger.Warn("Redis unavailable, keeping agent task results in memory only")
+		}
+	case "postgres":
+		taskResultRepo := repository.NewTaskResultRepository(db)
+		if err := taskResultRepo.CreateTables(ctx); err != nil {
+			logger.Error("Failed to create task result tables", "error", err)
+			os.Exit(1)
+		}
+		agentManager.SetResultStore(taskResultRepo)
+	default:
+		logger.Warn("Unknown AGENT_RESULT_STORE, keeping agent task results in memory only", "value", resultStore)
+	}
+
 	// Create enhanced analytics service (connector-only architecture)
 	enhancedAnalyticsService := services.NewEnhancedAnalyticsService(connectorService, ollamaConn, nil, nil, logger)
+	enhancedAnalyticsService.SetSchemaScanner(scannerService)
+	enhancedAnalyticsService.SetDomainRetriever(domainGenerator)
+	enhancedAnalyticsService.SetDefinitionSource(semanticService)
+	enhancedAnalyticsService.SetPlanService(planService)
 
 	// Create and register agents (PostgreSQL connections disabled - using connector-only architecture)
 	analyticsAgent := agent.NewAnalyticsAgent("analytics-1", nil, nil, ollamaConn, logger)
@@ -265,9 +358,45 @@ func main() {
 
 	// Create planner service
 	plannerService := services.NewPlannerService(ollamaConn, connectorService, logger)
+	plannerService.SetValueLinker(enhancedAnalyticsService)
+
+	// Relative dates such as "last quarter" resolve in the business time zone and fiscal calendar
+	if calendar, err := timeexpr.ParseConfig(os.Getenv("BUSINESS_TIMEZONE"), os.Getenv("FISCAL_YEAR_START_MONTH")); err == nil {
+		resolver := timeexpr.NewResolver(calendar)
+		plannerService.SetTimeResolver(resolver)
+		enhancedAnalyticsService.SetTimeResolver(resolver)
+	} else {
+		logger.Warn("Invalid BUSINESS_TIMEZONE or FISCAL_YEAR_START_MONTH, using UTC calendar years", "error", err)
+	}
+
+	// Follow-up questions are rewritten with the earlier questions of their session
+	conversationService := services.NewConversationService(conversationRepo, plannerService, analyticsService, logger)
+	queryJobService := services.NewQueryJobService(analyticsService, logger)
+	queryJobService.SetConversationService(conversationService)
+
+	// Periodically test every connector so statuses stay current for routing
+	healthInterval := services.DefaultHealthCheckInterval
+	if raw := os.Getenv("CONNECTOR_HEALTH_INTERVAL"); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil {
+			healthInterval = parsed
+		} else {
+			logger.Warn("Invalid CONNECTOR_HEALTH_INTERVAL, using default", "value", raw, "error", err)
+		}
+	}
+	if healthInterval > 0 {
+		services.NewHealthMonitor(connectorService, healthInterval, logger).Start(ctx)
+	} else {
+		logger.Info("Connector health monitor disabled")
+	}
 
 	// Create HTTP server with query history
 	httpServer := httpserver.NewServer(analyticsService, voiceService, connectorService, plannerService, authService, queryHistoryRepo, logger) // Fixed: Use alias
+	httpServer.SetContextRefresher(enhancedIngestionService.RefreshConnectorContext)
+	httpServer.SetSemanticService(semanticService)
+	httpServer.SetConversationService(conversationService)
+	httpServer.SetPlanService(planService)
+	httpServer.SetQueryJobService(queryJobService)
+	httpServer.SetDbtImporter(dbtImporter)
 
 	server := &http.Server{
 		Addr:              getEnvOrDefault("PORT", ":8080"),
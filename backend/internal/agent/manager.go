@@ -11,27 +11,81 @@ import (
 
 type Manager struct {
 	agents      map[string]Agent
-	taskQueue   chan Task
 	resultQueue chan TaskResult
-	results     map[string]*TaskResult
+	results     *resultCache
+	resultStore ResultStore // optional, shared with other replicas
 	logger      *slog.Logger
 	mu          sync.RWMutex
 
-	// Metrics
-	tasksProcessed int64
-	tasksInFlight  int64
+	// Scheduling, guarded by queueMu
+	queueMu            sync.Mutex
+	queue              *taskQueue
+	wake               chan struct{} // signals the dispatcher that tasks may be ready
+	capacity           int
+	userLimit          int
+	defaultConcurrency int
+	concurrency        map[string]int // by agent ID, when not the default
+	running            map[string]int // by agent ID
+
+	// Metrics, guarded by queueMu
+	tasksProcessed  int64
+	tasksInFlight   int64
+	tasksRejected   int64
+	tasksDispatched int64
+	totalWait       time.Duration
 }
 
 func NewManager(logger *slog.Logger) *Manager {
 	return &Manager{
-		agents:      make(map[string]Agent),
-		taskQueue:   make(chan Task, 1000),
-		resultQueue: make(chan TaskResult, 1000),
-		results:     make(map[string]*TaskResult),
-		logger:      logger.With("component", "agent_manager"),
+		agents:             make(map[string]Agent),
+		resultQueue:        make(chan TaskResult, 1000),
+		results:            newResultCache(DefaultResultTTL, DefaultMaxResults),
+		logger:             logger.With("component", "agent_manager"),
+		queue:              newTaskQueue(),
+		wake:               make(chan struct{}, 1),
+		capacity:           DefaultQueueCapacity,
+		userLimit:          DefaultUserQueueLimit,
+		defaultConcurrency: DefaultAgentConcurrency,
+		concurrency:        make(map[string]int),
+		running:            make(map[string]int),
 	}
 }
 
+// SetQueueLimits bounds how many tasks may wait, in total and per user
+func (m *Manager) SetQueueLimits(capacity, perUser int) {
+	m.queueMu.Lock()
+	defer m.queueMu.Unlock()
+	m.capacity = max(capacity, 1)
+	m.userLimit = max(perUser, 1)
+}
+
+// SetAgentConcurrency bounds how many tasks an agent processes at once
+func (m *Manager) SetAgentConcurrency(agentID string, n int) {
+	m.queueMu.Lock()
+	m.concurrency[agentID] = max(n, 1)
+	m.queueMu.Unlock()
+	m.signal()
+}
+
+// SetResultRetention bounds how long and how many task results are kept
+func (m *Manager) SetResultRetention(ttl time.Duration, maxResults int) {
+	if ttl <= 0 {
+		ttl = DefaultResultTTL
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.results.ttl = ttl
+	m.results.maxResults = max(maxResults, 1)
+}
+
+// SetResultStore keeps task results in a store shared with other replicas as well,
+// so they survive restarts and can be waited for from any replica
+func (m *Manager) SetResultStore(store ResultStore) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.resultStore = store
+}
+
 func (m *Manager) RegisterAgent(agent Agent) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -59,11 +113,15 @@ func (m *Manager) Start(ctx context.Context) error {
 	// Start task dispatcher
 	go m.taskDispatcher(ctx)
 	go m.resultCollector(ctx)
+	go m.resultSweeper(ctx)
 	go m.healthMonitor(ctx)
 
 	return nil
 }
 
+// SubmitTask queues a task for its agent. Tasks with lower priority values run
+// first, and users take turns among tasks of equal priority. ErrQueueFull is
+// returned when the queue, or the user's share of it, is full.
 func (m *Manager) SubmitTask(task Task) error {
 	// Set default timeout if not specified
 	if task.Timeout == 0 {
@@ -73,20 +131,43 @@ func (m *Manager) SubmitTask(task Task) error {
 	// Set creation time
 	task.CreatedAt = time.Now()
 
+	m.queueMu.Lock()
+	if m.queue.depth >= m.capacity {
+		m.tasksRejected++
+		full := ErrQueueFull{Depth: m.queue.depth, Capacity: m.capacity}
+		m.queueMu.Unlock()
+		m.logger.Warn("Task rejected, queue full", "task_id", task.ID, "depth", full.Depth)
+		return full
+	}
+	if queued := m.queue.perUser[task.UserID]; queued >= m.userLimit {
+		m.tasksRejected++
+		full := ErrQueueFull{Depth: queued, Capacity: m.userLimit, UserID: task.UserID}
+		m.queueMu.Unlock()
+		m.logger.Warn("Task rejected, user queue full", "task_id", task.ID, "user_id", task.UserID, "depth", queued)
+		return full
+	}
+	m.queue.push(task)
+	depth := m.queue.depth
+	m.queueMu.Unlock()
+
+	m.signal()
+	m.logger.Info("Task submitted", "task_id", task.ID, "agent_id", task.AgentID, "priority", task.Priority, "depth", depth)
+	return nil
+}
+
+// signal wakes up the dispatcher without waiting for it
+func (m *Manager) signal() {
 	select {
-	case m.taskQueue <- task:
-		m.logger.Info("Task submitted", "task_id", task.ID, "agent_id", task.AgentID)
-		return nil
+	case m.wake <- struct{}{}:
 	default:
-		return fmt.Errorf("task queue full")
 	}
 }
 
 func (m *Manager) taskDispatcher(ctx context.Context) {
 	for {
+		m.dispatchReady()
 		select {
-		case task := <-m.taskQueue:
-			m.dispatchTask(task)
+		case <-m.wake:
 		case <-ctx.Done():
 			m.logger.Info("Task dispatcher stopping")
 			return
@@ -94,50 +175,119 @@ func (m *Manager) taskDispatcher(ctx context.Context) {
 	}
 }
 
+// dispatchReady starts queued tasks while their agents have room for them
+func (m *Manager) dispatchReady() {
+	m.queueMu.Lock()
+	defer m.queueMu.Unlock()
+
+	canRun := func(agentID string) bool {
+		return m.running[agentID] < m.agentConcurrency(agentID)
+	}
+	for {
+		queued, ok := m.queue.pop(canRun)
+		if !ok {
+			return
+		}
+		m.running[queued.task.AgentID]++
+		m.tasksInFlight++
+		m.tasksDispatched++
+		m.totalWait += time.Since(queued.queuedAt)
+		go m.dispatchTask(queued.task)
+	}
+}
+
+func (m *Manager) agentConcurrency(agentID string) int {
+	if n, ok := m.concurrency[agentID]; ok {
+		return n
+	}
+	return m.defaultConcurrency
+}
+
+// dispatchTask processes a task with its agent and reports the result
 func (m *Manager) dispatchTask(task Task) {
+	defer func() {
+		m.queueMu.Lock()
+		m.running[task.AgentID]--
+		m.tasksInFlight--
+		m.tasksProcessed++
+		m.queueMu.Unlock()
+		m.signal()
+	}()
+
 	m.mu.RLock()
 	agent, exists := m.agents[task.AgentID]
 	m.mu.RUnlock()
 
-	if !exists {
-		m.logger.Error("Agent not found", "agent_id", task.AgentID, "task_id", task.ID)
-		return
-	}
-
 	// Create task context with sufficient timeout for LLM processing
 	ctx, cancel := context.WithTimeout(context.Background(), task.Timeout)
 	defer cancel()
 
-	go func() {
-		result, err := agent.ProcessTask(ctx, task)
-		if err != nil {
-			m.logger.Error("Task processing failed",
-				"task_id", task.ID,
-				"agent_id", task.AgentID,
-				"error", err)
-
-			// Create a failed task result
-			failedResult := TaskResult{
-				TaskID:      task.ID,
-				AgentID:     task.AgentID,
-				Status:      TaskStatusFailed,
-				Error:       err.Error(),
-				ProcessedAt: time.Now(),
-			}
+	var result *TaskResult
+	var err error
+	if exists {
+		result, err = agent.ProcessTask(ctx, task)
+	} else {
+		m.logger.Error("Agent not found", "agent_id", task.AgentID, "task_id", task.ID)
+		err = fmt.Errorf("agent %s not found", task.AgentID)
+	}
 
-			select {
-			case m.resultQueue <- failedResult:
-			case <-ctx.Done():
-				m.logger.Warn("Failed result queue timeout", "task_id", task.ID)
-			}
-		} else if result != nil {
-			select {
-			case m.resultQueue <- *result:
-			case <-ctx.Done():
-				m.logger.Warn("Result queue timeout", "task_id", task.ID)
-			}
+	if err != nil {
+		m.logger.Error("Task processing failed",
+			"task_id", task.ID,
+			"agent_id", task.AgentID,
+			"error", err)
+
+		// Create a failed task result
+		failedResult := TaskResult{
+			TaskID:      task.ID,
+			AgentID:     task.AgentID,
+			Status:      TaskStatusFailed,
+			Error:       err.Error(),
+			ProcessedAt: time.Now(),
 		}
-	}()
+
+		select {
+		case m.resultQueue <- failedResult:
+		case <-ctx.Done():
+			m.logger.Warn("Failed result queue timeout", "task_id", task.ID)
+		}
+	} else if result != nil {
+		select {
+		case m.resultQueue <- *result:
+		case <-ctx.Done():
+			m.logger.Warn("Result queue timeout", "task_id", task.ID)
+		}
+	}
+}
+
+// QueueMetrics reports the backlog and throughput of task scheduling
+func (m *Manager) QueueMetrics() QueueMetrics {
+	m.queueMu.Lock()
+	defer m.queueMu.Unlock()
+
+	metrics := QueueMetrics{
+		Depth:          m.queue.depth,
+		Capacity:       m.capacity,
+		UserLimit:      m.userLimit,
+		WaitingUsers:   len(m.queue.perUser),
+		InFlight:       m.tasksInFlight,
+		Processed:      m.tasksProcessed,
+		Rejected:       m.tasksRejected,
+		QueuedByAgent:  m.queue.queuedByAgent(),
+		RunningByAgent: make(map[string]int, len(m.running)),
+	}
+	for agentID, n := range m.running {
+		if n > 0 {
+			metrics.RunningByAgent[agentID] = n
+		}
+	}
+	if oldest, ok := m.queue.oldest(); ok {
+		metrics.OldestWait = time.Since(oldest)
+	}
+	if m.tasksDispatched > 0 {
+		metrics.AverageWait = m.totalWait / time.Duration(m.tasksDispatched)
+	}
+	return metrics
 }
 
 func (m *Manager) resultCollector(ctx context.Context) {
@@ -150,13 +300,60 @@ func (m *Manager) resultCollector(ctx context.Context) {
 				"status", result.Status,
 				"duration", result.Duration)
 
-			// Store the result for retrieval
+			m.storeResult(result)
+
+		case <-ctx.Done():
+			m.logger.Info("Result collector stopping")
+			return
+		}
+	}
+}
+
+// storeResult keeps a result for retrieval and hands it to the callers waiting for it
+func (m *Manager) storeResult(result TaskResult) {
+	m.mu.Lock()
+	waiters := m.results.put(&result, time.Now())
+	ttl, store := m.results.ttl, m.resultStore
+	m.mu.Unlock()
+
+	for _, ch := range waiters {
+		ch <- &result
+	}
+
+	if store != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), resultStoreTimeout)
+		defer cancel()
+		if err := store.SaveResult(ctx, result, ttl); err != nil {
+			m.logger.Warn("Failed to save task result to store", "task_id", result.TaskID, "error", err)
+		}
+	}
+}
+
+// resultSweeper drops expired results, from the store too when it does not expire them itself
+func (m *Manager) resultSweeper(ctx context.Context) {
+	ticker := time.NewTicker(resultSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
 			m.mu.Lock()
-			m.results[result.TaskID] = &result
+			evicted := m.results.evictExpired(time.Now())
+			store := m.resultStore
 			m.mu.Unlock()
+			if evicted > 0 {
+				m.logger.Debug("Expired task results evicted", "count", evicted)
+			}
 
+			if sweeper, ok := store.(resultSweeper); ok {
+				sweepCtx, cancel := context.WithTimeout(ctx, resultStoreTimeout)
+				if _, err := sweeper.DeleteExpiredResults(sweepCtx); err != nil {
+					m.logger.Warn("Failed to delete expired task results from store", "error", err)
+				}
+				cancel()
+			}
 		case <-ctx.Done():
-			m.logger.Info("Result collector stopping")
+			m.logger.Info("Result sweeper stopping")
 			return
 		}
 	}
@@ -201,12 +398,70 @@ func (m *Manager) GetAgentStatus() map[string]HealthStatus {
 	return status
 }
 
+// GetTaskResult returns the result of a task, or nil when it is not done or its
+// result expired
 func (m *Manager) GetTaskResult(taskID string) *TaskResult {
 	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	if result, exists := m.results[taskID]; exists {
+	result := m.results.get(taskID, time.Now())
+	store := m.resultStore
+	m.mu.RUnlock()
+	if result != nil || store == nil {
 		return result
 	}
-	return nil
+
+	ctx, cancel := context.WithTimeout(context.Background(), resultStoreTimeout)
+	defer cancel()
+	return m.loadResult(ctx, store, taskID)
+}
+
+// WaitForResult returns the result of a task once it is collected, by this replica
+// or, with a result store, by another one
+func (m *Manager) WaitForResult(ctx context.Context, taskID string) (*TaskResult, error) {
+	m.mu.Lock()
+	if result := m.results.get(taskID, time.Now()); result != nil {
+		m.mu.Unlock()
+		return result, nil
+	}
+	ch := m.results.wait(taskID)
+	store := m.resultStore
+	m.mu.Unlock()
+
+	defer func() {
+		m.mu.Lock()
+		m.results.stopWaiting(taskID, ch)
+		m.mu.Unlock()
+	}()
+
+	var poll <-chan time.Time
+	if store != nil {
+		if result := m.loadResult(ctx, store, taskID); result != nil {
+			return result, nil
+		}
+		ticker := time.NewTicker(resultStorePollInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+
+	for {
+		select {
+		case result := <-ch:
+			return result, nil
+		case <-poll:
+			if result := m.loadResult(ctx, store, taskID); result != nil {
+				return result, nil
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// loadResult looks a result up in the store, logging failures as misses
+func (m *Manager) loadResult(ctx context.Context, store ResultStore, taskID string) *TaskResult {
+	result, err := store.LoadResult(ctx, taskID)
+	if err != nil {
+		m.logger.Warn("Failed to load task result from store", "task_id", taskID, "error", err)
+		return nil
+	}
+	return result
 }
@@ -0,0 +1,193 @@
+// internal/agent/queue.go
+package agent
+
+import (
+	"container/heap"
+	"fmt"
+	"time"
+)
+
+// Defaults of task scheduling
+const (
+	// DefaultQueueCapacity is how many tasks may wait at once
+	DefaultQueueCapacity = 1000
+	// DefaultUserQueueLimit is how many of them may belong to one user
+	DefaultUserQueueLimit = 100
+	// DefaultAgentConcurrency is how many tasks an agent processes at once
+	DefaultAgentConcurrency = 4
+)
+
+// ErrQueueFull is returned by SubmitTask when there is no room for a task
+type ErrQueueFull struct {
+	Depth    int    // tasks waiting, of the user when UserID is set
+	Capacity int    // tasks that may wait, of the user when UserID is set
+	UserID   string // set when the user's share of the queue is full
+}
+
+func (e ErrQueueFull) Error() string {
+	if e.UserID != "" {
+		return fmt.Sprintf("task queue full for user %s: %d of %d tasks waiting", e.UserID, e.Depth, e.Capacity)
+	}
+	return fmt.Sprintf("task queue full: %d of %d tasks waiting", e.Depth, e.Capacity)
+}
+
+// QueueMetrics describes the load of the task queue
+type QueueMetrics struct {
+	Depth          int            `json:"depth"`
+	Capacity       int            `json:"capacity"`
+	UserLimit      int            `json:"user_limit"`
+	WaitingUsers   int            `json:"waiting_users"`
+	InFlight       int64          `json:"in_flight"`
+	Processed      int64          `json:"processed"`
+	Rejected       int64          `json:"rejected"`
+	QueuedByAgent  map[string]int `json:"queued_by_agent"`
+	RunningByAgent map[string]int `json:"running_by_agent"`
+	// OldestWait is how long the longest waiting task has been queued
+	OldestWait time.Duration `json:"oldest_wait"`
+	// AverageWait is how long dispatched tasks waited on average
+	AverageWait time.Duration `json:"average_wait"`
+}
+
+// queuedTask is a task waiting for its agent
+type queuedTask struct {
+	task     Task
+	seq      uint64 // submission order
+	queuedAt time.Time
+}
+
+// taskHeap orders the tasks of one user for one agent, lowest priority value
+// first, then in submission order
+type taskHeap []*queuedTask
+
+func (h taskHeap) Len() int { return len(h) }
+func (h taskHeap) Less(i, j int) bool {
+	if h[i].task.Priority != h[j].task.Priority {
+		return h[i].task.Priority < h[j].task.Priority
+	}
+	return h[i].seq < h[j].seq
+}
+func (h taskHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *taskHeap) Push(x interface{}) { *h = append(*h, x.(*queuedTask)) }
+func (h *taskHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
+
+// taskQueue holds waiting tasks by agent and user. The most urgent task goes
+// first; among equally urgent tasks of different users, the user served longest
+// ago goes first, so one user's batch cannot starve the others.
+type taskQueue struct {
+	tasks      map[string]map[string]*taskHeap // by agent ID, then user ID
+	depth      int
+	perUser    map[string]int
+	lastServed map[string]uint64 // dispatch count when each user was last served
+	seq        uint64
+	served     uint64
+}
+
+func newTaskQueue() *taskQueue {
+	return &taskQueue{
+		tasks:      make(map[string]map[string]*taskHeap),
+		perUser:    make(map[string]int),
+		lastServed: make(map[string]uint64),
+	}
+}
+
+func (q *taskQueue) push(task Task) {
+	users, ok := q.tasks[task.AgentID]
+	if !ok {
+		users = make(map[string]*taskHeap)
+		q.tasks[task.AgentID] = users
+	}
+	h, ok := users[task.UserID]
+	if !ok {
+		h = &taskHeap{}
+		users[task.UserID] = h
+	}
+
+	q.seq++
+	heap.Push(h, &queuedTask{task: task, seq: q.seq, queuedAt: time.Now()})
+	q.depth++
+	q.perUser[task.UserID]++
+}
+
+// pop removes and returns the next task whose agent can take one
+func (q *taskQueue) pop(canRun func(agentID string) bool) (*queuedTask, bool) {
+	var best *queuedTask
+	var bestHeap *taskHeap
+	for agentID, users := range q.tasks {
+		if !canRun(agentID) {
+			continue
+		}
+		for _, h := range users {
+			if candidate := (*h)[0]; best == nil || q.before(candidate, best) {
+				best, bestHeap = candidate, h
+			}
+		}
+	}
+	if best == nil {
+		return nil, false
+	}
+
+	heap.Pop(bestHeap)
+	agentID, userID := best.task.AgentID, best.task.UserID
+	if bestHeap.Len() == 0 {
+		delete(q.tasks[agentID], userID)
+		if len(q.tasks[agentID]) == 0 {
+			delete(q.tasks, agentID)
+		}
+	}
+	q.depth--
+	if q.perUser[userID]--; q.perUser[userID] == 0 {
+		delete(q.perUser, userID)
+	}
+	q.served++
+	q.lastServed[userID] = q.served
+	if len(q.perUser) == 0 {
+		// Nobody waits, so nobody is owed a turn
+		clear(q.lastServed)
+	}
+	return best, true
+}
+
+// before reports whether task a goes before task b
+func (q *taskQueue) before(a, b *queuedTask) bool {
+	if a.task.Priority != b.task.Priority {
+		return a.task.Priority < b.task.Priority
+	}
+	if sa, sb := q.lastServed[a.task.UserID], q.lastServed[b.task.UserID]; sa != sb {
+		return sa < sb
+	}
+	return a.seq < b.seq
+}
+
+// oldest returns when the longest waiting task was queued
+func (q *taskQueue) oldest() (time.Time, bool) {
+	var oldest time.Time
+	found := false
+	for _, users := range q.tasks {
+		for _, h := range users {
+			for _, qt := range *h {
+				if !found || qt.queuedAt.Before(oldest) {
+					oldest, found = qt.queuedAt, true
+				}
+			}
+		}
+	}
+	return oldest, found
+}
+
+// queuedByAgent counts the waiting tasks of each agent
+func (q *taskQueue) queuedByAgent() map[string]int {
+	counts := make(map[string]int, len(q.tasks))
+	for agentID, users := range q.tasks {
+		for _, h := range users {
+			counts[agentID] += h.Len()
+		}
+	}
+	return counts
+}
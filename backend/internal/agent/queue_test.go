@@ -0,0 +1,130 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestTaskQueueOrdersByPriorityThenTakesTurnsAcrossUsers(t *testing.T) {
+	q := newTaskQueue()
+	// One user's batch is queued before another user's two tasks
+	for _, id := range []string{"a1", "a2", "a3"} {
+		q.push(Task{ID: id, AgentID: "analytics", UserID: "alice", Priority: 1})
+	}
+	q.push(Task{ID: "b1", AgentID: "analytics", UserID: "bob", Priority: 1})
+	q.push(Task{ID: "b2", AgentID: "analytics", UserID: "bob", Priority: 1})
+	q.push(Task{ID: "urgent", AgentID: "analytics", UserID: "bob", Priority: 0})
+	q.push(Task{ID: "voice", AgentID: "voice", UserID: "carol", Priority: 0})
+
+	onlyAnalytics := func(agentID string) bool { return agentID == "analytics" }
+	var order []string
+	for {
+		next, ok := q.pop(onlyAnalytics)
+		if !ok {
+			break
+		}
+		order = append(order, next.task.ID)
+	}
+
+	want := []string{"urgent", "a1", "b1", "a2", "b2", "a3"}
+	if len(order) != len(want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+	if q.depth != 1 || q.queuedByAgent()["voice"] != 1 {
+		t.Errorf("depth = %d, queued = %v", q.depth, q.queuedByAgent())
+	}
+}
+
+func TestSubmitTaskRejectsWhenQueueFull(t *testing.T) {
+	m := NewManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	m.SetQueueLimits(3, 2)
+
+	submit := func(id, user string) error {
+		return m.SubmitTask(Task{ID: id, AgentID: "analytics", UserID: user})
+	}
+	if err := submit("a1", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := submit("a2", "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	var full ErrQueueFull
+	if err := submit("a3", "alice"); !errors.As(err, &full) || full.UserID != "alice" || full.Depth != 2 {
+		t.Fatalf("err = %v, want alice's share full", err)
+	}
+	if err := submit("b1", "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if err := submit("c1", "carol"); !errors.As(err, &full) || full.UserID != "" || full.Depth != 3 {
+		t.Fatalf("err = %v, want queue full", err)
+	}
+
+	if metrics := m.QueueMetrics(); metrics.Depth != 3 || metrics.Rejected != 2 || metrics.WaitingUsers != 2 {
+		t.Errorf("metrics = %+v", metrics)
+	}
+}
+
+type blockingAgent struct {
+	*BaseAgent
+	started chan string
+	release chan struct{}
+}
+
+func (a *blockingAgent) ProcessTask(ctx context.Context, task Task) (*TaskResult, error) {
+	a.started <- task.ID
+	<-a.release
+	return &TaskResult{TaskID: task.ID, AgentID: a.ID(), Status: TaskStatusCompleted}, nil
+}
+
+func TestManagerBoundsAgentConcurrency(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	a := &blockingAgent{
+		BaseAgent: NewBaseAgent("analytics", AgentTypeAnalytics, logger),
+		started:   make(chan string, 2),
+		release:   make(chan struct{}),
+	}
+	m := NewManager(logger)
+	if err := m.RegisterAgent(a); err != nil {
+		t.Fatal(err)
+	}
+	m.SetAgentConcurrency("analytics", 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := m.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"first", "second"} {
+		if err := m.SubmitTask(Task{ID: id, AgentID: "analytics"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if id := <-a.started; id != "first" {
+		t.Fatalf("started %s first", id)
+	}
+	select {
+	case id := <-a.started:
+		t.Fatalf("%s started while the agent was busy", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if metrics := m.QueueMetrics(); metrics.InFlight != 1 || metrics.Depth != 1 {
+		t.Errorf("metrics = %+v", metrics)
+	}
+
+	a.release <- struct{}{}
+	if id := <-a.started; id != "second" {
+		t.Fatalf("started %s second", id)
+	}
+	a.release <- struct{}{}
+}
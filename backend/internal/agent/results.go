@@ -0,0 +1,184 @@
+// internal/agent/results.go
+package agent
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Defaults of result retention
+const (
+	// DefaultResultTTL is how long results are kept after they were collected
+	DefaultResultTTL = 15 * time.Minute
+	// DefaultMaxResults is how many results are kept in memory at most
+	DefaultMaxResults = 10000
+)
+
+// Timing of result stores
+const (
+	// resultStoreTimeout bounds each call to a result store
+	resultStoreTimeout = 5 * time.Second
+	// resultStorePollInterval is how often waiters look for results collected by other replicas
+	resultStorePollInterval = time.Second
+	// resultSweepInterval is how often expired results are dropped
+	resultSweepInterval = time.Minute
+)
+
+// ResultStore keeps task results outside the process, so they survive restarts
+// and are visible to every replica of the backend
+type ResultStore interface {
+	SaveResult(ctx context.Context, result TaskResult, ttl time.Duration) error
+	// LoadResult returns nil without an error when there is no result for the task
+	LoadResult(ctx context.Context, taskID string) (*TaskResult, error)
+}
+
+// resultSweeper is implemented by result stores that drop expired results only when asked
+type resultSweeper interface {
+	DeleteExpiredResults(ctx context.Context) (int64, error)
+}
+
+// storedResult is a result kept in memory
+type storedResult struct {
+	result    *TaskResult
+	expiresAt time.Time
+	elem      *list.Element // in resultCache.order
+}
+
+// resultCache keeps the results collected by this replica for a while, up to a
+// number of them, and the channels of the callers waiting for results
+type resultCache struct {
+	ttl        time.Duration
+	maxResults int
+	results    map[string]*storedResult
+	order      *list.List // task IDs, oldest first
+	waiters    map[string][]chan *TaskResult
+}
+
+func newResultCache(ttl time.Duration, maxResults int) *resultCache {
+	return &resultCache{
+		ttl:        ttl,
+		maxResults: maxResults,
+		results:    make(map[string]*storedResult),
+		order:      list.New(),
+		waiters:    make(map[string][]chan *TaskResult),
+	}
+}
+
+// put keeps a result, evicting expired and excess ones, and returns the channels
+// of the callers waiting for it
+func (c *resultCache) put(result *TaskResult, now time.Time) []chan *TaskResult {
+	if old, ok := c.results[result.TaskID]; ok {
+		c.order.Remove(old.elem)
+	}
+	c.results[result.TaskID] = &storedResult{
+		result:    result,
+		expiresAt: now.Add(c.ttl),
+		elem:      c.order.PushBack(result.TaskID),
+	}
+
+	c.evictExpired(now)
+	for c.order.Len() > c.maxResults {
+		c.remove(c.order.Front())
+	}
+
+	waiters := c.waiters[result.TaskID]
+	delete(c.waiters, result.TaskID)
+	return waiters
+}
+
+// get returns a result unless it expired
+func (c *resultCache) get(taskID string, now time.Time) *TaskResult {
+	stored, ok := c.results[taskID]
+	if !ok || now.After(stored.expiresAt) {
+		return nil
+	}
+	return stored.result
+}
+
+// evictExpired drops expired results and returns how many. Results are kept in
+// the order they were collected, so the expired ones come first.
+func (c *resultCache) evictExpired(now time.Time) int {
+	evicted := 0
+	for elem := c.order.Front(); elem != nil; elem = c.order.Front() {
+		if !now.After(c.results[elem.Value.(string)].expiresAt) {
+			break
+		}
+		c.remove(elem)
+		evicted++
+	}
+	return evicted
+}
+
+func (c *resultCache) remove(elem *list.Element) {
+	delete(c.results, elem.Value.(string))
+	c.order.Remove(elem)
+}
+
+// wait registers a channel receiving the result of a task
+func (c *resultCache) wait(taskID string) chan *TaskResult {
+	ch := make(chan *TaskResult, 1)
+	c.waiters[taskID] = append(c.waiters[taskID], ch)
+	return ch
+}
+
+// stopWaiting unregisters a channel that no longer waits
+func (c *resultCache) stopWaiting(taskID string, ch chan *TaskResult) {
+	waiters := c.waiters[taskID]
+	for i, w := range waiters {
+		if w == ch {
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(waiters) == 0 {
+		delete(c.waiters, taskID)
+	} else {
+		c.waiters[taskID] = waiters
+	}
+}
+
+// Cache is a shared key-value store, such as Redis through *cache.RedisCache
+type Cache interface {
+	// Get returns nil without an error on a miss
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+}
+
+// CacheResultStore keeps task results in a shared cache, which expires them
+type CacheResultStore struct {
+	cache Cache
+}
+
+func NewCacheResultStore(cache Cache) *CacheResultStore {
+	return &CacheResultStore{cache: cache}
+}
+
+func resultKey(taskID string) string {
+	return "agent:result:" + taskID
+}
+
+// SaveResult keeps a result in the cache for ttl
+func (s *CacheResultStore) SaveResult(ctx context.Context, result TaskResult, ttl time.Duration) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task result: %w", err)
+	}
+	return s.cache.Set(ctx, resultKey(result.TaskID), data, ttl)
+}
+
+// LoadResult returns the result of a task from the cache, or nil when there is none
+func (s *CacheResultStore) LoadResult(ctx context.Context, taskID string) (*TaskResult, error) {
+	data, err := s.cache.Get(ctx, resultKey(taskID))
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	var result TaskResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal task result: %w", err)
+	}
+	return &result, nil
+}
@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestResultCacheEvictsExpiredAndExcessResults(t *testing.T) {
+	c := newResultCache(time.Minute, 2)
+	now := time.Now()
+
+	c.put(&TaskResult{TaskID: "old"}, now)
+	c.put(&TaskResult{TaskID: "a"}, now.Add(50*time.Second))
+	if c.get("old", now.Add(61*time.Second)) != nil {
+		t.Error("expired result returned")
+	}
+
+	c.put(&TaskResult{TaskID: "b"}, now.Add(61*time.Second))
+	if _, ok := c.results["old"]; ok {
+		t.Error("expired result kept")
+	}
+	c.put(&TaskResult{TaskID: "c"}, now.Add(62*time.Second))
+	if len(c.results) != 2 || c.get("a", now.Add(62*time.Second)) != nil || c.get("c", now.Add(62*time.Second)) == nil {
+		t.Errorf("kept %d results, want the 2 newest", len(c.results))
+	}
+}
+
+// memoryStore is a result store shared by managers, as Redis would be by replicas
+type memoryStore struct {
+	mu      sync.Mutex
+	results map[string]TaskResult
+}
+
+func (s *memoryStore) SaveResult(ctx context.Context, result TaskResult, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results[result.TaskID] = result
+	return nil
+}
+
+func (s *memoryStore) LoadResult(ctx context.Context, taskID string) (*TaskResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if result, ok := s.results[taskID]; ok {
+		return &result, nil
+	}
+	return nil, nil
+}
+
+func TestWaitForResult(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := &memoryStore{results: make(map[string]TaskResult)}
+	local, remote := NewManager(logger), NewManager(logger)
+	local.SetResultStore(store)
+	remote.SetResultStore(store)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Collected by this replica: the waiter is notified
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		local.storeResult(TaskResult{TaskID: "here", Status: TaskStatusCompleted})
+	}()
+	if result, err := local.WaitForResult(ctx, "here"); err != nil || result.TaskID != "here" {
+		t.Fatalf("result = %v, err = %v", result, err)
+	}
+
+	// Collected by another replica: found in the shared store
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		remote.storeResult(TaskResult{TaskID: "there", Status: TaskStatusCompleted})
+	}()
+	if result, err := local.WaitForResult(ctx, "there"); err != nil || result.TaskID != "there" {
+		t.Fatalf("result = %v, err = %v", result, err)
+	}
+
+	short, cancelShort := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancelShort()
+	if _, err := local.WaitForResult(short, "never"); err == nil {
+		t.Fatal("waiting for a task that never finishes returned no error")
+	}
+	if len(local.results.waiters) != 0 {
+		t.Errorf("%d waiters left registered", len(local.results.waiters))
+	}
+}
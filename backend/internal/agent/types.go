@@ -27,8 +27,9 @@ type Task struct {
 	ID        string                 `json:"id"`
 	Type      string                 `json:"type"`
 	AgentID   string                 `json:"agent_id"`
+	UserID    string                 `json:"user_id,omitempty"` // whose turn the task takes
 	Payload   map[string]interface{} `json:"payload"`
-	Priority  int                    `json:"priority"`
+	Priority  int                    `json:"priority"` // lower values run first
 	CreatedAt time.Time              `json:"created_at"`
 	Timeout   time.Duration          `json:"timeout"`
 }
@@ -0,0 +1,433 @@
+package connectors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"insightiq/backend/internal/models"
+)
+
+const (
+	// Upper bound on a single API response body
+	maxAPIResponseBytes = 10 << 20
+	// Pages fetched when an endpoint does not set max_pages
+	defaultAPIMaxPages = 10
+)
+
+// Pagination styles supported by API endpoint mappings
+const (
+	APIPaginationNone       = "none"
+	APIPaginationPage       = "page"
+	APIPaginationOffset     = "offset"
+	APIPaginationCursor     = "cursor"
+	APIPaginationLinkHeader = "link_header"
+)
+
+var linkNextPattern = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="?next"?`)
+
+// APIConnector reads records from REST/JSON endpoints declared in the connector config
+type APIConnector struct {
+	config *models.APIConfig
+	client *http.Client
+	logger *slog.Logger
+}
+
+// ParseAPIConfig decodes and validates a generic connector config map
+func ParseAPIConfig(config map[string]interface{}) (*models.APIConfig, error) {
+	raw, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode API config: %w", err)
+	}
+
+	var cfg models.APIConfig
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid API config: %w", err)
+	}
+
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("url is required for API connector")
+	}
+	if !strings.HasPrefix(cfg.URL, "http://") && !strings.HasPrefix(cfg.URL, "https://") {
+		return nil, fmt.Errorf("url must start with http:// or https://")
+	}
+	if len(cfg.Endpoints) == 0 {
+		return nil, fmt.Errorf("at least one endpoint is required for API connector")
+	}
+
+	seen := make(map[string]bool)
+	for i, ep := range cfg.Endpoints {
+		if ep.Name == "" || ep.Path == "" {
+			return nil, fmt.Errorf("endpoint %d: name and path are required", i)
+		}
+		if seen[ep.Name] {
+			return nil, fmt.Errorf("endpoint %q is declared more than once", ep.Name)
+		}
+		seen[ep.Name] = true
+
+		if ep.RecordsPath != "" {
+			if _, err := parseJSONPath(ep.RecordsPath); err != nil {
+				return nil, fmt.Errorf("endpoint %q: %w", ep.Name, err)
+			}
+		}
+
+		if ep.Pagination != nil {
+			switch ep.Pagination.Type {
+			case "", APIPaginationNone, APIPaginationPage, APIPaginationOffset, APIPaginationLinkHeader:
+			case APIPaginationCursor:
+				if ep.Pagination.CursorPath == "" {
+					return nil, fmt.Errorf("endpoint %q: cursor pagination requires cursor_path", ep.Name)
+				}
+				if _, err := parseJSONPath(ep.Pagination.CursorPath); err != nil {
+					return nil, fmt.Errorf("endpoint %q: %w", ep.Name, err)
+				}
+			default:
+				return nil, fmt.Errorf("endpoint %q: unsupported pagination type %q", ep.Name, ep.Pagination.Type)
+			}
+		}
+	}
+
+	return &cfg, nil
+}
+
+func NewAPIConnector(config *models.APIConfig, logger *slog.Logger) *APIConnector {
+	timeout := 30 * time.Second
+	if config.TimeoutSecs > 0 {
+		timeout = time.Duration(config.TimeoutSecs) * time.Second
+	}
+
+	return &APIConnector{
+		config: config,
+		client: &http.Client{
+			Timeout: timeout,
+		},
+		logger: logger.With("connector", "api"),
+	}
+}
+
+// Endpoint returns the endpoint mapping with the given name
+func (ac *APIConnector) Endpoint(name string) (*models.APIEndpoint, bool) {
+	for i := range ac.config.Endpoints {
+		if ac.config.Endpoints[i].Name == name {
+			return &ac.config.Endpoints[i], true
+		}
+	}
+	return nil, false
+}
+
+// TestConnection fetches the first page of every endpoint and returns the endpoint names
+func (ac *APIConnector) TestConnection(ctx context.Context) ([]string, error) {
+	var names []string
+	for i := range ac.config.Endpoints {
+		ep := &ac.config.Endpoints[i]
+		if _, err := ac.FetchRecords(ctx, ep, 1); err != nil {
+			return nil, fmt.Errorf("endpoint %q failed: %w", ep.Name, err)
+		}
+		names = append(names, ep.Name)
+	}
+	return names, nil
+}
+
+// EndpointTables describes each endpoint as a table using only the declared
+// metadata (no requests are made), which is enough for routing decisions
+func (ac *APIConnector) EndpointTables() []TableSchema {
+	tables := make([]TableSchema, 0, len(ac.config.Endpoints))
+	for _, ep := range ac.config.Endpoints {
+		table := TableSchema{Name: ep.Name, Description: ep.Description}
+		for field, hint := range ep.FieldTypes {
+			table.Columns = append(table.Columns, ColumnSchema{Name: field, DataType: apiFieldDataType(hint), Nullable: true})
+		}
+		tables = append(tables, table)
+	}
+	return tables
+}
+
+// InferEndpointSchemas samples each endpoint and derives columns from the
+// returned records, applying the declared field type hints
+func (ac *APIConnector) InferEndpointSchemas(ctx context.Context, sampleSize int) ([]TableSchema, error) {
+	if sampleSize <= 0 {
+		sampleSize = defaultMongoSampleSize
+	}
+
+	var tables []TableSchema
+	for i := range ac.config.Endpoints {
+		ep := &ac.config.Endpoints[i]
+		records, err := ac.FetchRecords(ctx, ep, sampleSize)
+		if err != nil {
+			ac.logger.Warn("Failed to sample endpoint", "endpoint", ep.Name, "error", err)
+			continue
+		}
+
+		columns := inferDocumentColumns(records)
+		for j := range columns {
+			if hint, ok := ep.FieldTypes[columns[j].Name]; ok {
+				columns[j].DataType = apiFieldDataType(hint)
+			} else if columns[j].DataType == "double" && allIntegral(records, columns[j].Name) {
+				columns[j].DataType = "int"
+			}
+		}
+
+		description := ep.Description
+		if description == "" {
+			description = fmt.Sprintf("Records returned by %s %s", http.MethodGet, ep.Path)
+		}
+
+		tables = append(tables, TableSchema{
+			Name:        ep.Name,
+			Description: description,
+			Columns:     columns,
+		})
+	}
+
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("no API endpoints could be sampled")
+	}
+
+	ac.logger.Info("Inferred API endpoint schemas", "endpoints", len(tables))
+	return tables, nil
+}
+
+// FetchRecords reads up to limit records from an endpoint, following its pagination.
+// A limit that is not positive reads up to DefaultMaxRows.
+func (ac *APIConnector) FetchRecords(ctx context.Context, ep *models.APIEndpoint, limit int) ([]map[string]interface{}, error) {
+	if limit <= 0 {
+		limit = DefaultMaxRows
+	}
+
+	pagination := models.APIPagination{Type: APIPaginationNone}
+	if ep.Pagination != nil {
+		pagination = *ep.Pagination
+	}
+	maxPages := pagination.MaxPages
+	if maxPages <= 0 {
+		maxPages = defaultAPIMaxPages
+	}
+
+	params := url.Values{}
+	for key, value := range ep.QueryParams {
+		params.Set(key, value)
+	}
+
+	page := pagination.StartPage
+	if page == 0 {
+		page = 1
+	}
+	offset := 0
+	pageSize := pagination.PageSize
+	if pageSize > 0 {
+		switch pagination.Type {
+		case APIPaginationPage:
+			params.Set(defaultString(pagination.SizeParam, "per_page"), strconv.Itoa(pageSize))
+		case APIPaginationOffset:
+			params.Set(defaultString(pagination.LimitParam, "limit"), strconv.Itoa(pageSize))
+		}
+	}
+
+	nextURL := ""
+	var records []map[string]interface{}
+
+paging:
+	for pageNum := 0; pageNum < maxPages && len(records) < limit; pageNum++ {
+		switch pagination.Type {
+		case APIPaginationPage:
+			params.Set(defaultString(pagination.PageParam, "page"), strconv.Itoa(page))
+		case APIPaginationOffset:
+			params.Set(defaultString(pagination.OffsetParam, "offset"), strconv.Itoa(offset))
+		}
+
+		requestURL := nextURL
+		if requestURL == "" {
+			var err error
+			requestURL, err = ac.buildURL(ep.Path, params)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		body, header, err := ac.get(ctx, requestURL)
+		if err != nil {
+			return nil, err
+		}
+
+		pageRecords, err := extractRecords(body, ep.RecordsPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract records from %s: %w", ep.Name, err)
+		}
+		records = append(records, pageRecords...)
+
+		if len(pageRecords) == 0 || (pageSize > 0 && len(pageRecords) < pageSize) {
+			break
+		}
+
+		switch pagination.Type {
+		case APIPaginationPage:
+			page++
+		case APIPaginationOffset:
+			offset += len(pageRecords)
+		case APIPaginationCursor:
+			cursor := extractScalar(body, pagination.CursorPath)
+			if cursor == "" {
+				break paging
+			}
+			params.Set(defaultString(pagination.CursorParam, "cursor"), cursor)
+		case APIPaginationLinkHeader:
+			next := parseNextLink(header.Get("Link"))
+			if next == "" {
+				break paging
+			}
+			resolved, err := ac.resolveLink(requestURL, next)
+			if err != nil {
+				return nil, err
+			}
+			nextURL = resolved
+		default:
+			break paging
+		}
+	}
+
+	if len(records) > limit {
+		records = records[:limit]
+	}
+
+	ac.logger.Info("Fetched API records", "endpoint", ep.Name, "records", len(records))
+	return records, nil
+}
+
+// buildURL joins the base URL, endpoint path and query parameters
+func (ac *APIConnector) buildURL(path string, params url.Values) (string, error) {
+	base, err := url.Parse(strings.TrimSuffix(ac.config.URL, "/") + "/" + strings.TrimPrefix(path, "/"))
+	if err != nil {
+		return "", fmt.Errorf("invalid endpoint url: %w", err)
+	}
+
+	query := base.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	base.RawQuery = query.Encode()
+	return base.String(), nil
+}
+
+// resolveLink resolves a Link header target and keeps it on the configured host
+func (ac *APIConnector) resolveLink(current, link string) (string, error) {
+	currentURL, err := url.Parse(current)
+	if err != nil {
+		return "", err
+	}
+	next, err := currentURL.Parse(link)
+	if err != nil {
+		return "", fmt.Errorf("invalid Link header url: %w", err)
+	}
+
+	base, err := url.Parse(ac.config.URL)
+	if err != nil {
+		return "", err
+	}
+	// SECURITY: never send the auth header to a host other than the configured one
+	if next.Host != base.Host {
+		return "", fmt.Errorf("pagination link points to a different host: %s", next.Host)
+	}
+	if next.Scheme != base.Scheme {
+		return "", fmt.Errorf("pagination link changes scheme to %s", next.Scheme)
+	}
+	return next.String(), nil
+}
+
+// get performs an authenticated GET and decodes the JSON body
+func (ac *APIConnector) get(ctx context.Context, requestURL string) (interface{}, http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	for key, value := range ac.config.Headers {
+		req.Header.Set(key, value)
+	}
+	if ac.config.AuthHeader != "" && ac.config.AuthValue != "" {
+		req.Header.Set(ac.config.AuthHeader, ac.config.AuthValue)
+	}
+
+	resp, err := ac.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(snippet)))
+	}
+
+	var body interface{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxAPIResponseBytes)).Decode(&body); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode JSON response: %w", err)
+	}
+
+	return body, resp.Header, nil
+}
+
+// parseNextLink extracts the rel="next" target of an RFC 8288 Link header
+func parseNextLink(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		if m := linkNextPattern.FindStringSubmatch(part); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// apiFieldDataType maps a declared field type hint onto the data type names used by the scanner
+func apiFieldDataType(hint string) string {
+	switch strings.ToLower(hint) {
+	case "integer", "int", "long":
+		return "int"
+	case "number", "float", "double", "decimal":
+		return "double"
+	case "boolean", "bool":
+		return "bool"
+	case "date", "datetime", "timestamp", "time":
+		return "date"
+	default:
+		return "string"
+	}
+}
+
+// allIntegral reports whether every numeric value of field is a whole number
+func allIntegral(records []map[string]interface{}, field string) bool {
+	for _, record := range records {
+		if f, ok := lookupPath(record, field).(float64); ok && f != math.Trunc(f) {
+			return false
+		}
+	}
+	return true
+}
+
+// lookupPath resolves a dotted field path within a nested record
+func lookupPath(record map[string]interface{}, path string) interface{} {
+	var current interface{} = record
+	for _, part := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[part]
+	}
+	return current
+}
+
+func defaultString(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
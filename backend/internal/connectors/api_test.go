@@ -0,0 +1,261 @@
+package connectors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"insightiq/backend/internal/models"
+)
+
+// TestParseJSONPath tests the supported JSONPath subset
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []jsonPathStep
+		wantErr bool
+	}{
+		{path: "$", want: nil},
+		{path: "", want: nil},
+		{path: "$.data.items", want: []jsonPathStep{{key: "data"}, {key: "items"}}},
+		{path: "$['odd key'][0]", want: []jsonPathStep{{key: "odd key"}, {index: 0, isIndex: true}}},
+		{path: `$["x"][-1]`, want: []jsonPathStep{{key: "x"}, {index: -1, isIndex: true}}},
+		{path: "$.results[*].user", want: []jsonPathStep{{key: "results"}, {wildcard: true}, {key: "user"}}},
+		{path: "$.*", want: []jsonPathStep{{wildcard: true}}},
+		{path: "data.items", wantErr: true},
+		{path: "$..items", wantErr: true},
+		{path: "$.items[0", wantErr: true},
+		{path: "$.items[?(@.x)]", wantErr: true},
+		{path: "$ items", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := parseJSONPath(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseJSONPath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseJSONPath(%q) = %+v, want %+v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestExtractRecords tests resolving records and cursors from a response
+func TestExtractRecords(t *testing.T) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(`{"data":{"items":[{"id":1},null,2]},"meta":{"next":"abc","page":3}}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := extractRecords(doc, "$.data.items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]interface{}{{"id": float64(1)}, {"value": float64(2)}}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("extractRecords() = %v, want %v", records, want)
+	}
+	if got := extractScalar(doc, "$.meta.next"); got != "abc" {
+		t.Errorf("cursor = %q, want abc", got)
+	}
+	if got := extractScalar(doc, "$.meta.page"); got != "3" {
+		t.Errorf("page = %q, want 3", got)
+	}
+	if got := extractScalar(doc, "$.meta.missing"); got != "" {
+		t.Errorf("missing cursor = %q, want empty", got)
+	}
+}
+
+// pagedServer serves /items as pages of ids 1..total, reading the position from the request
+func pagedServer(t *testing.T, total int, position func(r *http.Request) (start, size int), respond func(w http.ResponseWriter, r *http.Request, items []map[string]int, start int)) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		start, size := position(r)
+		var items []map[string]int
+		for id := start + 1; id <= total && id <= start+size; id++ {
+			items = append(items, map[string]int{"id": id})
+		}
+		respond(w, r, items, start)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestAPIConnector(url string) *APIConnector {
+	return NewAPIConnector(&models.APIConfig{
+		URL:        url,
+		AuthHeader: "Authorization",
+		AuthValue:  "Bearer secret",
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func recordIDs(records []map[string]interface{}) []int {
+	ids := make([]int, len(records))
+	for i, record := range records {
+		ids[i] = int(record["id"].(float64))
+	}
+	return ids
+}
+
+// TestFetchRecordsPagination tests following each pagination style
+func TestFetchRecordsPagination(t *testing.T) {
+	writeItems := func(w http.ResponseWriter, items []map[string]int) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+	}
+	query := func(r *http.Request, key string) int {
+		n, _ := strconv.Atoi(r.URL.Query().Get(key))
+		return n
+	}
+
+	tests := []struct {
+		name       string
+		pagination *models.APIPagination
+		position   func(r *http.Request) (int, int)
+		respond    func(w http.ResponseWriter, r *http.Request, items []map[string]int, start int)
+		limit      int
+		want       int
+	}{
+		{
+			name:       "page numbers",
+			pagination: &models.APIPagination{Type: APIPaginationPage, PageSize: 3},
+			position:   func(r *http.Request) (int, int) { return (query(r, "page") - 1) * 3, query(r, "per_page") },
+			respond:    func(w http.ResponseWriter, r *http.Request, items []map[string]int, start int) { writeItems(w, items) },
+			limit:      100,
+			want:       7,
+		},
+		{
+			name:       "offsets stop at the limit",
+			pagination: &models.APIPagination{Type: APIPaginationOffset, PageSize: 2},
+			position:   func(r *http.Request) (int, int) { return query(r, "offset"), query(r, "limit") },
+			respond:    func(w http.ResponseWriter, r *http.Request, items []map[string]int, start int) { writeItems(w, items) },
+			limit:      5,
+			want:       5,
+		},
+		{
+			name:       "cursor",
+			pagination: &models.APIPagination{Type: APIPaginationCursor, CursorPath: "$.next"},
+			position:   func(r *http.Request) (int, int) { return query(r, "cursor"), 4 },
+			respond: func(w http.ResponseWriter, r *http.Request, items []map[string]int, start int) {
+				body := map[string]interface{}{"items": items}
+				if start+len(items) < 7 {
+					body["next"] = strconv.Itoa(start + len(items))
+				}
+				json.NewEncoder(w).Encode(body)
+			},
+			limit: 100,
+			want:  7,
+		},
+		{
+			name:       "relative link header",
+			pagination: &models.APIPagination{Type: APIPaginationLinkHeader},
+			position:   func(r *http.Request) (int, int) { return query(r, "after"), 3 },
+			respond: func(w http.ResponseWriter, r *http.Request, items []map[string]int, start int) {
+				if start+len(items) < 7 {
+					w.Header().Set("Link", fmt.Sprintf(`</items?after=%d>; rel="next", </items>; rel="first"`, start+len(items)))
+				}
+				writeItems(w, items)
+			},
+			limit: 100,
+			want:  7,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := pagedServer(t, 7, tt.position, tt.respond)
+			ac := newTestAPIConnector(server.URL)
+
+			records, err := ac.FetchRecords(context.Background(), &models.APIEndpoint{Name: "items", Path: "/items", RecordsPath: "$.items", Pagination: tt.pagination}, tt.limit)
+			if err != nil {
+				t.Fatalf("FetchRecords() error = %v", err)
+			}
+			ids := recordIDs(records)
+			if len(ids) != tt.want {
+				t.Fatalf("got ids %v, want %d records", ids, tt.want)
+			}
+			for i, id := range ids {
+				if id != i+1 {
+					t.Fatalf("got ids %v, want 1..%d in order", ids, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// TestFetchRecordsMaxPages tests that pagination stops after max_pages requests
+func TestFetchRecordsMaxPages(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		fmt.Fprint(w, `{"items":[{"id":1}],"next":"again"}`)
+	}))
+	defer server.Close()
+
+	ac := newTestAPIConnector(server.URL)
+	ep := &models.APIEndpoint{Name: "items", Path: "/items", RecordsPath: "$.items",
+		Pagination: &models.APIPagination{Type: APIPaginationCursor, CursorPath: "$.next", MaxPages: 3}}
+	if _, err := ac.FetchRecords(context.Background(), ep, 100); err != nil {
+		t.Fatal(err)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("made %d requests, want 3", got)
+	}
+}
+
+// TestFetchRecordsLinkHeaderStaysOnHost tests that a Link header pointing elsewhere
+// is refused before the credentials are sent there
+func TestFetchRecordsLinkHeaderStaysOnHost(t *testing.T) {
+	var leaked atomic.Bool
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			leaked.Store(true)
+		}
+		fmt.Fprint(w, `{"items":[]}`)
+	}))
+	defer other.Close()
+
+	tests := []struct {
+		name    string
+		link    string
+		wantErr string
+	}{
+		{"other host", other.URL + "/items?page=2", "different host"},
+		{"protocol-relative", "//" + strings.TrimPrefix(other.URL, "http://") + "/items", "different host"},
+		{"scheme change", "https://HOST/items?page=2", "changes scheme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Link", "<"+strings.ReplaceAll(tt.link, "HOST", r.Host)+`>; rel="next"`)
+				fmt.Fprint(w, `{"items":[{"id":1}]}`)
+			}))
+			defer server.Close()
+
+			ac := newTestAPIConnector(server.URL)
+			ep := &models.APIEndpoint{Name: "items", Path: "/items", RecordsPath: "$.items",
+				Pagination: &models.APIPagination{Type: APIPaginationLinkHeader}}
+			if _, err := ac.FetchRecords(context.Background(), ep, 100); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("FetchRecords() error = %v, want %q", err, tt.wantErr)
+			}
+			if leaked.Load() {
+				t.Error("credentials were sent to another host")
+			}
+		})
+	}
+}
@@ -0,0 +1,431 @@
+package connectors
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"insightiq/backend/internal/models"
+)
+
+// QueryRecords evaluates a parsed query against in-memory records: filters and
+// time range first, then group-by with aggregations, then sort and limit.
+// Field names are resolved against columns the same way as MongoDB pipelines.
+// At most maxRows+1 records are returned, so truncation can be detected.
+func QueryRecords(records []map[string]interface{}, columns []ColumnSchema, pq *models.ParsedQuery, maxRows int) []map[string]interface{} {
+	if pq == nil {
+		return headRecords(records, readLimit(0, maxRows))
+	}
+	limit := 0
+	if pq.Limit != nil {
+		limit = *pq.Limit
+	}
+	limit = readLimit(limit, maxRows)
+
+	fields := make([]string, 0, len(columns))
+	for _, col := range columns {
+		fields = append(fields, col.Name)
+	}
+
+	filtered := filterRecords(records, columns, fields, pq)
+
+	groupNames := pq.GroupBy
+	if len(groupNames) == 0 {
+		groupNames = pq.Dimensions
+	}
+	var groupFields []string
+	for _, name := range groupNames {
+		if field := resolveFieldName(name, fields); field != "" && !containsString(groupFields, field) {
+			groupFields = append(groupFields, field)
+		}
+	}
+
+	aggregations := pq.Aggregations
+	if len(aggregations) == 0 && len(groupFields) > 0 {
+		for _, metric := range pq.Metrics {
+			aggregations = append(aggregations, models.Aggregation{Function: "SUM", Field: metric})
+		}
+		if len(aggregations) == 0 {
+			aggregations = append(aggregations, models.Aggregation{Function: "COUNT", Field: "*", Alias: "count"})
+		}
+	}
+
+	var result []map[string]interface{}
+	outputFields := fields
+
+	if len(aggregations) > 0 {
+		result, outputFields = aggregateRecords(filtered, fields, groupFields, aggregations)
+	} else {
+		result = projectRecords(filtered, fields, append(append([]string{}, pq.Metrics...), groupNames...))
+	}
+
+	if len(pq.SortBy) > 0 {
+		sortRecords(result, outputFields, pq.SortBy)
+	}
+
+	return headRecords(result, limit)
+}
+
+// filterRecords applies filters (AND, with OR groups) and the time range
+func filterRecords(records []map[string]interface{}, columns []ColumnSchema, fields []string, pq *models.ParsedQuery) []map[string]interface{} {
+	type condition struct {
+		field    string
+		operator string
+		value    interface{}
+	}
+
+	var andConds, orConds []condition
+	for _, filter := range pq.Filters {
+		field := resolveFieldName(filter.Field, fields)
+		if field == "" {
+			continue
+		}
+		c := condition{field: field, operator: strings.ToUpper(strings.TrimSpace(filter.Operator)), value: filter.Value}
+		if strings.EqualFold(filter.Condition, "OR") {
+			orConds = append(orConds, c)
+		} else {
+			andConds = append(andConds, c)
+		}
+	}
+
+	timeField := ""
+	var start, end *time.Time
+	if pq.TimeRange != nil && (pq.TimeRange.Start != nil || pq.TimeRange.End != nil) {
+		timeField = firstDatetimeColumn(columns)
+		start, end = pq.TimeRange.Start, pq.TimeRange.End
+	}
+
+	if len(andConds) == 0 && len(orConds) == 0 && timeField == "" {
+		return records
+	}
+
+	var out []map[string]interface{}
+	for _, record := range records {
+		keep := true
+		for _, c := range andConds {
+			if !matchCondition(lookupPath(record, c.field), c.operator, c.value) {
+				keep = false
+				break
+			}
+		}
+		if keep && len(orConds) > 0 {
+			anyMatch := false
+			for _, c := range orConds {
+				if matchCondition(lookupPath(record, c.field), c.operator, c.value) {
+					anyMatch = true
+					break
+				}
+			}
+			keep = anyMatch
+		}
+		if keep && timeField != "" {
+			t, ok := toTime(lookupPath(record, timeField))
+			if !ok || (start != nil && t.Before(*start)) || (end != nil && !t.Before(*end)) {
+				keep = false
+			}
+		}
+		if keep {
+			out = append(out, record)
+		}
+	}
+	return out
+}
+
+// matchCondition evaluates a single filter against a value
+func matchCondition(actual interface{}, operator string, expected interface{}) bool {
+	switch operator {
+	case "IN":
+		for _, candidate := range toSlice(expected) {
+			if compareValues(actual, candidate) == 0 {
+				return true
+			}
+		}
+		return false
+	case "NOT IN", "NOT_IN", "NIN":
+		return !matchCondition(actual, "IN", expected)
+	case "LIKE", "ILIKE", "CONTAINS":
+		pattern := strings.ToLower(strings.Trim(fmt.Sprint(expected), "%"))
+		return actual != nil && strings.Contains(strings.ToLower(fmt.Sprint(actual)), pattern)
+	}
+
+	if actual == nil {
+		return operator == "!=" || operator == "<>"
+	}
+
+	cmp := compareValues(actual, expected)
+	switch operator {
+	case "!=", "<>", "NE":
+		return cmp != 0
+	case ">", "GT":
+		return cmp > 0
+	case ">=", "GTE":
+		return cmp >= 0
+	case "<", "LT":
+		return cmp < 0
+	case "<=", "LTE":
+		return cmp <= 0
+	default:
+		return cmp == 0
+	}
+}
+
+// compareValues orders two values numerically, chronologically or case-insensitively
+func compareValues(a, b interface{}) int {
+	if af, ok := toFloat(a); ok {
+		if bf, ok := toFloat(b); ok {
+			switch {
+			case af < bf:
+				return -1
+			case af > bf:
+				return 1
+			default:
+				return 0
+			}
+		}
+	}
+	if at, ok := a.(time.Time); ok {
+		if bt, ok := toTime(b); ok {
+			return at.Compare(bt)
+		}
+	}
+	as := strings.ToLower(fmt.Sprint(a))
+	bs := strings.ToLower(fmt.Sprint(b))
+	return strings.Compare(as, bs)
+}
+
+// aggregateRecords groups records and computes aggregations, returning rows and their field names
+func aggregateRecords(records []map[string]interface{}, fields, groupFields []string, aggregations []models.Aggregation) ([]map[string]interface{}, []string) {
+	type accumulator struct {
+		sum, min, max float64
+		count         int
+		numeric       int
+	}
+	type group struct {
+		key  map[string]interface{}
+		accs []*accumulator
+	}
+
+	type aggSpec struct {
+		field, fn, alias string
+	}
+	var specs []aggSpec
+	for _, agg := range aggregations {
+		field := resolveFieldName(agg.Field, fields)
+		fn := strings.ToLower(agg.Function)
+		if fn == "average" {
+			fn = "avg"
+		}
+		if field == "" && fn != "count" {
+			continue
+		}
+		alias := agg.Alias
+		if alias == "" {
+			alias = mongoAlias(fn + "_" + firstNonEmpty(field, "records"))
+		}
+		specs = append(specs, aggSpec{field: field, fn: fn, alias: alias})
+	}
+
+	groups := make(map[string]*group)
+	var order []string
+
+	for _, record := range records {
+		keyParts := make([]string, len(groupFields))
+		for i, field := range groupFields {
+			keyParts[i] = fmt.Sprint(lookupPath(record, field))
+		}
+		key := strings.Join(keyParts, "\x00")
+
+		g, ok := groups[key]
+		if !ok {
+			g = &group{key: make(map[string]interface{})}
+			for _, field := range groupFields {
+				g.key[mongoAlias(field)] = lookupPath(record, field)
+			}
+			for range specs {
+				g.accs = append(g.accs, &accumulator{})
+			}
+			groups[key] = g
+			order = append(order, key)
+		}
+
+		for i, spec := range specs {
+			acc := g.accs[i]
+			if spec.field == "" {
+				acc.count++
+				continue
+			}
+			value := lookupPath(record, spec.field)
+			if value == nil {
+				continue
+			}
+			acc.count++
+			if f, ok := toFloat(value); ok {
+				if acc.numeric == 0 || f < acc.min {
+					acc.min = f
+				}
+				if acc.numeric == 0 || f > acc.max {
+					acc.max = f
+				}
+				acc.sum += f
+				acc.numeric++
+			}
+		}
+	}
+
+	outputFields := make([]string, 0, len(groupFields)+len(specs))
+	for _, field := range groupFields {
+		outputFields = append(outputFields, mongoAlias(field))
+	}
+	for _, spec := range specs {
+		outputFields = append(outputFields, spec.alias)
+	}
+
+	result := make([]map[string]interface{}, 0, len(order))
+	for _, key := range order {
+		g := groups[key]
+		row := make(map[string]interface{}, len(outputFields))
+		for k, v := range g.key {
+			row[k] = v
+		}
+		for i, spec := range specs {
+			acc := g.accs[i]
+			switch spec.fn {
+			case "count":
+				row[spec.alias] = acc.count
+			case "avg":
+				if acc.numeric > 0 {
+					row[spec.alias] = acc.sum / float64(acc.numeric)
+				} else {
+					row[spec.alias] = nil
+				}
+			case "min":
+				row[spec.alias] = nilIfEmpty(acc.numeric, acc.min)
+			case "max":
+				row[spec.alias] = nilIfEmpty(acc.numeric, acc.max)
+			default:
+				row[spec.alias] = acc.sum
+			}
+		}
+		result = append(result, row)
+	}
+
+	return result, outputFields
+}
+
+// projectRecords keeps only the requested fields, or every field when none resolve
+func projectRecords(records []map[string]interface{}, fields, requested []string) []map[string]interface{} {
+	var keep []string
+	for _, name := range requested {
+		if field := resolveFieldName(name, fields); field != "" && !containsString(keep, field) {
+			keep = append(keep, field)
+		}
+	}
+	if len(keep) == 0 {
+		return records
+	}
+
+	out := make([]map[string]interface{}, len(records))
+	for i, record := range records {
+		row := make(map[string]interface{}, len(keep))
+		for _, field := range keep {
+			row[field] = lookupPath(record, field)
+		}
+		out[i] = row
+	}
+	return out
+}
+
+// sortRecords sorts in place by the given criteria
+func sortRecords(records []map[string]interface{}, fields []string, criteria []models.SortCriteria) {
+	type key struct {
+		field string
+		desc  bool
+	}
+	var keys []key
+	for _, c := range criteria {
+		field := resolveFieldName(c.Field, fields)
+		if field == "" {
+			continue
+		}
+		keys = append(keys, key{field: field, desc: strings.EqualFold(c.Direction, "DESC")})
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		for _, k := range keys {
+			a, b := lookupPath(records[i], k.field), lookupPath(records[j], k.field)
+			if a == nil || b == nil {
+				if a == nil && b != nil {
+					return false // nulls last
+				}
+				if b == nil && a != nil {
+					return true
+				}
+				continue
+			}
+			cmp := compareValues(a, b)
+			if cmp == 0 {
+				continue
+			}
+			if k.desc {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return false
+	})
+}
+
+func headRecords(records []map[string]interface{}, limit int) []map[string]interface{} {
+	if len(records) > limit {
+		return records[:limit]
+	}
+	return records
+}
+
+func nilIfEmpty(count int, value float64) interface{} {
+	if count == 0 {
+		return nil
+	}
+	return value
+}
+
+// toFloat converts numeric values (and numeric strings) to float64
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case string:
+		f, err := strconv.ParseFloat(cleanNumber(strings.TrimSpace(n)), 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// toTime converts time values and date strings to time.Time
+func toTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case *time.Time:
+		if t != nil {
+			return *t, true
+		}
+	case string:
+		return parseDate(t)
+	}
+	return time.Time{}, false
+}
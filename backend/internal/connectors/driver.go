@@ -0,0 +1,289 @@
+package connectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+
+	"insightiq/backend/internal/models"
+)
+
+var (
+	// ErrUnsupportedConnectorType is returned when no driver is registered for a connector type
+	ErrUnsupportedConnectorType = errors.New("unsupported connector type")
+	// ErrDriverAlreadyRegistered is returned when a second driver claims the same connector type
+	ErrDriverAlreadyRegistered = errors.New("driver already registered")
+	// ErrNoMatchingData is returned when a source has nothing curated that answers the question
+	ErrNoMatchingData = errors.New("no dashboard or dataset matches the question")
+	// ErrQueryRejected wraps the error a database returned for a query, such as an unknown
+	// column, as opposed to connection failures
+	ErrQueryRejected = errors.New("query rejected by the database")
+)
+
+// Driver implements one kind of data source. Services never switch on connector
+// types; they look the driver up in a Registry and call it.
+type Driver interface {
+	// Type is the connector type the driver serves, e.g. "postgres"
+	Type() models.ConnectorType
+	// Name is a human readable name used in messages, e.g. "PostgreSQL"
+	Name() string
+	// ValidateConfig checks a connector configuration without connecting
+	ValidateConfig(config models.ConnectorConfig) error
+	// Test connects to the source and reports the datasets it exposes
+	Test(ctx context.Context, conn Connection) (*TestReport, error)
+	// ScanSchema describes the tables (or table-like objects) of the source
+	ScanSchema(ctx context.Context, conn Connection) ([]TableSchema, error)
+	// ExecuteQuery answers a question against the source. Results are capped at the
+	// connector's max_rows, with Truncated set when rows were dropped.
+	ExecuteQuery(ctx context.Context, req QueryRequest) (*QueryResult, error)
+	// Capabilities describes what the driver supports
+	Capabilities() Capabilities
+	// ConfigSchema is a JSON Schema describing the connector configuration
+	ConfigSchema() map[string]interface{}
+}
+
+// Connection identifies a configured connector passed to a driver
+type Connection struct {
+	ID     string // empty when testing a configuration that has not been saved
+	Name   string
+	Config models.ConnectorConfig
+}
+
+// TestReport describes a successful connection test
+type TestReport struct {
+	Message  string   // optional; defaults to "Successfully connected to <driver name>"
+	Datasets []string // tables, collections, endpoints or files the source exposes
+}
+
+// QueryRequest is a question to answer against one connector
+type QueryRequest struct {
+	Connection  Connection
+	Question    string
+	ParsedQuery *models.ParsedQuery // nil when the planner could not parse the question
+	SQL         string              // generated by the caller for drivers with a SQLDialect
+}
+
+// Capabilities describes what a driver supports, so callers can route and
+// prepare queries without knowing the connector type
+type Capabilities struct {
+	// SQL sources are relational databases
+	SQL bool `json:"sql"`
+	// SQLDialect is set when ExecuteQuery runs SQL generated by the caller in QueryRequest.SQL
+	SQLDialect string `json:"sql_dialect,omitempty"`
+	// Aggregation means parsed filters, group-bys and aggregations are evaluated by the driver
+	Aggregation bool `json:"aggregation"`
+	// Dashboards means the source holds curated dashboards and charts
+	Dashboards bool `json:"dashboards"`
+	// Sampling means schema scans include sample column values
+	Sampling bool `json:"sampling"`
+	// RoutingKeywords make the source a candidate for matching questions; empty means always a candidate
+	RoutingKeywords []string `json:"routing_keywords,omitempty"`
+	// SQLFallback means ExecuteQuery runs QueryRequest.SQL, written in the connector's
+	// FallbackSQLDialect, after a first attempt without SQL fails with ErrNoMatchingData
+	SQLFallback bool `json:"sql_fallback,omitempty"`
+}
+
+// FallbackSQLDialect is the dialect of SQL generated for drivers with SQLFallback,
+// set by the connector's sql_dialect and PostgreSQL by default
+func FallbackSQLDialect(config models.ConnectorConfig) string {
+	if dialect, ok := config["sql_dialect"].(string); ok && dialect != "" {
+		return dialect
+	}
+	return "PostgreSQL"
+}
+
+// RowStreamer is implemented by drivers that can return query rows as they are
+// read instead of collecting them first
+type RowStreamer interface {
+	StreamQuery(ctx context.Context, req QueryRequest) (RowIterator, error)
+}
+
+// QueryExplainer is implemented by drivers that can dry-run QueryRequest.SQL with
+// EXPLAIN, so generated SQL can be checked before it runs
+type QueryExplainer interface {
+	ExplainQuery(ctx context.Context, req QueryRequest) error
+}
+
+// StreamQuery runs a query through driver and returns its rows as an iterator,
+// streaming from the source when the driver supports it
+func StreamQuery(ctx context.Context, driver Driver, req QueryRequest) (RowIterator, error) {
+	if streamer, ok := driver.(RowStreamer); ok {
+		return streamer.StreamQuery(ctx, req)
+	}
+	result, err := driver.ExecuteQuery(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return result.Rows(), nil
+}
+
+// Registry holds the drivers available to the server, keyed by connector type
+type Registry struct {
+	mu      sync.RWMutex
+	drivers map[models.ConnectorType]Driver
+}
+
+// NewRegistry creates an empty driver registry
+func NewRegistry() *Registry {
+	return &Registry{drivers: make(map[models.ConnectorType]Driver)}
+}
+
+// NewDefaultRegistry creates a registry with the built-in drivers. fileStore may be nil
+// when file uploads are not configured.
+func NewDefaultRegistry(fileStore *FileStore, logger *slog.Logger) *Registry {
+	r := NewRegistry()
+	for _, driver := range []Driver{
+		NewSupersetDriver(logger),
+		NewPostgresDriver(logger),
+		NewMySQLDriver(logger),
+		NewMongoDBDriver(logger),
+		NewAPIDriver(logger),
+		NewFileDriver(fileStore, logger),
+	} {
+		r.MustRegister(driver)
+	}
+	return r
+}
+
+// Register adds a driver for its connector type
+func (r *Registry) Register(driver Driver) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.drivers[driver.Type()]; exists {
+		return fmt.Errorf("%w: %s", ErrDriverAlreadyRegistered, driver.Type())
+	}
+	r.drivers[driver.Type()] = driver
+	return nil
+}
+
+// MustRegister adds a driver and panics if its type is already registered
+func (r *Registry) MustRegister(driver Driver) {
+	if err := r.Register(driver); err != nil {
+		panic(err)
+	}
+}
+
+// Driver returns the driver for a connector type
+func (r *Registry) Driver(connectorType models.ConnectorType) (Driver, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	driver, ok := r.drivers[connectorType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedConnectorType, connectorType)
+	}
+	return driver, nil
+}
+
+// Drivers returns every registered driver ordered by connector type
+func (r *Registry) Drivers() []Driver {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	drivers := make([]Driver, 0, len(r.drivers))
+	for _, driver := range r.drivers {
+		drivers = append(drivers, driver)
+	}
+	sort.Slice(drivers, func(i, j int) bool { return drivers[i].Type() < drivers[j].Type() })
+	return drivers
+}
+
+// SensitiveFields returns the config fields a driver's schema marks writeOnly.
+// Their values are encrypted at rest and masked in API responses.
+func SensitiveFields(driver Driver) []string {
+	properties, _ := driver.ConfigSchema()["properties"].(map[string]interface{})
+
+	var fields []string
+	for name, prop := range properties {
+		if p, ok := prop.(map[string]interface{}); ok && p["writeOnly"] == true {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// SelectRelevantTable picks the table whose name and columns best match the question keywords.
+// A table named exactly in hints (e.g. parsed data sources) always wins.
+func SelectRelevantTable(tables []TableSchema, question string, hints []string) *TableSchema {
+	for _, hint := range hints {
+		for i := range tables {
+			if strings.EqualFold(tables[i].Name, hint) {
+				return &tables[i]
+			}
+		}
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(question), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != '_'
+	})
+
+	var best *TableSchema
+	bestScore := -1
+	for i := range tables {
+		table := &tables[i]
+		score := 0
+		tableName := strings.ToLower(table.Name)
+		for _, word := range words {
+			if len(word) < 3 {
+				continue
+			}
+			stem := strings.TrimSuffix(word, "s")
+			if strings.Contains(tableName, stem) {
+				score += 3
+			}
+			if strings.Contains(strings.ToLower(table.Description), stem) {
+				score++
+			}
+			for _, col := range table.Columns {
+				if strings.Contains(strings.ToLower(col.Name), stem) {
+					score++
+				}
+			}
+		}
+
+		if score > bestScore {
+			best = table
+			bestScore = score
+		}
+	}
+
+	return best
+}
+
+// configInt reads an optional numeric setting from a connector config
+func configInt(config map[string]interface{}, key string) int {
+	switch v := config[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	default:
+		return 0
+	}
+}
+
+// configFloat reads a numeric config value; ok is false when the key is unset
+func configFloat(config map[string]interface{}, key string) (float64, bool) {
+	switch v := config[key].(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// parsedQueryHints returns the data sources named by a parsed query, if any
+func parsedQueryHints(pq *models.ParsedQuery) []string {
+	if pq == nil {
+		return nil
+	}
+	return pq.DataSources
+}
@@ -0,0 +1,556 @@
+package connectors
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+var (
+	// ErrUnsupportedFileFormat is returned when an uploaded file has an unknown extension
+	ErrUnsupportedFileFormat = errors.New("unsupported file format")
+	// ErrFileNotFound is returned when a stored dataset file does not exist
+	ErrFileNotFound = errors.New("file not found")
+)
+
+const (
+	// Maximum rows loaded from a single file to bound memory use
+	maxFileRows = 200000
+	// Maximum length of a single JSON Lines record
+	maxJSONLineBytes = 1 << 20
+)
+
+// Supported dataset file formats keyed by extension
+var fileFormats = map[string]string{
+	".csv":    "csv",
+	".tsv":    "tsv",
+	".jsonl":  "jsonl",
+	".ndjson": "jsonl",
+	".xlsx":   "excel",
+}
+
+var (
+	safeFileNamePattern = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+	safeIDPattern       = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// Date layouts recognised when inferring column types from text
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	"2006/01/02",
+	"01/02/2006",
+	"1/2/2006",
+	"02-Jan-2006",
+	"Jan 2, 2006",
+}
+
+// FileStore keeps uploaded dataset files on local disk, one directory per connector
+type FileStore struct {
+	rootDir string
+	logger  *slog.Logger
+}
+
+// StoredFile describes an uploaded dataset file
+type StoredFile struct {
+	Name       string    `json:"name"`
+	Format     string    `json:"format"`
+	Size       int64     `json:"size"`
+	UploadedAt time.Time `json:"uploaded_at"`
+}
+
+// Dataset is a parsed file held in memory with typed values
+type Dataset struct {
+	Name    string                   `json:"name"`
+	Columns []ColumnSchema           `json:"columns"`
+	Rows    []map[string]interface{} `json:"-"`
+}
+
+func NewFileStore(rootDir string, logger *slog.Logger) *FileStore {
+	return &FileStore{
+		rootDir: rootDir,
+		logger:  logger.With("connector", "file"),
+	}
+}
+
+// FileFormat returns the dataset format for a file name, or "" when unsupported
+func FileFormat(name string) string {
+	return fileFormats[strings.ToLower(filepath.Ext(name))]
+}
+
+// connectorDir returns the storage directory for a connector
+// SECURITY: IDs are restricted to a safe character set to prevent path traversal
+func (fs *FileStore) connectorDir(connectorID string) (string, error) {
+	if !safeIDPattern.MatchString(connectorID) {
+		return "", fmt.Errorf("invalid connector id")
+	}
+	return filepath.Join(fs.rootDir, connectorID), nil
+}
+
+// filePath resolves a stored file name inside the connector directory
+func (fs *FileStore) filePath(connectorID, name string) (string, error) {
+	dir, err := fs.connectorDir(connectorID)
+	if err != nil {
+		return "", err
+	}
+	clean := filepath.Base(name)
+	if clean != name || clean == "." || clean == ".." {
+		return "", fmt.Errorf("invalid file name")
+	}
+	return filepath.Join(dir, clean), nil
+}
+
+// Save stores an uploaded file and verifies that it parses. Files that cannot be
+// parsed are removed again so scans never see broken datasets.
+func (fs *FileStore) Save(connectorID, filename string, r io.Reader) (*StoredFile, *Dataset, error) {
+	name := safeFileNamePattern.ReplaceAllString(filepath.Base(filename), "_")
+	format := FileFormat(name)
+	if format == "" {
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnsupportedFileFormat, filepath.Ext(filename))
+	}
+
+	dir, err := fs.connectorDir(connectorID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".upload-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	size, err := io.Copy(tmp, r)
+	closeErr := tmp.Close()
+	if err != nil || closeErr != nil {
+		os.Remove(tmp.Name())
+		return nil, nil, fmt.Errorf("failed to write upload: %w", errors.Join(err, closeErr))
+	}
+
+	dataset, err := parseDatasetFile(tmp.Name(), name, format)
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+
+	target := filepath.Join(dir, name)
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		os.Remove(tmp.Name())
+		return nil, nil, fmt.Errorf("failed to store file: %w", err)
+	}
+
+	fs.logger.Info("Stored dataset file",
+		"connector_id", connectorID,
+		"file", name,
+		"rows", len(dataset.Rows),
+		"columns", len(dataset.Columns))
+
+	return &StoredFile{Name: name, Format: format, Size: size, UploadedAt: time.Now()}, dataset, nil
+}
+
+// List returns the dataset files stored for a connector
+func (fs *FileStore) List(connectorID string) ([]StoredFile, error) {
+	dir, err := fs.connectorDir(connectorID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []StoredFile{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	files := []StoredFile{}
+	for _, entry := range entries {
+		format := FileFormat(entry.Name())
+		if entry.IsDir() || format == "" || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, StoredFile{
+			Name:       entry.Name(),
+			Format:     format,
+			Size:       info.Size(),
+			UploadedAt: info.ModTime(),
+		})
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files, nil
+}
+
+// Delete removes a stored dataset file
+func (fs *FileStore) Delete(connectorID, name string) error {
+	path, err := fs.filePath(connectorID, name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return ErrFileNotFound
+		}
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	fs.logger.Info("Deleted dataset file", "connector_id", connectorID, "file", name)
+	return nil
+}
+
+// DeleteAll removes every file stored for a connector
+func (fs *FileStore) DeleteAll(connectorID string) error {
+	dir, err := fs.connectorDir(connectorID)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// Load parses a single stored file
+func (fs *FileStore) Load(connectorID, name string) (*Dataset, error) {
+	path, err := fs.filePath(connectorID, name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, ErrFileNotFound
+	}
+	return parseDatasetFile(path, name, FileFormat(name))
+}
+
+// LoadAll parses every stored file for a connector, skipping files that fail to parse
+func (fs *FileStore) LoadAll(connectorID string) ([]*Dataset, error) {
+	files, err := fs.List(connectorID)
+	if err != nil {
+		return nil, err
+	}
+
+	var datasets []*Dataset
+	for _, file := range files {
+		dataset, err := fs.Load(connectorID, file.Name)
+		if err != nil {
+			fs.logger.Warn("Failed to parse dataset file", "connector_id", connectorID, "file", file.Name, "error", err)
+			continue
+		}
+		datasets = append(datasets, dataset)
+	}
+	return datasets, nil
+}
+
+// TableSchemas describes each stored file as a table
+func (fs *FileStore) TableSchemas(connectorID string) ([]TableSchema, error) {
+	datasets, err := fs.LoadAll(connectorID)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := make([]TableSchema, 0, len(datasets))
+	for _, ds := range datasets {
+		tables = append(tables, ds.TableSchema())
+	}
+	return tables, nil
+}
+
+// TableSchema describes the dataset as a table named after the file
+func (ds *Dataset) TableSchema() TableSchema {
+	return TableSchema{
+		Name:        DatasetTableName(ds.Name),
+		Description: fmt.Sprintf("Uploaded file %s (%d rows)", ds.Name, len(ds.Rows)),
+		Columns:     ds.Columns,
+	}
+}
+
+// DatasetTableName derives a table name from a file name ("Q3 sales.csv" -> "q3_sales")
+func DatasetTableName(fileName string) string {
+	base := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	base = strings.ToLower(safeFileNamePattern.ReplaceAllString(base, "_"))
+	return strings.Trim(strings.ReplaceAll(base, ".", "_"), "_")
+}
+
+// parseDatasetFile parses a file according to its format and types its columns
+func parseDatasetFile(path, name, format string) (*Dataset, error) {
+	switch format {
+	case "csv", "tsv":
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return datasetFromDelimited(name, f, format == "tsv")
+
+	case "excel":
+		f, err := excelize.OpenFile(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		sheets := f.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, fmt.Errorf("workbook has no sheets")
+		}
+		records, err := f.GetRows(sheets[0])
+		if err != nil {
+			return nil, err
+		}
+		if len(records) > maxFileRows+1 {
+			records = records[:maxFileRows+1]
+		}
+		return datasetFromTable(name, records)
+
+	case "jsonl":
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return datasetFromJSONLines(name, f)
+
+	default:
+		return nil, ErrUnsupportedFileFormat
+	}
+}
+
+// datasetFromDelimited builds a dataset from comma- or tab-separated text
+func datasetFromDelimited(name string, r io.Reader, tabs bool) (*Dataset, error) {
+	reader := csv.NewReader(bufio.NewReader(r))
+	if tabs {
+		reader.Comma = '\t'
+		reader.LazyQuotes = true
+	}
+	reader.FieldsPerRecord = -1
+
+	var records [][]string
+	for len(records) <= maxFileRows {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return datasetFromTable(name, records)
+}
+
+// datasetFromTable builds a dataset from a header row plus string cells
+func datasetFromTable(name string, records [][]string) (*Dataset, error) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("file is empty")
+	}
+
+	header := make([]string, len(records[0]))
+	seen := make(map[string]int)
+	for i, h := range records[0] {
+		h = strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))
+		if h == "" {
+			h = fmt.Sprintf("column_%d", i+1)
+		}
+		if n := seen[h]; n > 0 {
+			h = fmt.Sprintf("%s_%d", h, n+1)
+		}
+		seen[h]++
+		header[i] = h
+	}
+
+	// Infer a type per column from all non-empty cells
+	types := make([]string, len(header))
+	for col := range header {
+		var values []string
+		for _, record := range records[1:] {
+			if col < len(record) {
+				values = append(values, record[col])
+			}
+		}
+		types[col] = inferTextColumnType(values)
+	}
+
+	columns := make([]ColumnSchema, len(header))
+	for i, h := range header {
+		columns[i] = ColumnSchema{Name: h, DataType: types[i], Nullable: true}
+	}
+
+	rows := make([]map[string]interface{}, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]interface{}, len(header))
+		for i, h := range header {
+			if i < len(record) {
+				row[h] = convertTextValue(record[i], types[i])
+			} else {
+				row[h] = nil
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return &Dataset{Name: name, Columns: columns, Rows: rows}, nil
+}
+
+// datasetFromJSONLines builds a dataset from one JSON object per line
+func datasetFromJSONLines(name string, r io.Reader) (*Dataset, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxJSONLineBytes)
+
+	var rows []map[string]interface{}
+	lineNum := 0
+	for scanner.Scan() && len(rows) < maxFileRows {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var row map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &row); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("file is empty")
+	}
+
+	columns := inferDocumentColumns(rows)
+	for i := range columns {
+		if columns[i].DataType == "double" && allIntegral(rows, columns[i].Name) {
+			columns[i].DataType = "int"
+		}
+		// Parse date strings so filters and time ranges compare real times
+		if columns[i].DataType == "date" {
+			for _, row := range rows {
+				if s, ok := row[columns[i].Name].(string); ok {
+					if t, ok := parseDate(s); ok {
+						row[columns[i].Name] = t
+					}
+				}
+			}
+		}
+	}
+
+	return &Dataset{Name: name, Columns: columns, Rows: rows}, nil
+}
+
+// inferTextColumnType picks the narrowest type that every non-empty value satisfies
+func inferTextColumnType(values []string) string {
+	isInt, isFloat, isBool, isDate := true, true, true, true
+	nonEmpty := 0
+
+	for _, raw := range values {
+		v := strings.TrimSpace(raw)
+		if v == "" {
+			continue
+		}
+		nonEmpty++
+
+		if isInt {
+			if _, err := strconv.ParseInt(cleanNumber(v), 10, 64); err != nil {
+				isInt = false
+			}
+		}
+		if isFloat {
+			if _, err := strconv.ParseFloat(cleanNumber(v), 64); err != nil {
+				isFloat = false
+			}
+		}
+		if isBool {
+			switch strings.ToLower(v) {
+			case "true", "false", "yes", "no":
+			default:
+				isBool = false
+			}
+		}
+		if isDate {
+			if _, ok := parseDate(v); !ok {
+				isDate = false
+			}
+		}
+		if !isInt && !isFloat && !isBool && !isDate {
+			break
+		}
+	}
+
+	switch {
+	case nonEmpty == 0:
+		return "string"
+	case isInt:
+		return "int"
+	case isFloat:
+		return "double"
+	case isBool:
+		return "bool"
+	case isDate:
+		return "date"
+	default:
+		return "string"
+	}
+}
+
+// convertTextValue converts a cell to the inferred column type
+func convertTextValue(raw, dataType string) interface{} {
+	v := strings.TrimSpace(raw)
+	if v == "" {
+		return nil
+	}
+
+	switch dataType {
+	case "int":
+		if n, err := strconv.ParseInt(cleanNumber(v), 10, 64); err == nil {
+			return n
+		}
+	case "double":
+		if f, err := strconv.ParseFloat(cleanNumber(v), 64); err == nil && !math.IsInf(f, 0) {
+			return f
+		}
+	case "bool":
+		switch strings.ToLower(v) {
+		case "true", "yes":
+			return true
+		case "false", "no":
+			return false
+		}
+	case "date":
+		if t, ok := parseDate(v); ok {
+			return t
+		}
+	}
+	return v
+}
+
+// cleanNumber strips thousands separators and currency symbols
+func cleanNumber(v string) string {
+	return strings.NewReplacer(",", "", "$", "", "€", "", "£", "").Replace(v)
+}
+
+// parseDate tries the known date layouts
+func parseDate(v string) (time.Time, bool) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,214 @@
+package connectors
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"insightiq/backend/internal/models"
+)
+
+// TestInferTextColumnType tests choosing the narrowest type every value satisfies
+func TestInferTextColumnType(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   string
+	}{
+		{"integers with blanks", []string{"1", "", " 42 ", "-7"}, "int"},
+		{"thousands separators", []string{"1,200", "$3,400"}, "int"},
+		{"decimals", []string{"1", "2.5", "€3.75"}, "double"},
+		{"booleans", []string{"true", "No", "YES"}, "bool"},
+		{"iso dates", []string{"2024-01-31", "2024-02-01T10:00:00Z"}, "date"},
+		{"us dates", []string{"1/2/2024", "12/31/2024"}, "date"},
+		{"mixed", []string{"12", "north"}, "string"},
+		{"all empty", []string{"", "  "}, "string"},
+		{"no values", nil, "string"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inferTextColumnType(tt.values); got != tt.want {
+				t.Errorf("inferTextColumnType(%q) = %q, want %q", tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDatasetFromDelimited tests typing CSV and TSV columns and converting cells
+func TestDatasetFromDelimited(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		tabs     bool
+		wantCols []ColumnSchema
+		wantRows []map[string]interface{}
+	}{
+		{
+			name:  "csv",
+			input: "\ufeffregion,amount,,active,ordered\nnorth,\"1,200\",x,yes,2024-03-01\nsouth,,y,no\n",
+			wantCols: []ColumnSchema{
+				{Name: "region", DataType: "string", Nullable: true},
+				{Name: "amount", DataType: "int", Nullable: true},
+				{Name: "column_3", DataType: "string", Nullable: true},
+				{Name: "active", DataType: "bool", Nullable: true},
+				{Name: "ordered", DataType: "date", Nullable: true},
+			},
+			wantRows: []map[string]interface{}{
+				{"region": "north", "amount": int64(1200), "column_3": "x", "active": true, "ordered": time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+				{"region": "south", "amount": nil, "column_3": "y", "active": false, "ordered": nil},
+			},
+		},
+		{
+			name:  "tsv with stray quotes and duplicate headers",
+			input: "name\tname\tprice\nsay \"hi\"\ta\t2.5\nb\tc\t3\n",
+			tabs:  true,
+			wantCols: []ColumnSchema{
+				{Name: "name", DataType: "string", Nullable: true},
+				{Name: "name_2", DataType: "string", Nullable: true},
+				{Name: "price", DataType: "double", Nullable: true},
+			},
+			wantRows: []map[string]interface{}{
+				{"name": `say "hi"`, "name_2": "a", "price": 2.5},
+				{"name": "b", "name_2": "c", "price": 3.0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds, err := datasetFromDelimited("orders", strings.NewReader(tt.input), tt.tabs)
+			if err != nil {
+				t.Fatalf("datasetFromDelimited() error = %v", err)
+			}
+			if !reflect.DeepEqual(ds.Columns, tt.wantCols) {
+				t.Errorf("columns = %+v, want %+v", ds.Columns, tt.wantCols)
+			}
+			if !reflect.DeepEqual(ds.Rows, tt.wantRows) {
+				t.Errorf("rows = %v, want %v", ds.Rows, tt.wantRows)
+			}
+		})
+	}
+
+	if _, err := datasetFromDelimited("empty", strings.NewReader(""), false); err == nil {
+		t.Error("an empty file was accepted")
+	}
+}
+
+// TestDatasetFromJSONLines tests typing JSON Lines records
+func TestDatasetFromJSONLines(t *testing.T) {
+	input := `{"id": 1, "price": 2.5, "ordered": "2024-03-01T10:00:00Z", "customer": {"country": "FR"}}
+
+{"id": 2, "price": 4, "ordered": "2024-04-01T10:00:00Z"}
+`
+	ds, err := datasetFromJSONLines("orders", strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("datasetFromJSONLines() error = %v", err)
+	}
+
+	types := make(map[string]string)
+	for _, col := range ds.Columns {
+		types[col.Name] = col.DataType
+	}
+	want := map[string]string{"id": "int", "price": "double", "ordered": "date", "customer.country": "string"}
+	if !reflect.DeepEqual(types, want) {
+		t.Errorf("column types = %v, want %v", types, want)
+	}
+	if _, ok := ds.Rows[1]["ordered"].(time.Time); !ok {
+		t.Errorf("ordered = %T, want dates parsed to time.Time", ds.Rows[1]["ordered"])
+	}
+
+	if _, err := datasetFromJSONLines("bad", strings.NewReader("{\"id\": 1}\nnot json\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("error = %v, want the bad line reported", err)
+	}
+}
+
+// TestQueryRecords tests filtering, grouping, sorting and limiting in-memory records
+func TestQueryRecords(t *testing.T) {
+	ds, err := datasetFromDelimited("orders", strings.NewReader(`region,total_revenue,ordered_at
+north,100,2024-03-01
+south,250,2024-03-15
+north,50,2024-04-02
+east,,2024-03-20
+south,30,2024-02-10
+`), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	two, ten := 2, 10
+	march := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	april := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		pq   *models.ParsedQuery
+		want []map[string]interface{}
+	}{
+		{
+			"filter and project",
+			&models.ParsedQuery{
+				Metrics: []string{"revenue"},
+				Filters: []models.Filter{{Field: "revenue", Operator: ">=", Value: "100"}},
+			},
+			[]map[string]interface{}{{"total_revenue": int64(100)}, {"total_revenue": int64(250)}},
+		},
+		{
+			"or filters",
+			&models.ParsedQuery{
+				Dimensions:   []string{"region"},
+				Aggregations: []models.Aggregation{{Function: "COUNT", Field: "*", Alias: "orders"}},
+				Filters: []models.Filter{
+					{Field: "Region", Operator: "=", Value: "EAST", Condition: "OR"},
+					{Field: "region", Operator: "IN", Value: []string{"north"}, Condition: "OR"},
+				},
+			},
+			[]map[string]interface{}{{"region": "north", "orders": 2}, {"region": "east", "orders": 1}},
+		},
+		{
+			"time range grouped and sorted",
+			&models.ParsedQuery{
+				Metrics:   []string{"revenue"},
+				GroupBy:   []string{"region"},
+				TimeRange: &models.TimeRange{Start: &march, End: &april},
+				SortBy:    []models.SortCriteria{{Field: "sum_total_revenue", Direction: "DESC"}},
+			},
+			[]map[string]interface{}{
+				{"region": "south", "sum_total_revenue": 250.0},
+				{"region": "north", "sum_total_revenue": 100.0},
+				{"region": "east", "sum_total_revenue": 0.0},
+			},
+		},
+		{
+			"averages skip missing values",
+			&models.ParsedQuery{
+				GroupBy:      []string{"region"},
+				Aggregations: []models.Aggregation{{Function: "AVG", Field: "total_revenue", Alias: "avg"}, {Function: "MAX", Field: "total_revenue", Alias: "max"}},
+				SortBy:       []models.SortCriteria{{Field: "avg"}},
+				Limit:        &two,
+			},
+			[]map[string]interface{}{
+				{"region": "north", "avg": 75.0, "max": 100.0},
+				{"region": "south", "avg": 140.0, "max": 250.0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := QueryRecords(ds.Rows, ds.Columns, tt.pq, 100); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("QueryRecords() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := QueryRecords(ds.Rows, ds.Columns, nil, 100); len(got) != len(ds.Rows) {
+		t.Errorf("QueryRecords(nil) returned %d records, want all %d", len(got), len(ds.Rows))
+	}
+
+	// One record past max_rows is kept so the driver can report truncation
+	result := limitResult(QueryRecords(ds.Rows, ds.Columns, &models.ParsedQuery{Limit: &ten}, 3), models.ConnectorConfig{"max_rows": 3})
+	if len(result.Data) != 3 || !result.Truncated {
+		t.Errorf("QueryRecords() over max_rows returned %d records, truncated = %v; want 3, true", len(result.Data), result.Truncated)
+	}
+}
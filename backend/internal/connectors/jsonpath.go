@@ -0,0 +1,162 @@
+package connectors
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPathStep is a single navigation step of a parsed JSONPath expression
+type jsonPathStep struct {
+	key      string // object member name
+	index    int    // array index, used when isIndex is set
+	isIndex  bool
+	wildcard bool // [*] or .*
+}
+
+// parseJSONPath parses the JSONPath subset used by API endpoint mappings:
+// $, .name, ['name'], [n] and the [*] / .* wildcards
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	path = strings.TrimSpace(path)
+	if path == "" || path == "$" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("JSONPath must start with $: %q", path)
+	}
+
+	var steps []jsonPathStep
+	i := 1
+	for i < len(path) {
+		switch path[i] {
+		case '.':
+			i++
+			start := i
+			for i < len(path) && path[i] != '.' && path[i] != '[' {
+				i++
+			}
+			name := path[start:i]
+			if name == "" {
+				return nil, fmt.Errorf("empty member name in JSONPath %q", path)
+			}
+			if name == "*" {
+				steps = append(steps, jsonPathStep{wildcard: true})
+			} else {
+				steps = append(steps, jsonPathStep{key: name})
+			}
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated bracket in JSONPath %q", path)
+			}
+			inner := strings.TrimSpace(path[i+1 : i+end])
+			i += end + 1
+
+			switch {
+			case inner == "*":
+				steps = append(steps, jsonPathStep{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				steps = append(steps, jsonPathStep{key: inner[1 : len(inner)-1]})
+			default:
+				n, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("unsupported JSONPath selector [%s] in %q", inner, path)
+				}
+				steps = append(steps, jsonPathStep{index: n, isIndex: true})
+			}
+		default:
+			return nil, fmt.Errorf("unexpected character %q in JSONPath %q", path[i], path)
+		}
+	}
+
+	return steps, nil
+}
+
+// evaluateJSONPath returns every value matched by path within doc
+func evaluateJSONPath(doc interface{}, path string) ([]interface{}, error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	current := []interface{}{doc}
+	for _, step := range steps {
+		var next []interface{}
+		for _, node := range current {
+			switch v := node.(type) {
+			case map[string]interface{}:
+				if step.wildcard {
+					for _, child := range v {
+						next = append(next, child)
+					}
+				} else if !step.isIndex {
+					if child, ok := v[step.key]; ok {
+						next = append(next, child)
+					}
+				}
+			case []interface{}:
+				switch {
+				case step.wildcard:
+					next = append(next, v...)
+				case step.isIndex:
+					idx := step.index
+					if idx < 0 {
+						idx += len(v)
+					}
+					if idx >= 0 && idx < len(v) {
+						next = append(next, v[idx])
+					}
+				}
+			}
+		}
+		current = next
+	}
+
+	return current, nil
+}
+
+// extractRecords resolves the records array of a response. A path that matches a
+// single array yields its elements; otherwise every matched object is a record.
+func extractRecords(doc interface{}, path string) ([]map[string]interface{}, error) {
+	matches, err := evaluateJSONPath(doc, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(matches) == 1 {
+		if arr, ok := matches[0].([]interface{}); ok {
+			matches = arr
+		}
+	}
+
+	records := make([]map[string]interface{}, 0, len(matches))
+	for _, match := range matches {
+		switch v := match.(type) {
+		case map[string]interface{}:
+			records = append(records, v)
+		case nil:
+			// Skip nulls in sparse arrays
+		default:
+			records = append(records, map[string]interface{}{"value": v})
+		}
+	}
+
+	return records, nil
+}
+
+// extractScalar returns the first value matched by path as a string, or "" when absent
+func extractScalar(doc interface{}, path string) string {
+	matches, err := evaluateJSONPath(doc, path)
+	if err != nil || len(matches) == 0 || matches[0] == nil {
+		return ""
+	}
+
+	switch v := matches[0].(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
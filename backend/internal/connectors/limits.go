@@ -0,0 +1,137 @@
+package connectors
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"insightiq/backend/internal/models"
+	"insightiq/backend/internal/sqlparse"
+)
+
+const (
+	// DefaultMaxRows caps query results when a connector does not set max_rows
+	DefaultMaxRows = 10000
+	// DefaultMaxEstimatedCost refuses PostgreSQL queries the planner expects to cost
+	// more than this, in planner cost units, when a connector does not set max_estimated_cost
+	DefaultMaxEstimatedCost = 1_000_000
+)
+
+// QueryLimits bounds what a single connector query may read
+type QueryLimits struct {
+	// Rows returned before the result is truncated
+	MaxRows int
+	// Planner estimates above which a query is refused before it runs; zero disables
+	// the check. Only PostgreSQL connectors run the planner check.
+	MaxEstimatedCost float64
+	MaxEstimatedRows float64
+}
+
+// DefaultQueryLimits returns the limits applied when a connector sets none
+func DefaultQueryLimits() QueryLimits {
+	return QueryLimits{MaxRows: DefaultMaxRows, MaxEstimatedCost: DefaultMaxEstimatedCost}
+}
+
+// QueryLimitsFromConfig reads max_rows, max_estimated_cost and max_estimated_rows
+// from a connector configuration, falling back to the defaults
+func QueryLimitsFromConfig(config models.ConnectorConfig) QueryLimits {
+	limits := DefaultQueryLimits()
+	if n := configInt(config, "max_rows"); n > 0 {
+		limits.MaxRows = n
+	}
+	if cost, ok := configFloat(config, "max_estimated_cost"); ok {
+		limits.MaxEstimatedCost = cost
+	}
+	if rows, ok := configFloat(config, "max_estimated_rows"); ok {
+		limits.MaxEstimatedRows = rows
+	}
+	return limits
+}
+
+// limitQuery rewrites a validated query to fetch one row more than maxRows, so
+// truncation can be detected after scanning. It reports whether a LIMIT was added.
+func limitQuery(query string, stmt sqlparse.Statement, maxRows int, dialect sqlparse.Dialect) (string, bool) {
+	return sqlparse.WithRowLimit(query, stmt, maxRows+1, dialect)
+}
+
+// readLimit is how many rows a driver reads for a query that asks for limit rows, or
+// for any number when limit is not positive. Reads stop one row past maxRows, so
+// limitResult can tell that rows were left.
+func readLimit(limit, maxRows int) int {
+	if limit > 0 && limit <= maxRows {
+		return limit
+	}
+	return maxRows + 1
+}
+
+// truncateRows cuts data to maxRows and reports whether rows were dropped
+func truncateRows(data []map[string]interface{}, maxRows int) ([]map[string]interface{}, bool) {
+	if maxRows <= 0 || len(data) <= maxRows {
+		return data, false
+	}
+	return data[:maxRows], true
+}
+
+// limitResult truncates a driver result to the connector's max_rows and infers
+// its columns from the records
+func limitResult(data []map[string]interface{}, config models.ConnectorConfig) *QueryResult {
+	data, truncated := truncateRows(data, QueryLimitsFromConfig(config).MaxRows)
+	return &QueryResult{Columns: columnsFromRecords(data), Data: data, Truncated: truncated}
+}
+
+// explainPlan is a node of EXPLAIN (FORMAT JSON) output
+type explainPlan struct {
+	NodeType  string        `json:"Node Type"`
+	TotalCost float64       `json:"Total Cost"`
+	PlanRows  float64       `json:"Plan Rows"`
+	Plans     []explainPlan `json:"Plans"`
+}
+
+// explainQuery asks the PostgreSQL planner for the plan of query, with its bind
+// parameters, without running it
+func explainQuery(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) (*explainPlan, error) {
+	var raw []byte
+	if err := tx.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) "+query, args...).Scan(&raw); err != nil {
+		if rejectedByPostgres(err) {
+			return nil, fmt.Errorf("%w: %w", ErrQueryRejected, err)
+		}
+		return nil, fmt.Errorf("failed to plan query: %w", err)
+	}
+
+	var explained []struct {
+		Plan explainPlan `json:"Plan"`
+	}
+	if err := json.Unmarshal(raw, &explained); err != nil {
+		return nil, fmt.Errorf("failed to read query plan: %w", err)
+	}
+	if len(explained) == 0 {
+		return nil, fmt.Errorf("failed to read query plan: empty EXPLAIN output")
+	}
+	return &explained[0].Plan, nil
+}
+
+// checkPlanCost refuses a plan whose estimated cost or row count exceeds the limits.
+// When limited is set the query carries the LIMIT added by limitQuery, and rows are
+// read below that node.
+func checkPlanCost(plan *explainPlan, limited bool, limits QueryLimits) error {
+	rows := plan.PlanRows
+	if limited && plan.NodeType == "Limit" && len(plan.Plans) > 0 {
+		rows = plan.Plans[0].PlanRows
+	}
+
+	if limits.MaxEstimatedCost > 0 && plan.TotalCost > limits.MaxEstimatedCost {
+		return fmt.Errorf("%w: the planner estimates a cost of %.0f, above this connector's limit of %.0f; add filters or aggregate the data to make the query cheaper",
+			ErrQueryTooExpensive, plan.TotalCost, limits.MaxEstimatedCost)
+	}
+	if limits.MaxEstimatedRows > 0 && rows > limits.MaxEstimatedRows {
+		return fmt.Errorf("%w: the planner estimates the query returns %.0f rows, above this connector's limit of %.0f; add filters or aggregate the data",
+			ErrQueryTooExpensive, rows, limits.MaxEstimatedRows)
+	}
+	return nil
+}
+
+// checksCost reports whether any planner threshold is set
+func (l QueryLimits) checksCost() bool {
+	return l.MaxEstimatedCost > 0 || l.MaxEstimatedRows > 0
+}
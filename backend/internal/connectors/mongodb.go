@@ -0,0 +1,659 @@
+package connectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+
+	"insightiq/backend/internal/models"
+)
+
+// ErrDangerousPipeline is returned when an aggregation pipeline contains write or code-execution stages
+var ErrDangerousPipeline = errors.New("pipeline contains write or code-execution operators")
+
+const (
+	// Documents sampled per collection when inferring columns
+	defaultMongoSampleSize = 100
+	// Maximum nesting depth flattened into dotted column names
+	maxMongoFieldDepth = 2
+	// Server-side time limit for a single aggregation
+	mongoQueryTimeout = 30 * time.Second
+)
+
+// Aggregation operators that write data or run arbitrary JavaScript
+var disallowedPipelineOperators = map[string]bool{
+	"$out":         true,
+	"$merge":       true,
+	"$function":    true,
+	"$accumulator": true,
+	"$where":       true,
+	"$currentOp":   true,
+}
+
+type MongoDBConnector struct {
+	client   *mongo.Client
+	database string
+	logger   *slog.Logger
+}
+
+// NewMongoDBConnector connects to MongoDB. When database is empty, the database
+// named in the connection string path is used.
+func NewMongoDBConnector(uri, database string, logger *slog.Logger) (*MongoDBConnector, error) {
+	cs, err := connstring.ParseAndValidate(uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MongoDB connection string: %w", err)
+	}
+	if database == "" {
+		database = cs.Database
+	}
+	if database == "" {
+		return nil, fmt.Errorf("database is required for MongoDB connector")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri).SetConnectTimeout(10*time.Second))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to MongoDB: %w", err)
+	}
+
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(context.Background())
+		return nil, fmt.Errorf("failed to ping MongoDB: %w", err)
+	}
+
+	return &MongoDBConnector{
+		client:   client,
+		database: database,
+		logger:   logger.With("connector", "mongodb"),
+	}, nil
+}
+
+func (mc *MongoDBConnector) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return mc.client.Disconnect(ctx)
+}
+
+// TestConnection verifies the server is reachable and the database can be listed
+func (mc *MongoDBConnector) TestConnection(ctx context.Context) ([]string, error) {
+	if err := mc.client.Ping(ctx, nil); err != nil {
+		return nil, fmt.Errorf("failed to ping MongoDB: %w", err)
+	}
+	return mc.ListCollections(ctx)
+}
+
+// ListCollections returns the non-system collections of the configured database
+func (mc *MongoDBConnector) ListCollections(ctx context.Context) ([]string, error) {
+	names, err := mc.client.Database(mc.database).ListCollectionNames(ctx, bson.M{
+		"name": bson.M{"$not": primitive.Regex{Pattern: "^system\\."}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collections: %w", err)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// SampleDocuments returns up to n randomly sampled documents from a collection
+func (mc *MongoDBConnector) SampleDocuments(ctx context.Context, collection string, n int) ([]map[string]interface{}, error) {
+	if n <= 0 {
+		n = defaultMongoSampleSize
+	}
+	return mc.Aggregate(ctx, collection, []bson.M{{"$sample": bson.M{"size": n}}})
+}
+
+// InferCollectionSchemas samples each collection and infers a column per
+// (dotted) field path, treating each collection as a table
+func (mc *MongoDBConnector) InferCollectionSchemas(ctx context.Context, sampleSize int) ([]TableSchema, error) {
+	collections, err := mc.ListCollections(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var tables []TableSchema
+	for _, collection := range collections {
+		docs, err := mc.SampleDocuments(ctx, collection, sampleSize)
+		if err != nil {
+			mc.logger.Warn("Failed to sample collection", "collection", collection, "error", err)
+			continue
+		}
+
+		tables = append(tables, TableSchema{
+			Schema:      mc.database,
+			Name:        collection,
+			Description: fmt.Sprintf("MongoDB collection %s (%d documents sampled)", collection, len(docs)),
+			Columns:     inferDocumentColumns(docs),
+		})
+	}
+
+	mc.logger.Info("Inferred MongoDB collection schemas", "collections", len(tables))
+	return tables, nil
+}
+
+// Aggregate runs a read-only aggregation pipeline and returns normalized rows
+// SECURITY: Pipelines containing $out, $merge or JavaScript operators are rejected
+func (mc *MongoDBConnector) Aggregate(ctx context.Context, collection string, pipeline []bson.M) ([]map[string]interface{}, error) {
+	if err := ValidatePipeline(pipeline); err != nil {
+		mc.logger.Warn("Pipeline validation failed", "collection", collection, "error", err)
+		return nil, fmt.Errorf("pipeline validation failed: %w", err)
+	}
+
+	mc.logger.Info("Executing aggregation pipeline", "collection", collection, "stages", len(pipeline))
+
+	opts := options.Aggregate().SetMaxTime(mongoQueryTimeout)
+	cursor, err := mc.client.Database(mc.database).Collection(collection).Aggregate(ctx, pipeline, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute aggregation: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var docs []bson.M
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to read aggregation results: %w", err)
+	}
+
+	data := make([]map[string]interface{}, 0, len(docs))
+	for _, doc := range docs {
+		data = append(data, normalizeBSONValue(doc).(map[string]interface{}))
+	}
+
+	mc.logger.Info("Aggregation executed successfully", "rows", len(data))
+	return data, nil
+}
+
+// ValidatePipeline rejects pipelines that could modify data or execute code
+func ValidatePipeline(pipeline []bson.M) error {
+	for _, stage := range pipeline {
+		if err := validatePipelineValue(stage); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validatePipelineValue(value interface{}) error {
+	switch v := value.(type) {
+	case bson.M:
+		return validatePipelineValue(map[string]interface{}(v))
+	case map[string]interface{}:
+		for key, inner := range v {
+			if disallowedPipelineOperators[key] {
+				return fmt.Errorf("%w: %s", ErrDangerousPipeline, key)
+			}
+			if err := validatePipelineValue(inner); err != nil {
+				return err
+			}
+		}
+	case bson.D:
+		for _, elem := range v {
+			if disallowedPipelineOperators[elem.Key] {
+				return fmt.Errorf("%w: %s", ErrDangerousPipeline, elem.Key)
+			}
+			if err := validatePipelineValue(elem.Value); err != nil {
+				return err
+			}
+		}
+	case bson.A:
+		return validatePipelineValue([]interface{}(v))
+	case []interface{}:
+		for _, inner := range v {
+			if err := validatePipelineValue(inner); err != nil {
+				return err
+			}
+		}
+	case []bson.M:
+		for _, inner := range v {
+			if err := validatePipelineValue(inner); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// BuildAggregationPipeline translates a parsed query into a read-only aggregation
+// pipeline. Metric, dimension and filter names are resolved against the sampled
+// columns so loosely named intents ("revenue") map onto real fields ("total_revenue").
+// The pipeline returns at most maxRows+1 documents, so truncation can be detected.
+func BuildAggregationPipeline(pq *models.ParsedQuery, columns []ColumnSchema, maxRows int) []bson.M {
+	if pq == nil {
+		return []bson.M{{"$limit": readLimit(0, maxRows)}}
+	}
+	limit := 0
+	if pq.Limit != nil {
+		limit = *pq.Limit
+	}
+	limit = readLimit(limit, maxRows)
+
+	fields := make([]string, 0, len(columns))
+	for _, col := range columns {
+		fields = append(fields, col.Name)
+	}
+
+	var pipeline []bson.M
+
+	// Filters and time range become a single $match stage
+	match := bson.M{}
+	var orClauses []bson.M
+	for _, filter := range pq.Filters {
+		field := resolveFieldName(filter.Field, fields)
+		if field == "" {
+			continue
+		}
+		clause := bson.M{field: mongoCondition(filter.Operator, filter.Value)}
+		if strings.EqualFold(filter.Condition, "OR") {
+			orClauses = append(orClauses, clause)
+		} else {
+			mergeMatch(match, field, clause[field])
+		}
+	}
+	if len(orClauses) > 0 {
+		match["$or"] = orClauses
+	}
+	if pq.TimeRange != nil && (pq.TimeRange.Start != nil || pq.TimeRange.End != nil) {
+		if timeField := firstDatetimeColumn(columns); timeField != "" {
+			match["$and"] = bson.A{mongoTimeRange(timeField, pq.TimeRange.Start, pq.TimeRange.End)}
+		}
+	}
+	if len(match) > 0 {
+		pipeline = append(pipeline, bson.M{"$match": match})
+	}
+
+	// Group-by dimensions; fall back to dimensions when no explicit group-by was parsed
+	groupNames := pq.GroupBy
+	if len(groupNames) == 0 {
+		groupNames = pq.Dimensions
+	}
+	var groupFields []string
+	for _, name := range groupNames {
+		if field := resolveFieldName(name, fields); field != "" && !containsString(groupFields, field) {
+			groupFields = append(groupFields, field)
+		}
+	}
+
+	aggregations := pq.Aggregations
+	if len(aggregations) == 0 && len(groupFields) > 0 {
+		// Default to summing the requested metrics, or counting rows
+		for _, metric := range pq.Metrics {
+			aggregations = append(aggregations, models.Aggregation{Function: "SUM", Field: metric})
+		}
+		if len(aggregations) == 0 {
+			aggregations = append(aggregations, models.Aggregation{Function: "COUNT", Field: "*", Alias: "count"})
+		}
+	}
+
+	// Fields available to $sort once the pipeline has reshaped the documents
+	outputFields := fields
+
+	if len(aggregations) > 0 {
+		outputFields = nil
+		groupID := interface{}(nil)
+		if len(groupFields) > 0 {
+			id := bson.M{}
+			for _, field := range groupFields {
+				id[mongoAlias(field)] = "$" + field
+			}
+			groupID = id
+		}
+
+		group := bson.M{"_id": groupID}
+		project := bson.M{"_id": 0}
+		for _, field := range groupFields {
+			project[mongoAlias(field)] = "$_id." + mongoAlias(field)
+			outputFields = append(outputFields, mongoAlias(field))
+		}
+
+		for _, agg := range aggregations {
+			field := resolveFieldName(agg.Field, fields)
+			fn := strings.ToLower(agg.Function)
+			alias := agg.Alias
+			if alias == "" {
+				alias = mongoAlias(fn + "_" + firstNonEmpty(field, "records"))
+			}
+
+			switch {
+			case fn == "count" && (field == "" || agg.Field == "*"):
+				group[alias] = bson.M{"$sum": 1}
+			case fn == "count":
+				group[alias] = bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$ifNull": bson.A{"$" + field, false}}, 1, 0}}}
+			case field == "":
+				continue
+			case fn == "avg" || fn == "average":
+				group[alias] = bson.M{"$avg": "$" + field}
+			case fn == "min":
+				group[alias] = bson.M{"$min": "$" + field}
+			case fn == "max":
+				group[alias] = bson.M{"$max": "$" + field}
+			default:
+				group[alias] = bson.M{"$sum": "$" + field}
+			}
+			project[alias] = 1
+			outputFields = append(outputFields, alias)
+		}
+
+		pipeline = append(pipeline, bson.M{"$group": group}, bson.M{"$project": project})
+	} else if len(pq.Metrics) > 0 || len(groupFields) > 0 {
+		// Plain projection of the requested fields
+		project := bson.M{"_id": 0}
+		for _, name := range append(append([]string{}, pq.Metrics...), groupNames...) {
+			if field := resolveFieldName(name, fields); field != "" {
+				project[field] = 1
+			}
+		}
+		if len(project) > 1 {
+			pipeline = append(pipeline, bson.M{"$project": project})
+		}
+	}
+
+	if len(pq.SortBy) > 0 {
+		sortSpec := bson.D{}
+		for _, criteria := range pq.SortBy {
+			field := criteria.Field
+			if resolved := resolveFieldName(field, outputFields); resolved != "" {
+				field = resolved
+			}
+			direction := 1
+			if strings.EqualFold(criteria.Direction, "DESC") {
+				direction = -1
+			}
+			sortSpec = append(sortSpec, bson.E{Key: field, Value: direction})
+		}
+		pipeline = append(pipeline, bson.M{"$sort": sortSpec})
+	}
+
+	pipeline = append(pipeline, bson.M{"$limit": limit})
+	return pipeline
+}
+
+// mongoCondition converts a filter operator and value into a MongoDB condition
+func mongoCondition(operator string, value interface{}) interface{} {
+	switch strings.ToUpper(strings.TrimSpace(operator)) {
+	case "!=", "<>", "NE":
+		return bson.M{"$ne": value}
+	case ">", "GT":
+		return bson.M{"$gt": value}
+	case ">=", "GTE":
+		return bson.M{"$gte": value}
+	case "<", "LT":
+		return bson.M{"$lt": value}
+	case "<=", "LTE":
+		return bson.M{"$lte": value}
+	case "IN":
+		return bson.M{"$in": toSlice(value)}
+	case "NOT IN", "NOT_IN", "NIN":
+		return bson.M{"$nin": toSlice(value)}
+	case "LIKE", "ILIKE", "CONTAINS":
+		pattern := strings.Trim(fmt.Sprint(value), "%")
+		return primitive.Regex{Pattern: regexp.QuoteMeta(pattern), Options: "i"}
+	default:
+		return value
+	}
+}
+
+// mongoTimeRange matches field values within [start, end). Sampled dates may be
+// BSON dates or RFC 3339 strings, and MongoDB only compares values of the same
+// type, so strings are converted to dates before they are compared.
+func mongoTimeRange(field string, start, end *time.Time) bson.M {
+	dates := bson.M{}
+	asDate := bson.M{"$convert": bson.M{"input": "$" + field, "to": "date", "onError": nil, "onNull": nil}}
+	texts := bson.A{
+		bson.M{"$eq": bson.A{bson.M{"$type": "$" + field}, "string"}},
+		bson.M{"$ne": bson.A{asDate, nil}},
+	}
+	if start != nil {
+		dates["$gte"] = *start
+		texts = append(texts, bson.M{"$gte": bson.A{asDate, *start}})
+	}
+	if end != nil {
+		dates["$lt"] = *end
+		texts = append(texts, bson.M{"$lt": bson.A{asDate, *end}})
+	}
+	return bson.M{"$or": bson.A{
+		bson.M{field: dates},
+		bson.M{"$expr": bson.M{"$and": texts}},
+	}}
+}
+
+// mergeMatch adds a condition for field, combining operator documents when the field repeats
+func mergeMatch(match bson.M, field string, condition interface{}) {
+	existing, ok := match[field].(bson.M)
+	incoming, incomingOK := condition.(bson.M)
+	if ok && incomingOK {
+		for k, v := range incoming {
+			existing[k] = v
+		}
+		return
+	}
+	match[field] = condition
+}
+
+func toSlice(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		return v
+	case []string:
+		out := make([]interface{}, len(v))
+		for i, s := range v {
+			out[i] = s
+		}
+		return out
+	default:
+		return []interface{}{v}
+	}
+}
+
+func firstDatetimeColumn(columns []ColumnSchema) string {
+	for _, col := range columns {
+		if col.DataType == "date" {
+			return col.Name
+		}
+	}
+	return ""
+}
+
+// mongoAlias makes a field path safe to use as an output field name
+func mongoAlias(field string) string {
+	return strings.ReplaceAll(strings.TrimPrefix(field, "$"), ".", "_")
+}
+
+// resolveFieldName maps a loosely named field onto one of the known fields:
+// exact match first, then case/underscore-insensitive, then substring match
+func resolveFieldName(name string, fields []string) string {
+	if name == "" || name == "*" {
+		return ""
+	}
+
+	normalize := func(s string) string {
+		s = strings.ToLower(s)
+		s = strings.NewReplacer("_", "", " ", "", "-", "", ".", "").Replace(s)
+		return s
+	}
+
+	for _, field := range fields {
+		if field == name {
+			return field
+		}
+	}
+
+	target := normalize(name)
+	for _, field := range fields {
+		if normalize(field) == target {
+			return field
+		}
+	}
+
+	// Prefer the shortest field containing the name (e.g. "revenue" -> "revenue_usd" over "net_revenue_adjusted")
+	best := ""
+	for _, field := range fields {
+		if strings.Contains(normalize(field), target) && (best == "" || len(field) < len(best)) {
+			best = field
+		}
+	}
+	return best
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// inferDocumentColumns derives columns from sampled documents, flattening nested
+// documents into dotted paths and choosing the most common type per path
+func inferDocumentColumns(docs []map[string]interface{}) []ColumnSchema {
+	typeCounts := make(map[string]map[string]int)
+	presence := make(map[string]int)
+	var order []string
+
+	var walk func(prefix string, doc map[string]interface{}, depth int)
+	walk = func(prefix string, doc map[string]interface{}, depth int) {
+		keys := make([]string, 0, len(doc))
+		for key := range doc {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+
+			if nested, ok := doc[key].(map[string]interface{}); ok && depth < maxMongoFieldDepth {
+				walk(path, nested, depth+1)
+				continue
+			}
+
+			if _, seen := typeCounts[path]; !seen {
+				typeCounts[path] = make(map[string]int)
+				order = append(order, path)
+			}
+			presence[path]++
+			typeCounts[path][documentValueType(doc[key])]++
+		}
+	}
+
+	for _, doc := range docs {
+		walk("", doc, 0)
+	}
+
+	columns := make([]ColumnSchema, 0, len(order))
+	for _, path := range order {
+		dominant, best := "null", 0
+		for typ, count := range typeCounts[path] {
+			if typ != "null" && (count > best || (count == best && typ < dominant)) {
+				dominant, best = typ, count
+			}
+		}
+
+		columns = append(columns, ColumnSchema{
+			Name:       path,
+			DataType:   dominant,
+			Nullable:   presence[path] < len(docs) || typeCounts[path]["null"] > 0,
+			PrimaryKey: path == "_id",
+		})
+	}
+
+	return columns
+}
+
+// documentValueType names the type of a normalized document value
+func documentValueType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		if _, err := time.Parse(time.RFC3339, v); err == nil {
+			return "date"
+		}
+		return "string"
+	case bool:
+		return "bool"
+	case int, int32, int64:
+		return "int"
+	case float32, float64:
+		return "double"
+	case time.Time:
+		return "date"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// normalizeBSONValue converts driver-specific BSON types into plain Go values
+// that marshal cleanly to JSON
+func normalizeBSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case bson.M:
+		out := make(map[string]interface{}, len(v))
+		for key, inner := range v {
+			out[key] = normalizeBSONValue(inner)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, inner := range v {
+			out[key] = normalizeBSONValue(inner)
+		}
+		return out
+	case bson.D:
+		out := make(map[string]interface{}, len(v))
+		for _, elem := range v {
+			out[elem.Key] = normalizeBSONValue(elem.Value)
+		}
+		return out
+	case bson.A:
+		out := make([]interface{}, len(v))
+		for i, inner := range v {
+			out[i] = normalizeBSONValue(inner)
+		}
+		return out
+	case primitive.ObjectID:
+		return v.Hex()
+	case primitive.DateTime:
+		return v.Time().UTC()
+	case primitive.Timestamp:
+		return time.Unix(int64(v.T), 0).UTC()
+	case primitive.Decimal128:
+		if f, err := strconv.ParseFloat(v.String(), 64); err == nil {
+			return f
+		}
+		return v.String()
+	case primitive.Binary:
+		return fmt.Sprintf("<binary %d bytes>", len(v.Data))
+	case primitive.Regex:
+		return v.String()
+	default:
+		return v
+	}
+}
@@ -0,0 +1,176 @@
+package connectors
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"insightiq/backend/internal/models"
+)
+
+// TestValidatePipeline tests that write and code-execution operators are refused wherever they appear
+func TestValidatePipeline(t *testing.T) {
+	tests := []struct {
+		name     string
+		pipeline []bson.M
+		wantErr  bool
+	}{
+		{"read-only stages", []bson.M{
+			{"$match": bson.M{"region": "north"}},
+			{"$group": bson.M{"_id": "$region", "total": bson.M{"$sum": "$amount"}}},
+			{"$sort": bson.D{{Key: "total", Value: -1}}},
+			{"$limit": 10},
+		}, false},
+		{"out stage", []bson.M{{"$match": bson.M{}}, {"$out": "copy"}}, true},
+		{"merge stage", []bson.M{{"$merge": bson.M{"into": "copy"}}}, true},
+		{"merge inside facet", []bson.M{{"$facet": bson.M{"all": bson.A{bson.M{"$merge": "copy"}}}}}, true},
+		{"out inside lookup pipeline", []bson.M{{"$lookup": bson.M{"from": "orders", "pipeline": []interface{}{map[string]interface{}{"$out": "copy"}}}}}, true},
+		{"function in projection", []bson.M{{"$project": bson.M{"x": bson.M{"$function": bson.M{"body": "function() {}"}}}}}, true},
+		{"accumulator in ordered group", []bson.M{{"$group": bson.D{{Key: "_id", Value: nil}, {Key: "x", Value: bson.D{{Key: "$accumulator", Value: bson.M{}}}}}}}, true},
+		{"where in match", []bson.M{{"$match": bson.M{"$where": "sleep(1000)"}}}, true},
+		{"current operations", []bson.M{{"$currentOp": bson.M{}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePipeline(tt.pipeline)
+			if tt.wantErr && !errors.Is(err, ErrDangerousPipeline) {
+				t.Errorf("ValidatePipeline() = %v, want ErrDangerousPipeline", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("ValidatePipeline() = %v, want nil", err)
+			}
+		})
+	}
+}
+
+// TestBuildAggregationPipeline tests the translation of parsed queries into stages
+func TestBuildAggregationPipeline(t *testing.T) {
+	columns := []ColumnSchema{
+		{Name: "_id", DataType: "string"},
+		{Name: "region", DataType: "string"},
+		{Name: "total_revenue", DataType: "double"},
+		{Name: "customer.country", DataType: "string"},
+		{Name: "ordered_at", DataType: "date"},
+	}
+	five := 5
+	huge := 1_000_000
+	const maxRows = 100
+
+	tests := []struct {
+		name string
+		pq   *models.ParsedQuery
+		want []bson.M
+	}{
+		{"no query", nil, []bson.M{{"$limit": maxRows + 1}}},
+		{
+			"filters resolve loose names",
+			&models.ParsedQuery{Filters: []models.Filter{
+				{Field: "Region", Operator: "=", Value: "north"},
+				{Field: "revenue", Operator: ">", Value: 100},
+				{Field: "revenue", Operator: "<=", Value: 500},
+				{Field: "unknown", Operator: "=", Value: "x"},
+			}},
+			[]bson.M{
+				{"$match": bson.M{"region": "north", "total_revenue": bson.M{"$gt": 100, "$lte": 500}}},
+				{"$limit": maxRows + 1},
+			},
+		},
+		{
+			"or filters",
+			&models.ParsedQuery{Filters: []models.Filter{
+				{Field: "region", Operator: "IN", Value: []string{"north", "south"}, Condition: "OR"},
+				{Field: "customer.country", Operator: "!=", Value: "FR", Condition: "OR"},
+			}},
+			[]bson.M{
+				{"$match": bson.M{"$or": []bson.M{
+					{"region": bson.M{"$in": []interface{}{"north", "south"}}},
+					{"customer.country": bson.M{"$ne": "FR"}},
+				}}},
+				{"$limit": maxRows + 1},
+			},
+		},
+		{
+			"grouped metric sorted and limited",
+			&models.ParsedQuery{
+				Metrics: []string{"revenue"},
+				GroupBy: []string{"customer.country"},
+				SortBy:  []models.SortCriteria{{Field: "sum_total_revenue", Direction: "DESC"}},
+				Limit:   &five,
+			},
+			[]bson.M{
+				{"$group": bson.M{"_id": bson.M{"customer_country": "$customer.country"}, "sum_total_revenue": bson.M{"$sum": "$total_revenue"}}},
+				{"$project": bson.M{"_id": 0, "customer_country": "$_id.customer_country", "sum_total_revenue": 1}},
+				{"$sort": bson.D{{Key: "sum_total_revenue", Value: -1}}},
+				{"$limit": 5},
+			},
+		},
+		{
+			"limit capped past max rows",
+			&models.ParsedQuery{Limit: &huge},
+			[]bson.M{{"$limit": maxRows + 1}},
+		},
+		{
+			"count per group",
+			&models.ParsedQuery{Dimensions: []string{"region"}},
+			[]bson.M{
+				{"$group": bson.M{"_id": bson.M{"region": "$region"}, "count": bson.M{"$sum": 1}}},
+				{"$project": bson.M{"_id": 0, "region": "$_id.region", "count": 1}},
+				{"$limit": maxRows + 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BuildAggregationPipeline(tt.pq, columns, maxRows); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildAggregationPipeline() =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBuildAggregationPipelineTimeRange tests that time ranges match dates stored
+// as BSON dates and as RFC 3339 strings
+func TestBuildAggregationPipelineTimeRange(t *testing.T) {
+	docs := []map[string]interface{}{
+		{"region": "north", "ordered_at": "2024-03-05T10:00:00Z"},
+		{"region": "south", "ordered_at": "2024-04-01T08:30:00+02:00"},
+	}
+	columns := inferDocumentColumns(docs)
+	if got := firstDatetimeColumn(columns); got != "ordered_at" {
+		t.Fatalf("firstDatetimeColumn() = %q, want ordered_at for RFC 3339 strings", got)
+	}
+
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	pq := &models.ParsedQuery{
+		Filters:   []models.Filter{{Field: "region", Operator: "=", Value: "north", Condition: "OR"}},
+		TimeRange: &models.TimeRange{Start: &start, End: &end},
+	}
+
+	pipeline := BuildAggregationPipeline(pq, columns, DefaultMaxRows)
+	match, ok := pipeline[0]["$match"].(bson.M)
+	if !ok {
+		t.Fatalf("first stage = %v, want $match", pipeline[0])
+	}
+	if _, ok := match["$or"]; !ok {
+		t.Errorf("match %v lost the OR filters", match)
+	}
+
+	asDate := bson.M{"$convert": bson.M{"input": "$ordered_at", "to": "date", "onError": nil, "onNull": nil}}
+	want := bson.A{bson.M{"$or": bson.A{
+		bson.M{"ordered_at": bson.M{"$gte": start, "$lt": end}},
+		bson.M{"$expr": bson.M{"$and": bson.A{
+			bson.M{"$eq": bson.A{bson.M{"$type": "$ordered_at"}, "string"}},
+			bson.M{"$ne": bson.A{asDate, nil}},
+			bson.M{"$gte": bson.A{asDate, start}},
+			bson.M{"$lt": bson.A{asDate, end}},
+		}}},
+	}}}
+	if !reflect.DeepEqual(match["$and"], want) {
+		t.Errorf("time range = %v, want %v", match["$and"], want)
+	}
+}
@@ -0,0 +1,365 @@
+package connectors
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"insightiq/backend/internal/sqlparse"
+)
+
+const (
+	// Default MySQL port used when the configuration omits one
+	defaultMySQLPort = 3306
+)
+
+type MySQLConnector struct {
+	db     *sql.DB
+	limits QueryLimits
+	logger *slog.Logger
+}
+
+// NewMySQLConnector opens a MySQL connection. The dsn may be either a native
+// go-sql-driver DSN (user:pass@tcp(host:3306)/db) or a mysql:// URL.
+func NewMySQLConnector(dsn string, logger *slog.Logger) (*MySQLConnector, error) {
+	cfg, err := parseMySQLDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database connection: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	return &MySQLConnector{
+		db:     db,
+		limits: DefaultQueryLimits(),
+		logger: logger.With("connector", "mysql"),
+	}, nil
+}
+
+// SetQueryLimits replaces the default row limit. MySQL has no planner check, so
+// the estimate thresholds are ignored.
+func (mc *MySQLConnector) SetQueryLimits(limits QueryLimits) {
+	mc.limits = limits
+}
+
+// MySQLDSNFromConfig builds a DSN from connector configuration, accepting either
+// a "url" entry or discrete host/port/username/password/database fields
+func MySQLDSNFromConfig(config map[string]interface{}) (string, error) {
+	if rawURL, ok := config["url"].(string); ok && rawURL != "" {
+		return rawURL, nil
+	}
+
+	host, _ := config["host"].(string)
+	database, _ := config["database"].(string)
+	username, _ := config["username"].(string)
+	password, _ := config["password"].(string)
+
+	if host == "" {
+		return "", fmt.Errorf("either url or host is required for MySQL connector")
+	}
+	if username == "" {
+		return "", fmt.Errorf("username is required for MySQL connector")
+	}
+
+	port := defaultMySQLPort
+	switch p := config["port"].(type) {
+	case float64:
+		port = int(p)
+	case int:
+		port = p
+	case string:
+		if parsed, err := strconv.Atoi(p); err == nil {
+			port = parsed
+		}
+	}
+
+	cfg := mysql.NewConfig()
+	cfg.User = username
+	cfg.Passwd = password
+	cfg.Net = "tcp"
+	cfg.Addr = net.JoinHostPort(host, strconv.Itoa(port))
+	cfg.DBName = database
+
+	return cfg.FormatDSN(), nil
+}
+
+// parseMySQLDSN converts either DSN form into a driver config with safe defaults
+func parseMySQLDSN(dsn string) (*mysql.Config, error) {
+	var cfg *mysql.Config
+
+	if strings.HasPrefix(dsn, "mysql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MySQL url: %w", err)
+		}
+
+		cfg = mysql.NewConfig()
+		cfg.Net = "tcp"
+		if u.User != nil {
+			cfg.User = u.User.Username()
+			cfg.Passwd, _ = u.User.Password()
+		}
+
+		port := u.Port()
+		if port == "" {
+			port = strconv.Itoa(defaultMySQLPort)
+		}
+		cfg.Addr = net.JoinHostPort(u.Hostname(), port)
+		cfg.DBName = strings.TrimPrefix(u.Path, "/")
+
+		if len(u.Query()) > 0 {
+			cfg.Params = make(map[string]string)
+			for key, values := range u.Query() {
+				if len(values) > 0 {
+					cfg.Params[key] = values[0]
+				}
+			}
+		}
+	} else {
+		parsed, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MySQL DSN: %w", err)
+		}
+		cfg = parsed
+	}
+
+	// Return DATE/DATETIME columns as time.Time instead of raw bytes
+	cfg.ParseTime = true
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	return cfg, nil
+}
+
+func (mc *MySQLConnector) Close() error {
+	return mc.db.Close()
+}
+
+// ExecuteQuery executes a read-only SELECT query with security validation
+// SECURITY: Queries pass the same checks as PostgresConnector and run in a READ ONLY transaction
+func (mc *MySQLConnector) ExecuteQuery(ctx context.Context, query string) (*QueryResult, error) {
+	stmt, err := validateReadOnlyQuery(query, sqlparse.DialectMySQL, mc.logger)
+	if err != nil {
+		mc.logger.Error("Query validation failed",
+			"error", err,
+			"query_preview", query[:min(100, len(query))])
+		return nil, fmt.Errorf("query validation failed: %w", err)
+	}
+	query, _ = limitQuery(query, stmt, mc.limits.MaxRows, sqlparse.DialectMySQL)
+
+	mc.logger.Info("Executing validated query", "query_length", len(query))
+
+	tx, err := mc.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin read-only transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		mc.logger.Error("Query execution failed", "error", err)
+		if rejectedByMySQL(err) {
+			return nil, fmt.Errorf("%w: %w", ErrQueryRejected, err)
+		}
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	result, err := scanResult(rows, mc.limits.MaxRows)
+	if err != nil {
+		return nil, err
+	}
+
+	mc.logger.Info("Query executed successfully", "rows", len(result.Data), "truncated", result.Truncated)
+	return result, nil
+}
+
+// ExplainQuery dry-runs a query: it is validated and resolved with EXPLAIN inside a
+// READ ONLY transaction, but not run
+func (mc *MySQLConnector) ExplainQuery(ctx context.Context, query string) error {
+	stmt, err := validateReadOnlyQuery(query, sqlparse.DialectMySQL, mc.logger)
+	if err != nil {
+		return fmt.Errorf("query validation failed: %w", err)
+	}
+	if _, isExplain := stmt.(*sqlparse.Explain); isExplain {
+		return nil
+	}
+	query, _ = limitQuery(query, stmt, mc.limits.MaxRows, sqlparse.DialectMySQL)
+
+	tx, err := mc.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("failed to begin read-only transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, "EXPLAIN "+query)
+	if err != nil {
+		if rejectedByMySQL(err) {
+			return fmt.Errorf("%w: %w", ErrQueryRejected, err)
+		}
+		return fmt.Errorf("failed to explain query: %w", err)
+	}
+	return rows.Close()
+}
+
+// rejectedByMySQL reports whether MySQL refused a query for its text, with a syntax
+// error or an unknown table or column (SQLSTATE class 42)
+func rejectedByMySQL(err error) bool {
+	var myErr *mysql.MySQLError
+	return errors.As(err, &myErr) && string(myErr.SQLState[:2]) == "42"
+}
+
+func (mc *MySQLConnector) TestConnection(ctx context.Context) (*QueryResult, error) {
+	// Simple test query to verify connection
+	query := "SELECT 1 as test_connection"
+	return mc.ExecuteQuery(ctx, query)
+}
+
+// GetTableSchemas lists the base tables and columns of the connected database.
+// When the DSN does not select a database, all non-system schemas are returned.
+func (mc *MySQLConnector) GetTableSchemas(ctx context.Context) ([]TableSchema, error) {
+	query := `
+		SELECT
+			c.TABLE_SCHEMA,
+			c.TABLE_NAME,
+			c.COLUMN_NAME,
+			c.DATA_TYPE,
+			c.IS_NULLABLE,
+			c.COLUMN_KEY,
+			c.COLUMN_COMMENT,
+			t.TABLE_COMMENT,
+			COALESCE(t.TABLE_ROWS, 0)
+		FROM information_schema.COLUMNS c
+		JOIN information_schema.TABLES t
+			ON t.TABLE_SCHEMA = c.TABLE_SCHEMA AND t.TABLE_NAME = c.TABLE_NAME
+		WHERE t.TABLE_TYPE IN ('BASE TABLE', 'VIEW')
+			AND (c.TABLE_SCHEMA = DATABASE()
+				OR (DATABASE() IS NULL
+					AND c.TABLE_SCHEMA NOT IN ('mysql', 'information_schema', 'performance_schema', 'sys')))
+		ORDER BY c.TABLE_SCHEMA, c.TABLE_NAME, c.ORDINAL_POSITION`
+
+	rows, err := mc.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read information_schema: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []TableSchema
+	index := make(map[string]int)
+
+	for rows.Next() {
+		var (
+			schemaName, tableName, columnName, dataType string
+			isNullable, columnKey, columnComment        string
+			tableComment                                sql.NullString
+			rowEstimate                                 int64
+		)
+		if err := rows.Scan(&schemaName, &tableName, &columnName, &dataType,
+			&isNullable, &columnKey, &columnComment, &tableComment, &rowEstimate); err != nil {
+			return nil, fmt.Errorf("failed to scan column metadata: %w", err)
+		}
+
+		key := schemaName + "." + tableName
+		pos, ok := index[key]
+		if !ok {
+			tables = append(tables, TableSchema{
+				Schema:      schemaName,
+				Name:        tableName,
+				Description: tableComment.String,
+				RowEstimate: rowEstimate,
+			})
+			pos = len(tables) - 1
+			index[key] = pos
+		}
+
+		tables[pos].Columns = append(tables[pos].Columns, ColumnSchema{
+			Name:        columnName,
+			DataType:    strings.ToLower(dataType),
+			Nullable:    isNullable == "YES",
+			PrimaryKey:  columnKey == "PRI",
+			Unique:      columnKey == "PRI" || columnKey == "UNI",
+			Description: columnComment,
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	if err := mc.loadForeignKeys(ctx, tables, index); err != nil {
+		return nil, err
+	}
+
+	mc.logger.Info("Scanned MySQL schema", "tables", len(tables))
+	return tables, nil
+}
+
+// loadForeignKeys attaches foreign key constraints to the scanned tables
+func (mc *MySQLConnector) loadForeignKeys(ctx context.Context, tables []TableSchema, index map[string]int) error {
+	query := `
+		SELECT
+			TABLE_SCHEMA,
+			TABLE_NAME,
+			COLUMN_NAME,
+			REFERENCED_TABLE_SCHEMA,
+			REFERENCED_TABLE_NAME,
+			REFERENCED_COLUMN_NAME
+		FROM information_schema.KEY_COLUMN_USAGE
+		WHERE REFERENCED_TABLE_NAME IS NOT NULL
+			AND (TABLE_SCHEMA = DATABASE()
+				OR (DATABASE() IS NULL
+					AND TABLE_SCHEMA NOT IN ('mysql', 'information_schema', 'performance_schema', 'sys')))
+		ORDER BY TABLE_SCHEMA, TABLE_NAME, CONSTRAINT_NAME, ORDINAL_POSITION`
+
+	rows, err := mc.db.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to read foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var schemaName, tableName string
+		var fk ForeignKey
+		if err := rows.Scan(&schemaName, &tableName, &fk.Column, &fk.RefSchema, &fk.RefTable, &fk.RefColumn); err != nil {
+			return fmt.Errorf("failed to scan foreign key: %w", err)
+		}
+		if pos, ok := index[schemaName+"."+tableName]; ok {
+			tables[pos].ForeignKeys = append(tables[pos].ForeignKeys, fk)
+		}
+	}
+
+	return rows.Err()
+}
+
+// SampleValues fills each column's SampleValues from a bounded read of every table
+func (mc *MySQLConnector) SampleValues(ctx context.Context, tables []TableSchema, rows int) {
+	sampleColumnValues(ctx, mc.db, tables, rows, quoteMySQLIdentifier, mc.logger)
+}
+
+// ProfileDistinctValues fills the DistinctValues of low-cardinality text columns with
+// up to limit values each
+func (mc *MySQLConnector) ProfileDistinctValues(ctx context.Context, tables []TableSchema, limit int) {
+	profileDistinctValues(ctx, mc.db, tables, limit, quoteMySQLIdentifier, mc.logger)
+}
+
+// quoteMySQLIdentifier quotes an identifier for MySQL
+func quoteMySQLIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
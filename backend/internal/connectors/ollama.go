@@ -5,8 +5,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -69,7 +71,66 @@ func (oc *OllamaConnector) GenerateResponse(ctx context.Context, prompt string)
 	return result.Response, nil
 }
 
+// GenerateResponseStream generates a response like GenerateResponse, passing each
+// token to onToken as the model produces it
+func (oc *OllamaConnector) GenerateResponseStream(ctx context.Context, prompt string, onToken func(token string)) (string, error) {
+	request := OllamaRequest{
+		Model:  "llama3.2:1b",
+		Prompt: prompt,
+		Stream: true,
+	}
+
+	jsonData, _ := json.Marshal(request)
+
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		oc.baseURL+"/api/generate",
+		bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := oc.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("ollama returned status %d", resp.StatusCode)
+	}
+
+	// The response is one JSON object per token, the last one marked done
+	var text strings.Builder
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var chunk OllamaResponse
+		if err := decoder.Decode(&chunk); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return "", err
+		}
+		if chunk.Response != "" {
+			text.WriteString(chunk.Response)
+			onToken(chunk.Response)
+		}
+		if chunk.Done {
+			break
+		}
+	}
+
+	return text.String(), nil
+}
+
 func (oc *OllamaConnector) AnalyzeData(ctx context.Context, data []map[string]interface{}, question string) (string, error) {
+	return oc.AnalyzeDataStream(ctx, data, question, nil)
+}
+
+// AnalyzeDataStream writes insights on data like AnalyzeData, passing each token
+// to onToken as it is generated when onToken is set
+func (oc *OllamaConnector) AnalyzeDataStream(ctx context.Context, data []map[string]interface{}, question string, onToken func(token string)) (string, error) {
 	// Check if data is actually an error message
 	if len(data) == 1 {
 		if errMsg, ok := data[0]["error"].(string); ok {
@@ -96,6 +157,9 @@ Question: %s
 
 Provide 2-3 key insights in 50 words or less.`, len(data), string(dataJSON), question)
 
+	if onToken != nil {
+		return oc.GenerateResponseStream(ctx, prompt, onToken)
+	}
 	return oc.GenerateResponse(ctx, prompt)
 }
 
@@ -6,10 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"regexp"
 	"strings"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+
+	"insightiq/backend/internal/sqlparse"
 )
 
 var (
@@ -19,20 +21,29 @@ var (
 	ErrInvalidQuery = errors.New("invalid or malformed query")
 	// ErrQueryTooLong is returned when a query exceeds maximum length
 	ErrQueryTooLong = errors.New("query exceeds maximum allowed length")
+	// ErrQueryTooExpensive is returned when the planner's estimate exceeds the connector's thresholds
+	ErrQueryTooExpensive = errors.New("query refused because it is too expensive")
 )
 
 const (
 	// Maximum query length to prevent DoS
 	maxQueryLength = 10000
+	// DefaultStatementTimeout bounds read-only queries when the caller does not set a timeout
+	DefaultStatementTimeout = 30 * time.Second
 )
 
 type PostgresConnector struct {
 	db     *sql.DB
+	limits QueryLimits
 	logger *slog.Logger
 }
 
 type QueryResult struct {
-	Data []map[string]interface{} `json:"data"`
+	// Columns lists the result columns in order with their types and roles
+	Columns []ResultColumn           `json:"columns,omitempty"`
+	Data    []map[string]interface{} `json:"data"`
+	// Truncated is set when rows beyond the connector's max_rows were dropped
+	Truncated bool `json:"truncated"`
 }
 
 func NewPostgresConnector(dbURL string, logger *slog.Logger) (*PostgresConnector, error) {
@@ -47,157 +58,198 @@ func NewPostgresConnector(dbURL string, logger *slog.Logger) (*PostgresConnector
 
 	return &PostgresConnector{
 		db:     db,
+		limits: DefaultQueryLimits(),
 		logger: logger.With("connector", "postgres"),
 	}, nil
 }
 
+// SetQueryLimits replaces the default row limit and planner thresholds
+func (pc *PostgresConnector) SetQueryLimits(limits QueryLimits) {
+	pc.limits = limits
+}
+
 func (pc *PostgresConnector) Close() error {
 	return pc.db.Close()
 }
 
 // validateAndSanitizeQuery performs security checks on SQL queries
-func (pc *PostgresConnector) validateAndSanitizeQuery(query string) error {
+func (pc *PostgresConnector) validateAndSanitizeQuery(query string) (sqlparse.Statement, error) {
+	return validateReadOnlyQuery(query, sqlparse.DialectPostgres, pc.logger)
+}
+
+// validateReadOnlyQuery performs the read-only security checks shared by all SQL connectors.
+// The query is parsed with the rules of the engine it runs on, so identifiers such as
+// created_at or comments no longer trip keyword matches and comments can't hide code;
+// the error carries the parser's reason for the rejection.
+func validateReadOnlyQuery(query string, dialect sqlparse.Dialect, logger *slog.Logger) (sqlparse.Statement, error) {
 	// Check query length
 	if len(query) > maxQueryLength {
-		pc.logger.Warn("Query exceeds maximum length", "length", len(query))
-		return ErrQueryTooLong
+		logger.Warn("Query exceeds maximum length", "length", len(query))
+		return nil, ErrQueryTooLong
+	}
+
+	if strings.TrimSpace(query) == "" {
+		return nil, ErrInvalidQuery
 	}
 
-	// Trim and normalize whitespace
-	query = strings.TrimSpace(query)
-	if query == "" {
-		return ErrInvalidQuery
+	stmt, err := sqlparse.ValidateReadOnlyDialect(query, dialect)
+	if err != nil {
+		logger.Warn("Query rejected by read-only validation",
+			"reason", err,
+			"query_preview", query[:min(100, len(query))])
+		if errors.Is(err, sqlparse.ErrSyntax) {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidQuery, err)
+		}
+		return nil, fmt.Errorf("%w: %w", ErrDangerousQuery, err)
 	}
 
-	// Convert to uppercase for pattern matching
-	queryUpper := strings.ToUpper(query)
+	return stmt, nil
+}
 
-	// Only allow SELECT statements (read-only)
-	if !strings.HasPrefix(queryUpper, "SELECT") {
-		pc.logger.Warn("Non-SELECT query attempted", "query_prefix", query[:min(50, len(query))])
-		return ErrDangerousQuery
+// ExecuteQuery executes a read-only SELECT query with security validation
+// SECURITY: The query runs like ExecuteReadOnlyQuery, with the default statement timeout
+func (pc *PostgresConnector) ExecuteQuery(ctx context.Context, query string) (*QueryResult, error) {
+	return pc.ExecuteReadOnlyQuery(ctx, query, DefaultStatementTimeout)
+}
+
+// ExecuteReadOnlyQuery validates a query and runs it inside a READ ONLY transaction
+// with a statement_timeout, so generated SQL can neither write nor run unbounded.
+// The query is capped at the connector's row limit and refused up front when the
+// planner's estimate exceeds its cost thresholds.
+func (pc *PostgresConnector) ExecuteReadOnlyQuery(ctx context.Context, query string, timeout time.Duration) (*QueryResult, error) {
+	it, err := pc.QueryRows(ctx, query, timeout)
+	if err != nil {
+		return nil, err
 	}
+	defer it.Close()
 
-	// Disallow dangerous keywords and patterns
-	dangerousPatterns := []string{
-		"DROP",
-		"DELETE",
-		"INSERT",
-		"UPDATE",
-		"ALTER",
-		"CREATE",
-		"TRUNCATE",
-		"REPLACE",
-		"EXEC",
-		"EXECUTE",
-		"GRANT",
-		"REVOKE",
-		"MERGE",
-		"CALL",
-		";DROP",        // SQL injection attempt
-		"; DROP",       // SQL injection attempt
-		"--",           // SQL comments can hide attacks
-		"/*",           // Block comments
-		"*/",
-		"XP_",          // Extended stored procedures
-		"SP_",          // System stored procedures
-		"INFORMATION_SCHEMA", // Schema enumeration
-		"PG_SLEEP",     // PostgreSQL sleep (DoS)
-		"WAITFOR",      // SQL Server delay
-		"BENCHMARK",    // MySQL DoS
-		"INTO OUTFILE", // File system access
-		"INTO DUMPFILE",
-		"LOAD_FILE",
-		"COPY ",        // PostgreSQL COPY command
-		"\\COPY",       // PostgreSQL meta-command
+	result, err := collectRows(it)
+	if err != nil {
+		return nil, err
 	}
 
-	for _, pattern := range dangerousPatterns {
-		if strings.Contains(queryUpper, pattern) {
-			pc.logger.Warn("Dangerous pattern detected in query",
-				"pattern", pattern,
-				"query_preview", query[:min(100, len(query))])
-			return ErrDangerousQuery
-		}
+	pc.logger.Info("Read-only query executed successfully", "rows", len(result.Data), "truncated", result.Truncated)
+	return result, nil
+}
+
+// QueryRows runs a query like ExecuteReadOnlyQuery but returns its rows as they are
+// read. The read-only transaction stays open until the iterator is closed.
+func (pc *PostgresConnector) QueryRows(ctx context.Context, query string, timeout time.Duration) (RowIterator, error) {
+	return pc.queryRows(ctx, query, timeout, nil)
+}
+
+// queryRows runs a query with bind parameters read-only, after the planner check
+func (pc *PostgresConnector) queryRows(ctx context.Context, query string, timeout time.Duration, args []interface{}) (RowIterator, error) {
+	prepared, err := pc.prepareReadOnly(ctx, query, timeout)
+	if err != nil {
+		return nil, err
 	}
+	tx := prepared.tx
 
-	// Check for multiple statements (disallow semicolons except at the end)
-	trimmedQuery := strings.TrimSuffix(query, ";")
-	if strings.Contains(trimmedQuery, ";") {
-		pc.logger.Warn("Multiple SQL statements detected")
-		return ErrDangerousQuery
+	// EXPLAIN output is neither limited nor worth estimating
+	if !prepared.explain && pc.limits.checksCost() {
+		plan, err := explainQuery(ctx, tx, prepared.query, args...)
+		if err == nil {
+			err = checkPlanCost(plan, prepared.limited, pc.limits)
+		}
+		if err != nil {
+			tx.Rollback()
+			pc.logger.Warn("Query refused before running", "error", err)
+			return nil, err
+		}
 	}
 
-	// Validate SQL syntax using regex - must look like a valid SELECT
-	// This is a basic check, not a full SQL parser
-	selectPattern := regexp.MustCompile(`(?i)^\s*SELECT\s+.+\s+FROM\s+[\w\.]+`)
-	if !selectPattern.MatchString(query) {
-		// Allow simple SELECT without FROM (e.g., SELECT 1)
-		simpleSelectPattern := regexp.MustCompile(`(?i)^\s*SELECT\s+[\d\s\+\-\*\/]+(\s+as\s+\w+)?\s*$`)
-		if !simpleSelectPattern.MatchString(query) {
-			pc.logger.Warn("Query does not match expected SELECT pattern")
-			return ErrInvalidQuery
+	pc.logger.Info("Executing read-only query", "query_length", len(prepared.query), "param_count", len(args), "timeout", timeout)
+
+	rows, err := tx.QueryContext(ctx, prepared.query, args...)
+	if err != nil {
+		tx.Rollback()
+		pc.logger.Error("Query execution failed", "error", err)
+		if rejectedByPostgres(err) {
+			return nil, fmt.Errorf("%w: %w", ErrQueryRejected, err)
 		}
+		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
 
-	return nil
+	it, err := newSQLRowIterator(rows, pc.limits.MaxRows, tx.Rollback)
+	if err != nil {
+		rows.Close()
+		tx.Rollback()
+		return nil, err
+	}
+	return it, nil
 }
 
-// ExecuteQuery executes a read-only SELECT query with security validation
-// SECURITY: This function validates queries to prevent SQL injection
-func (pc *PostgresConnector) ExecuteQuery(ctx context.Context, query string) (*QueryResult, error) {
-	// Validate and sanitize the query before execution
-	if err := pc.validateAndSanitizeQuery(query); err != nil {
+// ExplainQuery dry-runs a query: it is validated and planned with EXPLAIN, but not
+// run. Planner errors such as unknown columns wrap ErrQueryRejected, and plans over
+// the connector's cost thresholds wrap ErrQueryTooExpensive.
+func (pc *PostgresConnector) ExplainQuery(ctx context.Context, query string, timeout time.Duration) error {
+	prepared, err := pc.prepareReadOnly(ctx, query, timeout)
+	if err != nil {
+		return err
+	}
+	defer prepared.tx.Rollback()
+
+	if prepared.explain {
+		return nil
+	}
+	plan, err := explainQuery(ctx, prepared.tx, prepared.query)
+	if err != nil {
+		return err
+	}
+	return checkPlanCost(plan, prepared.limited, pc.limits)
+}
+
+// rejectedByPostgres reports whether PostgreSQL refused a query for its text, with a
+// syntax error or an unknown name (SQLSTATE class 42). Connection failures, timeouts
+// and cancellations are not the query's fault and are not reported.
+func rejectedByPostgres(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code.Class() == "42"
+}
+
+// preparedQuery is a validated query with its row limit applied, and the read-only
+// transaction it runs in
+type preparedQuery struct {
+	tx      *sql.Tx
+	query   string
+	limited bool
+	explain bool
+}
+
+// prepareReadOnly validates query and opens a READ ONLY transaction with the statement
+// timeout set. The caller must end the transaction.
+func (pc *PostgresConnector) prepareReadOnly(ctx context.Context, query string, timeout time.Duration) (*preparedQuery, error) {
+	stmt, err := pc.validateAndSanitizeQuery(query)
+	if err != nil {
 		pc.logger.Error("Query validation failed",
 			"error", err,
 			"query_preview", query[:min(100, len(query))])
 		return nil, fmt.Errorf("query validation failed: %w", err)
 	}
 
-	pc.logger.Info("Executing validated query", "query_length", len(query))
-
-	rows, err := pc.db.QueryContext(ctx, query)
-	if err != nil {
-		pc.logger.Error("Query execution failed", "error", err)
-		return nil, fmt.Errorf("failed to execute query: %w", err)
+	if timeout <= 0 {
+		timeout = DefaultStatementTimeout
 	}
-	defer rows.Close()
 
-	columns, err := rows.Columns()
+	tx, err := pc.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get columns: %w", err)
+		return nil, fmt.Errorf("failed to begin read-only transaction: %w", err)
 	}
 
-	var data []map[string]interface{}
-	for rows.Next() {
-		values := make([]interface{}, len(columns))
-		valuePtrs := make([]interface{}, len(columns))
-		for i := range columns {
-			valuePtrs[i] = &values[i]
-		}
-
-		if err := rows.Scan(valuePtrs...); err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
-		}
-
-		row := make(map[string]interface{})
-		for i, col := range columns {
-			val := values[i]
-			if b, ok := val.([]byte); ok {
-				row[col] = string(b)
-			} else {
-				row[col] = val
-			}
-		}
-		data = append(data, row)
+	// SET LOCAL does not accept bind parameters; the value is an integer we format ourselves
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", timeout.Milliseconds())); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to set statement timeout: %w", err)
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("row iteration error: %w", err)
+	prepared := &preparedQuery{tx: tx, query: query}
+	if _, isExplain := stmt.(*sqlparse.Explain); isExplain {
+		prepared.explain = true
+	} else {
+		prepared.query, prepared.limited = limitQuery(query, stmt, pc.limits.MaxRows, sqlparse.DialectPostgres)
 	}
-
-	pc.logger.Info("Query executed successfully", "rows", len(data))
-	return &QueryResult{Data: data}, nil
+	return prepared, nil
 }
 
 func (pc *PostgresConnector) GetBikeSalesData(ctx context.Context) (*QueryResult, error) {
@@ -235,15 +287,8 @@ func (pc *PostgresConnector) TestConnection(ctx context.Context) (*QueryResult,
 // ExecuteQueryWithParams executes a parameterized query (safer than raw SQL)
 // SECURITY: Use this method when you need to include dynamic values in queries
 // Example: ExecuteQueryWithParams(ctx, "SELECT * FROM users WHERE id = $1", userId)
+// The query runs like ExecuteQuery, with the same row limit and planner check.
 func (pc *PostgresConnector) ExecuteQueryWithParams(ctx context.Context, query string, args ...interface{}) (*QueryResult, error) {
-	// Still validate the query structure
-	if err := pc.validateAndSanitizeQuery(query); err != nil {
-		pc.logger.Error("Parameterized query validation failed",
-			"error", err,
-			"query_preview", query[:min(100, len(query))])
-		return nil, fmt.Errorf("query validation failed: %w", err)
-	}
-
 	// Verify that the query uses placeholders if args are provided
 	if len(args) > 0 {
 		// Count placeholders ($1, $2, etc.)
@@ -263,50 +308,166 @@ func (pc *PostgresConnector) ExecuteQueryWithParams(ctx context.Context, query s
 		}
 	}
 
-	pc.logger.Info("Executing parameterized query",
-		"query_length", len(query),
-		"param_count", len(args))
+	it, err := pc.queryRows(ctx, query, DefaultStatementTimeout, args)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
 
-	rows, err := pc.db.QueryContext(ctx, query, args...)
+	result, err := collectRows(it)
 	if err != nil {
-		pc.logger.Error("Parameterized query execution failed", "error", err)
-		return nil, fmt.Errorf("failed to execute query: %w", err)
+		return nil, err
 	}
-	defer rows.Close()
 
-	columns, err := rows.Columns()
+	pc.logger.Info("Parameterized query executed successfully", "rows", len(result.Data), "truncated", result.Truncated)
+	return result, nil
+}
+
+// GetTableSchemas reads tables, views and their columns from pg_catalog, including
+// primary keys, single-column unique constraints, comments, row estimates and foreign keys
+func (pc *PostgresConnector) GetTableSchemas(ctx context.Context) ([]TableSchema, error) {
+	query := `
+		SELECT
+			n.nspname,
+			c.relname,
+			COALESCE(obj_description(c.oid, 'pg_class'), ''),
+			GREATEST(c.reltuples, 0)::bigint,
+			a.attname,
+			format_type(a.atttypid, NULL),
+			NOT a.attnotnull,
+			COALESCE(col_description(c.oid, a.attnum), ''),
+			EXISTS (
+				SELECT 1 FROM pg_index i
+				WHERE i.indrelid = c.oid AND i.indisprimary AND a.attnum = ANY(i.indkey)
+			),
+			EXISTS (
+				SELECT 1 FROM pg_index i
+				WHERE i.indrelid = c.oid AND i.indisunique AND i.indnatts = 1 AND i.indkey[0] = a.attnum
+			)
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped
+		WHERE c.relkind IN ('r', 'p', 'v', 'm')
+			AND NOT c.relispartition
+			AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+			AND n.nspname NOT LIKE 'pg_toast%'
+			AND n.nspname NOT LIKE 'pg_temp%'
+			AND has_table_privilege(c.oid, 'SELECT')
+		ORDER BY n.nspname, c.relname, a.attnum`
+
+	rows, err := pc.db.QueryContext(ctx, query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get columns: %w", err)
+		return nil, fmt.Errorf("failed to read pg_catalog: %w", err)
 	}
+	defer rows.Close()
+
+	var tables []TableSchema
+	index := make(map[string]int)
 
-	var data []map[string]interface{}
 	for rows.Next() {
-		values := make([]interface{}, len(columns))
-		valuePtrs := make([]interface{}, len(columns))
-		for i := range columns {
-			valuePtrs[i] = &values[i]
+		var (
+			schemaName, tableName, tableComment string
+			rowEstimate                         int64
+			columnName, dataType, columnComment string
+			nullable, primaryKey, unique        bool
+		)
+		if err := rows.Scan(&schemaName, &tableName, &tableComment, &rowEstimate,
+			&columnName, &dataType, &nullable, &columnComment, &primaryKey, &unique); err != nil {
+			return nil, fmt.Errorf("failed to scan column metadata: %w", err)
 		}
 
-		if err := rows.Scan(valuePtrs...); err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
+		key := schemaName + "." + tableName
+		pos, ok := index[key]
+		if !ok {
+			tables = append(tables, TableSchema{
+				Schema:      schemaName,
+				Name:        tableName,
+				Description: tableComment,
+				RowEstimate: rowEstimate,
+			})
+			pos = len(tables) - 1
+			index[key] = pos
 		}
 
-		row := make(map[string]interface{})
-		for i, col := range columns {
-			val := values[i]
-			if b, ok := val.([]byte); ok {
-				row[col] = string(b)
-			} else {
-				row[col] = val
-			}
-		}
-		data = append(data, row)
+		tables[pos].Columns = append(tables[pos].Columns, ColumnSchema{
+			Name:        columnName,
+			DataType:    dataType,
+			Nullable:    nullable,
+			PrimaryKey:  primaryKey,
+			Unique:      unique || primaryKey,
+			Description: columnComment,
+		})
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("row iteration error: %w", err)
 	}
 
-	pc.logger.Info("Parameterized query executed successfully", "rows", len(data))
-	return &QueryResult{Data: data}, nil
-}
\ No newline at end of file
+	if err := pc.loadForeignKeys(ctx, tables, index); err != nil {
+		return nil, err
+	}
+
+	pc.logger.Info("Scanned PostgreSQL schema", "tables", len(tables))
+	return tables, nil
+}
+
+// loadForeignKeys attaches foreign key constraints to the scanned tables
+func (pc *PostgresConnector) loadForeignKeys(ctx context.Context, tables []TableSchema, index map[string]int) error {
+	query := `
+		SELECT n.nspname, c.relname, a.attname, rn.nspname, rc.relname, ra.attname
+		FROM pg_constraint con
+		JOIN pg_class c ON c.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_class rc ON rc.oid = con.confrelid
+		JOIN pg_namespace rn ON rn.oid = rc.relnamespace
+		CROSS JOIN LATERAL unnest(con.conkey, con.confkey) AS k(attnum, refattnum)
+		JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+		JOIN pg_attribute ra ON ra.attrelid = con.confrelid AND ra.attnum = k.refattnum
+		WHERE con.contype = 'f'
+			AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+		ORDER BY n.nspname, c.relname, con.conname`
+
+	rows, err := pc.db.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to read foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var schemaName, tableName string
+		var fk ForeignKey
+		if err := rows.Scan(&schemaName, &tableName, &fk.Column, &fk.RefSchema, &fk.RefTable, &fk.RefColumn); err != nil {
+			return fmt.Errorf("failed to scan foreign key: %w", err)
+		}
+		if pos, ok := index[schemaName+"."+tableName]; ok {
+			tables[pos].ForeignKeys = append(tables[pos].ForeignKeys, fk)
+		}
+	}
+
+	return rows.Err()
+}
+
+// SampleValues fills each column's SampleValues from a bounded read of every table
+func (pc *PostgresConnector) SampleValues(ctx context.Context, tables []TableSchema, rows int) {
+	sampleColumnValues(ctx, pc.db, tables, rows, quotePostgresIdentifier, pc.logger)
+}
+
+// ProfileDistinctValues fills the DistinctValues of low-cardinality text columns with
+// up to limit values each
+func (pc *PostgresConnector) ProfileDistinctValues(ctx context.Context, tables []TableSchema, limit int) {
+	profileDistinctValues(ctx, pc.db, tables, limit, quotePostgresIdentifier, pc.logger)
+}
+
+// quotePostgresIdentifier quotes an identifier for PostgreSQL
+func quotePostgresIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// scanRows converts result rows into generic records with typed values
+func scanRows(rows *sql.Rows) ([]map[string]interface{}, error) {
+	result, err := scanResult(rows, 0)
+	if err != nil {
+		return nil, err
+	}
+	return result.Data, nil
+}
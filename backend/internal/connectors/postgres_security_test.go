@@ -1,9 +1,15 @@
 package connectors
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"os"
 	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
 )
 
 // TestValidateAndSanitizeQuery tests the SQL injection protection
@@ -54,11 +60,33 @@ func TestValidateAndSanitizeQuery(t *testing.T) {
 			wantError: false, // UNION is allowed in SELECT, but we could add stricter rules
 		},
 		{
-			name:      "SQL Injection - Comment bypass",
+			// Comments are dropped by the parser, so they cannot hide a second statement
+			name:      "Trailing comment",
 			query:     "SELECT * FROM users WHERE id = 1-- AND active = true",
+			wantError: false,
+		},
+		{
+			name:      "Columns named like keywords",
+			query:     "SELECT created_at, updated_by, last_update FROM orders",
+			wantError: false,
+		},
+		{
+			name:      "Valid WITH query",
+			query:     "WITH recent AS (SELECT * FROM orders WHERE created_at > now() - interval '7 days') SELECT count(*) FROM recent",
+			wantError: false,
+		},
+		{
+			name:      "Dangerous - data-modifying CTE",
+			query:     "WITH gone AS (DELETE FROM users RETURNING id) SELECT * FROM gone",
 			wantError: true,
 			errorType: ErrDangerousQuery,
 		},
+		{
+			name:      "Malformed SELECT",
+			query:     "SELECT FROM WHERE",
+			wantError: true,
+			errorType: ErrInvalidQuery,
+		},
 		{
 			name:      "Dangerous - DELETE statement",
 			query:     "DELETE FROM users WHERE id = 1",
@@ -138,20 +166,19 @@ func TestValidateAndSanitizeQuery(t *testing.T) {
 			errorType: ErrDangerousQuery,
 		},
 		{
-			name:      "Block comment injection",
+			name:      "Block comment",
 			query:     "SELECT * FROM users /* WHERE id = 1 */ WHERE id = 2",
-			wantError: true,
-			errorType: ErrDangerousQuery,
+			wantError: false,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := pc.validateAndSanitizeQuery(tt.query)
+			_, err := pc.validateAndSanitizeQuery(tt.query)
 			if tt.wantError {
 				if err == nil {
 					t.Errorf("validateAndSanitizeQuery() expected error but got none for query: %s", tt.query)
-				} else if tt.errorType != nil && err != tt.errorType {
+				} else if tt.errorType != nil && !errors.Is(err, tt.errorType) {
 					t.Errorf("validateAndSanitizeQuery() error = %v, want %v", err, tt.errorType)
 				}
 			} else {
@@ -204,7 +231,7 @@ func TestSQLInjectionScenarios(t *testing.T) {
 		t.Run("Injection: "+injection, func(t *testing.T) {
 			// All injection attempts should be caught somewhere in validation
 			query := "SELECT * FROM users WHERE id = " + injection
-			err := pc.validateAndSanitizeQuery(query)
+			_, err := pc.validateAndSanitizeQuery(query)
 
 			// We expect most injections to fail validation
 			// Some might pass if they look like valid SELECT statements
@@ -215,3 +242,35 @@ func TestSQLInjectionScenarios(t *testing.T) {
 		})
 	}
 }
+
+// TestRejectedByDatabase checks that only errors about the query text count as
+// rejections, so infrastructure failures are not sent back for repair
+func TestRejectedByDatabase(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		postgres bool
+		mysql    bool
+	}{
+		{name: "postgres undefined column", err: &pq.Error{Code: "42703"}, postgres: true},
+		{name: "postgres syntax error", err: fmt.Errorf("wrapped: %w", &pq.Error{Code: "42601"}), postgres: true},
+		{name: "postgres statement timeout", err: &pq.Error{Code: "57014"}},
+		{name: "postgres connection failure", err: &pq.Error{Code: "08006"}},
+		{name: "mysql unknown column", err: &mysql.MySQLError{Number: 1054, SQLState: [5]byte{'4', '2', 'S', '2', '2'}}, mysql: true},
+		{name: "mysql syntax error", err: &mysql.MySQLError{Number: 1064, SQLState: [5]byte{'4', '2', '0', '0', '0'}}, mysql: true},
+		{name: "mysql execution time exceeded", err: &mysql.MySQLError{Number: 3024, SQLState: [5]byte{'H', 'Y', '0', '0', '0'}}},
+		{name: "context deadline", err: context.DeadlineExceeded},
+		{name: "bad connection", err: mysql.ErrInvalidConn},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rejectedByPostgres(tt.err); got != tt.postgres {
+				t.Errorf("rejectedByPostgres() = %v, want %v", got, tt.postgres)
+			}
+			if got := rejectedByMySQL(tt.err); got != tt.mysql {
+				t.Errorf("rejectedByMySQL() = %v, want %v", got, tt.mysql)
+			}
+		})
+	}
+}
@@ -0,0 +1,458 @@
+package connectors
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ColumnRole is the analytical role of a result column
+type ColumnRole string
+
+const (
+	ColumnRoleMetric    ColumnRole = "metric"
+	ColumnRoleDimension ColumnRole = "dimension"
+	ColumnRoleTime      ColumnRole = "time"
+)
+
+// ResultColumn describes a column of a query result
+type ResultColumn struct {
+	Name string `json:"name"`
+	// DatabaseType is the type reported by the source, e.g. NUMERIC or VARCHAR; for
+	// sources without one it is inferred from the values
+	DatabaseType string `json:"database_type"`
+	// Nullable is false only when the source reports the column as NOT NULL
+	Nullable bool       `json:"nullable"`
+	Role     ColumnRole `json:"role"`
+}
+
+// RowIterator streams the rows of a query result, so large results can be written
+// out without being held in memory. Callers must Close it.
+type RowIterator interface {
+	// Columns describes the values of each row, in order
+	Columns() []ResultColumn
+	// Next advances to the next row and reports whether there is one
+	Next() bool
+	// Row returns the typed values of the current row; the slice is reused by Next
+	Row() []interface{}
+	// Err returns the error that stopped iteration, if any
+	Err() error
+	// Truncated reports, once Next has returned false, whether rows beyond the
+	// connector's row limit were skipped
+	Truncated() bool
+	Close() error
+}
+
+// Rows iterates over an in-memory result
+func (r *QueryResult) Rows() RowIterator {
+	columns := r.Columns
+	if len(columns) == 0 {
+		columns = columnsFromRecords(r.Data)
+	}
+	return &recordIterator{columns: columns, data: r.Data, index: -1, truncated: r.Truncated}
+}
+
+type recordIterator struct {
+	columns   []ResultColumn
+	data      []map[string]interface{}
+	index     int
+	row       []interface{}
+	truncated bool
+}
+
+func (it *recordIterator) Columns() []ResultColumn { return it.columns }
+func (it *recordIterator) Err() error              { return nil }
+func (it *recordIterator) Truncated() bool         { return it.truncated }
+func (it *recordIterator) Close() error            { return nil }
+func (it *recordIterator) Row() []interface{}      { return it.row }
+
+func (it *recordIterator) Next() bool {
+	it.index++
+	if it.index >= len(it.data) {
+		return false
+	}
+	if it.row == nil {
+		it.row = make([]interface{}, len(it.columns))
+	}
+	for i, col := range it.columns {
+		it.row[i] = it.data[it.index][col.Name]
+	}
+	return true
+}
+
+// sqlRowIterator converts database rows into typed values as they are read
+type sqlRowIterator struct {
+	rows      *sql.Rows
+	columns   []ResultColumn
+	raw       []interface{}
+	row       []interface{}
+	maxRows   int
+	count     int
+	truncated bool
+	err       error
+	onClose   func() error
+}
+
+// newSQLRowIterator reads the column metadata of rows. At most maxRows rows are
+// returned when maxRows is positive; onClose, if set, runs after rows are closed.
+func newSQLRowIterator(rows *sql.Rows, maxRows int, onClose func() error) (*sqlRowIterator, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get columns: %w", err)
+	}
+
+	columns := make([]ResultColumn, len(types))
+	for i, ct := range types {
+		dbType := strings.ToUpper(ct.DatabaseTypeName())
+		nullable, ok := ct.Nullable()
+		columns[i] = ResultColumn{
+			Name:         ct.Name(),
+			DatabaseType: dbType,
+			Nullable:     nullable || !ok,
+			Role:         inferColumnRole(ct.Name(), dbType),
+		}
+	}
+
+	return &sqlRowIterator{
+		rows:    rows,
+		columns: columns,
+		raw:     make([]interface{}, len(columns)),
+		row:     make([]interface{}, len(columns)),
+		maxRows: maxRows,
+		onClose: onClose,
+	}, nil
+}
+
+func (it *sqlRowIterator) Columns() []ResultColumn { return it.columns }
+func (it *sqlRowIterator) Row() []interface{}      { return it.row }
+func (it *sqlRowIterator) Err() error              { return it.err }
+func (it *sqlRowIterator) Truncated() bool         { return it.truncated }
+
+func (it *sqlRowIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if !it.rows.Next() {
+		if err := it.rows.Err(); err != nil {
+			it.err = fmt.Errorf("row iteration error: %w", err)
+		}
+		return false
+	}
+	if it.maxRows > 0 && it.count >= it.maxRows {
+		it.truncated = true
+		return false
+	}
+
+	ptrs := make([]interface{}, len(it.raw))
+	for i := range it.raw {
+		ptrs[i] = &it.raw[i]
+	}
+	if err := it.rows.Scan(ptrs...); err != nil {
+		it.err = fmt.Errorf("failed to scan row: %w", err)
+		return false
+	}
+	for i, col := range it.columns {
+		it.row[i] = convertSQLValue(it.raw[i], col.DatabaseType)
+	}
+	it.count++
+	return true
+}
+
+func (it *sqlRowIterator) Close() error {
+	err := it.rows.Close()
+	if it.onClose != nil {
+		if closeErr := it.onClose(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// closingIterator closes an extra resource, such as a connection pool, with the iterator
+type closingIterator struct {
+	RowIterator
+	close func() error
+}
+
+func (it *closingIterator) Close() error {
+	err := it.RowIterator.Close()
+	if closeErr := it.close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// collectRows reads all rows of an iterator into a QueryResult
+func collectRows(it RowIterator) (*QueryResult, error) {
+	columns := it.Columns()
+	data := []map[string]interface{}{}
+	for it.Next() {
+		record := make(map[string]interface{}, len(columns))
+		for i, value := range it.Row() {
+			record[columns[i].Name] = value
+		}
+		data = append(data, record)
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return &QueryResult{Columns: columns, Data: data, Truncated: it.Truncated()}, nil
+}
+
+// scanResult reads database rows into a typed result of at most maxRows rows
+func scanResult(rows *sql.Rows, maxRows int) (*QueryResult, error) {
+	it, err := newSQLRowIterator(rows, maxRows, nil)
+	if err != nil {
+		return nil, err
+	}
+	return collectRows(it)
+}
+
+// convertSQLValue turns the byte slices drivers return for numeric, JSON and text
+// columns into numbers, decoded JSON and strings
+func convertSQLValue(value interface{}, dbType string) interface{} {
+	b, ok := value.([]byte)
+	if !ok {
+		return value
+	}
+	text := string(b)
+
+	switch {
+	case isIntegerDatabaseType(dbType):
+		if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return n
+		}
+	case isDecimalDatabaseType(dbType):
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return f
+		}
+	case dbType == "JSON" || dbType == "JSONB":
+		var decoded interface{}
+		if err := json.Unmarshal(b, &decoded); err == nil {
+			return decoded
+		}
+	}
+	return text
+}
+
+func isIntegerDatabaseType(dbType string) bool {
+	switch dbType {
+	case "INT", "INT2", "INT4", "INT8", "INTEGER", "SMALLINT", "MEDIUMINT", "BIGINT", "TINYINT", "YEAR",
+		"UNSIGNED INT", "UNSIGNED SMALLINT", "UNSIGNED MEDIUMINT", "UNSIGNED BIGINT", "UNSIGNED TINYINT":
+		return true
+	}
+	return false
+}
+
+func isDecimalDatabaseType(dbType string) bool {
+	switch dbType {
+	case "NUMERIC", "DECIMAL", "FLOAT", "FLOAT4", "FLOAT8", "DOUBLE", "REAL":
+		return true
+	}
+	return false
+}
+
+func isTimeDatabaseType(dbType string) bool {
+	switch dbType {
+	case "DATE", "TIME", "TIMETZ", "TIMESTAMP", "TIMESTAMPTZ", "DATETIME":
+		return true
+	}
+	return false
+}
+
+// inferColumnRole classifies a result column from its name and type. Numeric
+// identifiers and codes such as zip codes are dimensions, not metrics.
+func inferColumnRole(name, dbType string) ColumnRole {
+	name = strings.ToLower(name)
+	upper := strings.ToUpper(dbType)
+
+	switch {
+	case isTimeDatabaseType(upper) || upper == "YEAR":
+		return ColumnRoleTime
+	case isIntegerDatabaseType(upper) || isDecimalDatabaseType(upper) || upper == "MONEY" || upper == "NUMBER":
+		if name == "id" || strings.HasSuffix(name, "_id") || containsAnyWord(name, []string{"zip", "postal", "code", "phone"}) {
+			return ColumnRoleDimension
+		}
+		if name == "year" || strings.HasSuffix(name, "_year") {
+			return ColumnRoleTime
+		}
+		return ColumnRoleMetric
+	default:
+		return ColumnRoleDimension
+	}
+}
+
+// containsAnyWord reports whether text contains any of the given fragments
+func containsAnyWord(text string, words []string) bool {
+	for _, word := range words {
+		if strings.Contains(text, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// columnsFromRecords infers result columns from schemaless records, such as
+// documents, API responses and file rows. Maps carry no order, so columns are
+// sorted by name.
+func columnsFromRecords(data []map[string]interface{}) []ResultColumn {
+	types := make(map[string]string)
+	nullable := make(map[string]bool)
+	for _, record := range data {
+		for name, value := range record {
+			valueType := recordValueType(value)
+			if valueType == "" {
+				nullable[name] = true
+				if _, seen := types[name]; !seen {
+					types[name] = ""
+				}
+				continue
+			}
+			if current := types[name]; current == "" {
+				types[name] = valueType
+			} else if current != valueType {
+				types[name] = mixedValueType(current, valueType)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	columns := make([]ResultColumn, len(names))
+	for i, name := range names {
+		columns[i] = ResultColumn{
+			Name:         name,
+			DatabaseType: types[name],
+			Nullable:     nullable[name],
+			Role:         inferColumnRole(name, types[name]),
+		}
+	}
+	return columns
+}
+
+// recordValueType names the type of a record value; "" for nil
+func recordValueType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return ""
+	case int, int32, int64:
+		return "BIGINT"
+	case float32, float64:
+		return "DOUBLE"
+	case json.Number:
+		return "NUMERIC"
+	}
+	switch documentValueType(value) {
+	case "date":
+		return "TIMESTAMP"
+	case "bool":
+		return "BOOLEAN"
+	case "string":
+		return "TEXT"
+	default:
+		return "JSON"
+	}
+}
+
+// mixedValueType widens two value types seen in the same column
+func mixedValueType(a, b string) string {
+	numeric := map[string]bool{"BIGINT": true, "DOUBLE": true, "NUMERIC": true}
+	if numeric[a] && numeric[b] {
+		return "DOUBLE"
+	}
+	return "TEXT"
+}
+
+// WriteJSON streams the rows of it to w as
+// {"columns": [...], "rows": [[...], ...], "truncated": false}. An error met
+// after output has started is reported in an "error" field and returned.
+func WriteJSON(w io.Writer, it RowIterator) error {
+	columns, err := json.Marshal(it.Columns())
+	if err != nil {
+		return fmt.Errorf("failed to encode columns: %w", err)
+	}
+	if _, err := fmt.Fprintf(w, `{"success":true,"columns":%s,"rows":[`, columns); err != nil {
+		return err
+	}
+
+	count := 0
+	for it.Next() {
+		row, err := json.Marshal(it.Row())
+		if err != nil {
+			return fmt.Errorf("failed to encode row %d: %w", count, err)
+		}
+		if count > 0 {
+			row = append([]byte{','}, row...)
+		}
+		if _, err := w.Write(row); err != nil {
+			return err
+		}
+		count++
+	}
+
+	if iterErr := it.Err(); iterErr != nil {
+		message, _ := json.Marshal(iterErr.Error())
+		fmt.Fprintf(w, `],"row_count":%d,"truncated":%t,"error":%s}`, count, it.Truncated(), message)
+		return iterErr
+	}
+	_, err = fmt.Fprintf(w, `],"row_count":%d,"truncated":%t}`, count, it.Truncated())
+	return err
+}
+
+// WriteCSV streams the rows of it to w as CSV with a header row
+func WriteCSV(w io.Writer, it RowIterator) error {
+	writer := csv.NewWriter(w)
+	columns := it.Columns()
+
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.Name
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	record := make([]string, len(columns))
+	for it.Next() {
+		for i, value := range it.Row() {
+			record[i] = formatCSVValue(value)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	if err := it.Err(); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+func formatCSVValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]interface{}, []interface{}:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	default:
+		return fmt.Sprint(v)
+	}
+}
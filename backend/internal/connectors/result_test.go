@@ -0,0 +1,86 @@
+package connectors
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// TestConvertSQLValue tests that driver byte slices become typed values
+func TestConvertSQLValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  interface{}
+		dbType string
+		want   interface{}
+	}{
+		{"numeric", []byte("12.50"), "NUMERIC", 12.5},
+		{"mysql integer", []byte("42"), "BIGINT", int64(42)},
+		{"text", []byte("north"), "VARCHAR", "north"},
+		{"unparseable number", []byte("NaN?"), "DECIMAL", "NaN?"},
+		{"already typed", int64(7), "INT8", int64(7)},
+		{"null", nil, "NUMERIC", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := convertSQLValue(tt.value, tt.dbType); got != tt.want {
+				t.Errorf("convertSQLValue() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestInferColumnRole tests the metric, dimension and time classification
+func TestInferColumnRole(t *testing.T) {
+	tests := []struct {
+		name   string
+		dbType string
+		want   ColumnRole
+	}{
+		{"revenue", "NUMERIC", ColumnRoleMetric},
+		{"customer_id", "INT8", ColumnRoleDimension},
+		{"zip_code", "INT4", ColumnRoleDimension},
+		{"created_at", "TIMESTAMPTZ", ColumnRoleTime},
+		{"order_year", "INT4", ColumnRoleTime},
+		{"region", "TEXT", ColumnRoleDimension},
+	}
+
+	for _, tt := range tests {
+		if got := inferColumnRole(tt.name, tt.dbType); got != tt.want {
+			t.Errorf("inferColumnRole(%q, %q) = %q, want %q", tt.name, tt.dbType, got, tt.want)
+		}
+	}
+}
+
+// TestWriteJSON tests streaming an in-memory result
+func TestWriteJSON(t *testing.T) {
+	result := &QueryResult{
+		Data: []map[string]interface{}{
+			{"region": "north", "revenue": 10.5},
+			{"region": "south", "revenue": nil},
+		},
+		Truncated: true,
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, result.Rows()); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+
+	var decoded struct {
+		Columns   []ResultColumn  `json:"columns"`
+		Rows      [][]interface{} `json:"rows"`
+		RowCount  int             `json:"row_count"`
+		Truncated bool            `json:"truncated"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if len(decoded.Columns) != 2 || decoded.Columns[1].Name != "revenue" || decoded.Columns[1].Role != ColumnRoleMetric || !decoded.Columns[1].Nullable {
+		t.Errorf("unexpected columns: %+v", decoded.Columns)
+	}
+	if decoded.RowCount != 2 || decoded.Rows[0][1] != 10.5 || !decoded.Truncated {
+		t.Errorf("unexpected rows: %s", buf.String())
+	}
+}
@@ -0,0 +1,261 @@
+package connectors
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Bounds for value sampling during schema scans
+const (
+	// Rows read per table when the caller does not set a sample size
+	defaultSampleRows = 100
+	// Distinct values kept per column
+	maxSampleValuesPerColumn = 5
+	// Longer values are truncated before being stored as samples
+	maxSampleValueLength = 64
+	// Tables beyond this count are described without samples
+	maxSampledTables = 200
+	// Upper bound for a single table's sample query
+	sampleQueryTimeout = 5 * time.Second
+	// Distinct values listed per low-cardinality column when the caller does not set a limit
+	defaultDistinctValues = 100
+	// Columns profiled for distinct values per scan
+	maxProfiledColumns = 500
+	// Tables estimated to hold more rows are not profiled, since grouping them reads every row
+	maxProfiledRows = 10_000_000
+)
+
+// TableSchema describes a table (or table-like object) discovered in a data source
+type TableSchema struct {
+	Schema      string         `json:"schema,omitempty"`
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Columns     []ColumnSchema `json:"columns"`
+	RowEstimate int64          `json:"row_estimate,omitempty"`
+	ForeignKeys []ForeignKey   `json:"foreign_keys,omitempty"`
+	Metrics     []MetricSchema `json:"metrics,omitempty"` // metrics curated in the source
+	Tags        []string       `json:"tags,omitempty"`    // extra search tags supplied by the driver
+}
+
+// ColumnSchema describes a single column of a discovered table
+type ColumnSchema struct {
+	Name         string   `json:"name"`
+	DataType     string   `json:"data_type"`
+	Nullable     bool     `json:"nullable"`
+	PrimaryKey   bool     `json:"primary_key,omitempty"`
+	Unique       bool     `json:"unique,omitempty"`
+	Description  string   `json:"description,omitempty"`
+	SampleValues []string `json:"sample_values,omitempty"`
+	// DistinctValues lists every value of a low-cardinality column, most frequent first,
+	// when the scan profiled it
+	DistinctValues []string `json:"distinct_values,omitempty"`
+	// Temporal marks a column the source declares as a time axis (e.g. Superset is_dttm)
+	Temporal bool `json:"temporal,omitempty"`
+	// Groupable is the source's own dimension flag; nil leaves it to name and type heuristics
+	Groupable *bool `json:"groupable,omitempty"`
+}
+
+// MetricSchema describes a metric defined in the source, such as a Superset saved metric
+type MetricSchema struct {
+	Name        string `json:"name"`
+	Label       string `json:"label,omitempty"`
+	Description string `json:"description,omitempty"`
+	Expression  string `json:"expression"`
+}
+
+// ForeignKey describes a column that references a column of another table
+type ForeignKey struct {
+	Column    string `json:"column"`
+	RefSchema string `json:"ref_schema,omitempty"`
+	RefTable  string `json:"ref_table"`
+	RefColumn string `json:"ref_column"`
+}
+
+// sampleColumnValues reads a bounded number of rows from each table inside a
+// read-only transaction and keeps a few distinct non-null values per column.
+// Identifiers come from the catalog and are escaped with quote for the dialect.
+// Sampling is best effort: failures are logged and the table is left without samples.
+func sampleColumnValues(ctx context.Context, db *sql.DB, tables []TableSchema, rows int, quote func(string) string, logger *slog.Logger) {
+	if rows <= 0 {
+		rows = defaultSampleRows
+	}
+
+	for i := range tables {
+		if i >= maxSampledTables {
+			logger.Info("Skipping value sampling for remaining tables", "sampled", maxSampledTables, "total", len(tables))
+			return
+		}
+
+		table := &tables[i]
+		var selected []string
+		positions := make(map[string]int)
+		for j, col := range table.Columns {
+			if isUnsampledType(col.DataType) {
+				continue
+			}
+			selected = append(selected, quote(col.Name))
+			positions[col.Name] = j
+		}
+		if len(selected) == 0 {
+			continue
+		}
+
+		name := quote(table.Name)
+		if table.Schema != "" {
+			name = quote(table.Schema) + "." + name
+		}
+		query := fmt.Sprintf("SELECT %s FROM %s LIMIT %d", strings.Join(selected, ", "), name, rows)
+
+		data, err := sampleQuery(ctx, db, query)
+		if err != nil {
+			logger.Warn("Failed to sample table values", "table", table.Name, "error", err)
+			continue
+		}
+
+		for colName, j := range positions {
+			seen := make(map[string]bool)
+			for _, record := range data {
+				if len(table.Columns[j].SampleValues) >= maxSampleValuesPerColumn {
+					break
+				}
+				value, ok := formatSampleValue(record[colName])
+				if !ok || seen[value] {
+					continue
+				}
+				seen[value] = true
+				table.Columns[j].SampleValues = append(table.Columns[j].SampleValues, value)
+			}
+		}
+	}
+}
+
+// profileDistinctValues lists every value of the low-cardinality text columns of
+// each table, most frequent first, so values named in questions can be linked to
+// their columns. Columns with more than limit values are left unprofiled; a
+// negative limit disables profiling. Like sampling, profiling is best effort.
+func profileDistinctValues(ctx context.Context, db *sql.DB, tables []TableSchema, limit int, quote func(string) string, logger *slog.Logger) {
+	if limit < 0 {
+		return
+	}
+	if limit == 0 {
+		limit = defaultDistinctValues
+	}
+
+	profiled := 0
+	for i := range tables {
+		table := &tables[i]
+		if table.RowEstimate > maxProfiledRows {
+			continue
+		}
+
+		name := quote(table.Name)
+		if table.Schema != "" {
+			name = quote(table.Schema) + "." + name
+		}
+
+		for j := range table.Columns {
+			col := &table.Columns[j]
+			if col.PrimaryKey || col.Unique || !isProfiledType(col.DataType) {
+				continue
+			}
+			if profiled >= maxProfiledColumns {
+				logger.Info("Skipping value profiling for remaining columns", "profiled", maxProfiledColumns)
+				return
+			}
+			profiled++
+
+			column := quote(col.Name)
+			query := fmt.Sprintf("SELECT %s AS value FROM %s WHERE %s IS NOT NULL GROUP BY %s ORDER BY COUNT(*) DESC LIMIT %d",
+				column, name, column, column, limit+1)
+			data, err := sampleQuery(ctx, db, query)
+			if err != nil {
+				logger.Warn("Failed to profile column values", "table", table.Name, "column", col.Name, "error", err)
+				continue
+			}
+			if len(data) > limit {
+				continue
+			}
+
+			for _, record := range data {
+				// Values are kept verbatim since filters compare them for equality
+				if value := fmt.Sprint(record["value"]); record["value"] != nil && len(value) <= maxSampleValueLength {
+					col.DistinctValues = append(col.DistinctValues, value)
+				}
+			}
+		}
+	}
+}
+
+// isProfiledType reports whether a column type holds categorical text worth profiling
+func isProfiledType(dataType string) bool {
+	dataType = strings.ToLower(dataType)
+	if isUnsampledType(dataType) {
+		return false
+	}
+	for _, t := range []string{"char", "text", "string", "enum", "user-defined"} {
+		if strings.Contains(dataType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// sampleQuery runs a catalog-derived query in a short read-only transaction
+func sampleQuery(ctx context.Context, db *sql.DB, query string) ([]map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, sampleQueryTimeout)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRows(rows)
+}
+
+// isUnsampledType reports whether values of a type are too large or opaque to sample
+func isUnsampledType(dataType string) bool {
+	dataType = strings.ToLower(dataType)
+	for _, t := range []string{"bytea", "blob", "binary", "json", "xml", "geometry", "geography", "tsvector", "array", "[]"} {
+		if strings.Contains(dataType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatSampleValue renders a sampled value as a short string
+func formatSampleValue(v interface{}) (string, bool) {
+	var s string
+	switch value := v.(type) {
+	case nil:
+		return "", false
+	case time.Time:
+		if value.Hour() == 0 && value.Minute() == 0 && value.Second() == 0 {
+			s = value.Format("2006-01-02")
+		} else {
+			s = value.Format(time.RFC3339)
+		}
+	default:
+		s = strings.TrimSpace(fmt.Sprint(value))
+	}
+
+	if s == "" {
+		return "", false
+	}
+	if r := []rune(s); len(r) > maxSampleValueLength {
+		s = string(r[:maxSampleValueLength]) + "..."
+	}
+	return s, true
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"strings"
@@ -36,6 +37,44 @@ type SuperSetResponse struct {
 	Error  string                   `json:"error,omitempty"`
 }
 
+// SupersetDataset is the curated metadata of a Superset dataset from /api/v1/dataset/{id}
+type SupersetDataset struct {
+	ID          int              `json:"id"`
+	TableName   string           `json:"table_name"`
+	Schema      string           `json:"schema"`
+	Description string           `json:"description"`
+	SQL         string           `json:"sql"` // set for virtual datasets
+	MainDttmCol string           `json:"main_dttm_col"`
+	Columns     []SupersetColumn `json:"columns"`
+	Metrics     []SupersetMetric `json:"metrics"`
+	Database    struct {
+		ID           int    `json:"id"`
+		DatabaseName string `json:"database_name"`
+	} `json:"database"`
+}
+
+// SupersetColumn is a dataset column with its semantic flags
+type SupersetColumn struct {
+	ColumnName  string `json:"column_name"`
+	VerboseName string `json:"verbose_name"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	IsDttm      bool   `json:"is_dttm"`
+	Groupby     bool   `json:"groupby"`
+	Filterable  bool   `json:"filterable"`
+	Expression  string `json:"expression"` // set for calculated columns
+}
+
+// SupersetMetric is a saved metric defined on a dataset
+type SupersetMetric struct {
+	MetricName  string `json:"metric_name"`
+	VerboseName string `json:"verbose_name"`
+	Description string `json:"description"`
+	Expression  string `json:"expression"`
+	MetricType  string `json:"metric_type"`
+	D3Format    string `json:"d3format"`
+}
+
 func NewSuperSetConnector(baseURL, username, password string, logger *slog.Logger) *SuperSetConnector {
 	return &SuperSetConnector{
 		baseURL:  baseURL,
@@ -172,6 +211,10 @@ func (sc *SuperSetConnector) ExecuteSQL(ctx context.Context, sql string) (*Super
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, sqlLabError(resp)
+	}
+
 	var result SuperSetResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return nil, err
@@ -190,6 +233,33 @@ func (sc *SuperSetConnector) ExecuteSQL(ctx context.Context, sql string) (*Super
 	return &result, nil
 }
 
+// sqlLabError describes a failed SQL Lab request. Errors about the query itself,
+// such as an unknown column, wrap ErrQueryRejected.
+func sqlLabError(resp *http.Response) error {
+	var body struct {
+		Message string `json:"message"`
+		Errors  []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&body)
+
+	message := body.Message
+	if len(body.Errors) > 0 && body.Errors[0].Message != "" {
+		message = body.Errors[0].Message
+	}
+	if message == "" {
+		message = http.StatusText(resp.StatusCode)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return fmt.Errorf("%w: %s", ErrQueryRejected, message)
+	default:
+		return fmt.Errorf("SQL Lab returned status %d: %s", resp.StatusCode, message)
+	}
+}
+
 // extractKeywords extracts meaningful keywords from a user query
 func extractKeywords(query string) []string {
 	// Remove common stop words
@@ -387,52 +457,6 @@ func (sc *SuperSetConnector) QueryDashboardData(ctx context.Context, dashboardID
 	}, nil
 }
 
-// generateDashboardSQL generates appropriate SQL based on dashboard context
-func (sc *SuperSetConnector) generateDashboardSQL(dashboardTitle, query string) string {
-	// For World Bank / Health dashboards
-	if strings.Contains(dashboardTitle, "world") || strings.Contains(dashboardTitle, "health") {
-		return `
-		SELECT
-			country_name,
-			country_code,
-			year,
-			sp_pop_totl as population,
-			sh_dyn_mort as mortality_rate,
-			sp_dyn_le00_in as life_expectancy,
-			sh_xpd_chex_pc_cd as health_expenditure_per_capita
-		FROM wb_health_population
-		WHERE year >= 2015
-		ORDER BY year DESC, country_name
-		LIMIT 100
-		`
-	}
-
-	// For bike/vehicle sales dashboards
-	if strings.Contains(dashboardTitle, "bike") || strings.Contains(dashboardTitle, "vehicle") ||
-		strings.Contains(dashboardTitle, "sales") {
-		return `
-		SELECT
-			quarter,
-			bike_category,
-			total_revenue,
-			total_bikes_sold
-		FROM bike_sales
-		ORDER BY quarter, bike_category
-		LIMIT 100
-		`
-	}
-
-	// Generic query - try to discover tables
-	return `
-	SELECT table_name, column_name, data_type
-	FROM information_schema.columns
-	WHERE table_schema = 'public'
-	AND table_name NOT LIKE 'pg_%'
-	ORDER BY table_name, ordinal_position
-	LIMIT 50
-	`
-}
-
 // DEPRECATED: Use FindRelevantDataset and query actual data instead
 // This method always returns bike_sales data and should not be used
 func (sc *SuperSetConnector) GetSampleData(ctx context.Context) (*SuperSetResponse, error) {
@@ -458,7 +482,8 @@ func (sc *SuperSetConnector) GetDatasets(ctx context.Context) ([]map[string]inte
 		}
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", sc.baseURL+"/api/v1/dataset/", nil)
+	// Rison-encoded query; the API otherwise returns only the first 20 datasets
+	req, err := http.NewRequestWithContext(ctx, "GET", sc.baseURL+"/api/v1/dataset/?q=(page_size:100)", nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create dataset request: %w", err)
 	}
@@ -487,6 +512,126 @@ func (sc *SuperSetConnector) GetDatasets(ctx context.Context) ([]map[string]inte
 	return result.Result, nil
 }
 
+// GetDataset retrieves the full metadata of a single dataset, including its
+// columns, saved metrics, verbose names and descriptions
+func (sc *SuperSetConnector) GetDataset(ctx context.Context, datasetID int) (*SupersetDataset, error) {
+	if sc.token == "" {
+		if err := sc.Authenticate(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	url := fmt.Sprintf("%s/api/v1/dataset/%d", sc.baseURL, datasetID)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dataset request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+sc.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := sc.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dataset: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("dataset API returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Result SupersetDataset `json:"result"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse dataset response: %w", err)
+	}
+
+	if result.Result.ID == 0 {
+		result.Result.ID = datasetID
+	}
+
+	return &result.Result, nil
+}
+
+// GetDatasetSchemas reads every dataset's curated metadata and describes it as a table.
+// Datasets that cannot be read are skipped.
+func (sc *SuperSetConnector) GetDatasetSchemas(ctx context.Context) ([]TableSchema, error) {
+	datasets, err := sc.GetDatasets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Superset datasets: %w", err)
+	}
+
+	var tables []TableSchema
+	for _, item := range datasets {
+		id, ok := item["id"].(float64)
+		if !ok {
+			continue
+		}
+
+		dataset, err := sc.GetDataset(ctx, int(id))
+		if err != nil {
+			sc.logger.Warn("Failed to read Superset dataset", "dataset_id", int(id), "error", err)
+			continue
+		}
+
+		tables = append(tables, dataset.TableSchema())
+	}
+
+	if len(tables) == 0 && len(datasets) > 0 {
+		return nil, fmt.Errorf("none of the %d Superset datasets could be read", len(datasets))
+	}
+
+	return tables, nil
+}
+
+// TableSchema describes the dataset as a table. Superset's is_dttm and groupby flags
+// are kept as column hints so they take precedence over name and type heuristics.
+func (d *SupersetDataset) TableSchema() TableSchema {
+	table := TableSchema{
+		Schema:      d.Schema,
+		Name:        d.TableName,
+		Description: d.Description,
+		Tags:        []string{"superset"},
+	}
+	if table.Description == "" {
+		table.Description = fmt.Sprintf("Superset dataset %s", d.TableName)
+		if d.Database.DatabaseName != "" {
+			table.Description += fmt.Sprintf(" (database %s)", d.Database.DatabaseName)
+		}
+	}
+
+	for _, col := range d.Columns {
+		description := col.Description
+		if col.VerboseName != "" && col.VerboseName != col.ColumnName {
+			description = strings.TrimSpace(col.VerboseName + ". " + description)
+		}
+
+		// Calculated columns have no type; Superset marks them groupable
+		groupable := col.Groupby
+		table.Columns = append(table.Columns, ColumnSchema{
+			Name:        col.ColumnName,
+			DataType:    strings.ToLower(col.Type),
+			Nullable:    true,
+			Description: description,
+			Temporal:    col.IsDttm || col.ColumnName == d.MainDttmCol,
+			Groupable:   &groupable,
+		})
+	}
+
+	for _, metric := range d.Metrics {
+		table.Metrics = append(table.Metrics, MetricSchema{
+			Name:        metric.MetricName,
+			Label:       metric.VerboseName,
+			Description: metric.Description,
+			Expression:  metric.Expression,
+		})
+	}
+
+	return table
+}
+
 // GetDashboards retrieves all dashboards from Superset
 func (sc *SuperSetConnector) GetDashboards(ctx context.Context) ([]map[string]interface{}, error) {
 	// Try cache first if available
@@ -736,11 +881,9 @@ func (sc *SuperSetConnector) QueryDataset(ctx context.Context, userQuery string)
 	// Try multiple data retrieval methods based on query type
 	data, err := sc.getRelevantData(ctx, userQuery)
 	if err != nil {
-		sc.logger.Warn("🔄 Comprehensive data fetching failed, falling back to SQL", "error", err)
-		// Fallback to SQL generation
-		sql := sc.generateSQLFromQuery(userQuery)
-		sc.logger.Info("Generated SQL from user query", "query", userQuery, "sql", sql)
-		return sc.ExecuteSQL(ctx, sql)
+		// Callers with the dataset schema can generate SQL instead
+		sc.logger.Warn("🔄 Comprehensive data fetching failed", "error", err)
+		return nil, fmt.Errorf("%w: %w", ErrNoMatchingData, err)
 	}
 
 	sc.logger.Info("✅ Comprehensive data fetching succeeded", "rows", len(data))
@@ -944,75 +1087,6 @@ func (sc *SuperSetConnector) getRelevantData(ctx context.Context, userQuery stri
 	return sampleData, nil
 }
 
-// generateSQLFromQuery creates SQL queries based on user intent
-func (sc *SuperSetConnector) generateSQLFromQuery(userQuery string) string {
-	queryLower := strings.ToLower(userQuery)
-
-	// Gaming/Entertainment related queries - try very simple approach first
-	if strings.Contains(queryLower, "game") || strings.Contains(queryLower, "gaming") ||
-	   strings.Contains(queryLower, "entertainment") || strings.Contains(queryLower, "top game") {
-
-		// Try the simplest possible query first - just list tables
-		return `SELECT 1 as test_value, 'gaming query test' as test_message`
-	}
-
-	// Sales performance queries
-	if strings.Contains(queryLower, "sales") || strings.Contains(queryLower, "revenue") {
-		return `
-		SELECT *
-		FROM (
-			SELECT
-				COALESCE(product_name, name, item_name) as name,
-				COALESCE(category, type, class) as category,
-				COALESCE(total_sales, sales, revenue, amount) as value,
-				COALESCE(quarter, period, month) as time_period,
-				COALESCE(region, location, area) as region
-			FROM sales_data
-
-			UNION ALL
-
-			SELECT
-				COALESCE(bike_model, product_name, name) as name,
-				COALESCE(bike_category, category, type) as category,
-				COALESCE(total_revenue, revenue, sales) as value,
-				COALESCE(quarter, period, month_year) as time_period,
-				COALESCE(region, store_id, location) as region
-			FROM bike_sales
-		) combined
-		WHERE name IS NOT NULL AND value IS NOT NULL
-		ORDER BY value DESC
-		LIMIT 20`
-	}
-
-	// User activity queries
-	if strings.Contains(queryLower, "user") || strings.Contains(queryLower, "active") {
-		return `
-		SELECT
-			COALESCE(month_year, period, date) as time_period,
-			COALESCE(active_users, users, count) as active_count,
-			COALESCE(new_users, new_count, acquisitions) as new_count,
-			COALESCE(platform, source, channel) as platform,
-			COALESCE(region, location, area) as region
-		FROM monthly_active_users
-		WHERE month_year IS NOT NULL OR period IS NOT NULL
-		ORDER BY time_period DESC
-		LIMIT 12`
-	}
-
-	// Default: Explore available data with flexible column detection
-	return `
-	SELECT
-		table_name,
-		column_name,
-		data_type
-	FROM information_schema.columns
-	WHERE table_schema = 'public'
-	AND table_name NOT LIKE 'pg_%'
-	AND table_name NOT LIKE 'sql_%'
-	ORDER BY table_name, ordinal_position
-	LIMIT 50`
-}
-
 func (sc *SuperSetConnector) HealthCheck(ctx context.Context) error {
 	// Try multiple health check endpoints that Superset might use
 	endpoints := []string{"/api/v1/security/csrf_token/", "/health", "/heartbeat"}
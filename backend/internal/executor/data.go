@@ -0,0 +1,443 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"insightiq/backend/internal/models"
+)
+
+// DataHandlers returns handlers for the steps that reshape rows already retrieved:
+// filter, sort, aggregation, join and transformation. Each reads its settings from
+// the parameters of its step and passes the rows through unchanged without them.
+func DataHandlers() Handlers {
+	return Handlers{
+		models.TaskStepTypeFilter:         filterStep,
+		models.TaskStepTypeSort:           sortStep,
+		models.TaskStepTypeAggregation:    aggregateStep,
+		models.TaskStepTypeJoin:           joinStep,
+		models.TaskStepTypeTransformation: transformStep,
+	}
+}
+
+// Rows concatenates the rows of the inputs of a step
+func Rows(inputs []Input) []map[string]interface{} {
+	var rows []map[string]interface{}
+	for _, in := range inputs {
+		rows = append(rows, in.Output.Data...)
+	}
+	return rows
+}
+
+// Param decodes a parameter of a step into dst, whether it was set in Go or
+// decoded from JSON. It reports whether the parameter was set.
+func Param(step models.TaskStep, key string, dst interface{}) (bool, error) {
+	value, ok := step.Parameters[key]
+	if !ok || value == nil {
+		return false, nil
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode parameter %s: %w", key, err)
+	}
+	if err := json.Unmarshal(raw, dst); err != nil {
+		return false, fmt.Errorf("invalid parameter %s: %w", key, err)
+	}
+	return true, nil
+}
+
+// filterStep keeps the rows matching the "filters" parameter ([]models.Filter).
+// Filters on fields a row does not have are not applied to it.
+func filterStep(_ context.Context, step models.TaskStep, inputs []Input) (*Output, error) {
+	rows := Rows(inputs)
+	var filters []models.Filter
+	if ok, err := Param(step, "filters", &filters); err != nil || !ok {
+		return &Output{Data: rows}, err
+	}
+
+	kept := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		match := true
+		for i, f := range filters {
+			value, ok := row[f.Field]
+			if !ok {
+				continue
+			}
+			m := matchFilter(value, f)
+			if i > 0 && strings.EqualFold(f.Condition, "OR") {
+				match = match || m
+			} else {
+				match = match && m
+			}
+		}
+		if match {
+			kept = append(kept, row)
+		}
+	}
+	return &Output{Data: kept}, nil
+}
+
+func matchFilter(value interface{}, f models.Filter) bool {
+	switch strings.ToUpper(f.Operator) {
+	case "", "=", "==":
+		return compareValues(value, f.Value) == 0
+	case "!=", "<>":
+		return compareValues(value, f.Value) != 0
+	case ">":
+		return compareValues(value, f.Value) > 0
+	case "<":
+		return compareValues(value, f.Value) < 0
+	case ">=":
+		return compareValues(value, f.Value) >= 0
+	case "<=":
+		return compareValues(value, f.Value) <= 0
+	case "IN":
+		values, ok := f.Value.([]interface{})
+		if !ok {
+			if strs, isStrings := f.Value.([]string); isStrings {
+				for _, s := range strs {
+					values = append(values, s)
+				}
+			} else {
+				values = []interface{}{f.Value}
+			}
+		}
+		for _, v := range values {
+			if compareValues(value, v) == 0 {
+				return true
+			}
+		}
+		return false
+	case "LIKE":
+		pattern := strings.ToLower(fmt.Sprint(f.Value))
+		text := strings.ToLower(fmt.Sprint(value))
+		return strings.Contains(text, strings.Trim(pattern, "%"))
+	}
+	return true
+}
+
+// sortStep orders the rows by the "sort_by" parameter ([]models.SortCriteria)
+func sortStep(_ context.Context, step models.TaskStep, inputs []Input) (*Output, error) {
+	rows := slices.Clone(Rows(inputs))
+	var criteria []models.SortCriteria
+	if ok, err := Param(step, "sort_by", &criteria); err != nil || !ok {
+		return &Output{Data: rows}, err
+	}
+
+	slices.SortStableFunc(rows, func(a, b map[string]interface{}) int {
+		for _, c := range criteria {
+			cmp := compareValues(a[c.Field], b[c.Field])
+			if strings.EqualFold(c.Direction, "DESC") {
+				cmp = -cmp
+			}
+			if cmp != 0 {
+				return cmp
+			}
+		}
+		return 0
+	})
+	return &Output{Data: rows}, nil
+}
+
+// aggregateStep groups the rows by the "group_by" parameter ([]string) and
+// computes the "aggregations" parameter ([]models.Aggregation) for each group
+func aggregateStep(_ context.Context, step models.TaskStep, inputs []Input) (*Output, error) {
+	rows := Rows(inputs)
+	var aggregations []models.Aggregation
+	if ok, err := Param(step, "aggregations", &aggregations); err != nil || !ok {
+		return &Output{Data: rows}, err
+	}
+	var groupBy []string
+	if _, err := Param(step, "group_by", &groupBy); err != nil {
+		return nil, err
+	}
+
+	type group struct {
+		key  map[string]interface{}
+		rows []map[string]interface{}
+	}
+	var groups []*group
+	byKey := make(map[string]*group)
+	for _, row := range rows {
+		parts := make([]string, len(groupBy))
+		for i, field := range groupBy {
+			parts[i] = fmt.Sprint(row[field])
+		}
+		k := strings.Join(parts, "\x00")
+		g, ok := byKey[k]
+		if !ok {
+			g = &group{key: make(map[string]interface{}, len(groupBy))}
+			for _, field := range groupBy {
+				g.key[field] = row[field]
+			}
+			byKey[k] = g
+			groups = append(groups, g)
+		}
+		g.rows = append(g.rows, row)
+	}
+
+	result := make([]map[string]interface{}, 0, len(groups))
+	for _, g := range groups {
+		out := make(map[string]interface{}, len(g.key)+len(aggregations))
+		for field, value := range g.key {
+			out[field] = value
+		}
+		for _, agg := range aggregations {
+			value, err := aggregate(agg, g.rows)
+			if err != nil {
+				return nil, err
+			}
+			out[aggregationAlias(agg)] = value
+		}
+		result = append(result, out)
+	}
+	return &Output{Data: result}, nil
+}
+
+func aggregationAlias(agg models.Aggregation) string {
+	if agg.Alias != "" {
+		return agg.Alias
+	}
+	if agg.Field == "" || agg.Field == "*" {
+		return strings.ToLower(agg.Function)
+	}
+	return strings.ToLower(agg.Function) + "_" + agg.Field
+}
+
+func aggregate(agg models.Aggregation, rows []map[string]interface{}) (interface{}, error) {
+	function := strings.ToUpper(agg.Function)
+	if function == "COUNT" {
+		if agg.Field == "" || agg.Field == "*" {
+			return len(rows), nil
+		}
+		n := 0
+		for _, row := range rows {
+			if row[agg.Field] != nil {
+				n++
+			}
+		}
+		return n, nil
+	}
+
+	var result interface{}
+	sum, n := 0.0, 0
+	for _, row := range rows {
+		value := row[agg.Field]
+		if value == nil {
+			continue
+		}
+		switch function {
+		case "MIN":
+			if result == nil || compareValues(value, result) < 0 {
+				result = value
+			}
+		case "MAX":
+			if result == nil || compareValues(value, result) > 0 {
+				result = value
+			}
+		case "SUM", "AVG":
+			f, ok := toFloat(value)
+			if !ok {
+				return nil, fmt.Errorf("cannot %s non-numeric value %v of %s", function, value, agg.Field)
+			}
+			sum += f
+			n++
+		default:
+			return nil, fmt.Errorf("unsupported aggregation %s", agg.Function)
+		}
+	}
+	switch function {
+	case "SUM":
+		return sum, nil
+	case "AVG":
+		if n == 0 {
+			return nil, nil
+		}
+		return sum / float64(n), nil
+	}
+	return result, nil
+}
+
+// joinStep joins the rows of its inputs in order on the "on" parameter (a column
+// or list of columns), or on the columns they have in common. The "type" parameter
+// is INNER (default), LEFT or FULL. Inputs without common columns are concatenated.
+func joinStep(_ context.Context, step models.TaskStep, inputs []Input) (*Output, error) {
+	if len(inputs) < 2 {
+		return &Output{Data: Rows(inputs)}, nil
+	}
+	var on []string
+	var single string
+	if ok, err := Param(step, "on", &single); err == nil && ok {
+		on = []string{single}
+	} else if _, err := Param(step, "on", &on); err != nil {
+		return nil, err
+	}
+	var joinType string
+	if _, err := Param(step, "type", &joinType); err != nil {
+		return nil, err
+	}
+	joinType = strings.ToUpper(joinType)
+
+	rows := inputs[0].Output.Data
+	for _, in := range inputs[1:] {
+		keys := on
+		if len(keys) == 0 {
+			keys = commonColumns(rows, in.Output.Data)
+		}
+		if len(keys) == 0 {
+			rows = append(slices.Clone(rows), in.Output.Data...)
+			continue
+		}
+		rows = hashJoin(rows, in.Output.Data, keys, joinType)
+	}
+	return &Output{Data: rows}, nil
+}
+
+func hashJoin(left, right []map[string]interface{}, keys []string, joinType string) []map[string]interface{} {
+	rowKey := func(row map[string]interface{}) string {
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = fmt.Sprint(row[k])
+		}
+		return strings.Join(parts, "\x00")
+	}
+
+	index := make(map[string][]int)
+	for i, row := range right {
+		index[rowKey(row)] = append(index[rowKey(row)], i)
+	}
+
+	var result []map[string]interface{}
+	matched := make([]bool, len(right))
+	for _, l := range left {
+		matches := index[rowKey(l)]
+		for _, i := range matches {
+			matched[i] = true
+			joined := make(map[string]interface{}, len(l)+len(right[i]))
+			for k, v := range right[i] {
+				joined[k] = v
+			}
+			for k, v := range l {
+				joined[k] = v
+			}
+			result = append(result, joined)
+		}
+		if len(matches) == 0 && (joinType == "LEFT" || joinType == "FULL") {
+			result = append(result, l)
+		}
+	}
+	if joinType == "FULL" {
+		for i, r := range right {
+			if !matched[i] {
+				result = append(result, r)
+			}
+		}
+	}
+	return result
+}
+
+func commonColumns(a, b []map[string]interface{}) []string {
+	if len(a) == 0 || len(b) == 0 {
+		return nil
+	}
+	var common []string
+	for column := range a[0] {
+		if _, ok := b[0][column]; ok {
+			common = append(common, column)
+		}
+	}
+	slices.Sort(common)
+	return common
+}
+
+// transformStep aligns the rows of its inputs on one set of columns, filling the
+// columns a source lacks with nil. A "columns" parameter ([]string) keeps only
+// those columns.
+func transformStep(_ context.Context, step models.TaskStep, inputs []Input) (*Output, error) {
+	rows := Rows(inputs)
+	var columns []string
+	if _, err := Param(step, "columns", &columns); err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		seen := make(map[string]bool)
+		for _, row := range rows {
+			for column := range row {
+				if !seen[column] {
+					seen[column] = true
+					columns = append(columns, column)
+				}
+			}
+		}
+	}
+
+	aligned := make([]map[string]interface{}, len(rows))
+	for i, row := range rows {
+		out := make(map[string]interface{}, len(columns))
+		for _, column := range columns {
+			out[column] = row[column]
+		}
+		aligned[i] = out
+	}
+	return &Output{Data: aligned}, nil
+}
+
+// compareValues orders two cell values: numerically when both are numbers, by
+// time when both are times, and otherwise as case-insensitive text. nil sorts first.
+func compareValues(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	if fa, ok := toFloat(a); ok {
+		if fb, ok := toFloat(b); ok {
+			switch {
+			case fa < fb:
+				return -1
+			case fa > fb:
+				return 1
+			}
+			return 0
+		}
+	}
+	if ta, ok := a.(time.Time); ok {
+		if tb, ok := b.(time.Time); ok {
+			return ta.Compare(tb)
+		}
+	}
+	return strings.Compare(strings.ToLower(fmt.Sprint(a)), strings.ToLower(fmt.Sprint(b)))
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	case []byte:
+		f, err := strconv.ParseFloat(strings.TrimSpace(string(n)), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,372 @@
+// Package executor runs the task graphs produced by the planner. Steps run once
+// their dependencies completed, independent steps in parallel, each by the handler
+// registered for its step type.
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"insightiq/backend/internal/models"
+)
+
+var (
+	// ErrInvalidGraph is returned for graphs with unknown dependencies or cycles
+	ErrInvalidGraph = errors.New("invalid task graph")
+	// ErrNoHandler is returned when a graph has a step of a type no handler is registered for
+	ErrNoHandler = errors.New("no handler for step type")
+)
+
+// Bounds of execution
+const (
+	// DefaultMaxParallel is how many steps run at once unless set otherwise
+	DefaultMaxParallel = 8
+	// maxRetryDelay caps the backoff between attempts of a step
+	maxRetryDelay = time.Minute
+)
+
+// Output is what a step produced; it is passed on to the steps depending on it
+type Output struct {
+	Data  []map[string]interface{} // rows
+	Value interface{}              // anything else, e.g. the text of an analysis
+}
+
+// Size is the number of rows of an output
+func (o *Output) Size() int {
+	if o == nil {
+		return 0
+	}
+	return len(o.Data)
+}
+
+// Input is the output of a completed dependency of a step
+type Input struct {
+	StepID string
+	Output *Output
+}
+
+// Handler runs one step. Inputs are the outputs of its dependencies in the order
+// they are listed; optional dependencies that failed are left out.
+type Handler func(ctx context.Context, step models.TaskStep, inputs []Input) (*Output, error)
+
+// Handlers maps each step type to the handler running it
+type Handlers map[models.TaskStepType]Handler
+
+// Executor runs task graphs
+type Executor struct {
+	handlers    Handlers
+	maxParallel int
+	progress    func(graph *models.TaskGraph)
+	logger      *slog.Logger
+}
+
+// New creates an executor running steps with the given handlers
+func New(handlers Handlers, logger *slog.Logger) *Executor {
+	return &Executor{
+		handlers:    handlers,
+		maxParallel: DefaultMaxParallel,
+		logger:      logger.With("component", "executor"),
+	}
+}
+
+// SetMaxParallel bounds how many steps run at once
+func (e *Executor) SetMaxParallel(n int) {
+	e.maxParallel = max(n, 1)
+}
+
+// SetProgressFunc sets a function called whenever steps start or finish. It is
+// called from the goroutine running the graph, so it may read the graph but must
+// not keep it past the call.
+func (e *Executor) SetProgressFunc(fn func(graph *models.TaskGraph)) {
+	e.progress = fn
+}
+
+// stepResult is reported by a finished step
+type stepResult struct {
+	index    int
+	output   *Output
+	err      error
+	attempts int
+	started  time.Time
+	finished time.Time
+}
+
+// Execute runs the steps of a graph in dependency order and returns their outputs
+// by step ID. Status, attempts, timing, output size and errors are recorded on the
+// steps of the graph, and the overall outcome on the graph itself. A failed step
+// skips the steps depending on it unless it is optional; independent steps still
+// run. The error joins the errors of all failed steps.
+func (e *Executor) Execute(ctx context.Context, graph *models.TaskGraph) (map[string]*Output, error) {
+	deps, err := resolveDependencies(graph)
+	if err != nil {
+		return nil, err
+	}
+	for _, step := range graph.Steps {
+		if e.handlers[step.Type] == nil {
+			return nil, fmt.Errorf("%w %q (step %s)", ErrNoHandler, step.Type, step.ID)
+		}
+	}
+
+	start := time.Now()
+	graph.Status = models.TaskStatusExecuting
+	dependents := make([][]int, len(graph.Steps))
+	remaining := make([]int, len(graph.Steps))
+	var ready []int
+	for i := range graph.Steps {
+		resetStep(&graph.Steps[i])
+		remaining[i] = len(deps[i])
+		if remaining[i] == 0 {
+			ready = append(ready, i)
+		}
+		for _, d := range deps[i] {
+			dependents[d] = append(dependents[d], i)
+		}
+	}
+
+	outputs := make(map[string]*Output)
+	results := make(chan stepResult)
+	var failures []error
+	pending := len(graph.Steps)
+	running := 0
+
+	for pending > 0 {
+		// Start ready steps, lowest priority value first
+		slices.SortStableFunc(ready, func(a, b int) int {
+			return graph.Steps[a].Priority - graph.Steps[b].Priority
+		})
+		started := false
+		for len(ready) > 0 && running < e.maxParallel && ctx.Err() == nil {
+			i := ready[0]
+			ready = ready[1:]
+			inputs := make([]Input, 0, len(deps[i]))
+			for _, d := range deps[i] {
+				if out, ok := outputs[graph.Steps[d].ID]; ok {
+					inputs = append(inputs, Input{StepID: graph.Steps[d].ID, Output: out})
+				}
+			}
+			graph.Steps[i].Status = models.TaskStatusExecuting
+			started = true
+			running++
+			go func(i int, step models.TaskStep) {
+				results <- e.run(ctx, i, step, inputs)
+			}(i, graph.Steps[i])
+		}
+		if started {
+			e.reportProgress(graph)
+		}
+		if running == 0 {
+			break // cancelled, with nothing left in flight
+		}
+
+		res := <-results
+		running--
+		pending--
+		step := &graph.Steps[res.index]
+		step.Attempts = res.attempts
+		step.StartedAt = &res.started
+		step.FinishedAt = &res.finished
+		step.Duration = res.finished.Sub(res.started)
+
+		if res.err != nil {
+			step.Status = models.TaskStatusFailed
+			step.Error = res.err.Error()
+			e.logger.Warn("Step failed", "graph", graph.ID, "step", step.ID, "attempts", res.attempts, "optional", step.Optional, "error", res.err)
+			if !step.Optional {
+				failures = append(failures, fmt.Errorf("step %s failed: %w", step.ID, res.err))
+				pending -= skipDependents(graph, dependents, res.index)
+				e.reportProgress(graph)
+				continue
+			}
+		} else {
+			step.Status = models.TaskStatusCompleted
+			step.OutputSize = res.output.Size()
+			outputs[step.ID] = res.output
+			e.logger.Debug("Step completed", "graph", graph.ID, "step", step.ID, "duration", step.Duration, "rows", step.OutputSize)
+		}
+
+		e.reportProgress(graph)
+		for _, d := range dependents[res.index] {
+			remaining[d]--
+			if remaining[d] == 0 && graph.Steps[d].Status == models.TaskStatusPlanned {
+				ready = append(ready, d)
+			}
+		}
+	}
+
+	graph.ExecutionTime = time.Since(start)
+	if ctx.Err() != nil {
+		for i := range graph.Steps {
+			if graph.Steps[i].Status == models.TaskStatusPlanned {
+				graph.Steps[i].Status = models.TaskStatusCancelled
+			}
+		}
+		graph.Status = models.TaskStatusCancelled
+		e.reportProgress(graph)
+		return outputs, errors.Join(append(failures, ctx.Err())...)
+	}
+	if len(failures) > 0 {
+		graph.Status = models.TaskStatusFailed
+		e.reportProgress(graph)
+		return outputs, errors.Join(failures...)
+	}
+	graph.Status = models.TaskStatusCompleted
+	e.reportProgress(graph)
+	e.logger.Info("Task graph executed", "graph", graph.ID, "steps", len(graph.Steps), "duration", graph.ExecutionTime)
+	return outputs, nil
+}
+
+func (e *Executor) reportProgress(graph *models.TaskGraph) {
+	if e.progress != nil {
+		e.progress(graph)
+	}
+}
+
+// run runs a step, retrying failed attempts as its retry configuration allows
+func (e *Executor) run(ctx context.Context, index int, step models.TaskStep, inputs []Input) stepResult {
+	res := stepResult{index: index, started: time.Now()}
+	retries := 0
+	if step.Retry != nil {
+		retries = max(step.Retry.MaxRetries, 0)
+	}
+
+	for {
+		res.attempts++
+		res.output, res.err = e.attempt(ctx, step, inputs)
+		if res.err == nil || res.attempts > retries || ctx.Err() != nil {
+			break
+		}
+
+		delay := retryDelay(step.Retry, res.attempts)
+		e.logger.Info("Retrying step", "step", step.ID, "attempt", res.attempts, "delay", delay, "error", res.err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			res.finished = time.Now()
+			return res
+		}
+	}
+	res.finished = time.Now()
+	return res
+}
+
+// attempt calls the handler of a step once, turning a panic into an error
+func (e *Executor) attempt(ctx context.Context, step models.TaskStep, inputs []Input) (out *Output, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("step panicked: %v", r)
+		}
+	}()
+	out, err = e.handlers[step.Type](ctx, step, inputs)
+	if err == nil && out == nil {
+		out = &Output{}
+	}
+	return out, err
+}
+
+// retryDelay is the wait before the next attempt after the given failed attempt:
+// constant, growing linearly, or doubling with exponential backoff
+func retryDelay(cfg *models.RetryConfig, attempt int) time.Duration {
+	delay := cfg.Delay
+	switch cfg.Backoff {
+	case "linear":
+		delay *= time.Duration(attempt)
+	case "exponential":
+		for i := 1; i < attempt && delay < maxRetryDelay; i++ {
+			delay *= 2
+		}
+	}
+	return min(delay, maxRetryDelay)
+}
+
+// resolveDependencies returns the indexes of the dependencies of each step, from
+// both the steps and the dependency map of the graph, and rejects graphs with
+// unknown dependencies or cycles
+func resolveDependencies(graph *models.TaskGraph) ([][]int, error) {
+	index := make(map[string]int, len(graph.Steps))
+	for i, step := range graph.Steps {
+		if _, ok := index[step.ID]; ok {
+			return nil, fmt.Errorf("%w: duplicate step %s", ErrInvalidGraph, step.ID)
+		}
+		index[step.ID] = i
+	}
+
+	deps := make([][]int, len(graph.Steps))
+	for i, step := range graph.Steps {
+		ids := append(slices.Clone(step.Dependencies), graph.Dependencies[step.ID]...)
+		for _, id := range ids {
+			d, ok := index[id]
+			if !ok {
+				return nil, fmt.Errorf("%w: step %s depends on unknown step %s", ErrInvalidGraph, step.ID, id)
+			}
+			if !slices.Contains(deps[i], d) {
+				deps[i] = append(deps[i], d)
+			}
+		}
+	}
+
+	// Kahn's algorithm visits every step only when there is no cycle
+	remaining := make([]int, len(deps))
+	dependents := make([][]int, len(deps))
+	var queue []int
+	for i := range deps {
+		remaining[i] = len(deps[i])
+		if remaining[i] == 0 {
+			queue = append(queue, i)
+		}
+		for _, d := range deps[i] {
+			dependents[d] = append(dependents[d], i)
+		}
+	}
+	visited := 0
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		visited++
+		for _, d := range dependents[i] {
+			if remaining[d]--; remaining[d] == 0 {
+				queue = append(queue, d)
+			}
+		}
+	}
+	if visited < len(deps) {
+		return nil, fmt.Errorf("%w: dependency cycle", ErrInvalidGraph)
+	}
+	return deps, nil
+}
+
+// skipDependents marks the steps depending on a failed step, directly or not, as
+// skipped and returns how many were newly skipped
+func skipDependents(graph *models.TaskGraph, dependents [][]int, failed int) int {
+	skipped := 0
+	queue := slices.Clone(dependents[failed])
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		step := &graph.Steps[i]
+		if step.Status != models.TaskStatusPlanned {
+			continue
+		}
+		step.Status = models.TaskStatusSkipped
+		step.Error = fmt.Sprintf("dependency %s failed", graph.Steps[failed].ID)
+		skipped++
+		queue = append(queue, dependents[i]...)
+	}
+	return skipped
+}
+
+// resetStep clears the execution state of a step so a graph can be run again
+func resetStep(step *models.TaskStep) {
+	step.Status = models.TaskStatusPlanned
+	step.Attempts = 0
+	step.StartedAt = nil
+	step.FinishedAt = nil
+	step.Duration = 0
+	step.OutputSize = 0
+	step.Error = ""
+}
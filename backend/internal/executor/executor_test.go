@@ -0,0 +1,241 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"insightiq/backend/internal/models"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestExecuteRunsIndependentStepsInParallel(t *testing.T) {
+	var running, peak atomic.Int32
+	fetch := func(ctx context.Context, step models.TaskStep, inputs []Input) (*Output, error) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		running.Add(-1)
+		return &Output{Data: []map[string]interface{}{{"source": step.ID}}}, nil
+	}
+	handlers := DataHandlers()
+	handlers[models.TaskStepTypeDataRetrieval] = fetch
+
+	graph := &models.TaskGraph{Steps: []models.TaskStep{
+		{ID: "a", Type: models.TaskStepTypeDataRetrieval},
+		{ID: "b", Type: models.TaskStepTypeDataRetrieval},
+		{ID: "combine", Type: models.TaskStepTypeTransformation, Dependencies: []string{"a", "b"}},
+	}}
+
+	outputs, err := New(handlers, testLogger()).Execute(context.Background(), graph)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if peak.Load() != 2 {
+		t.Errorf("peak parallelism = %d, want 2", peak.Load())
+	}
+	if got := outputs["combine"].Size(); got != 2 {
+		t.Errorf("combine rows = %d, want 2", got)
+	}
+	if graph.Status != models.TaskStatusCompleted {
+		t.Errorf("graph status = %s", graph.Status)
+	}
+	for _, step := range graph.Steps {
+		if step.Status != models.TaskStatusCompleted || step.StartedAt == nil || step.Attempts != 1 {
+			t.Errorf("step %s: status %s, attempts %d", step.ID, step.Status, step.Attempts)
+		}
+	}
+	if graph.Steps[2].OutputSize != 2 {
+		t.Errorf("combine output size = %d, want 2", graph.Steps[2].OutputSize)
+	}
+}
+
+func TestExecuteRetriesWithBackoff(t *testing.T) {
+	var calls int
+	var times []time.Time
+	handlers := Handlers{
+		models.TaskStepTypeDataRetrieval: func(ctx context.Context, step models.TaskStep, inputs []Input) (*Output, error) {
+			calls++
+			times = append(times, time.Now())
+			if calls < 3 {
+				return nil, errors.New("timeout")
+			}
+			return &Output{}, nil
+		},
+	}
+	graph := &models.TaskGraph{Steps: []models.TaskStep{{
+		ID:    "fetch",
+		Type:  models.TaskStepTypeDataRetrieval,
+		Retry: &models.RetryConfig{MaxRetries: 2, Delay: 10 * time.Millisecond, Backoff: "exponential"},
+	}}}
+
+	if _, err := New(handlers, testLogger()).Execute(context.Background(), graph); err != nil {
+		t.Fatal(err)
+	}
+	if graph.Steps[0].Attempts != 3 {
+		t.Errorf("attempts = %d, want 3", graph.Steps[0].Attempts)
+	}
+	if gap := times[2].Sub(times[1]); gap < 20*time.Millisecond {
+		t.Errorf("second retry after %s, want at least 20ms", gap)
+	}
+}
+
+func TestExecuteSkipsDependentsOfFailedSteps(t *testing.T) {
+	fail := errors.New("boom")
+	handlers := DataHandlers()
+	handlers[models.TaskStepTypeDataRetrieval] = func(ctx context.Context, step models.TaskStep, inputs []Input) (*Output, error) {
+		if step.ID != "ok" {
+			return nil, fail
+		}
+		return &Output{Data: []map[string]interface{}{{"n": 1}}}, nil
+	}
+	handlers[models.TaskStepTypeAnalysis] = func(ctx context.Context, step models.TaskStep, inputs []Input) (*Output, error) {
+		return &Output{Data: Rows(inputs)}, nil
+	}
+
+	graph := &models.TaskGraph{Steps: []models.TaskStep{
+		{ID: "ok", Type: models.TaskStepTypeDataRetrieval},
+		{ID: "flaky", Type: models.TaskStepTypeDataRetrieval, Optional: true},
+		{ID: "broken", Type: models.TaskStepTypeDataRetrieval},
+		{ID: "analysis", Type: models.TaskStepTypeAnalysis, Dependencies: []string{"ok", "flaky"}},
+		{ID: "sort", Type: models.TaskStepTypeSort, Dependencies: []string{"broken"}},
+		{ID: "report", Type: models.TaskStepTypeAnalysis, Dependencies: []string{"sort"}},
+	}}
+
+	outputs, err := New(handlers, testLogger()).Execute(context.Background(), graph)
+	if !errors.Is(err, fail) {
+		t.Fatalf("err = %v, want %v", err, fail)
+	}
+	if graph.Status != models.TaskStatusFailed {
+		t.Errorf("graph status = %s", graph.Status)
+	}
+	want := map[string]models.TaskStatus{
+		"ok":       models.TaskStatusCompleted,
+		"flaky":    models.TaskStatusFailed,
+		"broken":   models.TaskStatusFailed,
+		"analysis": models.TaskStatusCompleted,
+		"sort":     models.TaskStatusSkipped,
+		"report":   models.TaskStatusSkipped,
+	}
+	for _, step := range graph.Steps {
+		if step.Status != want[step.ID] {
+			t.Errorf("step %s status = %s, want %s", step.ID, step.Status, want[step.ID])
+		}
+	}
+	if outputs["analysis"].Size() != 1 {
+		t.Errorf("analysis rows = %d, want 1", outputs["analysis"].Size())
+	}
+}
+
+func TestExecuteRejectsInvalidGraphs(t *testing.T) {
+	handlers := DataHandlers()
+	tests := []struct {
+		name  string
+		steps []models.TaskStep
+		want  error
+	}{
+		{"cycle", []models.TaskStep{
+			{ID: "a", Type: models.TaskStepTypeSort, Dependencies: []string{"b"}},
+			{ID: "b", Type: models.TaskStepTypeSort, Dependencies: []string{"a"}},
+		}, ErrInvalidGraph},
+		{"unknown dependency", []models.TaskStep{
+			{ID: "a", Type: models.TaskStepTypeSort, Dependencies: []string{"missing"}},
+		}, ErrInvalidGraph},
+		{"no handler", []models.TaskStep{
+			{ID: "a", Type: models.TaskStepTypeVisualization},
+		}, ErrNoHandler},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graph := &models.TaskGraph{Steps: tt.steps}
+			if _, err := New(handlers, testLogger()).Execute(context.Background(), graph); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDataHandlers(t *testing.T) {
+	input := []Input{{StepID: "fetch", Output: &Output{Data: []map[string]interface{}{
+		{"region": "West", "revenue": 100.0},
+		{"region": "East", "revenue": 50.0},
+		{"region": "West", "revenue": 25.0},
+	}}}}
+	handlers := DataHandlers()
+
+	aggregated, err := handlers[models.TaskStepTypeAggregation](context.Background(), models.TaskStep{Parameters: map[string]interface{}{
+		"group_by":     []string{"region"},
+		"aggregations": []models.Aggregation{{Function: "SUM", Field: "revenue", Alias: "total"}},
+	}}, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sorted, err := handlers[models.TaskStepTypeSort](context.Background(), models.TaskStep{Parameters: map[string]interface{}{
+		"sort_by": []interface{}{map[string]interface{}{"field": "total", "direction": "ASC"}},
+	}}, []Input{{Output: aggregated}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sorted.Data) != 2 || sorted.Data[0]["region"] != "East" || sorted.Data[1]["total"] != 125.0 {
+		t.Errorf("sorted = %v", sorted.Data)
+	}
+
+	filtered, err := handlers[models.TaskStepTypeFilter](context.Background(), models.TaskStep{Parameters: map[string]interface{}{
+		"filters": []models.Filter{{Field: "revenue", Operator: ">", Value: 30}},
+	}}, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered.Data) != 2 {
+		t.Errorf("filtered = %v", filtered.Data)
+	}
+}
+
+func TestExecuteReportsProgressAndStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handlers := Handlers{
+		models.TaskStepTypeDataRetrieval: func(ctx context.Context, step models.TaskStep, inputs []Input) (*Output, error) {
+			cancel()
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+		models.TaskStepTypeAnalysis: func(ctx context.Context, step models.TaskStep, inputs []Input) (*Output, error) {
+			t.Error("step started after cancellation")
+			return nil, nil
+		},
+	}
+	graph := &models.TaskGraph{Steps: []models.TaskStep{
+		{ID: "fetch", Type: models.TaskStepTypeDataRetrieval},
+		{ID: "analysis", Type: models.TaskStepTypeAnalysis, Dependencies: []string{"fetch"}},
+	}}
+
+	var reported []models.TaskStatus
+	exec := New(handlers, testLogger())
+	exec.SetProgressFunc(func(graph *models.TaskGraph) {
+		reported = append(reported, graph.Steps[0].Status)
+	})
+	if _, err := exec.Execute(ctx, graph); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want %v", err, context.Canceled)
+	}
+	if graph.Status != models.TaskStatusCancelled || graph.Steps[1].Status != models.TaskStatusSkipped {
+		t.Errorf("graph %s, analysis %s", graph.Status, graph.Steps[1].Status)
+	}
+	if len(reported) < 2 || reported[0] != models.TaskStatusExecuting || reported[len(reported)-1] != models.TaskStatusFailed {
+		t.Errorf("reported fetch states %v", reported)
+	}
+}
@@ -1,11 +1,18 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"insightiq/backend/internal/connectors"
 	"insightiq/backend/internal/models"
+	"insightiq/backend/internal/schema"
 	"insightiq/backend/internal/services"
 	"insightiq/backend/internal/validation"
 )
@@ -38,7 +45,7 @@ func (h *ConnectorHandlers) handleGetConnectors(w http.ResponseWriter, r *http.R
 
 	response := map[string]interface{}{
 		"success": true,
-		"data":    connectors,
+		"data":    h.connectorService.RedactConnectors(connectors),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -76,7 +83,7 @@ func (h *ConnectorHandlers) handleCreateConnector(w http.ResponseWriter, r *http
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(connector)
+	json.NewEncoder(w).Encode(h.connectorService.RedactConnector(connector))
 }
 
 // handleGetConnector retrieves a specific connector
@@ -105,7 +112,7 @@ func (h *ConnectorHandlers) handleGetConnector(w http.ResponseWriter, r *http.Re
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(connector)
+	json.NewEncoder(w).Encode(h.connectorService.RedactConnector(connector))
 }
 
 // handleUpdateConnector updates an existing connector
@@ -146,7 +153,7 @@ func (h *ConnectorHandlers) handleUpdateConnector(w http.ResponseWriter, r *http
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(connector)
+	json.NewEncoder(w).Encode(h.connectorService.RedactConnector(connector))
 }
 
 // handleDeleteConnector deletes a connector
@@ -169,6 +176,12 @@ func (h *ConnectorHandlers) handleDeleteConnector(w http.ResponseWriter, r *http
 		return
 	}
 
+	if importer := h.server.dbtImporter; importer != nil {
+		if err := importer.Delete(id); err != nil && !errors.Is(err, schema.ErrNoDbtArtifacts) {
+			h.server.logger.Warn("Failed to delete dbt artifacts of connector", "error", err, "id", id)
+		}
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -221,7 +234,88 @@ func (h *ConnectorHandlers) handleTestConnectorConfig(w http.ResponseWriter, r *
 	json.NewEncoder(w).Encode(result)
 }
 
-// handleGetConnectorData retrieves data from a specific connector
+// handleGetConnectorTypes lists the registered connector drivers with their
+// capabilities and configuration JSON schemas
+func (h *ConnectorHandlers) handleGetConnectorTypes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var types []map[string]interface{}
+	for _, driver := range h.connectorService.Drivers().Drivers() {
+		types = append(types, map[string]interface{}{
+			"type":          driver.Type(),
+			"name":          driver.Name(),
+			"capabilities":  driver.Capabilities(),
+			"config_schema": driver.ConfigSchema(),
+		})
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"data":    types,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleGetConnectorHealthHistory returns recent connection test results, newest first
+func (h *ConnectorHandlers) handleGetConnectorHealthHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := h.extractConnectorIDFromHealthHistoryPath(r.URL.Path)
+	if id == "" {
+		http.Error(w, "Invalid connector ID", http.StatusBadRequest)
+		return
+	}
+
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	connector, err := h.connectorService.GetConnector(r.Context(), id)
+	if err != nil {
+		h.server.logger.Error("Failed to get connector", "error", err, "id", id)
+		http.Error(w, "Failed to retrieve connector", http.StatusInternalServerError)
+		return
+	}
+	if connector == nil {
+		http.Error(w, "Connector not found", http.StatusNotFound)
+		return
+	}
+
+	history, err := h.connectorService.GetHealthHistory(r.Context(), id, limit)
+	if err != nil {
+		h.server.logger.Error("Failed to get health history", "error", err, "id", id)
+		http.Error(w, "Failed to retrieve health history", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success":     true,
+		"status":      connector.Status,
+		"last_tested": connector.LastTested,
+		"data":        history,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleGetConnectorData streams the rows a connector returns for a query as JSON,
+// or as CSV with format=csv. SQL connectors take a read-only query in the sql
+// parameter; other connectors answer the question in the query parameter.
 func (h *ConnectorHandlers) handleGetConnectorData(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -238,21 +332,211 @@ func (h *ConnectorHandlers) handleGetConnectorData(w http.ResponseWriter, r *htt
 	if query != "" {
 		query = validation.SanitizeString(query)
 	}
+	// SQL is validated as read-only by the connector itself
+	sqlQuery := r.URL.Query().Get("sql")
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		http.Error(w, "format must be json or csv", http.StatusBadRequest)
+		return
+	}
+
+	connector, err := h.connectorService.GetConnector(r.Context(), id)
+	if err != nil {
+		h.server.logger.Error("Failed to get connector", "error", err, "id", id)
+		http.Error(w, "Failed to retrieve connector", http.StatusInternalServerError)
+		return
+	}
+	if connector == nil {
+		http.Error(w, "Connector not found", http.StatusNotFound)
+		return
+	}
+
+	rows, err := h.connectorService.QueryConnector(r.Context(), connector, query, sqlQuery)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidConnectorQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.server.logger.Error("Failed to query connector", "error", err, "id", id)
+		http.Error(w, "Failed to query connector: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer rows.Close()
+
+	// Rows are written as they are read; errors after this point end the stream
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "connector-"+id+".csv"))
+		err = connectors.WriteCSV(w, rows)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		err = connectors.WriteJSON(w, rows)
+	}
+	if err != nil {
+		h.server.logger.Error("Failed to stream connector data", "error", err, "id", id)
+	}
+}
 
-	// TODO: Implement data retrieval through connector
-	// This would involve getting the connector, establishing connection,
-	// and executing the query through the appropriate connector type
+// handleListConnectorFiles lists the dataset files uploaded to a file connector
+func (h *ConnectorHandlers) handleListConnectorFiles(w http.ResponseWriter, r *http.Request) {
+	id, fileStore, ok := h.fileConnector(w, r)
+	if !ok {
+		return
+	}
+
+	files, err := fileStore.List(id)
+	if err != nil {
+		h.server.logger.Error("Failed to list connector files", "error", err, "id", id)
+		http.Error(w, "Failed to list files", http.StatusInternalServerError)
+		return
+	}
 
 	response := map[string]interface{}{
-		"message": "Connector data retrieval not yet implemented",
-		"id":      id,
-		"query":   query,
+		"success": true,
+		"data":    files,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// handleUploadConnectorFile stores a CSV, TSV, JSON Lines or Excel file for a file connector
+func (h *ConnectorHandlers) handleUploadConnectorFile(w http.ResponseWriter, r *http.Request) {
+	id, fileStore, ok := h.fileConnector(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse multipart form, spilling large files to disk
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		h.server.logger.Error("Failed to parse upload form", "error", err, "id", id)
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "No file provided", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	if err := validation.ValidateDatasetUpload(header.Filename, header.Size); err != nil {
+		h.server.logger.Error("Invalid dataset upload", "error", err, "filename", header.Filename, "size", header.Size)
+		http.Error(w, "Invalid file upload: supported formats are .csv, .tsv, .jsonl, .ndjson and .xlsx up to 50MB", http.StatusBadRequest)
+		return
+	}
+
+	stored, dataset, err := fileStore.Save(id, header.Filename, file)
+	if err != nil {
+		h.server.logger.Error("Failed to store dataset file", "error", err, "id", id, "filename", header.Filename)
+		if errors.Is(err, connectors.ErrUnsupportedFileFormat) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	h.refreshConnectorContext(id)
+
+	response := map[string]interface{}{
+		"success": true,
+		"file":    stored,
+		"table":   connectors.DatasetTableName(stored.Name),
+		"columns": dataset.Columns,
+		"rows":    len(dataset.Rows),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleDeleteConnectorFile removes an uploaded dataset file
+func (h *ConnectorHandlers) handleDeleteConnectorFile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, fileStore, ok := h.fileConnector(w, r)
+	if !ok {
+		return
+	}
+
+	name := h.extractFileNameFromFilesPath(r.URL.Path)
+	if name == "" {
+		http.Error(w, "Invalid file name", http.StatusBadRequest)
+		return
+	}
+
+	if err := fileStore.Delete(id, name); err != nil {
+		if errors.Is(err, connectors.ErrFileNotFound) {
+			http.Error(w, "File not found", http.StatusNotFound)
+			return
+		}
+		h.server.logger.Error("Failed to delete dataset file", "error", err, "id", id, "file", name)
+		http.Error(w, "Failed to delete file", http.StatusInternalServerError)
+		return
+	}
+
+	h.refreshConnectorContext(id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// fileConnector resolves the file connector addressed by a /files request and
+// writes an error response when it cannot be used for uploads
+func (h *ConnectorHandlers) fileConnector(w http.ResponseWriter, r *http.Request) (string, *connectors.FileStore, bool) {
+	id := h.extractConnectorIDFromFilesPath(r.URL.Path)
+	if id == "" {
+		http.Error(w, "Invalid connector ID", http.StatusBadRequest)
+		return "", nil, false
+	}
+
+	fileStore := h.connectorService.FileStore()
+	if fileStore == nil {
+		http.Error(w, "File uploads are not enabled", http.StatusServiceUnavailable)
+		return "", nil, false
+	}
+
+	connector, err := h.connectorService.GetConnector(r.Context(), id)
+	if err != nil {
+		h.server.logger.Error("Failed to get connector", "error", err, "id", id)
+		http.Error(w, "Failed to retrieve connector", http.StatusInternalServerError)
+		return "", nil, false
+	}
+	if connector == nil {
+		http.Error(w, "Connector not found", http.StatusNotFound)
+		return "", nil, false
+	}
+	if connector.Type != models.ConnectorTypeFile {
+		http.Error(w, "Connector does not accept file uploads", http.StatusBadRequest)
+		return "", nil, false
+	}
+
+	return id, fileStore, true
+}
+
+// refreshConnectorContext rebuilds the connector's domain contexts in the background
+func (h *ConnectorHandlers) refreshConnectorContext(id string) {
+	refresh := h.server.contextRefresher
+	if refresh == nil {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+		if err := refresh(ctx, id); err != nil {
+			h.server.logger.Warn("Failed to refresh connector context", "error", err, "id", id)
+		}
+	}()
+}
+
 // Helper methods to extract IDs from URL paths
 
 func (h *ConnectorHandlers) extractConnectorID(path string) string {
@@ -280,4 +564,31 @@ func (h *ConnectorHandlers) extractConnectorIDFromDataPath(path string) string {
 		return parts[3]
 	}
 	return ""
-}
\ No newline at end of file
+}
+
+func (h *ConnectorHandlers) extractConnectorIDFromHealthHistoryPath(path string) string {
+	// Extract ID from /api/connectors/{id}/health-history
+	parts := strings.Split(path, "/")
+	if len(parts) >= 5 && parts[2] == "connectors" && parts[4] == "health-history" {
+		return parts[3]
+	}
+	return ""
+}
+
+func (h *ConnectorHandlers) extractConnectorIDFromFilesPath(path string) string {
+	// Extract ID from /api/connectors/{id}/files[/{name}]
+	parts := strings.Split(path, "/")
+	if len(parts) >= 5 && parts[2] == "connectors" && parts[4] == "files" {
+		return parts[3]
+	}
+	return ""
+}
+
+func (h *ConnectorHandlers) extractFileNameFromFilesPath(path string) string {
+	// Extract file name from /api/connectors/{id}/files/{name}
+	parts := strings.Split(path, "/")
+	if len(parts) == 6 && parts[2] == "connectors" && parts[4] == "files" {
+		return parts[5]
+	}
+	return ""
+}
@@ -0,0 +1,113 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"insightiq/backend/internal/repository"
+)
+
+// handleSessions lists the conversation sessions of the authenticated user
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.conversationService == nil {
+		http.Error(w, "Conversation sessions are not available", http.StatusServiceUnavailable)
+		return
+	}
+	userID, ok := r.Context().Value("user_id").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit := 50
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	offset := 0
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	sessions, err := s.conversationService.ListSessions(r.Context(), userID, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to list conversation sessions", "error", err, "user_id", userID)
+		http.Error(w, "Failed to list sessions", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"data":    sessions,
+		"limit":   limit,
+		"offset":  offset,
+		"count":   len(sessions),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleSessionByID resumes (GET, with its turns) or deletes (DELETE) a
+// conversation session of the authenticated user: /api/sessions/{id}
+func (s *Server) handleSessionByID(w http.ResponseWriter, r *http.Request) {
+	if s.conversationService == nil {
+		http.Error(w, "Conversation sessions are not available", http.StatusServiceUnavailable)
+		return
+	}
+	userID, ok := r.Context().Value("user_id").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID := strings.TrimPrefix(r.URL.Path, "/api/sessions/")
+	if sessionID == "" || strings.Contains(sessionID, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		session, err := s.conversationService.GetSession(r.Context(), userID, sessionID)
+		if err != nil {
+			s.writeSessionError(w, "Failed to get conversation session", err, sessionID)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    session,
+		})
+	case http.MethodDelete:
+		if err := s.conversationService.DeleteSession(r.Context(), userID, sessionID); err != nil {
+			s.writeSessionError(w, "Failed to delete conversation session", err, sessionID)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Session deleted successfully",
+		})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// writeSessionError maps conversation session errors to HTTP status codes
+func (s *Server) writeSessionError(w http.ResponseWriter, message string, err error, sessionID string) {
+	if errors.Is(err, repository.ErrSessionNotFound) {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	s.logger.Error(message, "error", err, "session_id", sessionID)
+	http.Error(w, message, http.StatusInternalServerError)
+}
@@ -0,0 +1,165 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"insightiq/backend/internal/schema"
+)
+
+// Largest dbt artifact accepted by an upload; manifests of big projects run to tens of MB
+const maxDbtArtifactSize = 200 << 20
+
+// handleDbtArtifacts serves /api/connectors/{id}/dbt:
+// GET summarizes the documented context, POST uploads manifest.json and optionally
+// semantic_manifest.json as multipart files (or re-ingests the stored ones when no
+// files are sent), DELETE removes the stored artifacts
+func (h *ConnectorHandlers) handleDbtArtifacts(w http.ResponseWriter, r *http.Request) {
+	importer := h.server.dbtImporter
+	if importer == nil {
+		http.Error(w, "dbt import is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	id := h.extractConnectorID(r.URL.Path)
+	if id == "" {
+		http.Error(w, "Invalid connector ID", http.StatusBadRequest)
+		return
+	}
+
+	connector, err := h.connectorService.GetConnector(r.Context(), id)
+	if err != nil {
+		h.server.logger.Error("Failed to get connector", "error", err, "id", id)
+		http.Error(w, "Failed to retrieve connector", http.StatusInternalServerError)
+		return
+	}
+	if connector == nil {
+		http.Error(w, "Connector not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		doc, err := importer.ConnectorDocumentation(r.Context(), id)
+		if err != nil {
+			h.server.logger.Error("Failed to read dbt artifacts", "error", err, "id", id)
+			http.Error(w, "Failed to read dbt artifacts", http.StatusInternalServerError)
+			return
+		}
+		if doc == nil {
+			http.Error(w, "No dbt artifacts imported", http.StatusNotFound)
+			return
+		}
+		h.writeDbtResponse(w, http.StatusOK, doc)
+
+	case http.MethodPost:
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			doc, err := importer.ConnectorDocumentation(r.Context(), id)
+			if err != nil {
+				h.server.logger.Error("Failed to read dbt artifacts", "error", err, "id", id)
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+			if doc == nil {
+				http.Error(w, "No dbt artifacts imported", http.StatusNotFound)
+				return
+			}
+			h.refreshConnectorContext(id)
+			h.writeDbtResponse(w, http.StatusAccepted, doc)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, 2*maxDbtArtifactSize)
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			h.server.logger.Error("Failed to parse dbt upload form", "error", err, "id", id)
+			http.Error(w, "Failed to parse form", http.StatusBadRequest)
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		manifest, err := readFormFile(r.MultipartForm, "manifest")
+		if err != nil || len(manifest) == 0 {
+			http.Error(w, "manifest.json is required in the manifest field", http.StatusBadRequest)
+			return
+		}
+		semanticManifest, err := readFormFile(r.MultipartForm, "semantic_manifest")
+		if err != nil {
+			http.Error(w, "Failed to read semantic_manifest", http.StatusBadRequest)
+			return
+		}
+
+		doc, err := importer.Store(id, manifest, semanticManifest)
+		if err != nil {
+			h.server.logger.Error("Failed to import dbt artifacts", "error", err, "id", id)
+			if errors.Is(err, schema.ErrInvalidDbtArtifact) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "Failed to store dbt artifacts", http.StatusInternalServerError)
+			return
+		}
+
+		h.refreshConnectorContext(id)
+		h.writeDbtResponse(w, http.StatusCreated, doc)
+
+	case http.MethodDelete:
+		if err := importer.Delete(id); err != nil {
+			if errors.Is(err, schema.ErrNoDbtArtifacts) {
+				http.Error(w, "No dbt artifacts imported", http.StatusNotFound)
+				return
+			}
+			h.server.logger.Error("Failed to delete dbt artifacts", "error", err, "id", id)
+			http.Error(w, "Failed to delete dbt artifacts", http.StatusInternalServerError)
+			return
+		}
+
+		h.refreshConnectorContext(id)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// writeDbtResponse summarizes a documented schema. Tables are counted rather than
+// listed since big projects document thousands of them.
+func (h *ConnectorHandlers) writeDbtResponse(w http.ResponseWriter, status int, doc *schema.DocumentedSchema) {
+	response := map[string]interface{}{
+		"success": true,
+		"data": map[string]interface{}{
+			"project":       doc.Project,
+			"tables":        len(doc.Tables),
+			"relationships": len(doc.Relationships),
+			"metrics":       doc.Metrics,
+			"glossary":      doc.Glossary,
+			"warnings":      doc.Warnings,
+			"imported_at":   doc.ImportedAt,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// readFormFile returns the content of an optional multipart file, or nil when absent
+func readFormFile(form *multipart.Form, field string) ([]byte, error) {
+	headers := form.File[field]
+	if len(headers) == 0 {
+		return nil, nil
+	}
+	if headers[0].Size > maxDbtArtifactSize {
+		return nil, errors.New("file too large")
+	}
+
+	file, err := headers[0].Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
@@ -3,14 +3,24 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"insightiq/backend/internal/agent"
+	"insightiq/backend/internal/models"
+	"insightiq/backend/internal/repository"
+	"insightiq/backend/internal/services"
 	"insightiq/backend/internal/validation"
 )
 
+// queueFullRetryAfter is how many seconds clients are asked to wait when the
+// agent task queue is full
+const queueFullRetryAfter = 5
+
 type ServiceHealth struct {
 	Status  string `json:"status"`
 	Message string `json:"message,omitempty"`
@@ -43,6 +53,36 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(health)
 }
 
+// handleAgentStatus reports the health of the agents and the load of their task queue
+func (s *Server) handleAgentStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    s.analyticsService.GetAgentStatus(),
+	})
+}
+
+// writeQueueFull answers requests whose task did not fit in the agent task queue,
+// asking the client to retry later, and reports whether err was such an error
+func writeQueueFull(w http.ResponseWriter, err error) bool {
+	var full agent.ErrQueueFull
+	if !errors.As(err, &full) {
+		return false
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(queueFullRetryAfter))
+	status := http.StatusServiceUnavailable
+	if full.UserID != "" {
+		status = http.StatusTooManyRequests
+	}
+	http.Error(w, full.Error(), status)
+	return true
+}
+
 func (s *Server) handleTestPostgres(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -102,6 +142,10 @@ func (s *Server) handleTextQuery(w http.ResponseWriter, r *http.Request) {
 
 	var req struct {
 		Query string `json:"query"`
+		// Filters correct the filters recognized in the query, as returned by an earlier response
+		Filters *[]models.Filter `json:"filters,omitempty"`
+		// SessionID continues a conversation session; a new one is started when empty
+		SessionID string `json:"session_id,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -118,8 +162,45 @@ func (s *Server) handleTextQuery(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := s.analyticsService.ProcessQuery(r.Context(), req.Query)
+	// Signed-in users ask within conversation sessions, so follow-ups are understood
+	userID, _ := r.Context().Value("user_id").(string)
+	if s.conversationService != nil && userID != "" {
+		reply, err := s.conversationService.Ask(r.Context(), userID, req.SessionID, req.Query, services.QueryOptions{Filters: req.Filters})
+		if err != nil {
+			if errors.Is(err, repository.ErrSessionNotFound) {
+				http.Error(w, "Session not found", http.StatusNotFound)
+				return
+			}
+			if errors.Is(err, services.ErrPlanCancelled) {
+				http.Error(w, "Query was cancelled", http.StatusConflict)
+				return
+			}
+			if writeQueueFull(w, err) {
+				return
+			}
+			s.logger.Error("Text query failed", "error", err, "session_id", req.SessionID)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(reply)
+		return
+	}
+	if req.SessionID != "" {
+		http.Error(w, "Conversation sessions are not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	result, err := s.analyticsService.ProcessQueryWithOptions(r.Context(), req.Query, services.QueryOptions{Filters: req.Filters, UserID: userID})
 	if err != nil {
+		if errors.Is(err, services.ErrPlanCancelled) {
+			http.Error(w, "Query was cancelled", http.StatusConflict)
+			return
+		}
+		if writeQueueFull(w, err) {
+			return
+		}
 		s.logger.Error("Text query failed", "error", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -170,8 +251,12 @@ func (s *Server) handleVoiceQuery(w http.ResponseWriter, r *http.Request) {
 		format = strings.ToLower(header.Filename[len(header.Filename)-3:])
 	}
 
-	result, err := s.voiceService.ProcessVoiceQuery(r.Context(), audioData, format)
+	userID, _ := r.Context().Value("user_id").(string)
+	result, err := s.voiceService.ProcessVoiceQuery(r.Context(), userID, audioData, format)
 	if err != nil {
+		if writeQueueFull(w, err) {
+			return
+		}
 		s.logger.Error("Voice query failed", "error", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -204,7 +289,7 @@ func (s *Server) handleSQLQuery(w http.ResponseWriter, r *http.Request) {
 
 	if err := validation.ValidateSQL(req.SQL); err != nil {
 		s.logger.Error("Invalid SQL input", "error", err, "sql", req.SQL, "remote_addr", r.RemoteAddr)
-		http.Error(w, "Invalid SQL query", http.StatusBadRequest)
+		http.Error(w, "Invalid SQL query: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -214,8 +299,12 @@ func (s *Server) handleSQLQuery(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := s.analyticsService.ExecuteCustomSQL(r.Context(), req.SQL, req.Question)
+	userID, _ := r.Context().Value("user_id").(string)
+	result, err := s.analyticsService.ExecuteCustomSQL(r.Context(), userID, req.SQL, req.Question)
 	if err != nil {
+		if writeQueueFull(w, err) {
+			return
+		}
 		s.logger.Error("SQL query failed", "error", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
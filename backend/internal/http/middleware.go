@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"insightiq/backend/internal/services"
+	"insightiq/backend/internal/validation"
 )
 
 func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
@@ -72,8 +73,12 @@ func (s *Server) securityMiddleware(next http.Handler) http.Handler {
 		w.Header().Set("Referrer-Policy", "strict-origin-when-cross-origin")
 		w.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'self'; style-src 'self' 'unsafe-inline'")
 
-		// Request size limit (10MB)
-		r.Body = http.MaxBytesReader(w, r.Body, 10<<20)
+		// Request size limit (10MB, larger for dataset uploads)
+		maxBytes := int64(10 << 20)
+		if r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/api/connectors/") && strings.HasSuffix(r.URL.Path, "/files") {
+			maxBytes = validation.MaxDatasetUploadSize + 1<<20 // allow for multipart overhead
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
 
 		next.ServeHTTP(w, r)
 	})
@@ -0,0 +1,116 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"insightiq/backend/internal/models"
+	"insightiq/backend/internal/repository"
+	"insightiq/backend/internal/services"
+)
+
+// handlePlans lists the plans of the user, optionally only those with ?status=
+func (s *Server) handlePlans(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.planService == nil {
+		http.Error(w, "Plans are not available", http.StatusServiceUnavailable)
+		return
+	}
+	// Without authentication every plan belongs to the anonymous user
+	userID, _ := r.Context().Value("user_id").(string)
+
+	limit := 50
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	offset := 0
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+	status := models.TaskStatus(r.URL.Query().Get("status"))
+
+	plans, err := s.planService.List(r.Context(), userID, status, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to list plans", "error", err, "user_id", userID)
+		http.Error(w, "Failed to list plans", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"data":    plans,
+		"limit":   limit,
+		"offset":  offset,
+		"count":   len(plans),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handlePlanByID reports a plan with the state of its steps and, once finished,
+// its result (GET /api/plans/{id}), or cancels it (POST /api/plans/{id}/cancel)
+func (s *Server) handlePlanByID(w http.ResponseWriter, r *http.Request) {
+	if s.planService == nil {
+		http.Error(w, "Plans are not available", http.StatusServiceUnavailable)
+		return
+	}
+	userID, _ := r.Context().Value("user_id").(string)
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/plans/")
+	planID, action, _ := strings.Cut(path, "/")
+	if planID == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		plan, err := s.planService.Get(r.Context(), userID, planID)
+		if err != nil {
+			s.writePlanError(w, "Failed to get plan", err, planID)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    plan,
+		})
+	case action == "cancel" && r.Method == http.MethodPost:
+		if err := s.planService.Cancel(r.Context(), userID, planID); err != nil {
+			s.writePlanError(w, "Failed to cancel plan", err, planID)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Plan cancellation requested",
+		})
+	case action == "" || action == "cancel":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// writePlanError maps plan errors to HTTP status codes
+func (s *Server) writePlanError(w http.ResponseWriter, message string, err error, planID string) {
+	switch {
+	case errors.Is(err, repository.ErrPlanNotFound):
+		http.Error(w, "Plan not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrPlanNotRunning):
+		http.Error(w, "Plan is not running", http.StatusConflict)
+	default:
+		s.logger.Error(message, "error", err, "plan_id", planID)
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
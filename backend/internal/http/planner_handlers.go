@@ -69,7 +69,8 @@ func (h *PlannerHandlers) handleAnalyzeQuery(w http.ResponseWriter, r *http.Requ
 	}
 
 	var req struct {
-		Query string `json:"query"`
+		Query        string   `json:"query"`
+		ConnectorIDs []string `json:"connector_ids,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -86,7 +87,8 @@ func (h *PlannerHandlers) handleAnalyzeQuery(w http.ResponseWriter, r *http.Requ
 	h.server.logger.Info("Processing query analysis request", "query", req.Query)
 
 	plannerReq := &models.PlannerRequest{
-		Query: req.Query,
+		Query:   req.Query,
+		Context: map[string]interface{}{"connector_ids": req.ConnectorIDs},
 	}
 
 	response, err := h.plannerService.ParseIntent(r.Context(), plannerReq)
@@ -104,6 +106,8 @@ func (h *PlannerHandlers) handleAnalyzeQuery(w http.ResponseWriter, r *http.Requ
 			"entities":   response.Intent.Entities,
 		},
 		"parsed_query": response.Intent.ParsedQuery,
+		// Values recognized in the question, to review and correct before running it
+		"filters": response.Intent.ParsedQuery.Filters,
 		"task_graph": map[string]interface{}{
 			"steps":          len(response.TaskGraph.Steps),
 			"estimated_time": response.TaskGraph.EstimatedTime.String(),
@@ -0,0 +1,176 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"insightiq/backend/internal/models"
+	"insightiq/backend/internal/services"
+	"insightiq/backend/internal/validation"
+)
+
+// sseKeepAlive is how often an idle event stream gets a comment, so proxies
+// keep the connection open
+const sseKeepAlive = 15 * time.Second
+
+// handleSubmitQuery starts answering a question in the background and returns
+// the job to follow: POST /api/queries
+func (s *Server) handleSubmitQuery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.queryJobService == nil {
+		http.Error(w, "Asynchronous queries are not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req struct {
+		Query string `json:"query"`
+		// Filters correct the filters recognized in the query, as returned by an earlier response
+		Filters *[]models.Filter `json:"filters,omitempty"`
+		// SessionID continues a conversation session; a new one is started when empty
+		SessionID string `json:"session_id,omitempty"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.logger.Error("Invalid JSON in query job request", "error", err, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	req.Query = validation.SanitizeString(req.Query)
+	if err := validation.ValidateTextQuery(req.Query); err != nil {
+		s.logger.Error("Invalid text query input", "error", err, "query", req.Query, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Invalid query input", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := r.Context().Value("user_id").(string)
+	if req.SessionID != "" && (s.conversationService == nil || userID == "") {
+		http.Error(w, "Conversation sessions are not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	job := s.queryJobService.Submit(r.Context(), userID, req.SessionID, req.Query, req.Filters)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/queries/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data": map[string]string{
+			"id":         job.ID,
+			"status":     string(models.TaskStatusExecuting),
+			"events_url": "/api/queries/" + job.ID + "/events",
+			"poll_url":   "/api/queries/" + job.ID,
+		},
+	})
+}
+
+// handleQueryJob reports a job (GET /api/queries/{id}, for clients polling with
+// ?after= the ID of the last event they saw) or streams its events as Server-Sent
+// Events (GET /api/queries/{id}/events)
+func (s *Server) handleQueryJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.queryJobService == nil {
+		http.Error(w, "Asynchronous queries are not available", http.StatusServiceUnavailable)
+		return
+	}
+	userID, _ := r.Context().Value("user_id").(string)
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/queries/")
+	jobID, action, _ := strings.Cut(path, "/")
+	if jobID == "" || (action != "" && action != "events") {
+		http.NotFound(w, r)
+		return
+	}
+
+	job, err := s.queryJobService.Get(userID, jobID)
+	if err != nil {
+		if errors.Is(err, services.ErrQueryJobNotFound) {
+			http.Error(w, "Query job not found", http.StatusNotFound)
+			return
+		}
+		s.logger.Error("Failed to get query job", "error", err, "job_id", jobID)
+		http.Error(w, "Failed to get query job", http.StatusInternalServerError)
+		return
+	}
+
+	after, _ := strconv.Atoi(r.URL.Query().Get("after"))
+	if action == "events" {
+		// Reconnecting EventSource clients send the ID of the last event they got
+		if last, err := strconv.Atoi(r.Header.Get("Last-Event-ID")); err == nil {
+			after = last
+		}
+		s.streamQueryEvents(w, r, job, after)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    job.Snapshot(after),
+	})
+}
+
+// streamQueryEvents writes the events of a job after the given one as they
+// happen, until the job finished or the client went away
+func (s *Server) streamQueryEvents(w http.ResponseWriter, r *http.Request, job *services.QueryJob, after int) {
+	rc := http.NewResponseController(w)
+	// Answers take longer than the server's write timeout
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		s.logger.Warn("Failed to clear write deadline for event stream", "error", err, "job_id", job.ID)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		events, finished, changed := job.EventsSince(after)
+		for _, event := range events {
+			data, err := json.Marshal(event.Data)
+			if err != nil {
+				s.logger.Error("Failed to encode query event", "error", err, "job_id", job.ID, "event", event.Type)
+				data = []byte("null")
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+				return
+			}
+			after = event.ID
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+		if finished {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"insightiq/backend/internal/models"
+	"insightiq/backend/internal/repository"
+	"insightiq/backend/internal/services"
+)
+
+// Largest semantic model file accepted by an import
+const maxSemanticImportSize = 1 << 20
+
+// routeSemantic dispatches /api/connectors/{id}/semantic requests
+func (h *ConnectorHandlers) routeSemantic(w http.ResponseWriter, r *http.Request) {
+	if h.server.semanticService == nil {
+		http.Error(w, "Semantic definitions are not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	id, rest := h.extractSemanticPath(r.URL.Path)
+	if id == "" {
+		http.Error(w, "Invalid connector ID", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case rest == "":
+		switch r.Method {
+		case http.MethodGet:
+			h.handleListDefinitions(w, r, id)
+		case http.MethodPost:
+			h.handleCreateDefinition(w, r, id)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	case rest == "export":
+		h.handleExportDefinitions(w, r, id)
+	case rest == "import":
+		h.handleImportDefinitions(w, r, id)
+	case strings.HasSuffix(rest, "/versions") && strings.Count(rest, "/") == 1:
+		h.handleGetDefinitionVersions(w, r, id, strings.TrimSuffix(rest, "/versions"))
+	case !strings.Contains(rest, "/"):
+		switch r.Method {
+		case http.MethodGet:
+			h.handleGetDefinition(w, r, id, rest)
+		case http.MethodPut:
+			h.handleUpdateDefinition(w, r, id, rest)
+		case http.MethodDelete:
+			h.handleDeleteDefinition(w, r, id, rest)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleListDefinitions lists the metric and dimension definitions of a connector
+func (h *ConnectorHandlers) handleListDefinitions(w http.ResponseWriter, r *http.Request, id string) {
+	defs, err := h.server.semanticService.ListDefinitions(r.Context(), id)
+	if err != nil {
+		h.writeSemanticError(w, "Failed to list semantic definitions", err, id)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"data":    defs,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleCreateDefinition adds a metric or dimension definition
+func (h *ConnectorHandlers) handleCreateDefinition(w http.ResponseWriter, r *http.Request, id string) {
+	var req models.SemanticDefinitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.server.logger.Error("Invalid JSON in create definition request", "error", err)
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	def, err := h.server.semanticService.CreateDefinition(r.Context(), id, &req, requestUser(r))
+	if err != nil {
+		h.writeSemanticError(w, "Failed to create semantic definition", err, id)
+		return
+	}
+
+	h.refreshConnectorContext(id)
+
+	response := map[string]interface{}{
+		"success": true,
+		"data":    def,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleGetDefinition retrieves a definition
+func (h *ConnectorHandlers) handleGetDefinition(w http.ResponseWriter, r *http.Request, id, defID string) {
+	def, err := h.server.semanticService.GetDefinition(r.Context(), id, defID)
+	if err != nil {
+		h.writeSemanticError(w, "Failed to get semantic definition", err, id)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"data":    def,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleUpdateDefinition replaces a definition. A version in the body or an
+// If-Match header makes the update fail with 409 when someone else changed it.
+func (h *ConnectorHandlers) handleUpdateDefinition(w http.ResponseWriter, r *http.Request, id, defID string) {
+	var req struct {
+		models.SemanticDefinitionRequest
+		Version int `json:"version,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.server.logger.Error("Invalid JSON in update definition request", "error", err)
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	expectedVersion := req.Version
+	if match := strings.Trim(r.Header.Get("If-Match"), `"`); match != "" {
+		version, err := strconv.Atoi(match)
+		if err != nil {
+			http.Error(w, "Invalid If-Match version", http.StatusBadRequest)
+			return
+		}
+		expectedVersion = version
+	}
+
+	def, err := h.server.semanticService.UpdateDefinition(r.Context(), id, defID, &req.SemanticDefinitionRequest, expectedVersion, requestUser(r))
+	if err != nil {
+		h.writeSemanticError(w, "Failed to update semantic definition", err, id)
+		return
+	}
+
+	h.refreshConnectorContext(id)
+
+	response := map[string]interface{}{
+		"success": true,
+		"data":    def,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleDeleteDefinition removes a definition
+func (h *ConnectorHandlers) handleDeleteDefinition(w http.ResponseWriter, r *http.Request, id, defID string) {
+	if err := h.server.semanticService.DeleteDefinition(r.Context(), id, defID); err != nil {
+		h.writeSemanticError(w, "Failed to delete semantic definition", err, id)
+		return
+	}
+
+	h.refreshConnectorContext(id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetDefinitionVersions lists the history of a definition
+func (h *ConnectorHandlers) handleGetDefinitionVersions(w http.ResponseWriter, r *http.Request, id, defID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	versions, err := h.server.semanticService.DefinitionVersions(r.Context(), id, defID)
+	if err != nil {
+		h.writeSemanticError(w, "Failed to get semantic definition versions", err, id)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"data":    versions,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleExportDefinitions downloads the definitions of a connector as YAML
+func (h *ConnectorHandlers) handleExportDefinitions(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, err := h.server.semanticService.ExportYAML(r.Context(), id)
+	if err != nil {
+		h.writeSemanticError(w, "Failed to export semantic definitions", err, id)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Header().Set("Content-Disposition", `attachment; filename="semantic-model.yaml"`)
+	w.Write(data)
+}
+
+// handleImportDefinitions creates or updates definitions from a YAML body
+func (h *ConnectorHandlers) handleImportDefinitions(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSemanticImportSize))
+	if err != nil {
+		http.Error(w, "Semantic model file too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	result, err := h.server.semanticService.ImportYAML(r.Context(), id, data, requestUser(r))
+	if result != nil && result.Created+result.Updated > 0 {
+		h.refreshConnectorContext(id)
+	}
+	if err != nil {
+		h.writeSemanticError(w, "Failed to import semantic definitions", err, id)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"data":    result,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// writeSemanticError maps semantic service errors to status codes
+func (h *ConnectorHandlers) writeSemanticError(w http.ResponseWriter, msg string, err error, id string) {
+	switch {
+	case errors.Is(err, services.ErrConnectorNotFound):
+		http.Error(w, "Connector not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrDefinitionNotFound):
+		http.Error(w, "Semantic definition not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrDuplicateDefinition), errors.Is(err, repository.ErrDefinitionVersionConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, services.ErrInvalidDefinition):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		h.server.logger.Error(msg, "error", err, "id", id)
+		http.Error(w, msg, http.StatusInternalServerError)
+	}
+}
+
+// extractSemanticPath splits /api/connectors/{id}/semantic[/rest] into the
+// connector ID and the rest of the path
+func (h *ConnectorHandlers) extractSemanticPath(path string) (string, string) {
+	parts := strings.SplitN(strings.TrimSuffix(path, "/"), "/", 6)
+	if len(parts) < 5 || parts[2] != "connectors" || parts[4] != "semantic" {
+		return "", ""
+	}
+	if len(parts) == 6 {
+		return parts[3], parts[5]
+	}
+	return parts[3], ""
+}
+
+// requestUser identifies the authenticated user for ownership and audit fields
+func requestUser(r *http.Request) string {
+	if email, ok := r.Context().Value("user_email").(string); ok && email != "" {
+		return email
+	}
+	userID, _ := r.Context().Value("user_id").(string)
+	return userID
+}
@@ -2,24 +2,32 @@
 package http
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 	"strings"
 
 	"insightiq/backend/internal/auth"
+	"insightiq/backend/internal/schema"
 	"insightiq/backend/internal/services"
 	"github.com/supertokens/supertokens-golang/supertokens"
 )
 
 type Server struct {
-	analyticsService  *services.AnalyticsService
-	voiceService      *services.VoiceService
-	connectorService  *services.ConnectorService
-	plannerService    *services.PlannerService
-	authService       *services.AuthService
-	queryHistoryRepo  interface{} // repository.QueryHistoryRepository
-	logger            *slog.Logger
-	mux               *http.ServeMux
+	analyticsService    *services.AnalyticsService
+	voiceService        *services.VoiceService
+	connectorService    *services.ConnectorService
+	plannerService      *services.PlannerService
+	authService         *services.AuthService
+	semanticService     *services.SemanticService
+	conversationService *services.ConversationService
+	planService         *services.PlanService
+	queryJobService     *services.QueryJobService
+	dbtImporter         *schema.DbtImporter
+	queryHistoryRepo    interface{} // repository.QueryHistoryRepository
+	contextRefresher    func(ctx context.Context, connectorID string) error
+	logger              *slog.Logger
+	mux                 *http.ServeMux
 }
 
 func NewServer(analytics *services.AnalyticsService, voice *services.VoiceService, connector *services.ConnectorService, planner *services.PlannerService, auth *services.AuthService, queryHistoryRepo interface{}, logger *slog.Logger) *Server {
@@ -38,6 +46,38 @@ func NewServer(analytics *services.AnalyticsService, voice *services.VoiceServic
 	return s
 }
 
+// SetContextRefresher sets the hook used to rebuild a connector's domain contexts
+// after its data changes (e.g. a dataset file is uploaded)
+func (s *Server) SetContextRefresher(refresher func(ctx context.Context, connectorID string) error) {
+	s.contextRefresher = refresher
+}
+
+// SetSemanticService enables the semantic definition endpoints of connectors
+func (s *Server) SetSemanticService(semanticService *services.SemanticService) {
+	s.semanticService = semanticService
+}
+
+// SetConversationService enables conversation sessions: questions asked in a
+// session are read with its earlier questions
+func (s *Server) SetConversationService(conversationService *services.ConversationService) {
+	s.conversationService = conversationService
+}
+
+// SetPlanService enables following and cancelling the plans questions are answered with
+func (s *Server) SetPlanService(planService *services.PlanService) {
+	s.planService = planService
+}
+
+// SetQueryJobService enables asynchronous queries whose progress is streamed as events
+func (s *Server) SetQueryJobService(queryJobService *services.QueryJobService) {
+	s.queryJobService = queryJobService
+}
+
+// SetDbtImporter enables importing dbt artifacts as connector business context
+func (s *Server) SetDbtImporter(importer *schema.DbtImporter) {
+	s.dbtImporter = importer
+}
+
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Apply security middleware stack
 	handler := s.corsMiddleware(
@@ -82,6 +122,15 @@ func (s *Server) setupRoutes() {
 	s.mux.HandleFunc("/api/voice", s.withAuth(s.handleVoiceQuery))
 	s.mux.HandleFunc("/api/sql", s.withAuth(s.handleSQLQuery))
 
+	// Protected conversation session routes
+	s.mux.HandleFunc("/api/sessions", s.withAuth(s.handleSessions))
+	s.mux.HandleFunc("/api/sessions/", s.withAuth(s.handleSessionByID))
+	s.mux.HandleFunc("/api/plans", s.withAuth(s.handlePlans))
+	s.mux.HandleFunc("/api/plans/", s.withAuth(s.handlePlanByID))
+	s.mux.HandleFunc("/api/queries", s.withAuth(s.handleSubmitQuery))
+	s.mux.HandleFunc("/api/queries/", s.withAuth(s.handleQueryJob))
+	s.mux.HandleFunc("/api/agents/status", s.withAuth(s.handleAgentStatus))
+
 	// Protected connector routes
 	if s.connectorService != nil {
 		connectorHandlers := NewConnectorHandlers(s.connectorService, s)
@@ -145,6 +194,12 @@ func (s *Server) routeConnectorsByID(handlers *ConnectorHandlers) http.HandlerFu
 			return
 		}
 
+		// Handle driver listing endpoint: /api/connectors/types
+		if path == "/api/connectors/types" {
+			handlers.handleGetConnectorTypes(w, r)
+			return
+		}
+
 		// Extract connector ID and action from path
 		if len(path) < len("/api/connectors/") {
 			http.NotFound(w, r)
@@ -152,7 +207,24 @@ func (s *Server) routeConnectorsByID(handlers *ConnectorHandlers) http.HandlerFu
 		}
 
 		// Handle specific connector endpoints
-		if strings.HasSuffix(path, "/test") {
+		if strings.Count(path, "/") >= 4 && strings.Split(path, "/")[4] == "semantic" { // /api/connectors/{id}/semantic/...
+			handlers.routeSemantic(w, r)
+		} else if strings.HasSuffix(path, "/files") { // /api/connectors/{id}/files
+			switch r.Method {
+			case http.MethodGet:
+				handlers.handleListConnectorFiles(w, r)
+			case http.MethodPost:
+				handlers.handleUploadConnectorFile(w, r)
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+		} else if strings.Count(path, "/") == 5 && strings.Contains(path, "/files/") { // /api/connectors/{id}/files/{name}
+			handlers.handleDeleteConnectorFile(w, r)
+		} else if strings.HasSuffix(path, "/dbt") { // /api/connectors/{id}/dbt
+			handlers.handleDbtArtifacts(w, r)
+		} else if strings.HasSuffix(path, "/health-history") {
+			handlers.handleGetConnectorHealthHistory(w, r)
+		} else if strings.HasSuffix(path, "/test") {
 			handlers.handleTestConnector(w, r)
 		} else if strings.HasSuffix(path, "/data") {
 			handlers.handleGetConnectorData(w, r)
@@ -15,6 +15,7 @@ const (
 	ConnectorTypeMySQL    ConnectorType = "mysql"
 	ConnectorTypeMongoDB  ConnectorType = "mongodb"
 	ConnectorTypeAPI      ConnectorType = "api"
+	ConnectorTypeFile     ConnectorType = "file"
 )
 
 type ConnectorStatus string
@@ -84,6 +85,59 @@ type PostgresConfig struct {
 	Port     int    `json:"port,omitempty"`
 }
 
+// MySQLConfig represents MySQL-specific configuration. URL may be a mysql:// URL
+// or a native DSN; otherwise the discrete host fields are used.
+type MySQLConfig struct {
+	URL      string `json:"url,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Database string `json:"database,omitempty"`
+	Host     string `json:"host,omitempty"`
+	Port     int    `json:"port,omitempty"`
+}
+
+// MongoDBConfig represents MongoDB-specific configuration
+type MongoDBConfig struct {
+	URL        string `json:"url"`
+	Database   string `json:"database,omitempty"`
+	SampleSize int    `json:"sample_size,omitempty"`
+}
+
+// APIConfig represents a generic REST/JSON API connector configuration
+type APIConfig struct {
+	URL         string            `json:"url"`
+	AuthHeader  string            `json:"auth_header,omitempty"` // e.g. "Authorization" or "X-API-Key"
+	AuthValue   string            `json:"auth_value,omitempty"`  // e.g. "Bearer <token>"
+	Headers     map[string]string `json:"headers,omitempty"`
+	Endpoints   []APIEndpoint     `json:"endpoints"`
+	TimeoutSecs int               `json:"timeout_seconds,omitempty"`
+}
+
+// APIEndpoint declares how to read records from a single API endpoint
+type APIEndpoint struct {
+	Name        string            `json:"name"`
+	Path        string            `json:"path"`
+	Description string            `json:"description,omitempty"`
+	QueryParams map[string]string `json:"query_params,omitempty"`
+	RecordsPath string            `json:"records_path,omitempty"` // JSONPath to the records array, e.g. "$.data.items"
+	Pagination  *APIPagination    `json:"pagination,omitempty"`
+	FieldTypes  map[string]string `json:"field_types,omitempty"` // field -> string|integer|number|boolean|date|datetime
+}
+
+// APIPagination describes the pagination style of an endpoint
+type APIPagination struct {
+	Type        string `json:"type"` // "page", "offset", "cursor", "link_header" or "none"
+	PageParam   string `json:"page_param,omitempty"`
+	SizeParam   string `json:"size_param,omitempty"`
+	PageSize    int    `json:"page_size,omitempty"`
+	StartPage   int    `json:"start_page,omitempty"`
+	OffsetParam string `json:"offset_param,omitempty"`
+	LimitParam  string `json:"limit_param,omitempty"`
+	CursorParam string `json:"cursor_param,omitempty"`
+	CursorPath  string `json:"cursor_path,omitempty"` // JSONPath to the next cursor in the response
+	MaxPages    int    `json:"max_pages,omitempty"`
+}
+
 // ConnectorTestResult represents the result of testing a connector
 type ConnectorTestResult struct {
 	Success           bool     `json:"success"`
@@ -93,6 +147,16 @@ type ConnectorTestResult struct {
 	Error             string   `json:"error,omitempty"`
 }
 
+// ConnectorHealthCheck records the outcome of one connection test
+type ConnectorHealthCheck struct {
+	ID           int64     `json:"id" db:"id"`
+	ConnectorID  string    `json:"connector_id" db:"connector_id"`
+	CheckedAt    time.Time `json:"checked_at" db:"checked_at"`
+	Success      bool      `json:"success" db:"success"`
+	ResponseTime *int64    `json:"response_time,omitempty" db:"response_time_ms"` // in milliseconds
+	Error        string    `json:"error,omitempty" db:"error"`
+}
+
 // CreateConnectorRequest represents the request to create a new connector
 type CreateConnectorRequest struct {
 	Name   string          `json:"name" validate:"required,min=1,max=100"`
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// ConversationSession is a series of related questions asked by one user, so
+// follow-ups such as "what about last year?" can be answered in context
+type ConversationSession struct {
+	ID        string    `json:"id" db:"id"`
+	UserID    string    `json:"user_id" db:"user_id"`
+	Title     string    `json:"title" db:"title"` // the first question
+	TurnCount int       `json:"turn_count" db:"turn_count"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	Turns []ConversationTurn `json:"turns,omitempty" db:"-"`
+}
+
+// ConversationTurn is one question of a session and what answered it
+type ConversationTurn struct {
+	ID        string `json:"id" db:"id"`
+	SessionID string `json:"session_id" db:"session_id"`
+	Question  string `json:"question" db:"question"`
+	// ResolvedQuestion is the question rewritten with the context of earlier turns;
+	// the question itself when it needed none
+	ResolvedQuestion string       `json:"resolved_question" db:"resolved_question"`
+	ParsedQuery      *ParsedQuery `json:"parsed_query,omitempty" db:"-"`
+	SQL              string       `json:"sql,omitempty" db:"generated_sql"`
+	ResultSummary    string       `json:"result_summary,omitempty" db:"result_summary"`
+	RowCount         int          `json:"row_count" db:"row_count"`
+	Status           string       `json:"status" db:"status"` // "success", "error"
+	ErrorMessage     string       `json:"error_message,omitempty" db:"error_message"`
+	CreatedAt        time.Time    `json:"created_at" db:"created_at"`
+}
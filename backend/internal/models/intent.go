@@ -2,6 +2,8 @@ package models
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -49,10 +51,12 @@ type ParsedQuery struct {
 
 // TimeRange represents time-based filters
 type TimeRange struct {
-	Start    *time.Time `json:"start,omitempty"`
-	End      *time.Time `json:"end,omitempty"`
-	Period   string     `json:"period"`    // "last_week", "last_month", "last_quarter", "last_year"
-	Relative string     `json:"relative"`  // "today", "yesterday", "last_7_days"
+	Start       *time.Time `json:"start,omitempty"`
+	End         *time.Time `json:"end,omitempty"` // exclusive
+	Period      string     `json:"period"`    // "last_week", "last_month", "last_quarter", "last_year"
+	Relative    string     `json:"relative"`  // "today", "yesterday", "last_7_days"
+	Granularity string     `json:"granularity,omitempty"` // "day", "week", "month", "quarter", "year"
+	Comparison  *TimeRange `json:"comparison,omitempty"`  // period to compare with, e.g. the same period last year
 }
 
 // Filter represents a data filter condition
@@ -61,6 +65,10 @@ type Filter struct {
 	Operator  string      `json:"operator"` // "=", "!=", ">", "<", ">=", "<=", "IN", "LIKE"
 	Value     interface{} `json:"value"`
 	Condition string      `json:"condition"` // "AND", "OR"
+	// Set on filters linked from values named in the question
+	Table       string `json:"table,omitempty"`        // table of Field
+	ConnectorID string `json:"connector_id,omitempty"` // connector whose data holds the values
+	Text        string `json:"text,omitempty"`         // words of the question the values were recognized in
 }
 
 // Aggregation represents data aggregation operations
@@ -93,6 +101,8 @@ type TaskGraph struct {
 	EstimatedTime time.Duration `json:"estimated_time"`
 	CreatedAt   time.Time   `json:"created_at"`
 	Status      TaskStatus  `json:"status"`
+	// ExecutionTime is how long the executor took to run the graph
+	ExecutionTime time.Duration `json:"execution_time,omitempty"`
 }
 
 // TaskStep represents an individual step in the execution plan
@@ -107,6 +117,17 @@ type TaskStep struct {
 	EstimatedTime time.Duration        `json:"estimated_time"`
 	Priority    int                    `json:"priority"`
 	Retry       *RetryConfig           `json:"retry,omitempty"`
+	// Optional steps may fail without stopping the steps that depend on them
+	Optional bool `json:"optional,omitempty"`
+
+	// Execution state, recorded by the executor
+	Status     TaskStatus    `json:"status,omitempty"`
+	Attempts   int           `json:"attempts,omitempty"`
+	StartedAt  *time.Time    `json:"started_at,omitempty"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+	Duration   time.Duration `json:"duration,omitempty"`
+	OutputSize int           `json:"output_size,omitempty"` // rows produced
+	Error      string        `json:"error,omitempty"`
 }
 
 // TaskStepType represents different types of execution steps
@@ -133,6 +154,7 @@ const (
 	TaskStatusCompleted  TaskStatus = "completed"
 	TaskStatusFailed     TaskStatus = "failed"
 	TaskStatusCancelled  TaskStatus = "cancelled"
+	TaskStatusSkipped    TaskStatus = "skipped" // a step whose dependency failed
 )
 
 // RetryConfig represents retry configuration for tasks
@@ -167,16 +189,60 @@ func (it IntentType) String() string {
 	return string(it)
 }
 
+// Describe renders the time range for prompts, e.g. "last month: 2024-07-01 to
+// 2024-08-01 (end exclusive, UTC) by month"
+func (tr *TimeRange) Describe() string {
+	if tr.Start == nil && tr.End == nil {
+		if tr.Relative != "" {
+			return tr.Relative
+		}
+		return tr.Period
+	}
+
+	var b strings.Builder
+	if tr.Relative != "" {
+		b.WriteString(tr.Relative + ": ")
+	}
+	switch {
+	case tr.Start != nil && tr.End != nil:
+		b.WriteString(fmt.Sprintf("%s to %s (end exclusive, %s)", formatBound(*tr.Start), formatBound(*tr.End), tr.Start.Location()))
+	case tr.Start != nil:
+		b.WriteString(fmt.Sprintf("from %s (%s)", formatBound(*tr.Start), tr.Start.Location()))
+	default:
+		b.WriteString(fmt.Sprintf("before %s (%s)", formatBound(*tr.End), tr.End.Location()))
+	}
+	if tr.Granularity != "" {
+		b.WriteString(" by " + tr.Granularity)
+	}
+	if tr.Comparison != nil {
+		b.WriteString("; compared with " + tr.Comparison.Describe())
+	}
+	return b.String()
+}
+
+// formatBound prints a date, with the time of day only when it is not midnight
+func formatBound(t time.Time) string {
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
 // MarshalJSON implements json.Marshaler for TaskGraph
 func (tg *TaskGraph) MarshalJSON() ([]byte, error) {
 	type Alias TaskGraph
-	return json.Marshal(&struct {
+	aux := &struct {
 		EstimatedTime string `json:"estimated_time"`
+		ExecutionTime string `json:"execution_time,omitempty"`
 		*Alias
 	}{
 		EstimatedTime: tg.EstimatedTime.String(),
 		Alias:         (*Alias)(tg),
-	})
+	}
+	if tg.ExecutionTime > 0 {
+		aux.ExecutionTime = tg.ExecutionTime.String()
+	}
+	return json.Marshal(aux)
 }
 
 // UnmarshalJSON implements json.Unmarshaler for TaskGraph
@@ -184,6 +250,7 @@ func (tg *TaskGraph) UnmarshalJSON(data []byte) error {
 	type Alias TaskGraph
 	aux := &struct {
 		EstimatedTime string `json:"estimated_time"`
+		ExecutionTime string `json:"execution_time,omitempty"`
 		*Alias
 	}{
 		Alias: (*Alias)(tg),
@@ -200,6 +267,13 @@ func (tg *TaskGraph) UnmarshalJSON(data []byte) error {
 		}
 		tg.EstimatedTime = duration
 	}
+	if aux.ExecutionTime != "" {
+		duration, err := time.ParseDuration(aux.ExecutionTime)
+		if err != nil {
+			return err
+		}
+		tg.ExecutionTime = duration
+	}
 
 	return nil
 }
\ No newline at end of file
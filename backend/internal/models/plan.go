@@ -0,0 +1,55 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Plan is a stored run of a task graph: its steps and their states while it
+// runs, and the answer once it finished, so clients can reattach to it
+type Plan struct {
+	ID         string          `json:"id" db:"id"`
+	UserID     string          `json:"user_id" db:"user_id"`
+	Query      string          `json:"query" db:"query"`
+	Status     TaskStatus      `json:"status" db:"status"`
+	Graph      *TaskGraph      `json:"task_graph,omitempty" db:"-"`
+	Result     json.RawMessage `json:"result,omitempty" db:"-"`
+	Error      string          `json:"error,omitempty" db:"error_message"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at" db:"updated_at"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty" db:"finished_at"`
+
+	Progress PlanProgress `json:"progress" db:"-"`
+}
+
+// PlanProgress counts the steps of a task graph by state
+type PlanProgress struct {
+	TotalSteps     int     `json:"total_steps"`
+	CompletedSteps int     `json:"completed_steps"`
+	RunningSteps   int     `json:"running_steps"`
+	FailedSteps    int     `json:"failed_steps"`
+	SkippedSteps   int     `json:"skipped_steps"`
+	Percent        float64 `json:"percent"` // share of steps that finished, 0-100
+}
+
+// Progress counts the steps of the graph by state
+func (tg *TaskGraph) Progress() PlanProgress {
+	p := PlanProgress{TotalSteps: len(tg.Steps)}
+	for _, step := range tg.Steps {
+		switch step.Status {
+		case TaskStatusCompleted:
+			p.CompletedSteps++
+		case TaskStatusExecuting:
+			p.RunningSteps++
+		case TaskStatusFailed:
+			p.FailedSteps++
+		case TaskStatusSkipped, TaskStatusCancelled:
+			p.SkippedSteps++
+		}
+	}
+	if p.TotalSteps > 0 {
+		finished := p.CompletedSteps + p.FailedSteps + p.SkippedSteps
+		p.Percent = float64(finished) * 100 / float64(p.TotalSteps)
+	}
+	return p
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+)
+
+// DefinitionKind distinguishes metric from dimension definitions
+type DefinitionKind string
+
+const (
+	DefinitionKindMetric    DefinitionKind = "metric"
+	DefinitionKindDimension DefinitionKind = "dimension"
+)
+
+// SemanticDefinitionSpec holds the user-editable fields of a metric or dimension
+// definition, as sent to the API and written to YAML exports
+type SemanticDefinitionSpec struct {
+	Name        string `json:"name" yaml:"name" db:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty" db:"description"`
+	Table       string `json:"table" yaml:"table" db:"table_name"`
+	// Expression is SQL over Table: the column or expression Aggregation applies to,
+	// or a complete aggregate such as SUM(amount) / COUNT(*) when Aggregation is empty
+	Expression    string   `json:"expression" yaml:"expression" db:"expression"`
+	Aggregation   string   `json:"aggregation,omitempty" yaml:"aggregation,omitempty" db:"aggregation"` // sum, count, count_distinct, avg, min, max
+	TimeDimension string   `json:"time_dimension,omitempty" yaml:"time_dimension,omitempty" db:"time_dimension"`
+	Format        string   `json:"format,omitempty" yaml:"format,omitempty" db:"format"` // e.g. "currency", "percent", "0.00"
+	Synonyms      []string `json:"synonyms,omitempty" yaml:"synonyms,omitempty" db:"-"`
+	Owner         string   `json:"owner,omitempty" yaml:"owner,omitempty" db:"owner"`
+	Certified     bool     `json:"certified" yaml:"certified" db:"certified"`
+}
+
+// SemanticDefinition is a persisted metric or dimension of a connector. Every
+// change increments Version and keeps the previous definition in its history.
+type SemanticDefinition struct {
+	ID          string         `json:"id" db:"id"`
+	ConnectorID string         `json:"connector_id" db:"connector_id"`
+	Kind        DefinitionKind `json:"kind" db:"kind"`
+	SemanticDefinitionSpec
+	Version   int       `json:"version" db:"version"`
+	UpdatedBy string    `json:"updated_by,omitempty" db:"updated_by"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// SemanticDefinitionRequest creates or replaces a definition
+type SemanticDefinitionRequest struct {
+	Kind DefinitionKind `json:"kind"`
+	SemanticDefinitionSpec
+}
+
+// SemanticDefinitionVersion is a past or current version of a definition
+type SemanticDefinitionVersion struct {
+	DefinitionID string             `json:"definition_id"`
+	Version      int                `json:"version"`
+	Definition   SemanticDefinition `json:"definition"`
+	UpdatedBy    string             `json:"updated_by,omitempty"`
+	CreatedAt    time.Time          `json:"created_at"`
+}
+
+// SemanticModelFile is the YAML document definitions are imported from and exported to
+type SemanticModelFile struct {
+	Version    int                      `yaml:"version"`
+	Metrics    []SemanticDefinitionSpec `yaml:"metrics,omitempty"`
+	Dimensions []SemanticDefinitionSpec `yaml:"dimensions,omitempty"`
+}
+
+// SemanticImportResult counts what an import changed
+type SemanticImportResult struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+}
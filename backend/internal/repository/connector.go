@@ -35,6 +35,17 @@ func (r *ConnectorRepository) CreateTables(ctx context.Context) error {
 	CREATE INDEX IF NOT EXISTS idx_data_connectors_type ON data_connectors(type);
 	CREATE INDEX IF NOT EXISTS idx_data_connectors_status ON data_connectors(status);
 	CREATE INDEX IF NOT EXISTS idx_data_connectors_created_at ON data_connectors(created_at);
+
+	CREATE TABLE IF NOT EXISTS connector_health_checks (
+		id BIGSERIAL PRIMARY KEY,
+		connector_id VARCHAR(36) NOT NULL REFERENCES data_connectors(id) ON DELETE CASCADE,
+		checked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		success BOOLEAN NOT NULL,
+		response_time_ms BIGINT,
+		error TEXT NOT NULL DEFAULT ''
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_connector_health_checks_connector ON connector_health_checks(connector_id, checked_at DESC);
 	`
 
 	_, err := r.db.ExecContext(ctx, query)
@@ -198,6 +209,59 @@ func (r *ConnectorRepository) UpdateStatus(ctx context.Context, id string, statu
 	return err
 }
 
+// RecordHealthCheck stores a connection test result and keeps only the most recent
+// keep results for the connector
+func (r *ConnectorRepository) RecordHealthCheck(ctx context.Context, check *models.ConnectorHealthCheck, keep int) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO connector_health_checks (connector_id, checked_at, success, response_time_ms, error)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+	if err := tx.GetContext(ctx, &check.ID, query, check.ConnectorID, check.CheckedAt, check.Success, check.ResponseTime, check.Error); err != nil {
+		return err
+	}
+
+	prune := `
+		DELETE FROM connector_health_checks
+		WHERE connector_id = $1 AND id NOT IN (
+			SELECT id FROM connector_health_checks
+			WHERE connector_id = $1
+			ORDER BY checked_at DESC, id DESC
+			LIMIT $2
+		)
+	`
+	if _, err := tx.ExecContext(ctx, prune, check.ConnectorID, keep); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetHealthHistory retrieves the most recent connection test results, newest first
+func (r *ConnectorRepository) GetHealthHistory(ctx context.Context, connectorID string, limit int) ([]*models.ConnectorHealthCheck, error) {
+	var checks []*models.ConnectorHealthCheck
+	query := `
+		SELECT id, connector_id, checked_at, success, response_time_ms, error
+		FROM connector_health_checks
+		WHERE connector_id = $1
+		ORDER BY checked_at DESC, id DESC
+		LIMIT $2
+	`
+
+	err := r.db.SelectContext(ctx, &checks, query, connectorID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return checks, nil
+}
+
 // Delete deletes a connector
 func (r *ConnectorRepository) Delete(ctx context.Context, id string) error {
 	query := `DELETE FROM data_connectors WHERE id = $1`
@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"insightiq/backend/internal/models"
+)
+
+var (
+	ErrSessionNotFound = errors.New("conversation session not found")
+)
+
+type ConversationRepository struct {
+	db *sqlx.DB
+}
+
+func NewConversationRepository(db *sqlx.DB) *ConversationRepository {
+	return &ConversationRepository{db: db}
+}
+
+// CreateTables creates the conversation tables if they don't exist
+func (r *ConversationRepository) CreateTables(ctx context.Context) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS conversation_sessions (
+		id VARCHAR(36) PRIMARY KEY,
+		user_id VARCHAR(255) NOT NULL,
+		title TEXT NOT NULL DEFAULT '',
+		turn_count INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_conversation_sessions_user ON conversation_sessions(user_id, updated_at DESC);
+
+	CREATE TABLE IF NOT EXISTS conversation_turns (
+		id VARCHAR(36) PRIMARY KEY,
+		session_id VARCHAR(36) NOT NULL REFERENCES conversation_sessions(id) ON DELETE CASCADE,
+		question TEXT NOT NULL,
+		resolved_question TEXT NOT NULL,
+		parsed_query JSONB,
+		generated_sql TEXT NOT NULL DEFAULT '',
+		result_summary TEXT NOT NULL DEFAULT '',
+		row_count INTEGER NOT NULL DEFAULT 0,
+		status VARCHAR(50) NOT NULL DEFAULT 'success',
+		error_message TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_conversation_turns_session ON conversation_turns(session_id, created_at);
+	`
+
+	_, err := r.db.ExecContext(ctx, query)
+	return err
+}
+
+// CreateSession starts a new session for a user
+func (r *ConversationRepository) CreateSession(ctx context.Context, session *models.ConversationSession) error {
+	session.ID = uuid.New().String()
+	session.CreatedAt = time.Now()
+	session.UpdatedAt = session.CreatedAt
+
+	query := `
+		INSERT INTO conversation_sessions (id, user_id, title, turn_count, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		session.ID, session.UserID, session.Title, session.TurnCount, session.CreatedAt, session.UpdatedAt,
+	)
+	return err
+}
+
+// GetSession retrieves a session of a user with its turns, oldest first
+func (r *ConversationRepository) GetSession(ctx context.Context, id, userID string) (*models.ConversationSession, error) {
+	var session models.ConversationSession
+	query := `
+		SELECT id, user_id, title, turn_count, created_at, updated_at
+		FROM conversation_sessions
+		WHERE id = $1 AND user_id = $2
+	`
+	if err := r.db.GetContext(ctx, &session, query, id, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+
+	turns, err := r.getTurns(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	session.Turns = turns
+	return &session, nil
+}
+
+func (r *ConversationRepository) getTurns(ctx context.Context, sessionID string) ([]models.ConversationTurn, error) {
+	query := `
+		SELECT id, session_id, question, resolved_question, parsed_query, generated_sql,
+		       result_summary, row_count, status, error_message, created_at
+		FROM conversation_turns
+		WHERE session_id = $1
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var turns []models.ConversationTurn
+	for rows.Next() {
+		var turn models.ConversationTurn
+		var parsedQueryJSON []byte
+		if err := rows.Scan(
+			&turn.ID, &turn.SessionID, &turn.Question, &turn.ResolvedQuestion, &parsedQueryJSON, &turn.SQL,
+			&turn.ResultSummary, &turn.RowCount, &turn.Status, &turn.ErrorMessage, &turn.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if parsedQueryJSON != nil {
+			if err := json.Unmarshal(parsedQueryJSON, &turn.ParsedQuery); err != nil {
+				return nil, err
+			}
+		}
+		turns = append(turns, turn)
+	}
+	return turns, rows.Err()
+}
+
+// ListSessions retrieves the sessions of a user, most recently active first
+func (r *ConversationRepository) ListSessions(ctx context.Context, userID string, limit, offset int) ([]models.ConversationSession, error) {
+	query := `
+		SELECT id, user_id, title, turn_count, created_at, updated_at
+		FROM conversation_sessions
+		WHERE user_id = $1
+		ORDER BY updated_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	var sessions []models.ConversationSession
+	if err := r.db.SelectContext(ctx, &sessions, query, userID, limit, offset); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// AddTurn appends a turn to a session and marks the session active
+func (r *ConversationRepository) AddTurn(ctx context.Context, turn *models.ConversationTurn) error {
+	turn.ID = uuid.New().String()
+	turn.CreatedAt = time.Now()
+
+	var parsedQueryJSON []byte
+	if turn.ParsedQuery != nil {
+		var err error
+		parsedQueryJSON, err = json.Marshal(turn.ParsedQuery)
+		if err != nil {
+			return err
+		}
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO conversation_turns (
+			id, session_id, question, resolved_question, parsed_query, generated_sql,
+			result_summary, row_count, status, error_message, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+	if _, err := tx.ExecContext(ctx, query,
+		turn.ID, turn.SessionID, turn.Question, turn.ResolvedQuestion, parsedQueryJSON, turn.SQL,
+		turn.ResultSummary, turn.RowCount, turn.Status, turn.ErrorMessage, turn.CreatedAt,
+	); err != nil {
+		return err
+	}
+
+	query = `UPDATE conversation_sessions SET turn_count = turn_count + 1, updated_at = $2 WHERE id = $1`
+	result, err := tx.ExecContext(ctx, query, turn.SessionID, turn.CreatedAt)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+	return tx.Commit()
+}
+
+// DeleteSession removes a session of a user and its turns
+func (r *ConversationRepository) DeleteSession(ctx context.Context, id, userID string) error {
+	query := `DELETE FROM conversation_sessions WHERE id = $1 AND user_id = $2`
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"insightiq/backend/internal/models"
+)
+
+var (
+	ErrPlanNotFound = errors.New("plan not found")
+)
+
+type PlanRepository struct {
+	db *sqlx.DB
+}
+
+func NewPlanRepository(db *sqlx.DB) *PlanRepository {
+	return &PlanRepository{db: db}
+}
+
+// CreateTables creates the task_plans table if it doesn't exist
+func (r *PlanRepository) CreateTables(ctx context.Context) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS task_plans (
+		id VARCHAR(36) PRIMARY KEY,
+		user_id VARCHAR(255) NOT NULL DEFAULT '',
+		query TEXT NOT NULL,
+		status VARCHAR(20) NOT NULL,
+		graph JSONB,
+		result JSONB,
+		error_message TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		finished_at TIMESTAMP WITH TIME ZONE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_task_plans_user ON task_plans(user_id, created_at DESC);
+	CREATE INDEX IF NOT EXISTS idx_task_plans_status ON task_plans(status);
+	`
+
+	_, err := r.db.ExecContext(ctx, query)
+	return err
+}
+
+// Create saves a new plan
+func (r *PlanRepository) Create(ctx context.Context, plan *models.Plan) error {
+	now := time.Now()
+	plan.CreatedAt = now
+	plan.UpdatedAt = now
+
+	graphJSON, err := marshalGraph(plan.Graph)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO task_plans (id, user_id, query, status, graph, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err = r.db.ExecContext(ctx, query,
+		plan.ID, plan.UserID, plan.Query, plan.Status, graphJSON, plan.CreatedAt, plan.UpdatedAt,
+	)
+	return err
+}
+
+// UpdateProgress saves the current state of a running plan's graph
+func (r *PlanRepository) UpdateProgress(ctx context.Context, id string, status models.TaskStatus, graph *models.TaskGraph) error {
+	graphJSON, err := marshalGraph(graph)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE task_plans SET status = $2, graph = $3, updated_at = $4 WHERE id = $1 AND finished_at IS NULL`
+	_, err = r.db.ExecContext(ctx, query, id, status, graphJSON, time.Now())
+	return err
+}
+
+// Finish saves the outcome of a plan: its final graph and the answer or the error
+func (r *PlanRepository) Finish(ctx context.Context, id string, status models.TaskStatus, graph *models.TaskGraph, result json.RawMessage, errorMessage string) error {
+	graphJSON, err := marshalGraph(graph)
+	if err != nil {
+		return err
+	}
+	var resultJSON []byte
+	if len(result) > 0 {
+		resultJSON = result
+	}
+
+	now := time.Now()
+	query := `
+		UPDATE task_plans
+		SET status = $2, graph = $3, result = $4, error_message = $5, updated_at = $6, finished_at = $6
+		WHERE id = $1
+	`
+	res, err := r.db.ExecContext(ctx, query, id, status, graphJSON, resultJSON, errorMessage, now)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrPlanNotFound
+	}
+	return nil
+}
+
+// Touch records that a running plan is still being worked on
+func (r *PlanRepository) Touch(ctx context.Context, id string) error {
+	query := `UPDATE task_plans SET updated_at = $2 WHERE id = $1 AND finished_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, id, time.Now())
+	return err
+}
+
+// AbandonUnfinished fails the plans that have not finished and were last updated
+// before updatedBefore, and returns how many
+func (r *PlanRepository) AbandonUnfinished(ctx context.Context, errorMessage string, updatedBefore time.Time) (int64, error) {
+	query := `
+		UPDATE task_plans
+		SET status = $1, error_message = $2, updated_at = $3, finished_at = $3
+		WHERE finished_at IS NULL AND updated_at < $4
+	`
+	res, err := r.db.ExecContext(ctx, query, models.TaskStatusFailed, errorMessage, time.Now(), updatedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// GetByID retrieves a plan of a user with its graph and result
+func (r *PlanRepository) GetByID(ctx context.Context, id, userID string) (*models.Plan, error) {
+	query := `
+		SELECT id, user_id, query, status, graph, result, error_message, created_at, updated_at, finished_at
+		FROM task_plans
+		WHERE id = $1 AND user_id = $2
+	`
+
+	var plan models.Plan
+	var graphJSON, resultJSON []byte
+	err := r.db.QueryRowContext(ctx, query, id, userID).Scan(
+		&plan.ID, &plan.UserID, &plan.Query, &plan.Status, &graphJSON, &resultJSON,
+		&plan.Error, &plan.CreatedAt, &plan.UpdatedAt, &plan.FinishedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrPlanNotFound
+		}
+		return nil, err
+	}
+
+	if graphJSON != nil {
+		if err := json.Unmarshal(graphJSON, &plan.Graph); err != nil {
+			return nil, err
+		}
+	}
+	if resultJSON != nil {
+		plan.Result = resultJSON
+	}
+	return &plan, nil
+}
+
+// ListByUser retrieves the plans of a user, newest first, without their results.
+// An empty status lists plans in any state.
+func (r *PlanRepository) ListByUser(ctx context.Context, userID string, status models.TaskStatus, limit, offset int) ([]models.Plan, error) {
+	query := `
+		SELECT id, user_id, query, status, graph, error_message, created_at, updated_at, finished_at
+		FROM task_plans
+		WHERE user_id = $1 AND ($2::text = '' OR status = $2::text)
+		ORDER BY created_at DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var plans []models.Plan
+	for rows.Next() {
+		var plan models.Plan
+		var graphJSON []byte
+		if err := rows.Scan(
+			&plan.ID, &plan.UserID, &plan.Query, &plan.Status, &graphJSON,
+			&plan.Error, &plan.CreatedAt, &plan.UpdatedAt, &plan.FinishedAt,
+		); err != nil {
+			return nil, err
+		}
+		if graphJSON != nil {
+			if err := json.Unmarshal(graphJSON, &plan.Graph); err != nil {
+				return nil, err
+			}
+		}
+		plans = append(plans, plan)
+	}
+	return plans, rows.Err()
+}
+
+// marshalGraph encodes a graph as JSON, or NULL when there is none
+func marshalGraph(graph *models.TaskGraph) ([]byte, error) {
+	if graph == nil {
+		return nil, nil
+	}
+	return json.Marshal(graph)
+}
@@ -0,0 +1,298 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"insightiq/backend/internal/models"
+)
+
+var (
+	ErrDefinitionNotFound        = errors.New("semantic definition not found")
+	ErrDuplicateDefinition       = errors.New("a definition with this name already exists")
+	ErrDefinitionVersionConflict = errors.New("semantic definition was changed by someone else")
+)
+
+type SemanticRepository struct {
+	db *sqlx.DB
+}
+
+func NewSemanticRepository(db *sqlx.DB) *SemanticRepository {
+	return &SemanticRepository{db: db}
+}
+
+// CreateTables creates the semantic definition tables if they don't exist
+func (r *SemanticRepository) CreateTables(ctx context.Context) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS semantic_definitions (
+		id VARCHAR(36) PRIMARY KEY,
+		connector_id VARCHAR(36) NOT NULL REFERENCES data_connectors(id) ON DELETE CASCADE,
+		kind VARCHAR(20) NOT NULL,
+		name VARCHAR(100) NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		table_name VARCHAR(255) NOT NULL,
+		expression TEXT NOT NULL,
+		aggregation VARCHAR(20) NOT NULL DEFAULT '',
+		time_dimension VARCHAR(100) NOT NULL DEFAULT '',
+		format VARCHAR(50) NOT NULL DEFAULT '',
+		synonyms JSONB NOT NULL DEFAULT '[]',
+		owner VARCHAR(255) NOT NULL DEFAULT '',
+		certified BOOLEAN NOT NULL DEFAULT FALSE,
+		version INTEGER NOT NULL DEFAULT 1,
+		updated_by VARCHAR(255) NOT NULL DEFAULT '',
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+	);
+
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_semantic_definitions_name ON semantic_definitions(connector_id, kind, LOWER(name));
+
+	CREATE TABLE IF NOT EXISTS semantic_definition_versions (
+		definition_id VARCHAR(36) NOT NULL REFERENCES semantic_definitions(id) ON DELETE CASCADE,
+		version INTEGER NOT NULL,
+		definition JSONB NOT NULL,
+		updated_by VARCHAR(255) NOT NULL DEFAULT '',
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (definition_id, version)
+	);
+	`
+
+	_, err := r.db.ExecContext(ctx, query)
+	return err
+}
+
+// definitionRow adds the JSON encoded synonyms column to a definition
+type definitionRow struct {
+	models.SemanticDefinition
+	SynonymsJSON []byte `db:"synonyms"`
+}
+
+func (row *definitionRow) definition() (*models.SemanticDefinition, error) {
+	def := row.SemanticDefinition
+	if len(row.SynonymsJSON) > 0 {
+		if err := json.Unmarshal(row.SynonymsJSON, &def.Synonyms); err != nil {
+			return nil, err
+		}
+	}
+	return &def, nil
+}
+
+const definitionColumns = `id, connector_id, kind, name, description, table_name, expression, aggregation,
+		time_dimension, format, synonyms, owner, certified, version, updated_by, created_at, updated_at`
+
+// Create saves a new definition as version 1
+func (r *SemanticRepository) Create(ctx context.Context, def *models.SemanticDefinition) error {
+	def.ID = uuid.New().String()
+	def.Version = 1
+	def.CreatedAt = time.Now()
+	def.UpdatedAt = def.CreatedAt
+
+	synonyms, err := json.Marshal(nonNilStrings(def.Synonyms))
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO semantic_definitions (` + definitionColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+	`
+	_, err = tx.ExecContext(ctx, query,
+		def.ID, def.ConnectorID, def.Kind, def.Name, def.Description, def.Table, def.Expression, def.Aggregation,
+		def.TimeDimension, def.Format, synonyms, def.Owner, def.Certified, def.Version, def.UpdatedBy, def.CreatedAt, def.UpdatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicateDefinition
+		}
+		return err
+	}
+
+	if err := r.recordVersion(ctx, tx, def); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Update replaces a definition and increments its version. When expectedVersion is
+// not zero the update only applies if the stored version still matches it.
+func (r *SemanticRepository) Update(ctx context.Context, def *models.SemanticDefinition, expectedVersion int) error {
+	synonyms, err := json.Marshal(nonNilStrings(def.Synonyms))
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE semantic_definitions
+		SET name = $3, description = $4, table_name = $5, expression = $6, aggregation = $7,
+			time_dimension = $8, format = $9, synonyms = $10, owner = $11, certified = $12,
+			updated_by = $13, version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND connector_id = $2 AND ($14 = 0 OR version = $14)
+		RETURNING kind, version, created_at, updated_at
+	`
+	err = tx.QueryRowxContext(ctx, query,
+		def.ID, def.ConnectorID, def.Name, def.Description, def.Table, def.Expression, def.Aggregation,
+		def.TimeDimension, def.Format, synonyms, def.Owner, def.Certified, def.UpdatedBy, expectedVersion,
+	).Scan(&def.Kind, &def.Version, &def.CreatedAt, &def.UpdatedAt)
+	if err == sql.ErrNoRows {
+		if _, getErr := r.GetByID(ctx, def.ConnectorID, def.ID); getErr != nil {
+			return getErr
+		}
+		return ErrDefinitionVersionConflict
+	}
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicateDefinition
+		}
+		return err
+	}
+
+	if err := r.recordVersion(ctx, tx, def); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// recordVersion keeps a snapshot of a definition in its history
+func (r *SemanticRepository) recordVersion(ctx context.Context, tx *sqlx.Tx, def *models.SemanticDefinition) error {
+	snapshot, err := json.Marshal(def)
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO semantic_definition_versions (definition_id, version, definition, updated_by, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err = tx.ExecContext(ctx, query, def.ID, def.Version, snapshot, def.UpdatedBy, def.UpdatedAt)
+	return err
+}
+
+// GetByID retrieves a definition of a connector
+func (r *SemanticRepository) GetByID(ctx context.Context, connectorID, id string) (*models.SemanticDefinition, error) {
+	var row definitionRow
+	query := `SELECT ` + definitionColumns + ` FROM semantic_definitions WHERE id = $1 AND connector_id = $2`
+
+	if err := r.db.GetContext(ctx, &row, query, id, connectorID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrDefinitionNotFound
+		}
+		return nil, err
+	}
+	return row.definition()
+}
+
+// GetByName retrieves a definition by kind and case-insensitive name
+func (r *SemanticRepository) GetByName(ctx context.Context, connectorID string, kind models.DefinitionKind, name string) (*models.SemanticDefinition, error) {
+	var row definitionRow
+	query := `SELECT ` + definitionColumns + ` FROM semantic_definitions WHERE connector_id = $1 AND kind = $2 AND LOWER(name) = LOWER($3)`
+
+	if err := r.db.GetContext(ctx, &row, query, connectorID, kind, name); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrDefinitionNotFound
+		}
+		return nil, err
+	}
+	return row.definition()
+}
+
+// ListByConnector retrieves the definitions of a connector, certified ones first
+func (r *SemanticRepository) ListByConnector(ctx context.Context, connectorID string) ([]*models.SemanticDefinition, error) {
+	var rows []definitionRow
+	query := `
+		SELECT ` + definitionColumns + `
+		FROM semantic_definitions
+		WHERE connector_id = $1
+		ORDER BY certified DESC, kind, LOWER(name)
+	`
+
+	if err := r.db.SelectContext(ctx, &rows, query, connectorID); err != nil {
+		return nil, err
+	}
+
+	defs := make([]*models.SemanticDefinition, 0, len(rows))
+	for i := range rows {
+		def, err := rows[i].definition()
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, def)
+	}
+	return defs, nil
+}
+
+// GetVersions retrieves the history of a definition, newest first
+func (r *SemanticRepository) GetVersions(ctx context.Context, id string) ([]*models.SemanticDefinitionVersion, error) {
+	query := `
+		SELECT definition_id, version, definition, updated_by, created_at
+		FROM semantic_definition_versions
+		WHERE definition_id = $1
+		ORDER BY version DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []*models.SemanticDefinitionVersion
+	for rows.Next() {
+		var v models.SemanticDefinitionVersion
+		var snapshot []byte
+		if err := rows.Scan(&v.DefinitionID, &v.Version, &snapshot, &v.UpdatedBy, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(snapshot, &v.Definition); err != nil {
+			return nil, err
+		}
+		versions = append(versions, &v)
+	}
+	return versions, rows.Err()
+}
+
+// Delete removes a definition and its history
+func (r *SemanticRepository) Delete(ctx context.Context, connectorID, id string) error {
+	query := `DELETE FROM semantic_definitions WHERE id = $1 AND connector_id = $2`
+	result, err := r.db.ExecContext(ctx, query, id, connectorID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrDefinitionNotFound
+	}
+	return nil
+}
+
+// isUniqueViolation reports whether err is a PostgreSQL unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
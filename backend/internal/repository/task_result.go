@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"insightiq/backend/internal/agent"
+)
+
+// TaskResultRepository keeps agent task results in Postgres, so they survive
+// restarts and are visible to every replica
+type TaskResultRepository struct {
+	db *sqlx.DB
+}
+
+func NewTaskResultRepository(db *sqlx.DB) *TaskResultRepository {
+	return &TaskResultRepository{db: db}
+}
+
+// CreateTables creates the agent_task_results table if it doesn't exist
+func (r *TaskResultRepository) CreateTables(ctx context.Context) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS agent_task_results (
+		task_id VARCHAR(255) PRIMARY KEY,
+		result JSONB NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_agent_task_results_expires ON agent_task_results(expires_at);
+	`
+
+	_, err := r.db.ExecContext(ctx, query)
+	return err
+}
+
+// SaveResult keeps a result until ttl passed, replacing an earlier one of the task
+func (r *TaskResultRepository) SaveResult(ctx context.Context, result agent.TaskResult, ttl time.Duration) error {
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	query := `
+		INSERT INTO agent_task_results (task_id, result, created_at, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (task_id) DO UPDATE SET result = $2, created_at = $3, expires_at = $4
+	`
+	_, err = r.db.ExecContext(ctx, query, result.TaskID, resultJSON, now, now.Add(ttl))
+	return err
+}
+
+// LoadResult returns the result of a task unless it expired, or nil when there is none
+func (r *TaskResultRepository) LoadResult(ctx context.Context, taskID string) (*agent.TaskResult, error) {
+	query := `SELECT result FROM agent_task_results WHERE task_id = $1 AND expires_at > NOW()`
+
+	var resultJSON []byte
+	if err := r.db.QueryRowContext(ctx, query, taskID).Scan(&resultJSON); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var result agent.TaskResult
+	if err := json.Unmarshal(resultJSON, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteExpiredResults deletes the results that expired and returns how many
+func (r *TaskResultRepository) DeleteExpiredResults(ctx context.Context) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM agent_task_results WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
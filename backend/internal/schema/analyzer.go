@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"insightiq/backend/internal/connectors"
+	"insightiq/backend/internal/models"
 )
 
 // AnalyzerService handles business context analysis and domain generation
@@ -217,6 +218,8 @@ func (a *AnalyzerService) generateDomainContexts(ctx context.Context, schemaCont
 	var domainContexts []DomainContext
 	for _, context := range domainMap {
 		a.enrichDomainContext(context)
+		applyGlossary(context, schemaContext.Glossary)
+		applyDefinitions(context, schemaContext.Definitions)
 		domainContexts = append(domainContexts, *context)
 	}
 
@@ -281,6 +284,119 @@ func (a *AnalyzerService) enrichDomainContext(context *DomainContext) {
 	context.Confidence = a.calculateDomainConfidence(context)
 }
 
+// applyGlossary adds documented terms related to the domain's tables, replacing
+// predefined terms of the same name
+func applyGlossary(context *DomainContext, glossary []BusinessGlossary) {
+	tables := make(map[string]bool)
+	for _, table := range context.Tables {
+		tables[strings.ToLower(table.TableName)] = true
+	}
+
+	var terms []BusinessGlossary
+	var keywords []string
+	replaced := make(map[string]bool)
+	for _, term := range glossary {
+		related := false
+		for _, name := range term.RelatedTerms {
+			related = related || tables[strings.ToLower(name)]
+		}
+		if !related {
+			continue
+		}
+
+		term.Domain = context.Domain
+		terms = append(terms, term)
+		keywords = append(keywords, strings.ToLower(term.Term))
+		for _, synonym := range term.Synonyms {
+			keywords = append(keywords, strings.ToLower(synonym))
+		}
+		replaced[strings.ToLower(term.Term)] = true
+	}
+	if len(terms) == 0 {
+		return
+	}
+
+	for _, term := range context.Glossary {
+		if !replaced[strings.ToLower(term.Term)] {
+			terms = append(terms, term)
+		}
+	}
+	context.Keywords = mergeTerms(keywords, context.Keywords)
+	context.Glossary = terms
+}
+
+// applyDefinitions puts the user-maintained definitions of the domain's tables ahead
+// of the heuristics, certified ones first, so retrieval surfaces the agreed meaning
+// of a term. A definition replaces predefined glossary terms of the same name.
+func applyDefinitions(context *DomainContext, defs []models.SemanticDefinition) {
+	tables := make(map[string]bool)
+	for _, table := range context.Tables {
+		tables[strings.ToLower(table.TableName)] = true
+	}
+
+	var metrics, dimensions, keywords []string
+	var glossary []BusinessGlossary
+	replaced := make(map[string]bool)
+	for _, def := range defs {
+		if !tables[strings.ToLower(def.Table)] {
+			continue
+		}
+
+		switch def.Kind {
+		case models.DefinitionKindMetric:
+			metrics = append(metrics, def.Name)
+		case models.DefinitionKindDimension:
+			dimensions = append(dimensions, def.Name)
+		}
+		keywords = append(keywords, strings.ToLower(def.Name))
+		for _, synonym := range def.Synonyms {
+			keywords = append(keywords, strings.ToLower(synonym))
+		}
+
+		definition := def.Description
+		if definition == "" {
+			definition = def.Expression
+		}
+		if def.Certified {
+			definition += " (certified)"
+		}
+		glossary = append(glossary, BusinessGlossary{
+			Term:       def.Name,
+			Definition: definition,
+			Domain:     context.Domain,
+			Synonyms:   def.Synonyms,
+		})
+		replaced[strings.ToLower(def.Name)] = true
+	}
+	if len(glossary) == 0 {
+		return
+	}
+
+	for _, term := range context.Glossary {
+		if !replaced[strings.ToLower(term.Term)] {
+			glossary = append(glossary, term)
+		}
+	}
+
+	context.Metrics = mergeTerms(metrics, context.Metrics)
+	context.Dimensions = mergeTerms(dimensions, context.Dimensions)
+	context.Keywords = mergeTerms(keywords, context.Keywords)
+	context.Glossary = glossary
+}
+
+// mergeTerms appends the terms of rest missing from first
+func mergeTerms(first, rest []string) []string {
+	seen := make(map[string]bool)
+	var merged []string
+	for _, term := range append(first, rest...) {
+		if !seen[strings.ToLower(term)] {
+			seen[strings.ToLower(term)] = true
+			merged = append(merged, term)
+		}
+	}
+	return merged
+}
+
 // generateDomainDescription creates a business description for the domain
 func (a *AnalyzerService) generateDomainDescription(domain Domain, tables []TableContext) string {
 	tableCount := len(tables)
@@ -0,0 +1,931 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrInvalidDbtArtifact is returned when a dbt manifest or semantic manifest cannot be read
+var ErrInvalidDbtArtifact = errors.New("invalid dbt artifact")
+
+var (
+	// ref('orders') or ref('package', 'orders') inside test kwargs and legacy metrics
+	dbtRefPattern = regexp.MustCompile(`ref\(\s*['"]([^'"]+)['"](?:\s*,\s*['"]([^'"]+)['"])?`)
+	// {{ Dimension('order__status') }} and {{ Entity('customer') }} in MetricFlow filters
+	dbtFilterRefPattern = regexp.MustCompile(`\{\{\s*(Dimension|Entity|TimeDimension)\(\s*['"]([^'"]+)['"][^)]*\)\s*\}\}`)
+	// {{ metric('revenue') }} in legacy derived metrics
+	dbtMetricRefPattern     = regexp.MustCompile(`\{\{\s*metric\(\s*['"]([^'"]+)['"]\s*\)\s*\}\}`)
+	identifierPattern       = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+	simpleIdentifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// dbtManifest is the subset of manifest.json that describes models, sources, tests
+// and, since dbt 1.6, semantic models and metrics
+type dbtManifest struct {
+	Metadata struct {
+		ProjectName string `json:"project_name"`
+	} `json:"metadata"`
+	Nodes          map[string]dbtNode          `json:"nodes"`
+	Sources        map[string]dbtNode          `json:"sources"`
+	SemanticModels map[string]dbtSemanticModel `json:"semantic_models"`
+	Metrics        map[string]dbtMetric        `json:"metrics"`
+}
+
+type dbtNode struct {
+	UniqueID     string               `json:"unique_id"`
+	ResourceType string               `json:"resource_type"`
+	Name         string               `json:"name"`
+	Alias        string               `json:"alias"`
+	Identifier   string               `json:"identifier"`
+	Schema       string               `json:"schema"`
+	Description  string               `json:"description"`
+	Columns      map[string]dbtColumn `json:"columns"`
+	Tags         []string             `json:"tags"`
+	TestMetadata *dbtTestMetadata     `json:"test_metadata"`
+	ColumnName   string               `json:"column_name"`
+	AttachedNode string               `json:"attached_node"`
+	DependsOn    struct {
+		Nodes []string `json:"nodes"`
+	} `json:"depends_on"`
+}
+
+type dbtColumn struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	DataType    string `json:"data_type"`
+}
+
+type dbtTestMetadata struct {
+	Name      string                 `json:"name"`
+	Namespace string                 `json:"namespace"`
+	Kwargs    map[string]interface{} `json:"kwargs"`
+}
+
+// dbtSemanticManifest is the subset of semantic_manifest.json used for MetricFlow
+type dbtSemanticManifest struct {
+	SemanticModels []dbtSemanticModel `json:"semantic_models"`
+	Metrics        []dbtMetric        `json:"metrics"`
+}
+
+type dbtSemanticModel struct {
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	NodeRelation struct {
+		Alias      string `json:"alias"`
+		SchemaName string `json:"schema_name"`
+	} `json:"node_relation"`
+	Defaults *struct {
+		AggTimeDimension string `json:"agg_time_dimension"`
+	} `json:"defaults"`
+	Entities   []dbtSemanticElement `json:"entities"`
+	Measures   []dbtMeasure         `json:"measures"`
+	Dimensions []dbtSemanticElement `json:"dimensions"`
+}
+
+// dbtSemanticElement is an entity or dimension of a semantic model
+type dbtSemanticElement struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Expr        string `json:"expr"`
+	Description string `json:"description"`
+}
+
+type dbtMeasure struct {
+	Name             string `json:"name"`
+	Description      string `json:"description"`
+	Agg              string `json:"agg"`
+	Expr             string `json:"expr"`
+	AggTimeDimension string `json:"agg_time_dimension"`
+}
+
+type dbtMetric struct {
+	Name        string         `json:"name"`
+	Label       string         `json:"label"`
+	Description string         `json:"description"`
+	Type        string         `json:"type"`
+	Filter      dbtWhereFilter `json:"filter"`
+	TypeParams  struct {
+		Measure     *dbtMetricInput  `json:"measure"`
+		Numerator   *dbtMetricInput  `json:"numerator"`
+		Denominator *dbtMetricInput  `json:"denominator"`
+		Expr        string           `json:"expr"`
+		Metrics     []dbtMetricInput `json:"metrics"`
+	} `json:"type_params"`
+
+	// Metrics of dbt before 1.6
+	CalculationMethod string `json:"calculation_method"`
+	Expression        string `json:"expression"`
+	Model             string `json:"model"`
+	Timestamp         string `json:"timestamp"`
+	Filters           []struct {
+		Field    string `json:"field"`
+		Operator string `json:"operator"`
+		Value    string `json:"value"`
+	} `json:"filters"`
+}
+
+type dbtMetricInput struct {
+	Name          string         `json:"name"`
+	Alias         string         `json:"alias"`
+	Filter        dbtWhereFilter `json:"filter"`
+	OffsetWindow  interface{}    `json:"offset_window"`
+	OffsetToGrain interface{}    `json:"offset_to_grain"`
+}
+
+// dbtWhereFilter holds the where templates of a metric filter, which are written
+// either as a list of filters or as a single template
+type dbtWhereFilter struct {
+	Templates []string
+}
+
+func (f *dbtWhereFilter) UnmarshalJSON(data []byte) error {
+	var template string
+	if err := json.Unmarshal(data, &template); err == nil {
+		if template != "" {
+			f.Templates = []string{template}
+		}
+		return nil
+	}
+
+	var filter struct {
+		WhereFilters []struct {
+			WhereSQLTemplate string `json:"where_sql_template"`
+		} `json:"where_filters"`
+	}
+	if err := json.Unmarshal(data, &filter); err != nil {
+		return err
+	}
+	for _, where := range filter.WhereFilters {
+		f.Templates = append(f.Templates, where.WhereSQLTemplate)
+	}
+	return nil
+}
+
+// ParseDbtArtifacts maps a dbt manifest.json and, when given, a MetricFlow
+// semantic_manifest.json to documented tables, relationships, metrics and glossary
+// terms. Without a semantic manifest, semantic models and metrics are read from the
+// manifest. Parts that cannot be expressed as SQL are skipped with a warning.
+func ParseDbtArtifacts(manifestData, semanticManifestData []byte) (*DocumentedSchema, error) {
+	var manifest dbtManifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, fmt.Errorf("%w: manifest.json: %v", ErrInvalidDbtArtifact, err)
+	}
+	if len(manifest.Nodes) == 0 && len(manifest.Sources) == 0 {
+		return nil, fmt.Errorf("%w: manifest.json has no nodes or sources", ErrInvalidDbtArtifact)
+	}
+
+	semanticModels := sortedValues(manifest.SemanticModels)
+	metrics := sortedValues(manifest.Metrics)
+	if len(semanticManifestData) > 0 {
+		var semanticManifest dbtSemanticManifest
+		if err := json.Unmarshal(semanticManifestData, &semanticManifest); err != nil {
+			return nil, fmt.Errorf("%w: semantic_manifest.json: %v", ErrInvalidDbtArtifact, err)
+		}
+		semanticModels = semanticManifest.SemanticModels
+		metrics = semanticManifest.Metrics
+	}
+
+	p := &dbtParser{
+		doc: &DocumentedSchema{
+			Source:     "dbt",
+			Project:    manifest.Metadata.ProjectName,
+			ImportedAt: time.Now(),
+		},
+		tables:       make(map[string]*TableContext),
+		tablesByName: make(map[string]*TableContext),
+		measures:     make(map[string]dbtMeasureRef),
+		metrics:      make(map[string]*dbtMetric),
+		resolved:     make(map[string]*BusinessMetric),
+		resolving:    make(map[string]bool),
+	}
+
+	p.addTables(manifest.Nodes, manifest.Sources)
+	p.applyTests(manifest.Nodes)
+	p.addSemanticModels(semanticModels)
+	p.addMetrics(metrics)
+
+	for _, table := range p.tableOrder {
+		p.doc.Tables = append(p.doc.Tables, *table)
+	}
+	return p.doc, nil
+}
+
+// dbtEntityKey is the column identifying an entity in a table
+type dbtEntityKey struct {
+	table  *TableContext
+	column string
+}
+
+// dbtMeasureRef is a measure with the semantic model it belongs to
+type dbtMeasureRef struct {
+	model   *dbtSemanticModel
+	measure dbtMeasure
+}
+
+type dbtParser struct {
+	doc *DocumentedSchema
+
+	tables       map[string]*TableContext // by unique ID
+	tablesByName map[string]*TableContext // by lower case node name and relation name
+	tableOrder   []*TableContext
+
+	measures  map[string]dbtMeasureRef
+	metrics   map[string]*dbtMetric
+	resolved  map[string]*BusinessMetric
+	resolving map[string]bool
+}
+
+func (p *dbtParser) warn(format string, args ...interface{}) {
+	p.doc.Warnings = append(p.doc.Warnings, fmt.Sprintf(format, args...))
+}
+
+// addTables documents the models, seeds, snapshots and sources of the project
+func (p *dbtParser) addTables(nodes, sources map[string]dbtNode) {
+	var ids []string
+	all := make(map[string]dbtNode)
+	for id, node := range nodes {
+		switch node.ResourceType {
+		case "model", "seed", "snapshot":
+			ids = append(ids, id)
+			all[id] = node
+		}
+	}
+	for id, node := range sources {
+		ids = append(ids, id)
+		all[id] = node
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		node := all[id]
+		table := &TableContext{
+			TableName:    firstNonEmpty(node.Alias, node.Identifier, node.Name),
+			Schema:       node.Schema,
+			Description:  strings.TrimSpace(node.Description),
+			BusinessTags: node.Tags,
+		}
+
+		var columnNames []string
+		for name := range node.Columns {
+			columnNames = append(columnNames, name)
+		}
+		sort.Strings(columnNames)
+		for _, name := range columnNames {
+			col := node.Columns[name]
+			table.Columns = append(table.Columns, ColumnInfo{
+				Name:        firstNonEmpty(col.Name, name),
+				Type:        col.DataType,
+				DataType:    strings.ToLower(col.DataType),
+				Description: strings.TrimSpace(col.Description),
+				IsDatetime:  col.DataType != "" && isDatetimeType(strings.ToLower(col.DataType)),
+				Nullable:    true,
+			})
+		}
+
+		p.tables[id] = table
+		p.tableOrder = append(p.tableOrder, table)
+		for _, name := range []string{node.Name, table.TableName} {
+			if _, exists := p.tablesByName[strings.ToLower(name)]; !exists {
+				p.tablesByName[strings.ToLower(name)] = table
+			}
+		}
+	}
+}
+
+// applyTests records unique, not_null, accepted_values and relationships tests
+func (p *dbtParser) applyTests(nodes map[string]dbtNode) {
+	var ids []string
+	for id, node := range nodes {
+		if node.ResourceType == "test" && node.TestMetadata != nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		node := nodes[id]
+		test := node.TestMetadata
+		if test.Namespace != "" && test.Namespace != "dbt" {
+			continue
+		}
+
+		table := p.testedTable(node)
+		columnName := firstNonEmpty(node.ColumnName, kwargString(test.Kwargs, "column_name"))
+		if table == nil || columnName == "" {
+			continue
+		}
+		col := ensureColumn(table, columnName)
+
+		switch test.Name {
+		case "unique":
+			col.Unique = true
+		case "not_null":
+			col.Nullable = false
+		case "accepted_values":
+			if values, ok := test.Kwargs["values"].([]interface{}); ok {
+				col.IsDimension = true
+				for _, value := range values {
+					col.SampleValues = append(col.SampleValues, fmt.Sprint(value))
+				}
+				// Accepted values are the complete set
+				col.Values = col.SampleValues
+			}
+		case "relationships":
+			target := p.relationshipTarget(node)
+			field := kwargString(test.Kwargs, "field")
+			if target == nil || field == "" {
+				p.warn("relationships test %s: target model not found", node.Name)
+				continue
+			}
+			col.IsID = true
+			p.addRelationship(TableRelationship{
+				FromTable:    table.TableName,
+				ToTable:      target.TableName,
+				FromColumn:   col.Name,
+				ToColumn:     field,
+				RelationType: "many_to_one",
+				Confidence:   1,
+			})
+		}
+	}
+}
+
+// testedTable returns the table a test is attached to
+func (p *dbtParser) testedTable(node dbtNode) *TableContext {
+	if table, ok := p.tables[node.AttachedNode]; ok {
+		return table
+	}
+	if name := refName(kwargString(node.TestMetadata.Kwargs, "model")); name != "" {
+		return p.tablesByName[strings.ToLower(name)]
+	}
+	if len(node.DependsOn.Nodes) == 1 {
+		return p.tables[node.DependsOn.Nodes[0]]
+	}
+	return nil
+}
+
+// relationshipTarget returns the table a relationships test points to
+func (p *dbtParser) relationshipTarget(node dbtNode) *TableContext {
+	if name := refName(kwargString(node.TestMetadata.Kwargs, "to")); name != "" {
+		if table, ok := p.tablesByName[strings.ToLower(name)]; ok {
+			return table
+		}
+	}
+	for _, id := range node.DependsOn.Nodes {
+		if id != node.AttachedNode {
+			if table, ok := p.tables[id]; ok {
+				return table
+			}
+		}
+	}
+	return nil
+}
+
+func (p *dbtParser) addRelationship(rel TableRelationship) {
+	for _, existing := range p.doc.Relationships {
+		if strings.EqualFold(existing.FromTable, rel.FromTable) && strings.EqualFold(existing.FromColumn, rel.FromColumn) &&
+			strings.EqualFold(existing.ToTable, rel.ToTable) && strings.EqualFold(existing.ToColumn, rel.ToColumn) {
+			return
+		}
+	}
+	p.doc.Relationships = append(p.doc.Relationships, rel)
+	if table, ok := p.tablesByName[strings.ToLower(rel.FromTable)]; ok {
+		table.Relationships = append(table.Relationships, rel)
+	}
+}
+
+// semanticTable returns the documented table of a semantic model, adding one when
+// the model is not in the manifest
+func (p *dbtParser) semanticTable(model *dbtSemanticModel) *TableContext {
+	name := firstNonEmpty(model.NodeRelation.Alias, model.Name)
+	if table, ok := p.tablesByName[strings.ToLower(name)]; ok {
+		return table
+	}
+	table := &TableContext{TableName: name, Schema: model.NodeRelation.SchemaName, Description: model.Description}
+	p.tableOrder = append(p.tableOrder, table)
+	p.tablesByName[strings.ToLower(name)] = table
+	return table
+}
+
+// addSemanticModels marks the dimensions and keys of semantic models on their
+// tables and joins foreign entities to the models where they are primary
+func (p *dbtParser) addSemanticModels(models []dbtSemanticModel) {
+	primaryEntities := make(map[string]dbtEntityKey)
+
+	for i := range models {
+		model := &models[i]
+		table := p.semanticTable(model)
+		if table.Description == "" {
+			table.Description = model.Description
+		}
+
+		for _, dim := range model.Dimensions {
+			column := firstNonEmpty(dim.Expr, dim.Name)
+			if !simpleIdentifierPattern.MatchString(column) {
+				continue
+			}
+			col := ensureColumn(table, column)
+			if strings.EqualFold(dim.Type, "time") {
+				col.IsDatetime = true
+			} else {
+				col.IsDimension = true
+			}
+			if col.Description == "" {
+				col.Description = dim.Description
+			}
+		}
+
+		for _, entity := range model.Entities {
+			column := firstNonEmpty(entity.Expr, entity.Name)
+			if !simpleIdentifierPattern.MatchString(column) {
+				continue
+			}
+			col := ensureColumn(table, column)
+			col.IsID = true
+			switch entity.Type {
+			case "primary", "unique", "natural":
+				col.Unique = entity.Type != "natural"
+				primaryEntities[entity.Name] = dbtEntityKey{table: table, column: col.Name}
+			}
+		}
+
+		for _, measure := range model.Measures {
+			if _, exists := p.measures[measure.Name]; exists {
+				p.warn("measure %s is defined more than once", measure.Name)
+				continue
+			}
+			p.measures[measure.Name] = dbtMeasureRef{model: model, measure: measure}
+		}
+	}
+
+	for i := range models {
+		model := &models[i]
+		table := p.semanticTable(model)
+		for _, entity := range model.Entities {
+			if entity.Type != "foreign" {
+				continue
+			}
+			target, ok := primaryEntities[entity.Name]
+			if !ok || target.table == table {
+				continue
+			}
+			p.addRelationship(TableRelationship{
+				FromTable:    table.TableName,
+				ToTable:      target.table.TableName,
+				FromColumn:   firstNonEmpty(entity.Expr, entity.Name),
+				ToColumn:     target.column,
+				RelationType: "many_to_one",
+				Confidence:   1,
+			})
+		}
+	}
+}
+
+// addMetrics converts metrics and the measures not exposed as a metric of the same
+// name into business metrics. Every metric also becomes a glossary term, even when
+// it cannot be expressed as SQL.
+func (p *dbtParser) addMetrics(metrics []dbtMetric) {
+	for i := range metrics {
+		p.metrics[metrics[i].Name] = &metrics[i]
+	}
+
+	defined := make(map[string]bool)
+	for i := range metrics {
+		metric := &metrics[i]
+		resolved, err := p.resolveMetric(metric.Name)
+		if err != nil {
+			p.warn("metric %s: %v", metric.Name, err)
+		} else {
+			p.doc.Metrics = append(p.doc.Metrics, *resolved)
+			defined[strings.ToLower(metric.Name)] = true
+		}
+		p.addMetricGlossary(metric, resolved)
+	}
+
+	var measureNames []string
+	for name := range p.measures {
+		measureNames = append(measureNames, name)
+	}
+	sort.Strings(measureNames)
+	for _, name := range measureNames {
+		if defined[strings.ToLower(name)] {
+			continue
+		}
+		ref := p.measures[name]
+		metric, err := p.measureMetric(ref, nil)
+		if err != nil {
+			p.warn("measure %s: %v", name, err)
+			continue
+		}
+		metric.Name = name
+		metric.Description = firstNonEmpty(ref.measure.Description, metric.Description)
+		metric.Keywords = dbtKeywords(name, "")
+		p.doc.Metrics = append(p.doc.Metrics, *metric)
+	}
+}
+
+func (p *dbtParser) addMetricGlossary(metric *dbtMetric, resolved *BusinessMetric) {
+	term := BusinessGlossary{
+		Term:       firstNonEmpty(metric.Label, metric.Name),
+		Definition: strings.TrimSpace(metric.Description),
+	}
+	if !strings.EqualFold(term.Term, metric.Name) {
+		term.Synonyms = []string{metric.Name}
+	}
+	if resolved != nil {
+		if term.Definition == "" {
+			term.Definition = fmt.Sprintf("%s on %s", resolved.Expression, resolved.Table)
+		}
+		term.RelatedTerms = []string{resolved.Table}
+	} else if ref, ok := p.metricMeasure(metric); ok {
+		term.RelatedTerms = []string{p.semanticTable(ref.model).TableName}
+	}
+	if term.Definition == "" {
+		term.Definition = fmt.Sprintf("%s metric defined in dbt", firstNonEmpty(metric.Type, metric.CalculationMethod))
+	}
+	p.doc.Glossary = append(p.doc.Glossary, term)
+}
+
+// metricMeasure returns the measure a simple, cumulative or conversion metric is built on
+func (p *dbtParser) metricMeasure(metric *dbtMetric) (dbtMeasureRef, bool) {
+	if metric.TypeParams.Measure == nil {
+		return dbtMeasureRef{}, false
+	}
+	ref, ok := p.measures[metric.TypeParams.Measure.Name]
+	return ref, ok
+}
+
+// resolveMetric builds the SQL of a metric, resolving the metrics it refers to
+func (p *dbtParser) resolveMetric(name string) (*BusinessMetric, error) {
+	if resolved, ok := p.resolved[name]; ok {
+		return resolved, nil
+	}
+	metric, ok := p.metrics[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown metric %s", name)
+	}
+	if p.resolving[name] {
+		return nil, fmt.Errorf("metric %s refers to itself", name)
+	}
+	p.resolving[name] = true
+	defer delete(p.resolving, name)
+
+	var resolved *BusinessMetric
+	var err error
+	switch {
+	case metric.CalculationMethod != "":
+		resolved, err = p.legacyMetric(metric)
+	case metric.Type == "simple":
+		resolved, err = p.simpleMetric(metric)
+	case metric.Type == "ratio":
+		resolved, err = p.ratioMetric(metric)
+	case metric.Type == "derived":
+		resolved, err = p.derivedMetric(metric)
+	default:
+		err = fmt.Errorf("%s metrics cannot be expressed as a single aggregate", firstNonEmpty(metric.Type, "untyped"))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resolved.Name = metric.Name
+	resolved.Description = firstNonEmpty(strings.TrimSpace(metric.Description), metric.Label, resolved.Description)
+	resolved.Keywords = dbtKeywords(metric.Name, metric.Label)
+	p.resolved[name] = resolved
+	return resolved, nil
+}
+
+func (p *dbtParser) simpleMetric(metric *dbtMetric) (*BusinessMetric, error) {
+	input := metric.TypeParams.Measure
+	if input == nil {
+		return nil, fmt.Errorf("simple metric without a measure")
+	}
+	ref, ok := p.measures[input.Name]
+	if !ok {
+		return nil, fmt.Errorf("unknown measure %s", input.Name)
+	}
+	filters := append(append([]string{}, metric.Filter.Templates...), input.Filter.Templates...)
+	return p.measureMetric(ref, filters)
+}
+
+// measureMetric aggregates a measure, applying the where templates of a metric
+func (p *dbtParser) measureMetric(ref dbtMeasureRef, filters []string) (*BusinessMetric, error) {
+	var conditions []string
+	for _, template := range filters {
+		condition, err := p.renderFilter(ref.model, template)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	expr := firstNonEmpty(ref.measure.Expr, ref.measure.Name)
+	sql, metricType, err := dbtAggregate(ref.measure.Agg, expr, strings.Join(conditions, " AND "))
+	if err != nil {
+		return nil, err
+	}
+
+	table := p.semanticTable(ref.model)
+	metric := &BusinessMetric{
+		Description:   fmt.Sprintf("%s of %s", ref.measure.Agg, expr),
+		Type:          metricType,
+		Table:         table.TableName,
+		Dimensions:    semanticDimensions(ref.model),
+		Expression:    sql,
+		TimeDimension: ref.measure.AggTimeDimension,
+	}
+	if metric.TimeDimension == "" && ref.model.Defaults != nil {
+		metric.TimeDimension = ref.model.Defaults.AggTimeDimension
+	}
+	if metric.TimeDimension != "" {
+		metric.TimeDimension = semanticColumn(ref.model, metric.TimeDimension)
+	}
+	if simpleIdentifierPattern.MatchString(expr) && len(conditions) == 0 {
+		metric.Column = expr
+	}
+	return metric, nil
+}
+
+func (p *dbtParser) ratioMetric(metric *dbtMetric) (*BusinessMetric, error) {
+	numerator, denominator := metric.TypeParams.Numerator, metric.TypeParams.Denominator
+	if numerator == nil || denominator == nil {
+		return nil, fmt.Errorf("ratio metric without numerator and denominator")
+	}
+
+	inputs, err := p.inputMetrics([]dbtMetricInput{*numerator, *denominator})
+	if err != nil {
+		return nil, err
+	}
+	if len(metric.Filter.Templates) > 0 {
+		return nil, fmt.Errorf("filters on ratio metrics are not supported")
+	}
+
+	ratio := *inputs[0]
+	ratio.Type = "ratio"
+	ratio.Column = ""
+	ratio.Expression = fmt.Sprintf("%s * 1.0 / NULLIF(%s, 0)", inputs[0].Expression, inputs[1].Expression)
+	return &ratio, nil
+}
+
+func (p *dbtParser) derivedMetric(metric *dbtMetric) (*BusinessMetric, error) {
+	if metric.TypeParams.Expr == "" || len(metric.TypeParams.Metrics) == 0 {
+		return nil, fmt.Errorf("derived metric without an expression")
+	}
+	if len(metric.Filter.Templates) > 0 {
+		return nil, fmt.Errorf("filters on derived metrics are not supported")
+	}
+
+	inputs, err := p.inputMetrics(metric.TypeParams.Metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	replacements := make(map[string]string)
+	for i, input := range metric.TypeParams.Metrics {
+		replacements[firstNonEmpty(input.Alias, input.Name)] = inputs[i].Expression
+	}
+
+	derived := *inputs[0]
+	derived.Type = "derived"
+	derived.Column = ""
+	derived.Expression = substituteIdentifiers(metric.TypeParams.Expr, replacements)
+	return &derived, nil
+}
+
+// inputMetrics resolves the metrics another metric is computed from, which must
+// all aggregate the same table
+func (p *dbtParser) inputMetrics(inputs []dbtMetricInput) ([]*BusinessMetric, error) {
+	var resolved []*BusinessMetric
+	for _, input := range inputs {
+		if input.OffsetWindow != nil || input.OffsetToGrain != nil {
+			return nil, fmt.Errorf("offset metric inputs are not supported")
+		}
+		if len(input.Filter.Templates) > 0 {
+			return nil, fmt.Errorf("filters on metric inputs are not supported")
+		}
+		metric, err := p.resolveMetric(input.Name)
+		if err != nil {
+			return nil, err
+		}
+		if len(resolved) > 0 && !strings.EqualFold(metric.Table, resolved[0].Table) {
+			return nil, fmt.Errorf("metrics %s and %s aggregate different tables", resolved[0].Name, metric.Name)
+		}
+		resolved = append(resolved, metric)
+	}
+	return resolved, nil
+}
+
+// legacyMetric converts a metric of dbt before 1.6, defined by a calculation
+// method over a model
+func (p *dbtParser) legacyMetric(metric *dbtMetric) (*BusinessMetric, error) {
+	if metric.CalculationMethod == "derived" {
+		expr := metric.Expression
+		var table string
+		var err error
+		expr = dbtMetricRefPattern.ReplaceAllStringFunc(expr, func(match string) string {
+			name := dbtMetricRefPattern.FindStringSubmatch(match)[1]
+			input, resolveErr := p.resolveMetric(name)
+			if resolveErr != nil {
+				err = resolveErr
+				return match
+			}
+			if table != "" && !strings.EqualFold(table, input.Table) {
+				err = fmt.Errorf("derived metric combines metrics of different tables")
+			}
+			table = input.Table
+			return "(" + input.Expression + ")"
+		})
+		if err != nil {
+			return nil, err
+		}
+		if table == "" {
+			return nil, fmt.Errorf("derived metric refers to no metrics")
+		}
+		return &BusinessMetric{Type: "derived", Table: table, Expression: expr}, nil
+	}
+
+	table, ok := p.tablesByName[strings.ToLower(refName(metric.Model))]
+	if !ok {
+		return nil, fmt.Errorf("model %s not found", metric.Model)
+	}
+
+	var conditions []string
+	for _, filter := range metric.Filters {
+		conditions = append(conditions, fmt.Sprintf("%s %s %s", filter.Field, filter.Operator, filter.Value))
+	}
+	sql, metricType, err := dbtAggregate(metric.CalculationMethod, metric.Expression, strings.Join(conditions, " AND "))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BusinessMetric{
+		Type:          metricType,
+		Table:         table.TableName,
+		Expression:    sql,
+		TimeDimension: metric.Timestamp,
+	}
+	if simpleIdentifierPattern.MatchString(metric.Expression) && len(conditions) == 0 {
+		result.Column = metric.Expression
+	}
+	return result, nil
+}
+
+// renderFilter turns a MetricFlow where template into SQL over the measure's table.
+// Only dimensions and entities of the measure's own semantic model can be rendered.
+func (p *dbtParser) renderFilter(model *dbtSemanticModel, template string) (string, error) {
+	var err error
+	sql := dbtFilterRefPattern.ReplaceAllStringFunc(template, func(match string) string {
+		parts := dbtFilterRefPattern.FindStringSubmatch(match)
+		kind, path := parts[1], parts[2]
+		if kind == "TimeDimension" {
+			err = fmt.Errorf("time dimension filters are not supported")
+			return match
+		}
+
+		name := path
+		if i := strings.LastIndex(path, "__"); i >= 0 {
+			name = path[i+2:]
+		}
+		column := semanticColumn(model, name)
+		if column == "" {
+			err = fmt.Errorf("filter refers to %s, which is not part of semantic model %s", path, model.Name)
+			return match
+		}
+		return column
+	})
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(sql, "{{") || strings.Contains(sql, "{%") {
+		return "", fmt.Errorf("unsupported filter template %q", template)
+	}
+	return sql, nil
+}
+
+// dbtAggregate returns the aggregate SQL and business metric type of a dbt
+// aggregation of expr, counting only rows matching condition when it is not empty
+func dbtAggregate(agg, expr, condition string) (string, string, error) {
+	agg = strings.ToLower(agg)
+	if expr == "" {
+		return "", "", fmt.Errorf("%s without an expression", agg)
+	}
+
+	value := expr
+	if agg == "sum_boolean" {
+		value = fmt.Sprintf("CASE WHEN %s THEN 1 ELSE 0 END", expr)
+	}
+	if condition != "" {
+		value = fmt.Sprintf("CASE WHEN %s THEN %s END", condition, value)
+	}
+
+	switch agg {
+	case "sum", "sum_boolean":
+		return fmt.Sprintf("SUM(%s)", value), "sum", nil
+	case "count":
+		return fmt.Sprintf("COUNT(%s)", value), "count", nil
+	case "count_distinct":
+		return fmt.Sprintf("COUNT(DISTINCT %s)", value), "count", nil
+	case "average", "avg":
+		return fmt.Sprintf("AVG(%s)", value), "avg", nil
+	case "min":
+		return fmt.Sprintf("MIN(%s)", value), "min", nil
+	case "max":
+		return fmt.Sprintf("MAX(%s)", value), "max", nil
+	default:
+		return "", "", fmt.Errorf("aggregation %s is not supported", agg)
+	}
+}
+
+// semanticColumn returns the SQL of a dimension or entity of a semantic model
+func semanticColumn(model *dbtSemanticModel, name string) string {
+	for _, elements := range [][]dbtSemanticElement{model.Dimensions, model.Entities} {
+		for _, element := range elements {
+			if strings.EqualFold(element.Name, name) {
+				return firstNonEmpty(element.Expr, element.Name)
+			}
+		}
+	}
+	return ""
+}
+
+// semanticDimensions lists the categorical dimension columns of a semantic model
+func semanticDimensions(model *dbtSemanticModel) []string {
+	var dimensions []string
+	for _, dim := range model.Dimensions {
+		column := firstNonEmpty(dim.Expr, dim.Name)
+		if !strings.EqualFold(dim.Type, "time") && simpleIdentifierPattern.MatchString(column) {
+			dimensions = append(dimensions, column)
+		}
+	}
+	return dimensions
+}
+
+// substituteIdentifiers replaces whole identifiers in expr with parenthesized SQL
+func substituteIdentifiers(expr string, replacements map[string]string) string {
+	return identifierPattern.ReplaceAllStringFunc(expr, func(name string) string {
+		if sql, ok := replacements[name]; ok {
+			return "(" + sql + ")"
+		}
+		return name
+	})
+}
+
+// ensureColumn returns the documented column of a table, adding it when the
+// project does not describe it
+func ensureColumn(table *TableContext, name string) *ColumnInfo {
+	for i := range table.Columns {
+		if strings.EqualFold(table.Columns[i].Name, name) {
+			return &table.Columns[i]
+		}
+	}
+	table.Columns = append(table.Columns, ColumnInfo{Name: name, Nullable: true})
+	return &table.Columns[len(table.Columns)-1]
+}
+
+// refName returns the model name of ref('name') or ref('package', 'name')
+func refName(value string) string {
+	match := dbtRefPattern.FindStringSubmatch(value)
+	if match == nil {
+		return ""
+	}
+	if match[2] != "" {
+		return match[2]
+	}
+	return match[1]
+}
+
+func kwargString(kwargs map[string]interface{}, key string) string {
+	value, _ := kwargs[key].(string)
+	return value
+}
+
+func dbtKeywords(name, label string) []string {
+	keywords := []string{strings.ToLower(name)}
+	if spaced := strings.ReplaceAll(strings.ToLower(name), "_", " "); spaced != keywords[0] {
+		keywords = append(keywords, spaced)
+	}
+	if label != "" && !strings.EqualFold(label, name) {
+		keywords = append(keywords, strings.ToLower(label))
+	}
+	return keywords
+}
+
+// sortedValues returns the values of a map keyed by unique ID in ID order
+func sortedValues[T any](values map[string]T) []T {
+	ids := make([]string, 0, len(values))
+	for id := range values {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	result := make([]T, 0, len(ids))
+	for _, id := range ids {
+		result = append(result, values[id])
+	}
+	return result
+}
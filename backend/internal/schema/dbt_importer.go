@@ -0,0 +1,193 @@
+package schema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+const (
+	dbtManifestFile         = "manifest.json"
+	dbtSemanticManifestFile = "semantic_manifest.json"
+)
+
+// ErrNoDbtArtifacts is returned when a connector has no stored dbt manifest
+var ErrNoDbtArtifacts = errors.New("no dbt artifacts for connector")
+
+// SECURITY: connector IDs name directories, so they are restricted to a safe character set
+var safeConnectorIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// DbtImporter keeps the dbt artifacts of connectors on local disk, one directory
+// per connector, and documents scans of the connector with them. Artifacts can be
+// uploaded or written into the directory by a dbt job; scans pick up whatever is
+// there.
+type DbtImporter struct {
+	rootDir string
+	logger  *slog.Logger
+
+	mu    sync.Mutex
+	cache map[string]cachedDocumentation
+}
+
+// cachedDocumentation is a parsed manifest with the modification times it was read at
+type cachedDocumentation struct {
+	manifestMod time.Time
+	semanticMod time.Time
+	doc         *DocumentedSchema
+}
+
+// NewDbtImporter creates an importer storing artifacts under rootDir
+func NewDbtImporter(rootDir string, logger *slog.Logger) *DbtImporter {
+	return &DbtImporter{
+		rootDir: rootDir,
+		logger:  logger,
+		cache:   make(map[string]cachedDocumentation),
+	}
+}
+
+// Store validates and saves the artifacts of a connector, replacing earlier ones.
+// Without a semantic manifest a previously stored one is removed, so semantic
+// models are read from the new manifest.
+func (i *DbtImporter) Store(connectorID string, manifest, semanticManifest []byte) (*DocumentedSchema, error) {
+	doc, err := ParseDbtArtifacts(manifest, semanticManifest)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := i.connectorDir(connectorID)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create dbt artifact directory: %w", err)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if err := writeFileAtomic(dir, dbtManifestFile, manifest); err != nil {
+		return nil, err
+	}
+	semanticPath := filepath.Join(dir, dbtSemanticManifestFile)
+	if len(semanticManifest) > 0 {
+		if err := writeFileAtomic(dir, dbtSemanticManifestFile, semanticManifest); err != nil {
+			return nil, err
+		}
+	} else if err := os.Remove(semanticPath); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove semantic manifest: %w", err)
+	}
+	delete(i.cache, connectorID)
+
+	i.logger.Info("Stored dbt artifacts",
+		"connector_id", connectorID,
+		"project", doc.Project,
+		"tables", len(doc.Tables),
+		"relationships", len(doc.Relationships),
+		"metrics", len(doc.Metrics),
+		"warnings", len(doc.Warnings))
+
+	return doc, nil
+}
+
+// ConnectorDocumentation returns the business context of the connector's stored
+// artifacts, or nil when there are none
+func (i *DbtImporter) ConnectorDocumentation(ctx context.Context, connectorID string) (*DocumentedSchema, error) {
+	dir, err := i.connectorDir(connectorID)
+	if err != nil {
+		return nil, err
+	}
+
+	manifestPath := filepath.Join(dir, dbtManifestFile)
+	manifestInfo, err := os.Stat(manifestPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dbt manifest: %w", err)
+	}
+
+	semanticPath := filepath.Join(dir, dbtSemanticManifestFile)
+	var semanticMod time.Time
+	if info, err := os.Stat(semanticPath); err == nil {
+		semanticMod = info.ModTime()
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if cached, ok := i.cache[connectorID]; ok &&
+		cached.manifestMod.Equal(manifestInfo.ModTime()) && cached.semanticMod.Equal(semanticMod) {
+		return cached.doc, nil
+	}
+
+	manifest, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dbt manifest: %w", err)
+	}
+	var semanticManifest []byte
+	if !semanticMod.IsZero() {
+		if semanticManifest, err = os.ReadFile(semanticPath); err != nil {
+			return nil, fmt.Errorf("failed to read semantic manifest: %w", err)
+		}
+	}
+
+	doc, err := ParseDbtArtifacts(manifest, semanticManifest)
+	if err != nil {
+		return nil, err
+	}
+	i.cache[connectorID] = cachedDocumentation{
+		manifestMod: manifestInfo.ModTime(),
+		semanticMod: semanticMod,
+		doc:         doc,
+	}
+	return doc, nil
+}
+
+// Delete removes the stored artifacts of a connector
+func (i *DbtImporter) Delete(connectorID string) error {
+	dir, err := i.connectorDir(connectorID)
+	if err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	delete(i.cache, connectorID)
+	if _, err := os.Stat(filepath.Join(dir, dbtManifestFile)); os.IsNotExist(err) {
+		return ErrNoDbtArtifacts
+	}
+	return os.RemoveAll(dir)
+}
+
+func (i *DbtImporter) connectorDir(connectorID string) (string, error) {
+	if !safeConnectorIDPattern.MatchString(connectorID) {
+		return "", fmt.Errorf("invalid connector id")
+	}
+	return filepath.Join(i.rootDir, connectorID), nil
+}
+
+// writeFileAtomic replaces a file so readers never see a partial write
+func writeFileAtomic(dir, name string, data []byte) error {
+	tmp, err := os.CreateTemp(dir, ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	_, err = tmp.Write(data)
+	closeErr := tmp.Close()
+	if err != nil || closeErr != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write %s: %w", name, errors.Join(err, closeErr))
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, name)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to store %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package schema
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testManifest = `{
+  "metadata": {"project_name": "shop"},
+  "nodes": {
+    "model.shop.orders": {
+      "unique_id": "model.shop.orders", "resource_type": "model", "name": "orders", "alias": "orders",
+      "schema": "analytics", "description": "One row per order",
+      "columns": {
+        "order_id": {"name": "order_id", "description": "Primary key"},
+        "customer_id": {"name": "customer_id", "description": "Who placed the order"},
+        "status": {"name": "status", "description": "Order status"}
+      }
+    },
+    "model.shop.customers": {
+      "unique_id": "model.shop.customers", "resource_type": "model", "name": "customers", "alias": "dim_customers",
+      "schema": "analytics", "description": "One row per customer", "columns": {}
+    },
+    "test.shop.unique_orders_order_id": {
+      "resource_type": "test", "name": "unique_orders_order_id", "column_name": "order_id",
+      "attached_node": "model.shop.orders",
+      "test_metadata": {"name": "unique", "kwargs": {"column_name": "order_id"}},
+      "depends_on": {"nodes": ["model.shop.orders"]}
+    },
+    "test.shop.not_null_orders_order_id": {
+      "resource_type": "test", "name": "not_null_orders_order_id", "column_name": "order_id",
+      "attached_node": "model.shop.orders",
+      "test_metadata": {"name": "not_null", "kwargs": {"column_name": "order_id"}},
+      "depends_on": {"nodes": ["model.shop.orders"]}
+    },
+    "test.shop.accepted_values_orders_status": {
+      "resource_type": "test", "name": "accepted_values_orders_status",
+      "test_metadata": {"name": "accepted_values", "kwargs": {
+        "column_name": "status", "values": ["placed", "shipped"],
+        "model": "{{ get_where_subquery(ref('orders')) }}"}},
+      "depends_on": {"nodes": ["model.shop.orders"]}
+    },
+    "test.shop.relationships_orders_customer_id": {
+      "resource_type": "test", "name": "relationships_orders_customer_id", "column_name": "customer_id",
+      "attached_node": "model.shop.orders",
+      "test_metadata": {"name": "relationships", "kwargs": {"column_name": "customer_id", "to": "ref('customers')", "field": "id"}},
+      "depends_on": {"nodes": ["model.shop.customers", "model.shop.orders"]}
+    }
+  },
+  "sources": {}
+}`
+
+const testSemanticManifest = `{
+  "semantic_models": [
+    {
+      "name": "orders",
+      "node_relation": {"alias": "orders", "schema_name": "analytics"},
+      "defaults": {"agg_time_dimension": "ordered_at"},
+      "entities": [{"name": "order", "type": "primary", "expr": "order_id"}, {"name": "customer", "type": "foreign", "expr": "customer_id"}],
+      "dimensions": [{"name": "ordered_at", "type": "time"}, {"name": "order_status", "type": "categorical", "expr": "status"}],
+      "measures": [
+        {"name": "order_total", "agg": "sum", "expr": "amount"},
+        {"name": "order_count", "agg": "count", "expr": "1"},
+        {"name": "p90_amount", "agg": "percentile", "expr": "amount"}
+      ]
+    },
+    {
+      "name": "customers",
+      "node_relation": {"alias": "dim_customers", "schema_name": "analytics"},
+      "entities": [{"name": "customer", "type": "primary", "expr": "id"}]
+    }
+  ],
+  "metrics": [
+    {"name": "revenue", "label": "Gross revenue", "description": "Gross order value", "type": "simple",
+     "type_params": {"measure": {"name": "order_total"}}},
+    {"name": "shipped_orders", "type": "simple",
+     "filter": {"where_filters": [{"where_sql_template": "{{ Dimension('order__order_status') }} = 'shipped'"}]},
+     "type_params": {"measure": {"name": "order_count"}}},
+    {"name": "orders", "type": "simple", "type_params": {"measure": {"name": "order_count"}}},
+    {"name": "average_order_value", "type": "ratio",
+     "type_params": {"numerator": {"name": "revenue"}, "denominator": {"name": "orders"}}},
+    {"name": "net_revenue", "type": "derived",
+     "type_params": {"expr": "revenue - refunds", "metrics": [{"name": "revenue"}, {"name": "shipped_orders", "alias": "refunds"}]}},
+    {"name": "cumulative_revenue", "type": "cumulative", "type_params": {"measure": {"name": "order_total"}}}
+  ]
+}`
+
+func TestParseDbtArtifacts(t *testing.T) {
+	doc, err := ParseDbtArtifacts([]byte(testManifest), []byte(testSemanticManifest))
+	if err != nil {
+		t.Fatalf("ParseDbtArtifacts: %v", err)
+	}
+
+	if doc.Project != "shop" || len(doc.Tables) != 2 {
+		t.Fatalf("got project %q with %d tables", doc.Project, len(doc.Tables))
+	}
+
+	orders := documentedTable(t, doc, "orders")
+	if orders.Description != "One row per order" {
+		t.Errorf("orders description = %q", orders.Description)
+	}
+	orderID := documentedColumn(t, orders, "order_id")
+	if !orderID.Unique || orderID.Nullable || !orderID.IsID {
+		t.Errorf("order_id = %+v, want unique, not null key", orderID)
+	}
+	status := documentedColumn(t, orders, "status")
+	if !status.IsDimension || strings.Join(status.SampleValues, ",") != "placed,shipped" {
+		t.Errorf("status = %+v, want dimension with accepted values", status)
+	}
+	if !documentedColumn(t, orders, "ordered_at").IsDatetime {
+		t.Error("ordered_at should be a time dimension")
+	}
+
+	if len(doc.Relationships) != 1 {
+		t.Fatalf("relationships = %+v, want the tested foreign key once", doc.Relationships)
+	}
+	rel := doc.Relationships[0]
+	if rel.FromTable != "orders" || rel.FromColumn != "customer_id" || rel.ToTable != "dim_customers" || rel.ToColumn != "id" {
+		t.Errorf("relationship = %+v", rel)
+	}
+
+	want := map[string]string{
+		"revenue":             "SUM(amount)",
+		"shipped_orders":      "COUNT(CASE WHEN status = 'shipped' THEN 1 END)",
+		"average_order_value": "SUM(amount) * 1.0 / NULLIF(COUNT(1), 0)",
+		"net_revenue":         "(SUM(amount)) - (COUNT(CASE WHEN status = 'shipped' THEN 1 END))",
+		"order_total":         "SUM(amount)",
+	}
+	metrics := make(map[string]BusinessMetric)
+	for _, metric := range doc.Metrics {
+		metrics[metric.Name] = metric
+	}
+	for name, expression := range want {
+		metric, ok := metrics[name]
+		if !ok {
+			t.Errorf("metric %s missing", name)
+			continue
+		}
+		if metric.Expression != expression {
+			t.Errorf("metric %s = %q, want %q", name, metric.Expression, expression)
+		}
+		if metric.Table != "orders" || metric.TimeDimension != "ordered_at" {
+			t.Errorf("metric %s on %s by %s, want orders by ordered_at", name, metric.Table, metric.TimeDimension)
+		}
+	}
+	for _, name := range []string{"cumulative_revenue", "p90_amount"} {
+		if _, ok := metrics[name]; ok {
+			t.Errorf("metric %s cannot be expressed as SQL and should be skipped", name)
+		}
+	}
+	if len(doc.Warnings) != 2 {
+		t.Errorf("warnings = %q, want the cumulative metric and percentile measure", doc.Warnings)
+	}
+
+	var revenueTerm *BusinessGlossary
+	for i := range doc.Glossary {
+		if doc.Glossary[i].Term == "Gross revenue" {
+			revenueTerm = &doc.Glossary[i]
+		}
+	}
+	if revenueTerm == nil || revenueTerm.Definition != "Gross order value" ||
+		strings.Join(revenueTerm.Synonyms, ",") != "revenue" || strings.Join(revenueTerm.RelatedTerms, ",") != "orders" {
+		t.Errorf("revenue glossary term = %+v", revenueTerm)
+	}
+	if len(doc.Glossary) != 6 {
+		t.Errorf("got %d glossary terms, want one per metric", len(doc.Glossary))
+	}
+}
+
+func TestParseDbtArtifactsRejectsInvalidManifest(t *testing.T) {
+	for _, manifest := range []string{`not json`, `{"nodes": {}}`} {
+		if _, err := ParseDbtArtifacts([]byte(manifest), nil); !errors.Is(err, ErrInvalidDbtArtifact) {
+			t.Errorf("ParseDbtArtifacts(%q) error = %v, want ErrInvalidDbtArtifact", manifest, err)
+		}
+	}
+}
+
+func TestMergeDocumentation(t *testing.T) {
+	doc, err := ParseDbtArtifacts([]byte(testManifest), []byte(testSemanticManifest))
+	if err != nil {
+		t.Fatalf("ParseDbtArtifacts: %v", err)
+	}
+
+	tables := []TableContext{
+		{
+			TableName: "ORDERS",
+			Columns: []ColumnInfo{
+				{Name: "ORDER_ID", Nullable: true},
+				{Name: "CUSTOMER_ID", Nullable: true},
+				{Name: "AMOUNT", IsMetric: true},
+			},
+			Metrics: []BusinessMetric{{Name: "legacy_total", Table: "ORDERS"}},
+		},
+		{TableName: "DIM_CUSTOMERS"},
+	}
+
+	glossary := mergeDocumentation(tables, doc)
+	if len(glossary) != len(doc.Glossary) {
+		t.Errorf("got %d glossary terms, want %d", len(glossary), len(doc.Glossary))
+	}
+
+	orders := tables[0]
+	if orders.Description != "One row per order" {
+		t.Errorf("description = %q", orders.Description)
+	}
+	if col := orders.Columns[0]; !col.Unique || col.Nullable || col.Description != "Primary key" {
+		t.Errorf("ORDER_ID = %+v", col)
+	}
+	if len(orders.Relationships) != 1 || orders.Relationships[0].ToTable != "DIM_CUSTOMERS" {
+		t.Errorf("relationships = %+v, want one to DIM_CUSTOMERS", orders.Relationships)
+	}
+	if len(orders.Metrics) != len(doc.Metrics)+1 || orders.Metrics[0].Table != "ORDERS" {
+		t.Errorf("metrics = %+v, want documented metrics on ORDERS before the curated one", orders.Metrics)
+	}
+	if doc.Metrics[0].Table != "orders" {
+		t.Error("merging must not modify the documented schema")
+	}
+}
+
+func documentedTable(t *testing.T, doc *DocumentedSchema, name string) *TableContext {
+	t.Helper()
+	for i := range doc.Tables {
+		if doc.Tables[i].TableName == name {
+			return &doc.Tables[i]
+		}
+	}
+	t.Fatalf("table %s not documented", name)
+	return nil
+}
+
+func documentedColumn(t *testing.T, table *TableContext, name string) ColumnInfo {
+	t.Helper()
+	for _, col := range table.Columns {
+		if col.Name == name {
+			return col
+		}
+	}
+	t.Fatalf("column %s.%s not documented", table.TableName, name)
+	return ColumnInfo{}
+}
@@ -257,6 +257,30 @@ func (d *DomainGeneratorService) GetConnectorDomains(ctx context.Context, connec
 	return domains, nil
 }
 
+// SearchConnectorDomains retrieves the domain contexts of a connector closest to a question
+func (d *DomainGeneratorService) SearchConnectorDomains(ctx context.Context, connectorID, question string, limit int) ([]DomainContext, error) {
+	collectionName := fmt.Sprintf("domains_%s", connectorID)
+
+	embeddingResp, err := d.embeddingService.GenerateEmbedding(ctx, question)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate embedding for question: %w", err)
+	}
+
+	searchResults, err := d.vectorStore.SearchVectors(ctx, collectionName, embeddingResp.Embedding, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search connector domains: %w", err)
+	}
+
+	var domains []DomainContext
+	for _, result := range searchResults {
+		if domain := d.vectorResultToDomainContext(result); domain != nil {
+			domains = append(domains, *domain)
+		}
+	}
+
+	return domains, nil
+}
+
 // vectorResultToDomainContext converts a vector search result back to a domain context
 func (d *DomainGeneratorService) vectorResultToDomainContext(result vectorstore.SearchResult) *DomainContext {
 	metadata := result.Vector.Metadata
@@ -2,6 +2,8 @@ package schema
 
 import (
 	"context"
+
+	"insightiq/backend/internal/models"
 )
 
 // ConnectorInfo represents basic connector information
@@ -17,4 +19,15 @@ type ConnectorInfo struct {
 type ConnectorService interface {
 	ListConnectors(ctx context.Context) ([]ConnectorInfo, error)
 	GetConnector(ctx context.Context, id string) (*ConnectorInfo, error)
-}
\ No newline at end of file
+}
+
+// DefinitionSource provides the user-maintained metric and dimension definitions of a connector
+type DefinitionSource interface {
+	ConnectorDefinitions(ctx context.Context, connectorID string) ([]models.SemanticDefinition, error)
+}
+
+// DocumentationSource provides business context documented outside a connector's data source
+type DocumentationSource interface {
+	// ConnectorDocumentation returns nil when nothing is documented for the connector
+	ConnectorDocumentation(ctx context.Context, connectorID string) (*DocumentedSchema, error)
+}
@@ -1,6 +1,10 @@
 package schema
 
-import "time"
+import (
+	"time"
+
+	"insightiq/backend/internal/models"
+)
 
 // Domain represents a business domain category
 type Domain string
@@ -44,19 +48,23 @@ type ColumnInfo struct {
 	IsID         bool     `json:"is_id"`         // primary/foreign key columns
 	IsCurrency   bool     `json:"is_currency"`   // monetary fields
 	SampleValues []string `json:"sample_values,omitempty"` // for understanding data patterns
+	Values       []string `json:"values,omitempty"`        // every value of a low-cardinality column, when known
 	Nullable     bool     `json:"nullable"`
 	Unique       bool     `json:"unique"`
 }
 
 // TableContext represents metadata about a database table
 type TableContext struct {
-	TableName    string       `json:"table_name"`
-	Schema       string       `json:"schema,omitempty"`
-	Domain       Domain       `json:"domain"`
-	Description  string       `json:"description"`
-	Columns      []ColumnInfo `json:"columns"`
-	SampleValues []string     `json:"sample_values,omitempty"`
-	BusinessTags []string     `json:"business_tags"`
+	TableName     string              `json:"table_name"`
+	Schema        string              `json:"schema,omitempty"`
+	Domain        Domain              `json:"domain"`
+	Description   string              `json:"description"`
+	Columns       []ColumnInfo        `json:"columns"`
+	SampleValues  []string            `json:"sample_values,omitempty"`
+	BusinessTags  []string            `json:"business_tags"`
+	RowCount      int64               `json:"row_count,omitempty"`     // estimated rows, when the source reports it
+	Relationships []TableRelationship `json:"relationships,omitempty"` // foreign keys declared on this table
+	Metrics       []BusinessMetric    `json:"metrics,omitempty"`       // metrics curated in the source (e.g. Superset saved metrics)
 }
 
 // BusinessGlossary represents business terminology definitions
@@ -96,28 +104,50 @@ type DomainContext struct {
 
 // SchemaContext represents the complete schema analysis for a data source
 type SchemaContext struct {
-	ConnectorID     string           `json:"connector_id"`
-	ConnectorName   string           `json:"connector_name"`
-	ConnectorType   string           `json:"connector_type"`
-	Tables          []TableContext   `json:"tables"`
-	DetectedDomains []DomainContext  `json:"detected_domains"`
-	PrimaryDomain   Domain           `json:"primary_domain"`
-	Confidence      float64          `json:"confidence"`
-	SampleQueries   []string         `json:"sample_queries"`
-	BusinessMetrics []BusinessMetric `json:"business_metrics"`
-	AnalyzedAt      time.Time        `json:"analyzed_at"`
+	ConnectorID     string              `json:"connector_id"`
+	ConnectorName   string              `json:"connector_name"`
+	ConnectorType   string              `json:"connector_type"`
+	Tables          []TableContext      `json:"tables"`
+	DetectedDomains []DomainContext     `json:"detected_domains"`
+	PrimaryDomain   Domain              `json:"primary_domain"`
+	Confidence      float64             `json:"confidence"`
+	SampleQueries   []string            `json:"sample_queries"`
+	BusinessMetrics []BusinessMetric    `json:"business_metrics"`
+	Relationships   []TableRelationship `json:"relationships,omitempty"`
+	// Definitions are the user-maintained metrics and dimensions, certified ones first
+	Definitions []models.SemanticDefinition `json:"definitions,omitempty"`
+	// Glossary holds terms documented outside the source, e.g. dbt metrics
+	Glossary   []BusinessGlossary `json:"glossary,omitempty"`
+	AnalyzedAt time.Time          `json:"analyzed_at"`
+}
+
+// DocumentedSchema is business context documented outside the data source, such as
+// the models, tests and metrics of a dbt project. Scans merge it into the tables
+// they discover.
+type DocumentedSchema struct {
+	Source        string              `json:"source"`
+	Project       string              `json:"project,omitempty"`
+	Tables        []TableContext      `json:"tables"`
+	Relationships []TableRelationship `json:"relationships,omitempty"`
+	Metrics       []BusinessMetric    `json:"metrics,omitempty"`
+	Glossary      []BusinessGlossary  `json:"glossary,omitempty"`
+	Warnings      []string            `json:"warnings,omitempty"` // parts that could not be imported
+	ImportedAt    time.Time           `json:"imported_at"`
 }
 
 // BusinessMetric represents an identified business metric
 type BusinessMetric struct {
-	Name        string   `json:"name"`        // revenue, orders, conversions
-	Description string   `json:"description"` // Total revenue from sales
-	Type        string   `json:"type"`        // sum, count, avg, ratio
-	Table       string   `json:"table"`       // source table
-	Column      string   `json:"column"`      // source column
-	Dimensions  []string `json:"dimensions"`  // related dimension columns
-	Domain      Domain   `json:"domain"`      // business domain
-	Keywords    []string `json:"keywords"`    // for query matching
+	Name        string   `json:"name"`                 // revenue, orders, conversions
+	Description string   `json:"description"`          // Total revenue from sales
+	Type        string   `json:"type"`                 // sum, count, avg, ratio
+	Table       string   `json:"table"`                // source table
+	Column      string   `json:"column"`               // source column
+	Dimensions  []string `json:"dimensions"`           // related dimension columns
+	Domain      Domain   `json:"domain"`               // business domain
+	Keywords    []string `json:"keywords"`             // for query matching
+	Expression  string   `json:"expression,omitempty"` // SQL expression for curated metrics, e.g. SUM(amount) / COUNT(*)
+	// TimeDimension is the column time ranges of the metric filter on, when documented
+	TimeDimension string `json:"time_dimension,omitempty"`
 }
 
 // TableRelationship represents relationships between tables
@@ -126,6 +156,6 @@ type TableRelationship struct {
 	ToTable      string `json:"to_table"`
 	FromColumn   string `json:"from_column"`
 	ToColumn     string `json:"to_column"`
-	RelationType string `json:"relation_type"` // one_to_many, many_to_many, one_to_one
+	RelationType string `json:"relation_type"` // many_to_one, one_to_many, many_to_many, one_to_one
 	Confidence   float64 `json:"confidence"`
 }
\ No newline at end of file
@@ -4,24 +4,43 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"regexp"
 	"strings"
 	"time"
+
+	"insightiq/backend/internal/connectors"
+	"insightiq/backend/internal/models"
 )
 
 // ScannerService handles database schema scanning and analysis
 type ScannerService struct {
 	connectorService ConnectorService
+	drivers          *connectors.Registry
+	definitions      DefinitionSource
+	documentation    DocumentationSource
 	logger          *slog.Logger
 }
 
 // NewScannerService creates a new schema scanner service
-func NewScannerService(connectorService ConnectorService, logger *slog.Logger) *ScannerService {
+func NewScannerService(connectorService ConnectorService, drivers *connectors.Registry, logger *slog.Logger) *ScannerService {
 	return &ScannerService{
 		connectorService: connectorService,
+		drivers:          drivers,
 		logger:          logger,
 	}
 }
 
+// SetDefinitionSource sets where user-maintained definitions attached to scans come from
+func (s *ScannerService) SetDefinitionSource(definitions DefinitionSource) {
+	s.definitions = definitions
+}
+
+// SetDocumentationSource sets where business context documented outside the source,
+// such as a dbt project, comes from
+func (s *ScannerService) SetDocumentationSource(documentation DocumentationSource) {
+	s.documentation = documentation
+}
+
 // ScanDataSource performs comprehensive schema analysis for a data source
 func (s *ScannerService) ScanDataSource(ctx context.Context, connectorID string) (*SchemaContext, error) {
 	s.logger.Info("Starting schema scan", "connector_id", connectorID)
@@ -31,25 +50,33 @@ func (s *ScannerService) ScanDataSource(ctx context.Context, connectorID string)
 		return nil, fmt.Errorf("failed to get connector: %w", err)
 	}
 
-	// Scan tables based on connector type
-	var tables []TableContext
-	switch connector.Type {
-	case "postgres", "mysql":
-		tables, err = s.scanDatabaseTables(ctx, connector)
-	case "superset":
-		tables, err = s.scanSupersetTables(ctx, connector)
-	case "api":
-		tables, err = s.scanAPIEndpoints(ctx, connector)
-	default:
-		return nil, fmt.Errorf("unsupported connector type: %s", connector.Type)
+	driver, err := s.drivers.Driver(models.ConnectorType(connector.Type))
+	if err != nil {
+		return nil, err
 	}
 
+	tableSchemas, err := driver.ScanSchema(ctx, connectors.Connection{
+		ID:     connector.ID,
+		Name:   connector.Name,
+		Config: connector.Config,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan tables: %w", err)
 	}
 
-	// Analyze relationships between tables (not used in current implementation)
-	// relationships := s.analyzeTableRelationships(tables)
+	tables := s.buildTableContexts(tableSchemas)
+
+	// Documented descriptions, keys and metrics take precedence over the heuristics
+	var glossary []BusinessGlossary
+	if doc := s.connectorDocumentation(ctx, connectorID); doc != nil {
+		glossary = mergeDocumentation(tables, doc)
+	}
+
+	// Collect relationships declared as foreign keys by the source
+	var relationships []TableRelationship
+	for _, table := range tables {
+		relationships = append(relationships, table.Relationships...)
+	}
 
 	// Generate business metrics from schema
 	businessMetrics := s.extractBusinessMetrics(tables)
@@ -65,247 +92,367 @@ func (s *ScannerService) ScanDataSource(ctx context.Context, connectorID string)
 		Tables:          tables,
 		BusinessMetrics: businessMetrics,
 		SampleQueries:   sampleQueries,
+		Relationships:   relationships,
+		Definitions:     s.connectorDefinitions(ctx, connectorID),
+		Glossary:        glossary,
 		AnalyzedAt:      time.Now(),
 	}
 
 	s.logger.Info("Schema scan completed",
 		"connector_id", connectorID,
 		"tables", len(tables),
+		"relationships", len(relationships),
 		"metrics", len(businessMetrics))
 
 	return schemaContext, nil
 }
 
-// scanDatabaseTables scans PostgreSQL/MySQL database tables
-func (s *ScannerService) scanDatabaseTables(ctx context.Context, connector *ConnectorInfo) ([]TableContext, error) {
-	// For now, return mock data representing a typical business database
-	// In production, this would connect to the actual database and scan schema
-	tables := []TableContext{
-		{
-			TableName:   "orders",
-			Schema:      "public",
-			Description: "Customer order transactions",
-			Columns: []ColumnInfo{
-				{
-					Name:         "order_id",
-					Type:         "INTEGER",
-					DataType:     "int",
-					IsID:         true,
-					Unique:       true,
-					SampleValues: []string{"1001", "1002", "1003"},
-				},
-				{
-					Name:         "customer_id",
-					Type:         "INTEGER",
-					DataType:     "int",
-					IsID:         true,
-					SampleValues: []string{"501", "502", "503"},
-				},
-				{
-					Name:         "order_date",
-					Type:         "TIMESTAMP",
-					DataType:     "timestamp",
-					IsDatetime:   true,
-					SampleValues: []string{"2024-01-15", "2024-01-16", "2024-01-17"},
-				},
-				{
-					Name:         "total_amount",
-					Type:         "DECIMAL",
-					DataType:     "decimal",
-					IsMetric:     true,
-					IsCurrency:   true,
-					SampleValues: []string{"299.99", "149.50", "899.00"},
-				},
-				{
-					Name:         "status",
-					Type:         "VARCHAR",
-					DataType:     "varchar",
-					IsDimension:  true,
-					SampleValues: []string{"completed", "pending", "cancelled"},
-				},
-			},
-			BusinessTags: []string{"sales", "orders", "revenue", "transactions"},
-		},
-		{
-			TableName:   "customers",
-			Schema:      "public",
-			Description: "Customer information and demographics",
-			Columns: []ColumnInfo{
-				{
-					Name:         "customer_id",
-					Type:         "INTEGER",
-					DataType:     "int",
-					IsID:         true,
-					Unique:       true,
-					SampleValues: []string{"501", "502", "503"},
-				},
-				{
-					Name:         "email",
-					Type:         "VARCHAR",
-					DataType:     "varchar",
-					SampleValues: []string{"john@example.com", "jane@company.com"},
-				},
-				{
-					Name:         "registration_date",
-					Type:         "TIMESTAMP",
-					DataType:     "timestamp",
-					IsDatetime:   true,
-					SampleValues: []string{"2023-12-01", "2023-11-15", "2024-01-03"},
-				},
-				{
-					Name:         "customer_segment",
-					Type:         "VARCHAR",
-					DataType:     "varchar",
-					IsDimension:  true,
-					SampleValues: []string{"premium", "standard", "basic"},
-				},
-				{
-					Name:         "lifetime_value",
-					Type:         "DECIMAL",
-					DataType:     "decimal",
-					IsMetric:     true,
-					IsCurrency:   true,
-					SampleValues: []string{"1299.99", "599.50", "299.00"},
-				},
-			},
-			BusinessTags: []string{"customers", "crm", "segmentation", "ltv"},
-		},
-		{
-			TableName:   "products",
-			Schema:      "public",
-			Description: "Product catalog and inventory",
-			Columns: []ColumnInfo{
-				{
-					Name:         "product_id",
-					Type:         "INTEGER",
-					DataType:     "int",
-					IsID:         true,
-					Unique:       true,
-					SampleValues: []string{"101", "102", "103"},
-				},
-				{
-					Name:         "product_name",
-					Type:         "VARCHAR",
-					DataType:     "varchar",
-					SampleValues: []string{"Laptop Pro", "Wireless Mouse", "Monitor 4K"},
-				},
-				{
-					Name:         "category",
-					Type:         "VARCHAR",
-					DataType:     "varchar",
-					IsDimension:  true,
-					SampleValues: []string{"electronics", "accessories", "computers"},
-				},
-				{
-					Name:         "price",
-					Type:         "DECIMAL",
-					DataType:     "decimal",
-					IsMetric:     true,
-					IsCurrency:   true,
-					SampleValues: []string{"1299.99", "29.99", "599.00"},
-				},
-				{
-					Name:         "stock_quantity",
-					Type:         "INTEGER",
-					DataType:     "int",
-					IsMetric:     true,
-					SampleValues: []string{"50", "200", "25"},
-				},
-			},
-			BusinessTags: []string{"products", "inventory", "catalog", "pricing"},
-		},
-	}
-
-	return tables, nil
+// connectorDefinitions loads the connector's definitions. A scan without them is
+// still useful, so failures are only logged.
+func (s *ScannerService) connectorDefinitions(ctx context.Context, connectorID string) []models.SemanticDefinition {
+	if s.definitions == nil {
+		return nil
+	}
+	defs, err := s.definitions.ConnectorDefinitions(ctx, connectorID)
+	if err != nil {
+		s.logger.Warn("Failed to load semantic definitions", "connector_id", connectorID, "error", err)
+		return nil
+	}
+	return defs
 }
 
-// scanSupersetTables scans Superset dashboards and datasets
-func (s *ScannerService) scanSupersetTables(ctx context.Context, connector *ConnectorInfo) ([]TableContext, error) {
-	// This would connect to Superset API and extract available datasets
-	// For now, return representative data from typical Superset setup
-	return s.scanDatabaseTables(ctx, connector) // Fallback to database scanning
+// connectorDocumentation loads the connector's documented context, if any. Like
+// definitions, it is optional for a scan.
+func (s *ScannerService) connectorDocumentation(ctx context.Context, connectorID string) *DocumentedSchema {
+	if s.documentation == nil {
+		return nil
+	}
+	doc, err := s.documentation.ConnectorDocumentation(ctx, connectorID)
+	if err != nil {
+		s.logger.Warn("Failed to load documented schema", "connector_id", connectorID, "error", err)
+		return nil
+	}
+	return doc
 }
 
-// scanAPIEndpoints scans API endpoints to understand data structure
-func (s *ScannerService) scanAPIEndpoints(ctx context.Context, connector *ConnectorInfo) ([]TableContext, error) {
-	// This would call API endpoints and analyze response structures
-	// For now, return basic API table representation
-	tables := []TableContext{
-		{
-			TableName:   "api_response",
-			Description: "API endpoint response data",
-			Columns: []ColumnInfo{
-				{
-					Name:         "id",
-					Type:         "STRING",
-					DataType:     "string",
-					IsID:         true,
-					SampleValues: []string{"api_001", "api_002"},
-				},
-				{
-					Name:         "timestamp",
-					Type:         "TIMESTAMP",
-					DataType:     "timestamp",
-					IsDatetime:   true,
-					SampleValues: []string{"2024-01-15T10:30:00Z"},
-				},
-			},
-			BusinessTags: []string{"api", "external_data"},
-		},
-	}
-
-	return tables, nil
-}
+// mergeDocumentation applies documented descriptions, column facts, relationships
+// and metrics to the scanned tables they name, and returns the documented glossary.
+// Documented metrics replace the column heuristics of their table.
+func mergeDocumentation(tables []TableContext, doc *DocumentedSchema) []BusinessGlossary {
+	scanned := make(map[string]*TableContext)
+	for i := range tables {
+		scanned[strings.ToLower(tables[i].TableName)] = &tables[i]
+	}
+	find := func(name, schemaName string) *TableContext {
+		table, ok := scanned[strings.ToLower(name)]
+		if !ok || (schemaName != "" && table.Schema != "" && !strings.EqualFold(schemaName, table.Schema)) {
+			return nil
+		}
+		return table
+	}
 
-// analyzeTableRelationships identifies relationships between tables
-func (s *ScannerService) analyzeTableRelationships(tables []TableContext) []TableRelationship {
-	var relationships []TableRelationship
+	for _, documented := range doc.Tables {
+		table := find(documented.TableName, documented.Schema)
+		if table == nil {
+			continue
+		}
+		if documented.Description != "" {
+			table.Description = documented.Description
+		}
+		table.BusinessTags = mergeTerms(table.BusinessTags, documented.BusinessTags)
 
-	// Simple relationship detection based on column names
-	for i, table1 := range tables {
-		for j, table2 := range tables {
-			if i >= j {
-				continue // Avoid self-comparison and duplicates
+		for _, documentedCol := range documented.Columns {
+			for i := range table.Columns {
+				col := &table.Columns[i]
+				if !strings.EqualFold(col.Name, documentedCol.Name) {
+					continue
+				}
+				if documentedCol.Description != "" {
+					col.Description = documentedCol.Description
+				}
+				col.Unique = col.Unique || documentedCol.Unique
+				col.Nullable = col.Nullable && documentedCol.Nullable
+				col.IsID = col.IsID || documentedCol.IsID
+				col.IsDimension = col.IsDimension || documentedCol.IsDimension
+				col.IsDatetime = col.IsDatetime || documentedCol.IsDatetime
+				if len(documentedCol.SampleValues) > 0 {
+					col.SampleValues = documentedCol.SampleValues
+				}
+				if len(col.Values) == 0 {
+					col.Values = documentedCol.Values
+				}
 			}
+		}
+	}
 
-			// Look for foreign key relationships
-			for _, col1 := range table1.Columns {
-				for _, col2 := range table2.Columns {
-					if s.isPotentialRelationship(col1, col2, table1.TableName, table2.TableName) {
-						relationships = append(relationships, TableRelationship{
-							FromTable:    table1.TableName,
-							ToTable:      table2.TableName,
-							FromColumn:   col1.Name,
-							ToColumn:     col2.Name,
-							RelationType: "one_to_many",
-							Confidence:   0.8,
-						})
-					}
-				}
+	for _, rel := range doc.Relationships {
+		from, to := find(rel.FromTable, ""), find(rel.ToTable, "")
+		if from == nil || to == nil {
+			continue
+		}
+		rel.FromTable, rel.ToTable = from.TableName, to.TableName
+		if !hasRelationship(from.Relationships, rel) {
+			from.Relationships = append(from.Relationships, rel)
+		}
+	}
+
+	documentedMetrics := make(map[*TableContext][]BusinessMetric)
+	for _, metric := range doc.Metrics {
+		table := find(metric.Table, "")
+		if table == nil {
+			continue
+		}
+		metric.Table = table.TableName
+		documentedMetrics[table] = append(documentedMetrics[table], metric)
+	}
+	for table, metrics := range documentedMetrics {
+		for _, curated := range table.Metrics {
+			if !hasMetric(metrics, curated.Name) {
+				metrics = append(metrics, curated)
 			}
 		}
+		table.Metrics = metrics
 	}
 
-	return relationships
+	return doc.Glossary
 }
 
-// isPotentialRelationship checks if two columns represent a foreign key relationship
-func (s *ScannerService) isPotentialRelationship(col1, col2 ColumnInfo, table1, table2 string) bool {
-	// Check for matching ID columns
-	if col1.IsID && col2.IsID {
-		// customer_id in orders table -> customer_id in customers table
-		if col1.Name == col2.Name {
+func hasRelationship(relationships []TableRelationship, rel TableRelationship) bool {
+	for _, existing := range relationships {
+		if strings.EqualFold(existing.FromColumn, rel.FromColumn) &&
+			strings.EqualFold(existing.ToTable, rel.ToTable) && strings.EqualFold(existing.ToColumn, rel.ToColumn) {
 			return true
 		}
-		// order_id matches id in orders table
-		if strings.Contains(col1.Name, strings.TrimSuffix(table2, "s")) ||
-		   strings.Contains(col2.Name, strings.TrimSuffix(table1, "s")) {
+	}
+	return false
+}
+
+func hasMetric(metrics []BusinessMetric, name string) bool {
+	for _, metric := range metrics {
+		if strings.EqualFold(metric.Name, name) {
 			return true
 		}
 	}
 	return false
 }
 
+// buildTableContexts converts discovered table schemas into classified table contexts
+func (s *ScannerService) buildTableContexts(tableSchemas []connectors.TableSchema) []TableContext {
+	tables := make([]TableContext, 0, len(tableSchemas))
+
+	for _, ts := range tableSchemas {
+		table := TableContext{
+			TableName:   ts.Name,
+			Schema:      ts.Schema,
+			Description: ts.Description,
+			RowCount:    ts.RowEstimate,
+		}
+		if table.Description == "" {
+			table.Description = fmt.Sprintf("%s table", strings.Title(strings.ReplaceAll(ts.Name, "_", " ")))
+		}
+
+		for _, col := range ts.Columns {
+			table.Columns = append(table.Columns, classifyColumn(col))
+		}
+
+		for _, fk := range ts.ForeignKeys {
+			relationType := "many_to_one"
+			for _, col := range ts.Columns {
+				if col.Name == fk.Column && col.Unique {
+					relationType = "one_to_one"
+				}
+			}
+			table.Relationships = append(table.Relationships, TableRelationship{
+				FromTable:    ts.Name,
+				ToTable:      fk.RefTable,
+				FromColumn:   fk.Column,
+				ToColumn:     fk.RefColumn,
+				RelationType: relationType,
+				Confidence:   1.0,
+			})
+		}
+
+		table.Domain = s.inferDomainFromTable(table)
+		table.BusinessTags = append(tableBusinessTags(table), ts.Tags...)
+
+		for _, metric := range ts.Metrics {
+			table.Metrics = append(table.Metrics, curatedMetric(metric, table))
+		}
+
+		tables = append(tables, table)
+	}
+
+	return tables
+}
+
+// classifyColumn infers the analytical role of a column from its type and name
+func classifyColumn(col connectors.ColumnSchema) ColumnInfo {
+	dataType := strings.ToLower(col.DataType)
+	name := strings.ToLower(col.Name)
+
+	info := ColumnInfo{
+		Name:         col.Name,
+		Type:         strings.ToUpper(dataType),
+		DataType:     dataType,
+		Description:  col.Description,
+		Nullable:     col.Nullable,
+		Unique:       col.PrimaryKey || col.Unique,
+		SampleValues: col.SampleValues,
+		Values:       col.DistinctValues,
+	}
+
+	info.IsID = col.PrimaryKey || name == "id" || strings.HasSuffix(name, "_id") || strings.HasSuffix(name, "_uuid")
+	info.IsDatetime = col.Temporal || isDatetimeType(dataType)
+
+	switch {
+	case info.IsID || info.IsDatetime:
+		// Keys and timestamps are neither metrics nor dimensions
+	case isNumericType(dataType):
+		// Numeric codes such as years or zip codes behave like dimensions
+		if containsAnyWord(name, []string{"year", "zip", "postal", "code", "phone"}) {
+			info.IsDimension = true
+		} else {
+			info.IsMetric = true
+			info.IsCurrency = containsAnyWord(name, []string{"amount", "price", "revenue", "cost", "salary", "fee", "spend", "income", "profit"})
+		}
+	case isTextType(dataType) || dataType == "bool" || dataType == "boolean":
+		info.IsDimension = !containsAnyWord(name, []string{"email", "password", "description", "comment", "note", "url"})
+	}
+
+	// The source's own grouping flag wins over the heuristics above
+	if col.Groupable != nil && !info.IsID && !info.IsDatetime {
+		info.IsDimension = *col.Groupable && (info.IsDimension || dataType == "")
+	}
+
+	return info
+}
+
+// firstNonEmpty returns the first non-empty string
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// isNumericType reports whether a database type holds numbers
+func isNumericType(dataType string) bool {
+	return containsAnyWord(dataType, []string{"int", "decimal", "numeric", "float", "double", "real", "money", "number"})
+}
+
+// isDatetimeType reports whether a database type holds dates or times
+func isDatetimeType(dataType string) bool {
+	return containsAnyWord(dataType, []string{"date", "time", "year"})
+}
+
+// isTextType reports whether a database type holds text
+func isTextType(dataType string) bool {
+	return containsAnyWord(dataType, []string{"char", "text", "enum", "set", "string", "citext"})
+}
+
+// containsAnyWord reports whether text contains any of the given fragments
+func containsAnyWord(text string, words []string) bool {
+	for _, word := range words {
+		if strings.Contains(text, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// tableBusinessTags derives search tags from the table name and its domain
+func tableBusinessTags(table TableContext) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, part := range strings.FieldsFunc(strings.ToLower(table.TableName), func(r rune) bool {
+		return r == '_' || r == '-' || r == '.'
+	}) {
+		if len(part) > 2 && !seen[part] {
+			seen[part] = true
+			tags = append(tags, part)
+		}
+	}
+	if table.Domain != "" && table.Domain != DomainGeneral && !seen[string(table.Domain)] {
+		tags = append(tags, string(table.Domain))
+	}
+	return tags
+}
+
+var aggregateExpressionPattern = regexp.MustCompile(`(?i)^\s*(SUM|COUNT|AVG|MIN|MAX)\s*\(\s*(DISTINCT\s+)?([A-Za-z_][A-Za-z0-9_]*|\*)\s*\)\s*$`)
+
+// metricTypeFromExpression classifies a saved metric by its aggregate function
+func metricTypeFromExpression(expression string) string {
+	upper := strings.ToUpper(strings.TrimSpace(expression))
+	switch {
+	case strings.Contains(upper, "/"):
+		return "ratio"
+	case strings.HasPrefix(upper, "COUNT"):
+		return "count"
+	case strings.HasPrefix(upper, "AVG"):
+		return "avg"
+	case strings.HasPrefix(upper, "MIN"):
+		return "min"
+	case strings.HasPrefix(upper, "MAX"):
+		return "max"
+	case strings.HasPrefix(upper, "SUM"):
+		return "sum"
+	default:
+		return "custom"
+	}
+}
+
+// metricColumnFromExpression returns the column of a simple aggregate such as SUM(amount)
+func metricColumnFromExpression(expression string) string {
+	m := aggregateExpressionPattern.FindStringSubmatch(expression)
+	if m == nil || m[3] == "*" {
+		return ""
+	}
+	return m[3]
+}
+
+// curatedMetric converts a metric defined in the source into a business metric
+func curatedMetric(metric connectors.MetricSchema, table TableContext) BusinessMetric {
+	var dimensions []string
+	for _, col := range table.Columns {
+		if col.IsDimension {
+			dimensions = append(dimensions, col.Name)
+		}
+	}
+
+	description := firstNonEmpty(metric.Description, metric.Label, fmt.Sprintf("%s defined in the data source", metric.Name))
+
+	return BusinessMetric{
+		Name:        firstNonEmpty(metric.Label, metric.Name),
+		Description: description,
+		Type:        metricTypeFromExpression(metric.Expression),
+		Table:       table.TableName,
+		Column:      metricColumnFromExpression(metric.Expression),
+		Dimensions:  dimensions,
+		Domain:      table.Domain,
+		Keywords:    metricKeywords(metric, table.TableName),
+		Expression:  metric.Expression,
+	}
+}
+
+// metricKeywords derives search keywords from a curated metric's names
+func metricKeywords(metric connectors.MetricSchema, tableName string) []string {
+	var keywords []string
+	seen := make(map[string]bool)
+	for _, text := range []string{metric.Name, metric.Label, tableName} {
+		for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9')
+		}) {
+			if len(word) > 2 && !seen[word] {
+				seen[word] = true
+				keywords = append(keywords, word)
+			}
+		}
+	}
+	return keywords
+}
+
 // extractBusinessMetrics identifies potential business metrics from table schemas
 func (s *ScannerService) extractBusinessMetrics(tables []TableContext) []BusinessMetric {
 	var metrics []BusinessMetric
@@ -320,6 +467,12 @@ func (s *ScannerService) extractBusinessMetrics(tables []TableContext) []Busines
 	// }
 
 	for _, table := range tables {
+		// Metrics curated in the source replace the column heuristics
+		if len(table.Metrics) > 0 {
+			metrics = append(metrics, table.Metrics...)
+			continue
+		}
+
 		domain := s.inferDomainFromTable(table)
 
 		for _, col := range table.Columns {
@@ -0,0 +1,298 @@
+package schema
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Match types of a ValueMatch
+const (
+	ValueMatchExact   = "exact"
+	ValueMatchSynonym = "synonym"
+	ValueMatchFuzzy   = "fuzzy"
+)
+
+// ValueMatch is a dimension value named in a question
+type ValueMatch struct {
+	Text      string  `json:"text"` // the words of the question that matched
+	Table     string  `json:"table"`
+	Column    string  `json:"column"`
+	Value     string  `json:"value"` // the value as stored
+	MatchType string  `json:"match_type"`
+	Score     float64 `json:"score"`
+}
+
+// valueEntry is an indexed value of a column, under its own spelling or a synonym
+type valueEntry struct {
+	table   string
+	column  string
+	value   string
+	synonym bool
+}
+
+// ValueIndex maps the values of low-cardinality dimension columns back to the
+// columns holding them, so values named in questions can become filters. Values
+// match case-insensitively, through synonyms such as state names for state codes,
+// and with small typos.
+type ValueIndex struct {
+	entries  map[string][]valueEntry
+	keys     []string // sorted, for deterministic fuzzy matching
+	maxWords int
+	// reserved phrases name tables, columns and metrics rather than values
+	reserved map[string]bool
+}
+
+// Bounds for fuzzy matching
+const (
+	// Phrases shorter than this only match exactly
+	minFuzzyLength = 5
+	// Phrases at least this long tolerate two edits instead of one
+	twoEditLength = 9
+)
+
+// NewValueIndex indexes the known values of the dimension columns of a scanned
+// schema. Complete value lists are preferred; sampled values are indexed for
+// sources that cannot list them. Glossary synonyms of a value match it too.
+func NewValueIndex(schemaCtx *SchemaContext) *ValueIndex {
+	idx := &ValueIndex{
+		entries:  make(map[string][]valueEntry),
+		reserved: make(map[string]bool),
+	}
+
+	synonyms := make(map[string][]string)
+	for _, term := range schemaCtx.Glossary {
+		key := normalizePhrase(term.Term)
+		synonyms[key] = append(synonyms[key], term.Synonyms...)
+	}
+
+	for _, table := range schemaCtx.Tables {
+		idx.reserve(table.TableName)
+		for _, metric := range table.Metrics {
+			idx.reserve(metric.Name)
+		}
+		for _, col := range table.Columns {
+			idx.reserve(col.Name)
+			if !col.IsDimension || col.IsID || col.IsDatetime {
+				continue
+			}
+			values := col.Values
+			if len(values) == 0 {
+				values = col.SampleValues
+			}
+			for _, value := range values {
+				key := normalizePhrase(value)
+				if indexable(key) {
+					idx.add(key, valueEntry{table: table.TableName, column: col.Name, value: value})
+				}
+				// Codes too short to match on their own, such as IN, still match by name
+				for _, synonym := range append(builtinValueSynonyms[key], synonyms[key]...) {
+					idx.add(normalizePhrase(synonym), valueEntry{table: table.TableName, column: col.Name, value: value, synonym: true})
+				}
+			}
+		}
+	}
+	for _, metric := range schemaCtx.BusinessMetrics {
+		idx.reserve(metric.Name)
+	}
+
+	idx.keys = make([]string, 0, len(idx.entries))
+	for key := range idx.entries {
+		idx.keys = append(idx.keys, key)
+	}
+	slices.Sort(idx.keys)
+	return idx
+}
+
+// Len returns the number of indexed phrases
+func (idx *ValueIndex) Len() int {
+	return len(idx.entries)
+}
+
+// Match finds the indexed values named in a question, longest phrases first. A
+// phrase naming values of several columns matches the first column scanned.
+func (idx *ValueIndex) Match(question string) []ValueMatch {
+	words := strings.Fields(normalizePhrase(question))
+
+	var matches []ValueMatch
+	seen := make(map[[3]string]bool)
+	for i := 0; i < len(words); {
+		n := min(idx.maxWords, len(words)-i)
+		for ; n > 0; n-- {
+			match, ok := idx.lookup(strings.Join(words[i:i+n], " "))
+			if !ok {
+				continue
+			}
+			key := [3]string{match.Table, match.Column, match.Value}
+			if !seen[key] {
+				seen[key] = true
+				matches = append(matches, match)
+			}
+			break
+		}
+		i += max(n, 1)
+	}
+	return matches
+}
+
+// lookup matches a phrase exactly, through a synonym, or with small typos
+func (idx *ValueIndex) lookup(phrase string) (ValueMatch, bool) {
+	if idx.reserved[phrase] || stopWords[phrase] {
+		return ValueMatch{}, false
+	}
+
+	if entries, ok := idx.entries[phrase]; ok {
+		entry := entries[0]
+		for _, e := range entries {
+			if !e.synonym {
+				entry = e
+				break
+			}
+		}
+		if entry.synonym {
+			return entry.match(phrase, ValueMatchSynonym, 0.9), true
+		}
+		return entry.match(phrase, ValueMatchExact, 1), true
+	}
+
+	if len(phrase) < minFuzzyLength {
+		return ValueMatch{}, false
+	}
+	allowed := 1
+	if len(phrase) >= twoEditLength {
+		allowed = 2
+	}
+	bestKey, bestDistance := "", allowed+1
+	for _, key := range idx.keys {
+		if len(key) < minFuzzyLength || abs(len(key)-len(phrase)) > allowed {
+			continue
+		}
+		if d := editDistance(phrase, key); d < bestDistance {
+			bestKey, bestDistance = key, d
+		}
+	}
+	if bestKey == "" {
+		return ValueMatch{}, false
+	}
+	score := 0.9 * (1 - float64(bestDistance)/float64(len(bestKey)))
+	return idx.entries[bestKey][0].match(phrase, ValueMatchFuzzy, score), true
+}
+
+func (e valueEntry) match(text, matchType string, score float64) ValueMatch {
+	return ValueMatch{Text: text, Table: e.table, Column: e.column, Value: e.value, MatchType: matchType, Score: score}
+}
+
+func (idx *ValueIndex) add(key string, entry valueEntry) {
+	if key == "" || stopWords[key] {
+		return
+	}
+	for _, existing := range idx.entries[key] {
+		if existing.table == entry.table && existing.column == entry.column {
+			return
+		}
+	}
+	idx.entries[key] = append(idx.entries[key], entry)
+	idx.maxWords = max(idx.maxWords, len(strings.Fields(key)))
+}
+
+// reserve keeps a schema name from matching as a value, also in its spaced form
+// ("order_status" as "order status")
+func (idx *ValueIndex) reserve(name string) {
+	if key := normalizePhrase(name); key != "" {
+		idx.reserved[key] = true
+	}
+}
+
+// normalizePhrase lower-cases text and reduces punctuation to single spaces
+func normalizePhrase(text string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// indexable reports whether a value can be told apart from ordinary words of a
+// question: numbers, flags and placeholders cannot
+func indexable(key string) bool {
+	if len(key) < 2 || stopWords[key] || ignoredValues[key] {
+		return false
+	}
+	return strings.ContainsFunc(key, unicode.IsLetter)
+}
+
+// editDistance is the Levenshtein distance of two strings
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(min(prev[j]+1, curr[j-1]+1), prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// stopWords never match values, which keeps state codes such as IN, OR and ME
+// from matching ordinary words
+var stopWords = map[string]bool{
+	"a": true, "all": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "de": true, "do": true, "for": true, "from": true, "has": true,
+	"hi": true, "how": true, "in": true, "is": true, "it": true, "me": true, "my": true,
+	"no": true, "not": true, "of": true, "oh": true, "ok": true, "on": true, "or": true,
+	"per": true, "show": true, "so": true, "than": true, "that": true, "the": true, "to": true,
+	"top": true, "total": true, "us": true, "vs": true, "was": true, "we": true, "what": true,
+	"which": true, "who": true, "with": true,
+}
+
+// ignoredValues are placeholders that say nothing about which rows are meant
+var ignoredValues = map[string]bool{
+	"true": true, "false": true, "yes": true, "null": true, "none": true, "nil": true,
+	"n a": true, "na": true, "other": true, "others": true, "unknown": true, "default": true,
+}
+
+// builtinValueSynonyms map common codes to names and back, keyed by normalized value
+var builtinValueSynonyms = func() map[string][]string {
+	pairs := [][2]string{
+		{"al", "alabama"}, {"ak", "alaska"}, {"az", "arizona"}, {"ar", "arkansas"},
+		{"ca", "california"}, {"co", "colorado"}, {"ct", "connecticut"}, {"de", "delaware"},
+		{"dc", "district of columbia"}, {"fl", "florida"}, {"ga", "georgia"}, {"hi", "hawaii"},
+		{"id", "idaho"}, {"il", "illinois"}, {"in", "indiana"}, {"ia", "iowa"},
+		{"ks", "kansas"}, {"ky", "kentucky"}, {"la", "louisiana"}, {"me", "maine"},
+		{"md", "maryland"}, {"ma", "massachusetts"}, {"mi", "michigan"}, {"mn", "minnesota"},
+		{"ms", "mississippi"}, {"mo", "missouri"}, {"mt", "montana"}, {"ne", "nebraska"},
+		{"nv", "nevada"}, {"nh", "new hampshire"}, {"nj", "new jersey"}, {"nm", "new mexico"},
+		{"ny", "new york"}, {"nc", "north carolina"}, {"nd", "north dakota"}, {"oh", "ohio"},
+		{"ok", "oklahoma"}, {"or", "oregon"}, {"pa", "pennsylvania"}, {"ri", "rhode island"},
+		{"sc", "south carolina"}, {"sd", "south dakota"}, {"tn", "tennessee"}, {"tx", "texas"},
+		{"ut", "utah"}, {"vt", "vermont"}, {"va", "virginia"}, {"wa", "washington"},
+		{"wv", "west virginia"}, {"wi", "wisconsin"}, {"wy", "wyoming"},
+		{"us", "united states"}, {"usa", "united states"}, {"us", "usa"}, {"us", "america"},
+		{"uk", "united kingdom"}, {"gb", "united kingdom"}, {"uk", "great britain"},
+		{"uae", "united arab emirates"}, {"de", "germany"}, {"fr", "france"}, {"es", "spain"},
+		{"it", "italy"}, {"nl", "netherlands"}, {"jp", "japan"}, {"cn", "china"},
+		{"in", "india"}, {"br", "brazil"}, {"mx", "mexico"}, {"au", "australia"},
+		{"emea", "europe middle east and africa"}, {"apac", "asia pacific"},
+		{"latam", "latin america"},
+	}
+	synonyms := make(map[string][]string)
+	for _, pair := range pairs {
+		synonyms[pair[0]] = append(synonyms[pair[0]], pair[1])
+		synonyms[pair[1]] = append(synonyms[pair[1]], pair[0])
+	}
+	return synonyms
+}()
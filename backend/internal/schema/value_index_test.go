@@ -0,0 +1,57 @@
+package schema
+
+import "testing"
+
+func TestValueIndexMatch(t *testing.T) {
+	schemaCtx := &SchemaContext{
+		Tables: []TableContext{
+			{
+				TableName: "customers",
+				Columns: []ColumnInfo{
+					{Name: "region", IsDimension: true, Values: []string{"CA", "NY", "IN"}},
+					{Name: "customer_segment", IsDimension: true, Values: []string{"Premium", "Standard", "Unknown"}},
+					{Name: "status", IsDimension: true, SampleValues: []string{"active", "sales"}},
+					{Name: "customer_id", IsID: true, Values: []string{"premium"}},
+				},
+				Metrics: []BusinessMetric{{Name: "sales"}},
+			},
+		},
+		Glossary: []BusinessGlossary{{Term: "Standard", Synonyms: []string{"basic tier"}}},
+	}
+	index := NewValueIndex(schemaCtx)
+
+	tests := []struct {
+		question string
+		want     []ValueMatch
+	}{
+		{"sales in California for premium customers", []ValueMatch{
+			{Text: "california", Table: "customers", Column: "region", Value: "CA", MatchType: ValueMatchSynonym},
+			{Text: "premium", Table: "customers", Column: "customer_segment", Value: "Premium", MatchType: ValueMatchExact},
+		}},
+		{"orders from NY and Indiana", []ValueMatch{
+			{Text: "ny", Table: "customers", Column: "region", Value: "NY", MatchType: ValueMatchExact},
+			{Text: "indiana", Table: "customers", Column: "region", Value: "IN", MatchType: ValueMatchSynonym},
+		}},
+		{"revenue of premum accounts on the basic tier", []ValueMatch{
+			{Text: "premum", Table: "customers", Column: "customer_segment", Value: "Premium", MatchType: ValueMatchFuzzy},
+			{Text: "basic tier", Table: "customers", Column: "customer_segment", Value: "Standard", MatchType: ValueMatchSynonym},
+		}},
+		// Schema names, stop words and placeholders are not values
+		{"status of unknown customers in total", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.question, func(t *testing.T) {
+			got := index.Match(tt.question)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i, want := range tt.want {
+				got[i].Score = 0
+				if got[i] != want {
+					t.Errorf("match %d = %+v, want %+v", i, got[i], want)
+				}
+			}
+		})
+	}
+}
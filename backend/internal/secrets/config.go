@@ -0,0 +1,121 @@
+package secrets
+
+import "fmt"
+
+// Connector configs are JSON objects. A sensitive field holds either a string or
+// an object of strings (such as extra HTTP headers); both are handled value by value.
+
+// SealFields returns a copy of config with the sensitive fields sealed.
+// Values that are already sealed are kept as they are.
+func (k *Keyring) SealFields(config map[string]interface{}, fields []string) (map[string]interface{}, error) {
+	return transformFields(config, fields, func(field, value string) (string, error) {
+		if value == "" || IsSealed(value) {
+			return value, nil
+		}
+		sealed, err := k.Seal(value)
+		if err != nil {
+			return "", fmt.Errorf("failed to encrypt %s: %w", field, err)
+		}
+		return sealed, nil
+	})
+}
+
+// OpenFields returns a copy of config with the sensitive fields decrypted
+func (k *Keyring) OpenFields(config map[string]interface{}, fields []string) (map[string]interface{}, error) {
+	return transformFields(config, fields, func(field, value string) (string, error) {
+		plaintext, err := k.Open(value)
+		if err != nil {
+			return "", fmt.Errorf("failed to decrypt %s: %w", field, err)
+		}
+		return plaintext, nil
+	})
+}
+
+// FieldsNeedRotation reports whether any sensitive field is plaintext or sealed with an old key
+func (k *Keyring) FieldsNeedRotation(config map[string]interface{}, fields []string) bool {
+	needs := false
+	transformFields(config, fields, func(_, value string) (string, error) {
+		if value != "" && k.NeedsRotation(value) {
+			needs = true
+		}
+		return value, nil
+	})
+	return needs
+}
+
+// MaskFields returns a copy of config with every non-empty sensitive value replaced by Mask
+func MaskFields(config map[string]interface{}, fields []string) map[string]interface{} {
+	masked, _ := transformFields(config, fields, func(_, value string) (string, error) {
+		if value == "" {
+			return value, nil
+		}
+		return Mask, nil
+	})
+	return masked
+}
+
+// RestoreMasked returns a copy of updated where sensitive values sent back as Mask
+// are replaced by the corresponding values from stored
+func RestoreMasked(updated, stored map[string]interface{}, fields []string) map[string]interface{} {
+	restored := copyConfig(updated)
+	for _, field := range fields {
+		switch v := restored[field].(type) {
+		case string:
+			if v == Mask {
+				restored[field] = stored[field]
+			}
+		case map[string]interface{}:
+			storedValues, _ := stored[field].(map[string]interface{})
+			values := make(map[string]interface{}, len(v))
+			for name, value := range v {
+				if s, ok := value.(string); ok && s == Mask {
+					values[name] = storedValues[name]
+				} else {
+					values[name] = value
+				}
+			}
+			restored[field] = values
+		}
+	}
+	return restored
+}
+
+// transformFields applies fn to every string value of the sensitive fields of a copy of config
+func transformFields(config map[string]interface{}, fields []string, fn func(field, value string) (string, error)) (map[string]interface{}, error) {
+	out := copyConfig(config)
+	for _, field := range fields {
+		switch v := out[field].(type) {
+		case string:
+			transformed, err := fn(field, v)
+			if err != nil {
+				return nil, err
+			}
+			out[field] = transformed
+		case map[string]interface{}:
+			values := make(map[string]interface{}, len(v))
+			for name, value := range v {
+				s, ok := value.(string)
+				if !ok {
+					values[name] = value
+					continue
+				}
+				transformed, err := fn(field+"."+name, s)
+				if err != nil {
+					return nil, err
+				}
+				values[name] = transformed
+			}
+			out[field] = values
+		}
+	}
+	return out, nil
+}
+
+// copyConfig makes a shallow copy so callers' maps are never modified
+func copyConfig(config map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(config))
+	for k, v := range config {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,235 @@
+// Package secrets encrypts connector credentials at rest.
+//
+// Values are sealed with envelope encryption: each value gets a fresh 256-bit data
+// key that encrypts it with AES-GCM, and the data key is itself wrapped with AES-GCM
+// under a key-encryption key from the Keyring. The sealed form records the id of the
+// wrapping key so keys can be rotated: new values use the primary key while values
+// sealed under older keys stay readable until they are re-encrypted.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Mask replaces secret values in API responses. An update that sends Mask back
+// keeps the stored secret.
+const Mask = "********"
+
+// Prefix of sealed values: enc:v1:<key id>:<wrapped data key>:<ciphertext>
+const sealedPrefix = "enc:v1:"
+
+// Key-encryption keys are AES-256 keys
+const keySize = 32
+
+var (
+	// ErrUnknownKey is returned when a value was sealed with a key that is not in the keyring
+	ErrUnknownKey = errors.New("unknown encryption key")
+	// ErrMalformed is returned when a sealed value cannot be parsed
+	ErrMalformed = errors.New("malformed encrypted value")
+)
+
+// Keyring holds the key-encryption keys. The primary key seals new values;
+// the others are kept to open values sealed before a rotation.
+type Keyring struct {
+	primary string
+	keys    map[string][]byte
+}
+
+// NewKeyring creates a keyring whose primary key is primaryID
+func NewKeyring(primaryID string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[primaryID]; !ok {
+		return nil, fmt.Errorf("primary key %q is not in the keyring", primaryID)
+	}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid key id %q", id)
+		}
+		if len(key) != keySize {
+			return nil, fmt.Errorf("key %q must be %d bytes, got %d", id, keySize, len(key))
+		}
+	}
+	return &Keyring{primary: primaryID, keys: keys}, nil
+}
+
+// ParseKeyring reads keys written as "<id>:<base64 key>" separated by commas or
+// newlines. The first key is the primary. A single bare base64 key gets the id "default".
+func ParseKeyring(spec string) (*Keyring, error) {
+	entries := strings.FieldsFunc(spec, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r'
+	})
+
+	keys := make(map[string][]byte)
+	primary := ""
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		id, encoded, found := strings.Cut(entry, ":")
+		if !found {
+			id, encoded = "default", entry
+		}
+		id = strings.TrimSpace(id)
+
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("key %q is not valid base64: %w", id, err)
+		}
+		if _, dup := keys[id]; dup {
+			return nil, fmt.Errorf("key %q is declared more than once", id)
+		}
+		keys[id] = key
+		if primary == "" {
+			primary = id
+		}
+	}
+
+	if primary == "" {
+		return nil, fmt.Errorf("no encryption keys found")
+	}
+	return NewKeyring(primary, keys)
+}
+
+// LoadKeyring builds the keyring from an inline key spec or, when that is empty,
+// from a key file in the same format. It returns nil when neither is set.
+func LoadKeyring(spec, keyFile string) (*Keyring, error) {
+	if spec == "" && keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file: %w", err)
+		}
+		spec = string(data)
+	}
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	return ParseKeyring(spec)
+}
+
+// GenerateKey returns a new random key in the "<id>:<base64 key>" keyring format
+func GenerateKey(id string) (string, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return id + ":" + base64.StdEncoding.EncodeToString(key), nil
+}
+
+// PrimaryKeyID returns the id of the key used to seal new values
+func (k *Keyring) PrimaryKeyID() string {
+	return k.primary
+}
+
+// IsSealed reports whether value is in sealed form
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, sealedPrefix)
+}
+
+// Seal encrypts plaintext under a fresh data key wrapped with the primary key
+func (k *Keyring) Seal(plaintext string) (string, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	ciphertext, err := gcmSeal(dataKey, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	wrappedKey, err := gcmSeal(k.keys[k.primary], dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	return sealedPrefix + k.primary + ":" +
+		base64.RawStdEncoding.EncodeToString(wrappedKey) + ":" +
+		base64.RawStdEncoding.EncodeToString(ciphertext), nil
+}
+
+// Open decrypts a sealed value. Values that are not sealed are returned unchanged,
+// so secrets stored before encryption was enabled keep working.
+func (k *Keyring) Open(value string) (string, error) {
+	if !IsSealed(value) {
+		return value, nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, sealedPrefix), ":")
+	if len(parts) != 3 {
+		return "", ErrMalformed
+	}
+
+	kek, ok := k.keys[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownKey, parts[0])
+	}
+
+	wrappedKey, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrMalformed
+	}
+	ciphertext, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrMalformed
+	}
+
+	dataKey, err := gcmOpen(kek, wrappedKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	plaintext, err := gcmOpen(dataKey, ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %w", err)
+	}
+
+	return string(plaintext), nil
+}
+
+// NeedsRotation reports whether value is plaintext or sealed with a key other than the primary
+func (k *Keyring) NeedsRotation(value string) bool {
+	if !IsSealed(value) {
+		return true
+	}
+	keyID, _, _ := strings.Cut(strings.TrimPrefix(value, sealedPrefix), ":")
+	return keyID != k.primary
+}
+
+// gcmSeal encrypts with AES-GCM and prepends the random nonce
+func gcmSeal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// gcmOpen decrypts a nonce-prefixed AES-GCM ciphertext
+func gcmOpen(key, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, ErrMalformed
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
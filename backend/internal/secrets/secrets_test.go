@@ -0,0 +1,128 @@
+package secrets
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKeyring(t *testing.T, ids ...string) *Keyring {
+	t.Helper()
+	var spec []string
+	for _, id := range ids {
+		key, err := GenerateKey(id)
+		if err != nil {
+			t.Fatalf("GenerateKey: %v", err)
+		}
+		spec = append(spec, key)
+	}
+	keyring, err := ParseKeyring(strings.Join(spec, ","))
+	if err != nil {
+		t.Fatalf("ParseKeyring: %v", err)
+	}
+	return keyring
+}
+
+func TestSealOpen(t *testing.T) {
+	keyring := testKeyring(t, "k1")
+
+	sealed, err := keyring.Seal("s3cret")
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if !IsSealed(sealed) || strings.Contains(sealed, "s3cret") {
+		t.Fatalf("value not sealed: %s", sealed)
+	}
+
+	opened, err := keyring.Open(sealed)
+	if err != nil || opened != "s3cret" {
+		t.Fatalf("Open = %q, %v", opened, err)
+	}
+
+	// Plaintext stored before encryption was enabled passes through
+	if opened, _ := keyring.Open("legacy"); opened != "legacy" {
+		t.Errorf("Open(plaintext) = %q", opened)
+	}
+
+	tampered := sealed + "A"
+	if _, err := keyring.Open(tampered); err == nil {
+		t.Error("expected tampered value to fail")
+	}
+}
+
+func TestRotation(t *testing.T) {
+	oldKey, _ := GenerateKey("old")
+	newKey, _ := GenerateKey("new")
+
+	oldRing, err := ParseKeyring(oldKey)
+	if err != nil {
+		t.Fatalf("ParseKeyring: %v", err)
+	}
+	sealed, err := oldRing.Seal("token")
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	// New primary first, old key kept for decryption
+	rotated, err := ParseKeyring(newKey + "\n" + oldKey)
+	if err != nil {
+		t.Fatalf("ParseKeyring: %v", err)
+	}
+	if rotated.PrimaryKeyID() != "new" {
+		t.Fatalf("primary = %s", rotated.PrimaryKeyID())
+	}
+
+	if !rotated.NeedsRotation(sealed) {
+		t.Error("value sealed with old key should need rotation")
+	}
+	if opened, err := rotated.Open(sealed); err != nil || opened != "token" {
+		t.Fatalf("Open after rotation = %q, %v", opened, err)
+	}
+
+	resealed, _ := rotated.Seal("token")
+	if rotated.NeedsRotation(resealed) {
+		t.Error("value sealed with primary key should not need rotation")
+	}
+
+	if _, err := testKeyring(t, "other").Open(sealed); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestMaskAndRestore(t *testing.T) {
+	keyring := testKeyring(t, "k1")
+	fields := []string{"password", "headers"}
+	stored := map[string]interface{}{
+		"url":      "https://superset.example.com",
+		"password": "hunter2",
+		"headers":  map[string]interface{}{"X-API-Key": "abc"},
+	}
+
+	sealed, err := keyring.SealFields(stored, fields)
+	if err != nil {
+		t.Fatalf("SealFields: %v", err)
+	}
+	if sealed["url"] != stored["url"] || !IsSealed(sealed["password"].(string)) {
+		t.Fatalf("unexpected sealed config: %v", sealed)
+	}
+	if stored["password"] != "hunter2" {
+		t.Fatal("SealFields modified its input")
+	}
+
+	opened, err := keyring.OpenFields(sealed, fields)
+	if err != nil || opened["password"] != "hunter2" || opened["headers"].(map[string]interface{})["X-API-Key"] != "abc" {
+		t.Fatalf("OpenFields = %v, %v", opened, err)
+	}
+
+	masked := MaskFields(opened, fields)
+	if masked["password"] != Mask || masked["headers"].(map[string]interface{})["X-API-Key"] != Mask {
+		t.Fatalf("MaskFields = %v", masked)
+	}
+
+	updated := map[string]interface{}{"url": "https://new.example.com", "password": Mask, "headers": map[string]interface{}{"X-API-Key": Mask}}
+	restored := RestoreMasked(updated, opened, fields)
+	if restored["password"] != "hunter2" || restored["url"] != "https://new.example.com" ||
+		restored["headers"].(map[string]interface{})["X-API-Key"] != "abc" {
+		t.Fatalf("RestoreMasked = %v", restored)
+	}
+}
@@ -0,0 +1,654 @@
+package semantic
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"insightiq/backend/internal/models"
+	"insightiq/backend/internal/schema"
+)
+
+var (
+	// ErrEmptyQuery is returned when a parsed query names no metrics, aggregations or dimensions
+	ErrEmptyQuery = errors.New("query selects no metrics or dimensions")
+	// ErrUnknownMetric is returned when a metric is not defined in the model
+	ErrUnknownMetric = errors.New("unknown metric")
+	// ErrUnknownField is returned when a dimension, filter or sort field matches no definition or column
+	ErrUnknownField = errors.New("unknown field")
+	// ErrInvalidMetric is returned when a metric has neither an expression nor a supported type
+	ErrInvalidMetric = errors.New("metric cannot be compiled")
+	// ErrMixedMetricTables is returned when the requested metrics live on different tables
+	ErrMixedMetricTables = errors.New("metrics from different tables cannot be compiled into one query")
+	// ErrNoJoinPath is returned when a table cannot be reached from the metric table
+	// through many-to-one relationships
+	ErrNoJoinPath = errors.New("no join path between tables")
+	// ErrNoTimeDimension is returned when a time range is requested but no time dimension applies
+	ErrNoTimeDimension = errors.New("no time dimension for the time range")
+	// ErrUnsupportedTimeRange is returned for time periods the compiler does not know
+	ErrUnsupportedTimeRange = errors.New("unsupported time range")
+	// ErrInvalidFilter is returned for filters with an unknown operator or an unusable value
+	ErrInvalidFilter = errors.New("invalid filter")
+	// ErrUnsupportedDialect is returned by DialectFor for unknown SQL dialects
+	ErrUnsupportedDialect = errors.New("unsupported SQL dialect")
+)
+
+// Query is a compiled query
+type Query struct {
+	SQL string `json:"sql"`
+	// Table is the table metrics are read from; other tables are joined to it
+	Table string `json:"table"`
+	// Columns are the output column names, dimensions first
+	Columns []string `json:"columns"`
+}
+
+// Compile turns a parsed query into SQL against the model. Metrics and aggregations
+// are read from a single table; dimensions and filters on other tables are joined
+// along many-to-one relationships, so joins never duplicate metric rows. Joins
+// requested by the parsed query only choose the join type of tables the query
+// already needs. Every name must resolve; anything the model cannot express is
+// an error rather than a guess.
+func Compile(model *Model, pq *models.ParsedQuery, dialect Dialect) (*Query, error) {
+	if pq == nil {
+		return nil, ErrEmptyQuery
+	}
+	c := &compiler{
+		model:   model,
+		dialect: dialect,
+		aliases: make(map[string]string),
+	}
+	if err := c.compile(pq); err != nil {
+		return nil, err
+	}
+	return &Query{SQL: c.sql(), Table: c.base, Columns: c.columns()}, nil
+}
+
+type selectItem struct {
+	expr  string
+	alias string
+}
+
+type join struct {
+	kind  string
+	table string
+	on    string
+}
+
+type compiler struct {
+	model   *Model
+	dialect Dialect
+
+	base       string
+	metric     *Metric // first selected metric, whose time dimension time ranges use
+	dimensions []selectItem
+	measures   []selectItem
+	aliases    map[string]string // normalized name -> output alias
+	tables     []string          // tables referenced besides base, in order of use
+	joins      []join
+	where      string
+	having     string
+	orderBy    []string
+	limit      int
+}
+
+func (c *compiler) compile(pq *models.ParsedQuery) error {
+	if err := c.resolveMetrics(pq); err != nil {
+		return err
+	}
+	if err := c.resolveDimensions(append(append([]string{}, pq.Dimensions...), pq.GroupBy...)); err != nil {
+		return err
+	}
+	if err := c.resolveAggregations(pq.Aggregations); err != nil {
+		return err
+	}
+	if len(c.dimensions) == 0 && len(c.measures) == 0 {
+		return ErrEmptyQuery
+	}
+
+	var timeConditions []string
+	if pq.TimeRange != nil {
+		var err error
+		if timeConditions, err = c.timeRange(pq.TimeRange); err != nil {
+			return err
+		}
+	}
+	if err := c.resolveFilters(pq.Filters, timeConditions); err != nil {
+		return err
+	}
+	if err := c.resolveJoins(pq.Joins); err != nil {
+		return err
+	}
+	if err := c.resolveSort(pq.SortBy); err != nil {
+		return err
+	}
+	if pq.Limit != nil && *pq.Limit > 0 {
+		c.limit = *pq.Limit
+	}
+	return nil
+}
+
+// resolveMetrics selects the named metrics, and aggregations whose field is a metric
+func (c *compiler) resolveMetrics(pq *models.ParsedQuery) error {
+	names := append([]string{}, pq.Metrics...)
+	for _, agg := range pq.Aggregations {
+		if _, ok := c.model.Metric(agg.Field); ok {
+			names = append(names, agg.Field)
+		}
+	}
+
+	for _, name := range names {
+		metric, ok := c.model.Metric(name)
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownMetric, name)
+		}
+		if c.base == "" {
+			c.base = metric.Table
+			c.metric = metric
+		} else if !strings.EqualFold(c.base, metric.Table) {
+			return fmt.Errorf("%w: %s and %s", ErrMixedMetricTables, c.base, metric.Table)
+		}
+
+		expr, err := c.metricExpr(metric)
+		if err != nil {
+			return err
+		}
+		c.addMeasure(selectItem{expr: expr, alias: metric.Name})
+	}
+	return nil
+}
+
+func (c *compiler) metricExpr(metric *Metric) (string, error) {
+	if expr := strings.TrimSpace(metric.Expression); expr != "" {
+		return expr, nil
+	}
+
+	col := "*"
+	if metric.Column != "" {
+		col = c.ref(metric.Table, metric.Column)
+	}
+	switch strings.ToLower(metric.Type) {
+	case "sum", "avg", "min", "max":
+		if metric.Column == "" {
+			break
+		}
+		return fmt.Sprintf("%s(%s)", strings.ToUpper(metric.Type), col), nil
+	case "count":
+		return fmt.Sprintf("COUNT(%s)", col), nil
+	case "count_distinct":
+		if metric.Column == "" {
+			break
+		}
+		return fmt.Sprintf("COUNT(DISTINCT %s)", col), nil
+	}
+	return "", fmt.Errorf("%w: %s has no expression for type %q", ErrInvalidMetric, metric.Name, metric.Type)
+}
+
+// resolveDimensions selects dimensions, falling back to raw columns
+func (c *compiler) resolveDimensions(names []string) error {
+	for _, name := range names {
+		table, expr, alias, ok := c.field(name)
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownField, name)
+		}
+		if c.base == "" {
+			c.base = table
+		}
+		c.useTable(table)
+		if _, dup := c.aliases[normalizeName(alias)]; dup {
+			continue
+		}
+		c.aliases[normalizeName(alias)] = alias
+		c.aliases[normalizeName(name)] = alias
+		c.dimensions = append(c.dimensions, selectItem{expr: expr, alias: alias})
+	}
+	return nil
+}
+
+var aggregateFunctions = map[string]bool{"SUM": true, "COUNT": true, "AVG": true, "MIN": true, "MAX": true}
+
+// resolveAggregations selects aggregations over raw columns. Aggregations of
+// metrics were selected with the metrics.
+func (c *compiler) resolveAggregations(aggs []models.Aggregation) error {
+	for _, agg := range aggs {
+		if _, ok := c.model.Metric(agg.Field); ok {
+			continue
+		}
+
+		function := strings.ToUpper(strings.TrimSpace(agg.Function))
+		if !aggregateFunctions[function] {
+			return fmt.Errorf("%w: aggregation %s", ErrUnknownField, agg.Function)
+		}
+
+		var expr, alias string
+		if agg.Field == "" || agg.Field == "*" {
+			if function != "COUNT" || c.base == "" {
+				return fmt.Errorf("%w: %s(%s)", ErrUnknownField, function, agg.Field)
+			}
+			expr, alias = "COUNT(*)", "count"
+		} else {
+			table, column, ok := c.model.column(agg.Field, c.base)
+			if !ok {
+				return fmt.Errorf("%w: %s", ErrUnknownField, agg.Field)
+			}
+			if c.base == "" {
+				c.base = table
+			}
+			c.useTable(table)
+			expr = fmt.Sprintf("%s(%s)", function, c.ref(table, column))
+			alias = strings.ToLower(function) + "_" + column
+		}
+		if agg.Alias != "" {
+			alias = agg.Alias
+		}
+		c.addMeasure(selectItem{expr: expr, alias: alias})
+	}
+	return nil
+}
+
+func (c *compiler) addMeasure(item selectItem) {
+	key := normalizeName(item.alias)
+	if _, dup := c.aliases[key]; dup {
+		return
+	}
+	c.aliases[key] = item.alias
+	c.measures = append(c.measures, item)
+}
+
+// field resolves a dimension name or raw column to a table, SQL expression and output name
+func (c *compiler) field(name string) (table, expr, alias string, ok bool) {
+	if dim, found := c.model.Dimension(name); found {
+		return dim.Table, c.dimensionExpr(dim), dim.Name, true
+	}
+	if table, column, found := c.model.column(name, c.base); found {
+		return table, c.ref(table, column), column, true
+	}
+	return "", "", "", false
+}
+
+func (c *compiler) dimensionExpr(dim *Dimension) string {
+	if expr := strings.TrimSpace(dim.Expression); expr != "" {
+		return expr
+	}
+	return c.ref(dim.Table, dim.Column)
+}
+
+var periodPattern = regexp.MustCompile(`^last_(?:(\d+)_)?(day|week|month|quarter|year)s?$`)
+
+// timeRange returns the conditions restricting the metric's time dimension. Start
+// and End take precedence over relative periods, which are rolling windows ending today.
+func (c *compiler) timeRange(tr *models.TimeRange) ([]string, error) {
+	if tr.Start == nil && tr.End == nil && tr.Relative == "" && tr.Period == "" {
+		return nil, nil
+	}
+
+	dim, ok := c.model.timeDimension(c.metric, c.base)
+	if !ok {
+		return nil, fmt.Errorf("%w on %s", ErrNoTimeDimension, c.base)
+	}
+	c.useTable(dim.Table)
+	col := c.dimensionExpr(dim)
+
+	if tr.Start != nil || tr.End != nil {
+		var conds []string
+		if tr.Start != nil {
+			conds = append(conds, fmt.Sprintf("%s >= %s", col, c.timestamp(*tr.Start)))
+		}
+		if tr.End != nil {
+			conds = append(conds, fmt.Sprintf("%s < %s", col, c.timestamp(*tr.End)))
+		}
+		return conds, nil
+	}
+
+	period := tr.Relative
+	if period == "" {
+		period = tr.Period
+	}
+	period = normalizeName(period)
+
+	switch period {
+	case "today":
+		return []string{fmt.Sprintf("%s >= CURRENT_DATE", col)}, nil
+	case "yesterday":
+		return []string{
+			fmt.Sprintf("%s >= %s", col, c.dialect.DateSub(1, "day")),
+			fmt.Sprintf("%s < CURRENT_DATE", col),
+		}, nil
+	}
+
+	m := periodPattern.FindStringSubmatch(period)
+	if m == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedTimeRange, period)
+	}
+	n := 1
+	if m[1] != "" {
+		n, _ = strconv.Atoi(m[1])
+	}
+	unit := m[2]
+	switch unit {
+	case "week":
+		n, unit = n*7, "day"
+	case "quarter":
+		n, unit = n*3, "month"
+	}
+	return []string{fmt.Sprintf("%s >= %s", col, c.dialect.DateSub(n, unit))}, nil
+}
+
+func (c *compiler) timestamp(t time.Time) string {
+	return "TIMESTAMP " + c.dialect.QuoteString(t.UTC().Format("2006-01-02 15:04:05"))
+}
+
+// resolveFilters builds WHERE from filters on dimensions and columns and HAVING
+// from filters on metrics. Conditions combine left to right, each filter joined
+// to the ones before it by its own AND/OR.
+func (c *compiler) resolveFilters(filters []models.Filter, timeConditions []string) error {
+	var where, having string
+	for _, f := range filters {
+		var target string
+		var isMetric bool
+		if metric, ok := c.model.Metric(f.Field); ok && strings.EqualFold(metric.Table, c.base) {
+			expr, err := c.metricExpr(metric)
+			if err != nil {
+				return err
+			}
+			target, isMetric = expr, true
+		} else {
+			name := f.Field
+			if f.Table != "" {
+				name = f.Table + "." + f.Field
+			}
+			table, expr, _, ok := c.field(name)
+			if !ok {
+				return fmt.Errorf("%w: %s", ErrUnknownField, f.Field)
+			}
+			c.useTable(table)
+			target = expr
+		}
+
+		cond, err := c.condition(target, f)
+		if err != nil {
+			return err
+		}
+		if isMetric {
+			having = combine(having, cond, f.Condition)
+		} else {
+			where = combine(where, cond, f.Condition)
+		}
+	}
+
+	// combine leaves ORs parenthesized, so the time range applies to every filter
+	for _, cond := range timeConditions {
+		where = combine(where, cond, "AND")
+	}
+
+	c.where, c.having = where, having
+	return nil
+}
+
+// combine appends cond to expr, parenthesizing ORs so conditions group left to right
+func combine(expr, cond, conjunction string) string {
+	if expr == "" {
+		return cond
+	}
+	if strings.EqualFold(strings.TrimSpace(conjunction), "OR") {
+		return "(" + expr + " OR " + cond + ")"
+	}
+	return expr + " AND " + cond
+}
+
+var filterOperators = map[string]string{
+	"=": "=", "==": "=", "!=": "!=", "<>": "!=",
+	">": ">", "<": "<", ">=": ">=", "<=": "<=",
+	"IN": "IN", "NOT IN": "NOT IN", "LIKE": "LIKE", "NOT LIKE": "NOT LIKE",
+}
+
+func (c *compiler) condition(target string, f models.Filter) (string, error) {
+	op, ok := filterOperators[strings.Join(strings.Fields(strings.ToUpper(f.Operator)), " ")]
+	if !ok {
+		return "", fmt.Errorf("%w: operator %q on %s", ErrInvalidFilter, f.Operator, f.Field)
+	}
+
+	if f.Value == nil {
+		switch op {
+		case "=":
+			return target + " IS NULL", nil
+		case "!=":
+			return target + " IS NOT NULL", nil
+		}
+		return "", fmt.Errorf("%w: %s %s needs a value", ErrInvalidFilter, f.Field, op)
+	}
+
+	if op == "IN" || op == "NOT IN" {
+		values, err := c.literals(f.Value)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s: %v", ErrInvalidFilter, f.Field, err)
+		}
+		if len(values) == 0 {
+			return "", fmt.Errorf("%w: %s %s needs at least one value", ErrInvalidFilter, f.Field, op)
+		}
+		return fmt.Sprintf("%s %s (%s)", target, op, strings.Join(values, ", ")), nil
+	}
+
+	value, err := c.literal(f.Value)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %v", ErrInvalidFilter, f.Field, err)
+	}
+	return fmt.Sprintf("%s %s %s", target, op, value), nil
+}
+
+func (c *compiler) literals(value interface{}) ([]string, error) {
+	var items []interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		items = v
+	case []string:
+		for _, s := range v {
+			items = append(items, s)
+		}
+	default:
+		items = []interface{}{v}
+	}
+
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		lit, err := c.literal(item)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, lit)
+	}
+	return values, nil
+}
+
+func (c *compiler) literal(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return c.dialect.QuoteString(v), nil
+	case bool:
+		if v {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), nil
+	case int, int32, int64:
+		return fmt.Sprintf("%d", v), nil
+	case time.Time:
+		return c.timestamp(v), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v (%T)", value, value)
+	}
+}
+
+// resolveJoins finds a many-to-one path from the base table to every table the
+// query uses. Joins in the parsed query set the join type of a table; the default
+// is LEFT so rows without a match still count towards metrics.
+func (c *compiler) resolveJoins(requested []models.Join) error {
+	joined := map[string]bool{strings.ToLower(c.base): true}
+	for _, table := range c.tables {
+		path, ok := c.joinPath(table)
+		if !ok {
+			return fmt.Errorf("%w: %s to %s", ErrNoJoinPath, c.base, table)
+		}
+		for _, rel := range path {
+			from, fromCol, to, toCol := rel.FromTable, rel.FromColumn, rel.ToTable, rel.ToColumn
+			if joined[strings.ToLower(to)] {
+				// one-to-one relationship followed from its referenced side
+				from, fromCol, to, toCol = to, toCol, from, fromCol
+			}
+			if joined[strings.ToLower(to)] {
+				continue
+			}
+			joined[strings.ToLower(to)] = true
+			c.joins = append(c.joins, join{
+				kind:  joinKind(requested, to),
+				table: to,
+				on:    fmt.Sprintf("%s = %s", c.ref(from, fromCol), c.ref(to, toCol)),
+			})
+		}
+	}
+	return nil
+}
+
+// joinPath finds the relationships leading from the base table to table, following
+// foreign keys towards the referenced table, or either way for one-to-one keys
+func (c *compiler) joinPath(table string) ([]schema.TableRelationship, bool) {
+	type step struct {
+		table string
+		path  []schema.TableRelationship
+	}
+	seen := map[string]bool{strings.ToLower(c.base): true}
+	queue := []step{{table: c.base}}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if strings.EqualFold(current.table, table) {
+			return current.path, true
+		}
+
+		for _, rel := range c.model.Relationships {
+			var next string
+			switch {
+			case strings.EqualFold(rel.FromTable, current.table):
+				next = rel.ToTable
+			case strings.EqualFold(rel.ToTable, current.table) && rel.RelationType == "one_to_one":
+				next = rel.FromTable
+			default:
+				continue
+			}
+			if seen[strings.ToLower(next)] {
+				continue
+			}
+			seen[strings.ToLower(next)] = true
+			path := append(append([]schema.TableRelationship{}, current.path...), rel)
+			queue = append(queue, step{table: next, path: path})
+		}
+	}
+	return nil, false
+}
+
+func joinKind(requested []models.Join, table string) string {
+	for _, j := range requested {
+		if !strings.EqualFold(j.Table, table) {
+			continue
+		}
+		switch kind := strings.ToUpper(strings.TrimSpace(j.Type)); kind {
+		case "INNER", "LEFT", "RIGHT", "FULL":
+			return kind
+		}
+	}
+	return "LEFT"
+}
+
+// resolveSort orders by selected columns only
+func (c *compiler) resolveSort(sorts []models.SortCriteria) error {
+	for _, s := range sorts {
+		alias, ok := c.aliases[normalizeName(s.Field)]
+		if !ok {
+			if metric, found := c.model.Metric(s.Field); found {
+				alias, ok = c.aliases[normalizeName(metric.Name)]
+			}
+		}
+		if !ok {
+			return fmt.Errorf("%w: sort by %s, which is not selected", ErrUnknownField, s.Field)
+		}
+		direction := "ASC"
+		if strings.HasPrefix(strings.ToUpper(strings.TrimSpace(s.Direction)), "DESC") {
+			direction = "DESC"
+		}
+		c.orderBy = append(c.orderBy, fmt.Sprintf("%s %s", c.dialect.QuoteIdentifier(alias), direction))
+	}
+	return nil
+}
+
+// useTable records that the query reads from table
+func (c *compiler) useTable(table string) {
+	if strings.EqualFold(table, c.base) {
+		return
+	}
+	for _, t := range c.tables {
+		if strings.EqualFold(t, table) {
+			return
+		}
+	}
+	c.tables = append(c.tables, table)
+}
+
+func (c *compiler) ref(table, column string) string {
+	return c.dialect.QuoteIdentifier(table) + "." + c.dialect.QuoteIdentifier(column)
+}
+
+// tableRef names a table in FROM and JOIN, qualified by its schema when known
+func (c *compiler) tableRef(name string) string {
+	if t, ok := c.model.Table(name); ok && t.Schema != "" {
+		return c.dialect.QuoteIdentifier(t.Schema) + "." + c.dialect.QuoteIdentifier(t.Name)
+	}
+	return c.dialect.QuoteIdentifier(name)
+}
+
+func (c *compiler) columns() []string {
+	var columns []string
+	for _, item := range append(append([]selectItem{}, c.dimensions...), c.measures...) {
+		columns = append(columns, item.alias)
+	}
+	return columns
+}
+
+func (c *compiler) sql() string {
+	var selects, groupBy []string
+	for _, item := range c.dimensions {
+		selects = append(selects, fmt.Sprintf("%s AS %s", item.expr, c.dialect.QuoteIdentifier(item.alias)))
+		groupBy = append(groupBy, item.expr)
+	}
+	for _, item := range c.measures {
+		selects = append(selects, fmt.Sprintf("%s AS %s", item.expr, c.dialect.QuoteIdentifier(item.alias)))
+	}
+
+	var b strings.Builder
+	b.WriteString("SELECT " + strings.Join(selects, ", "))
+	b.WriteString("\nFROM " + c.tableRef(c.base))
+	for _, j := range c.joins {
+		b.WriteString(fmt.Sprintf("\n%s JOIN %s ON %s", j.kind, c.tableRef(j.table), j.on))
+	}
+	if c.where != "" {
+		b.WriteString("\nWHERE " + c.where)
+	}
+	if len(groupBy) > 0 {
+		b.WriteString("\nGROUP BY " + strings.Join(groupBy, ", "))
+	}
+	if c.having != "" {
+		b.WriteString("\nHAVING " + c.having)
+	}
+	if len(c.orderBy) > 0 {
+		b.WriteString("\nORDER BY " + strings.Join(c.orderBy, ", "))
+	}
+	if c.limit > 0 {
+		b.WriteString(fmt.Sprintf("\nLIMIT %d", c.limit))
+	}
+	return b.String()
+}
@@ -0,0 +1,291 @@
+package semantic
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"insightiq/backend/internal/models"
+	"insightiq/backend/internal/schema"
+)
+
+func testModel() *Model {
+	return &Model{
+		Tables: []Table{
+			{Name: "orders", Schema: "public", Columns: []string{"id", "customer_id", "amount", "status", "created_at"}},
+			{Name: "customers", Schema: "public", Columns: []string{"id", "name", "region", "country_id"}},
+			{Name: "countries", Columns: []string{"id", "name"}},
+			{Name: "order_items", Columns: []string{"order_id", "product", "quantity"}},
+		},
+		Metrics: []Metric{
+			{Name: "revenue", Table: "orders", Type: "sum", Column: "amount", Synonyms: []string{"sales"}},
+			{Name: "order_count", Table: "orders", Type: "count", Synonyms: []string{"orders"}},
+			{Name: "average_order_value", Table: "orders", Expression: "SUM(amount) / COUNT(*)"},
+			{Name: "items_sold", Table: "order_items", Type: "sum", Column: "quantity"},
+		},
+		Dimensions: []Dimension{
+			{Name: "status", Table: "orders", Column: "status"},
+			{Name: "created_at", Table: "orders", Column: "created_at", Time: true},
+			{Name: "region", Table: "customers", Column: "region"},
+			{Name: "country", Table: "countries", Column: "name", Synonyms: []string{"nation"}},
+		},
+		Relationships: []schema.TableRelationship{
+			{FromTable: "orders", FromColumn: "customer_id", ToTable: "customers", ToColumn: "id", RelationType: "many_to_one"},
+			{FromTable: "customers", FromColumn: "country_id", ToTable: "countries", ToColumn: "id", RelationType: "many_to_one"},
+			{FromTable: "order_items", FromColumn: "order_id", ToTable: "orders", ToColumn: "id", RelationType: "many_to_one"},
+		},
+	}
+}
+
+func intPtr(n int) *int { return &n }
+
+func TestCompile(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		dialect string
+		defs    []models.SemanticDefinition
+		query   models.ParsedQuery
+		want    string
+		wantErr error
+	}{
+		{
+			name:    "metric by dimension",
+			dialect: "PostgreSQL",
+			query:   models.ParsedQuery{Metrics: []string{"revenue"}, Dimensions: []string{"status"}},
+			want: `SELECT "orders"."status" AS "status", SUM("orders"."amount") AS "revenue"
+FROM "public"."orders"
+GROUP BY "orders"."status"`,
+		},
+		{
+			name:    "synonyms, sort and limit",
+			dialect: "PostgreSQL",
+			query: models.ParsedQuery{
+				Metrics:    []string{"Sales"},
+				Dimensions: []string{"region"},
+				SortBy:     []models.SortCriteria{{Field: "sales", Direction: "desc"}},
+				Limit:      intPtr(5),
+			},
+			want: `SELECT "customers"."region" AS "region", SUM("orders"."amount") AS "revenue"
+FROM "public"."orders"
+LEFT JOIN "public"."customers" ON "orders"."customer_id" = "customers"."id"
+GROUP BY "customers"."region"
+ORDER BY "revenue" DESC
+LIMIT 5`,
+		},
+		{
+			name:    "join through intermediate table with requested join type",
+			dialect: "PostgreSQL",
+			query: models.ParsedQuery{
+				Metrics:    []string{"order_count"},
+				Dimensions: []string{"nation"},
+				Joins:      []models.Join{{Type: "inner", Table: "countries"}},
+			},
+			want: `SELECT "countries"."name" AS "country", COUNT(*) AS "order_count"
+FROM "public"."orders"
+LEFT JOIN "public"."customers" ON "orders"."customer_id" = "customers"."id"
+INNER JOIN "countries" ON "customers"."country_id" = "countries"."id"
+GROUP BY "countries"."name"`,
+		},
+		{
+			name:    "filters combine left to right with a relative time range",
+			dialect: "PostgreSQL",
+			query: models.ParsedQuery{
+				Metrics: []string{"revenue"},
+				Filters: []models.Filter{
+					{Field: "status", Operator: "IN", Value: []interface{}{"paid", "shipped"}},
+					{Field: "region", Operator: "=", Value: "EMEA", Condition: "OR"},
+				},
+				TimeRange: &models.TimeRange{Relative: "last_30_days"},
+			},
+			want: `SELECT SUM("orders"."amount") AS "revenue"
+FROM "public"."orders"
+LEFT JOIN "public"."customers" ON "orders"."customer_id" = "customers"."id"
+WHERE ("orders"."status" IN ('paid', 'shipped') OR "customers"."region" = 'EMEA') AND "orders"."created_at" >= CURRENT_DATE - INTERVAL '30 day'`,
+		},
+		{
+			name:    "metric filters become HAVING",
+			dialect: "PostgreSQL",
+			query: models.ParsedQuery{
+				Metrics:    []string{"revenue"},
+				Dimensions: []string{"status"},
+				Filters:    []models.Filter{{Field: "revenue", Operator: ">", Value: 1000.5}},
+			},
+			want: `SELECT "orders"."status" AS "status", SUM("orders"."amount") AS "revenue"
+FROM "public"."orders"
+GROUP BY "orders"."status"
+HAVING SUM("orders"."amount") > 1000.5`,
+		},
+		{
+			name:    "explicit time bounds and null filter",
+			dialect: "PostgreSQL",
+			query: models.ParsedQuery{
+				Metrics:   []string{"average_order_value"},
+				Filters:   []models.Filter{{Field: "customer_id", Operator: "!=", Value: nil}},
+				TimeRange: &models.TimeRange{Start: &start, End: &end},
+			},
+			want: `SELECT SUM(amount) / COUNT(*) AS "average_order_value"
+FROM "public"."orders"
+WHERE "orders"."customer_id" IS NOT NULL AND "orders"."created_at" >= TIMESTAMP '2024-01-01 00:00:00' AND "orders"."created_at" < TIMESTAMP '2024-04-01 00:00:00'`,
+		},
+		{
+			name:    "raw column aggregations",
+			dialect: "PostgreSQL",
+			query: models.ParsedQuery{
+				GroupBy:      []string{"order_items.product"},
+				Aggregations: []models.Aggregation{{Function: "max", Field: "quantity"}, {Function: "COUNT", Field: "*", Alias: "lines"}},
+			},
+			want: `SELECT "order_items"."product" AS "product", MAX("order_items"."quantity") AS "max_quantity", COUNT(*) AS "lines"
+FROM "order_items"
+GROUP BY "order_items"."product"`,
+		},
+		{
+			name:    "mysql quoting, escaping and intervals",
+			dialect: "MySQL",
+			query: models.ParsedQuery{
+				Metrics:    []string{"revenue"},
+				Dimensions: []string{"region"},
+				Filters:    []models.Filter{{Field: "status", Operator: "like", Value: `it's\%`}},
+				TimeRange:  &models.TimeRange{Period: "last_quarter"},
+			},
+			want: "SELECT `customers`.`region` AS `region`, SUM(`orders`.`amount`) AS `revenue`\n" +
+				"FROM `public`.`orders`\n" +
+				"LEFT JOIN `public`.`customers` ON `orders`.`customer_id` = `customers`.`id`\n" +
+				"WHERE `orders`.`status` LIKE 'it''s\\\\%' AND `orders`.`created_at` >= CURRENT_DATE - INTERVAL 3 MONTH\n" +
+				"GROUP BY `customers`.`region`",
+		},
+		{
+			name:    "yesterday",
+			dialect: "PostgreSQL",
+			query:   models.ParsedQuery{Metrics: []string{"order_count"}, TimeRange: &models.TimeRange{Relative: "yesterday"}},
+			want: `SELECT COUNT(*) AS "order_count"
+FROM "public"."orders"
+WHERE "orders"."created_at" >= CURRENT_DATE - INTERVAL '1 day' AND "orders"."created_at" < CURRENT_DATE`,
+		},
+		{
+			name:    "certified definitions replace derived metrics and win synonyms",
+			dialect: "PostgreSQL",
+			defs: []models.SemanticDefinition{
+				{Kind: models.DefinitionKindMetric, SemanticDefinitionSpec: models.SemanticDefinitionSpec{
+					Name: "net_revenue", Table: "orders", Expression: "amount - discount", Aggregation: "sum",
+					Synonyms: []string{"sales"}, Certified: true,
+				}},
+				{Kind: models.DefinitionKindDimension, SemanticDefinitionSpec: models.SemanticDefinitionSpec{
+					Name: "status", Table: "orders", Expression: "UPPER(status)",
+				}},
+			},
+			query: models.ParsedQuery{Metrics: []string{"sales"}, Dimensions: []string{"status"}},
+			want: `SELECT UPPER(status) AS "status", SUM(amount - discount) AS "net_revenue"
+FROM "public"."orders"
+GROUP BY UPPER(status)`,
+		},
+		{
+			name:    "unknown metric",
+			dialect: "PostgreSQL",
+			query:   models.ParsedQuery{Metrics: []string{"churn"}},
+			wantErr: ErrUnknownMetric,
+		},
+		{
+			name:    "unknown dimension",
+			dialect: "PostgreSQL",
+			query:   models.ParsedQuery{Metrics: []string{"revenue"}, Dimensions: []string{"channel"}},
+			wantErr: ErrUnknownField,
+		},
+		{
+			name:    "metrics on different tables",
+			dialect: "PostgreSQL",
+			query:   models.ParsedQuery{Metrics: []string{"revenue", "items_sold"}},
+			wantErr: ErrMixedMetricTables,
+		},
+		{
+			name:    "join that would duplicate metric rows",
+			dialect: "PostgreSQL",
+			query:   models.ParsedQuery{Metrics: []string{"revenue"}, Dimensions: []string{"order_items.product"}},
+			wantErr: ErrNoJoinPath,
+		},
+		{
+			name:    "time range without a time dimension",
+			dialect: "PostgreSQL",
+			query:   models.ParsedQuery{Metrics: []string{"items_sold"}, TimeRange: &models.TimeRange{Period: "last_week"}},
+			wantErr: ErrNoTimeDimension,
+		},
+		{
+			name:    "unsupported time range",
+			dialect: "PostgreSQL",
+			query:   models.ParsedQuery{Metrics: []string{"revenue"}, TimeRange: &models.TimeRange{Period: "fiscal_ytd"}},
+			wantErr: ErrUnsupportedTimeRange,
+		},
+		{
+			name:    "unknown filter operator",
+			dialect: "PostgreSQL",
+			query:   models.ParsedQuery{Metrics: []string{"revenue"}, Filters: []models.Filter{{Field: "status", Operator: "~", Value: "x"}}},
+			wantErr: ErrInvalidFilter,
+		},
+		{
+			name:    "sort by a column that is not selected",
+			dialect: "PostgreSQL",
+			query:   models.ParsedQuery{Metrics: []string{"revenue"}, SortBy: []models.SortCriteria{{Field: "status"}}},
+			wantErr: ErrUnknownField,
+		},
+		{
+			name:    "empty query",
+			dialect: "PostgreSQL",
+			query:   models.ParsedQuery{MainAction: "show"},
+			wantErr: ErrEmptyQuery,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dialect, err := DialectFor(tt.dialect)
+			if err != nil {
+				t.Fatalf("DialectFor(%q) error = %v", tt.dialect, err)
+			}
+
+			model := testModel()
+			model.ApplyDefinitions(tt.defs)
+
+			got, err := Compile(model, &tt.query, dialect)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Compile() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			if got.SQL != tt.want {
+				t.Errorf("Compile() SQL =\n%s\nwant\n%s", got.SQL, tt.want)
+			}
+		})
+	}
+}
+
+func TestDialectFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "PostgreSQL", want: "PostgreSQL"},
+		{name: "postgres", want: "PostgreSQL"},
+		{name: "MariaDB", want: "MySQL"},
+		{name: "Oracle", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := DialectFor(tt.name)
+		if tt.wantErr {
+			if !errors.Is(err, ErrUnsupportedDialect) {
+				t.Errorf("DialectFor(%q) error = %v, want ErrUnsupportedDialect", tt.name, err)
+			}
+			continue
+		}
+		if err != nil || got.Name() != tt.want {
+			t.Errorf("DialectFor(%q) = %v, %v, want %s", tt.name, got, err, tt.want)
+		}
+	}
+}
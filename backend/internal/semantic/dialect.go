@@ -0,0 +1,63 @@
+package semantic
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dialect formats the parts of a query that differ between SQL engines
+type Dialect interface {
+	// Name is the dialect name used in prompts and errors, e.g. "PostgreSQL"
+	Name() string
+	QuoteIdentifier(name string) string
+	QuoteString(value string) string
+	// DateSub is an expression for the current date minus n units of day, month or year
+	DateSub(n int, unit string) string
+}
+
+// DialectFor returns the dialect for a connector's SQL dialect name, such as the
+// SQLDialect of a driver or the sql_dialect of a Superset connector
+func DialectFor(name string) (Dialect, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "postgresql", "postgres":
+		return postgresDialect{}, nil
+	case "mysql", "mariadb":
+		return mysqlDialect{}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedDialect, name)
+	}
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string { return "PostgreSQL" }
+
+func (postgresDialect) QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (postgresDialect) QuoteString(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+func (postgresDialect) DateSub(n int, unit string) string {
+	return fmt.Sprintf("CURRENT_DATE - INTERVAL '%d %s'", n, unit)
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string { return "MySQL" }
+
+func (mysqlDialect) QuoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// QuoteString also escapes backslashes, which MySQL treats as escape characters by default
+func (mysqlDialect) QuoteString(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+func (mysqlDialect) DateSub(n int, unit string) string {
+	return fmt.Sprintf("CURRENT_DATE - INTERVAL %d %s", n, strings.ToUpper(unit))
+}
@@ -0,0 +1,320 @@
+// Package semantic compiles parsed questions into SQL against declared metrics,
+// dimensions and join paths, so common questions are answered without an LLM
+// writing the query.
+package semantic
+
+import (
+	"fmt"
+	"strings"
+
+	"insightiq/backend/internal/models"
+	"insightiq/backend/internal/schema"
+)
+
+// Metric is an aggregate the compiler can select
+type Metric struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Table       string `json:"table"`
+	// Expression is a SQL aggregate over Table, e.g. SUM(amount) / COUNT(*). When
+	// empty, Type is applied to Column.
+	Expression string `json:"expression,omitempty"`
+	Type       string `json:"type,omitempty"` // sum, count, count_distinct, avg, min, max
+	Column     string `json:"column,omitempty"`
+	// TimeDimension names the dimension time ranges filter on; defaults to the
+	// first time dimension of Table
+	TimeDimension string   `json:"time_dimension,omitempty"`
+	Synonyms      []string `json:"synonyms,omitempty"`
+	// Certified metrics win over others sharing a name or synonym
+	Certified bool `json:"certified,omitempty"`
+}
+
+// Dimension is a column questions can group and filter by
+type Dimension struct {
+	Name   string `json:"name"`
+	Table  string `json:"table"`
+	Column string `json:"column"`
+	// Expression is SQL over Table used instead of Column, e.g. LOWER(country)
+	Expression string   `json:"expression,omitempty"`
+	Time       bool     `json:"time,omitempty"`
+	Synonyms   []string `json:"synonyms,omitempty"`
+	Certified  bool     `json:"certified,omitempty"`
+}
+
+// Table is a table the compiler can read from
+type Table struct {
+	Name    string   `json:"name"`
+	Schema  string   `json:"schema,omitempty"`
+	Columns []string `json:"columns"`
+}
+
+// Model declares what questions can be compiled against
+type Model struct {
+	Tables        []Table                    `json:"tables"`
+	Metrics       []Metric                   `json:"metrics"`
+	Dimensions    []Dimension                `json:"dimensions"`
+	Relationships []schema.TableRelationship `json:"relationships,omitempty"`
+}
+
+// ModelFromSchema derives a model from a scanned schema: business metrics become
+// metrics, dimension and datetime columns become dimensions, and foreign keys
+// become join paths
+func ModelFromSchema(schemaCtx *schema.SchemaContext) *Model {
+	model := &Model{Relationships: schemaCtx.Relationships}
+
+	for _, table := range schemaCtx.Tables {
+		t := Table{Name: table.TableName, Schema: table.Schema}
+		for _, col := range table.Columns {
+			t.Columns = append(t.Columns, col.Name)
+			if col.IsDimension || col.IsDatetime {
+				model.Dimensions = append(model.Dimensions, Dimension{
+					Name:   col.Name,
+					Table:  table.TableName,
+					Column: col.Name,
+					Time:   col.IsDatetime,
+				})
+			}
+		}
+		model.Tables = append(model.Tables, t)
+	}
+
+	for _, metric := range schemaCtx.BusinessMetrics {
+		model.Metrics = append(model.Metrics, Metric{
+			Name:          metric.Name,
+			Description:   metric.Description,
+			Table:         metric.Table,
+			Expression:    metric.Expression,
+			Type:          metric.Type,
+			Column:        metric.Column,
+			TimeDimension: metric.TimeDimension,
+			Synonyms:      metric.Keywords,
+		})
+	}
+
+	return model
+}
+
+// ApplyDefinitions adds user-maintained definitions to the model. A definition
+// replaces a derived metric or dimension of the same name, and certified
+// definitions come first so they win lookups.
+func (m *Model) ApplyDefinitions(defs []models.SemanticDefinition) {
+	var metrics []Metric
+	var dimensions []Dimension
+	for _, def := range defs {
+		switch def.Kind {
+		case models.DefinitionKindMetric:
+			metrics = append(metrics, metricFromDefinition(def))
+		case models.DefinitionKindDimension:
+			dimensions = append(dimensions, dimensionFromDefinition(def))
+		}
+	}
+
+	for _, metric := range m.Metrics {
+		if !containsName(len(metrics), func(i int) string { return metrics[i].Name }, metric.Name) {
+			metrics = append(metrics, metric)
+		}
+	}
+	for _, dim := range m.Dimensions {
+		if !containsName(len(dimensions), func(i int) string { return dimensions[i].Name }, dim.Name) {
+			dimensions = append(dimensions, dim)
+		}
+	}
+
+	sortCertifiedFirst(metrics, func(i int) bool { return metrics[i].Certified })
+	sortCertifiedFirst(dimensions, func(i int) bool { return dimensions[i].Certified })
+	m.Metrics, m.Dimensions = metrics, dimensions
+}
+
+func metricFromDefinition(def models.SemanticDefinition) Metric {
+	return Metric{
+		Name:          def.Name,
+		Description:   def.Description,
+		Table:         def.Table,
+		Expression:    MetricExpression(def),
+		TimeDimension: def.TimeDimension,
+		Synonyms:      def.Synonyms,
+		Certified:     def.Certified,
+	}
+}
+
+// MetricExpression is the aggregate SQL of a metric definition
+func MetricExpression(def models.SemanticDefinition) string {
+	switch aggregation := strings.ToLower(def.Aggregation); aggregation {
+	case "":
+		return def.Expression
+	case "count_distinct":
+		return fmt.Sprintf("COUNT(DISTINCT %s)", def.Expression)
+	default:
+		return fmt.Sprintf("%s(%s)", strings.ToUpper(aggregation), def.Expression)
+	}
+}
+
+func dimensionFromDefinition(def models.SemanticDefinition) Dimension {
+	return Dimension{
+		Name:       def.Name,
+		Table:      def.Table,
+		Expression: def.Expression,
+		Synonyms:   def.Synonyms,
+		Certified:  def.Certified,
+	}
+}
+
+func containsName(n int, name func(i int) string, want string) bool {
+	for i := 0; i < n; i++ {
+		if normalizeName(name(i)) == normalizeName(want) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortCertifiedFirst moves certified entries to the front, keeping their order
+func sortCertifiedFirst[T any](items []T, certified func(i int) bool) {
+	var first, rest []T
+	for i := range items {
+		if certified(i) {
+			first = append(first, items[i])
+		} else {
+			rest = append(rest, items[i])
+		}
+	}
+	copy(items, append(first, rest...))
+}
+
+// normalizeName folds a metric or dimension name for matching, so "Order Amount",
+// "order-amount" and "order_amount" are the same name
+func normalizeName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+}
+
+// matches reports whether name refers to a definition called defName or one of its synonyms
+func matches(name, defName string, synonyms []string) bool {
+	if normalizeName(defName) == name {
+		return true
+	}
+	for _, synonym := range synonyms {
+		if normalizeName(synonym) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup finds the entry called name, falling back to a synonym no other entry
+// shares. Certified entries are searched first, so a certified definition wins
+// over derived ones sharing its name or synonym.
+func lookup(n int, name func(i int) string, synonyms func(i int) []string, certified func(i int) bool, want string) (int, bool) {
+	key := normalizeName(want)
+	for _, pass := range []func(i int) bool{certified, func(int) bool { return true }} {
+		for i := 0; i < n; i++ {
+			if pass(i) && normalizeName(name(i)) == key {
+				return i, true
+			}
+		}
+		found := -1
+		for i := 0; i < n; i++ {
+			if pass(i) && matches(key, name(i), synonyms(i)) {
+				if found >= 0 {
+					return -1, false
+				}
+				found = i
+			}
+		}
+		if found >= 0 {
+			return found, true
+		}
+	}
+	return -1, false
+}
+
+// Metric finds a metric by name or synonym
+func (m *Model) Metric(name string) (*Metric, bool) {
+	i, ok := lookup(len(m.Metrics),
+		func(i int) string { return m.Metrics[i].Name },
+		func(i int) []string { return m.Metrics[i].Synonyms },
+		func(i int) bool { return m.Metrics[i].Certified },
+		name)
+	if !ok {
+		return nil, false
+	}
+	return &m.Metrics[i], true
+}
+
+// Dimension finds a dimension by name or synonym, falling back to table.column
+func (m *Model) Dimension(name string) (*Dimension, bool) {
+	i, ok := lookup(len(m.Dimensions),
+		func(i int) string { return m.Dimensions[i].Name },
+		func(i int) []string { return m.Dimensions[i].Synonyms },
+		func(i int) bool { return m.Dimensions[i].Certified },
+		name)
+	if ok {
+		return &m.Dimensions[i], true
+	}
+	key := normalizeName(name)
+	for i := range m.Dimensions {
+		d := &m.Dimensions[i]
+		if d.Column != "" && normalizeName(d.Table+"."+d.Column) == key {
+			return d, true
+		}
+	}
+	return nil, false
+}
+
+// Table finds a table by name
+func (m *Model) Table(name string) (*Table, bool) {
+	for i := range m.Tables {
+		if strings.EqualFold(m.Tables[i].Name, name) {
+			return &m.Tables[i], true
+		}
+	}
+	return nil, false
+}
+
+// column resolves a raw field to a table and column. Fields may be qualified as
+// table.column; bare names prefer preferTable.
+func (m *Model) column(field, preferTable string) (table, column string, ok bool) {
+	if tableName, colName, qualified := strings.Cut(field, "."); qualified {
+		if t, found := m.Table(tableName); found {
+			if col, found := t.columnName(colName); found {
+				return t.Name, col, true
+			}
+		}
+		return "", "", false
+	}
+
+	if t, found := m.Table(preferTable); found {
+		if col, found := t.columnName(field); found {
+			return t.Name, col, true
+		}
+	}
+	for _, t := range m.Tables {
+		if col, found := t.columnName(field); found {
+			return t.Name, col, true
+		}
+	}
+	return "", "", false
+}
+
+// columnName returns the declared spelling of a column of the table
+func (t *Table) columnName(name string) (string, bool) {
+	for _, col := range t.Columns {
+		if strings.EqualFold(col, name) {
+			return col, true
+		}
+	}
+	return "", false
+}
+
+// timeDimension returns the dimension time ranges on a metric filter on
+func (m *Model) timeDimension(metric *Metric, table string) (*Dimension, bool) {
+	if metric != nil && metric.TimeDimension != "" {
+		return m.Dimension(metric.TimeDimension)
+	}
+	for i := range m.Dimensions {
+		if m.Dimensions[i].Time && strings.EqualFold(m.Dimensions[i].Table, table) {
+			return &m.Dimensions[i], true
+		}
+	}
+	return nil, false
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"log/slog"
 	"time"
@@ -36,13 +37,19 @@ type AnalyticsRequest struct {
 }
 
 type AnalyticsResponse struct {
-	Query       string                   `json:"query"`
-	Data        []map[string]interface{} `json:"data"`
-	Insights    string                   `json:"insights"`
-	Timestamp   time.Time                `json:"timestamp"`
-	ProcessTime time.Duration            `json:"process_time"`
-	TaskID      string                   `json:"task_id"`
-	Status      string                   `json:"status"`
+	Query       string                    `json:"query"`
+	Data        []map[string]interface{}  `json:"data"`
+	Insights    string                    `json:"insights"`
+	Timestamp   time.Time                 `json:"timestamp"`
+	ProcessTime time.Duration             `json:"process_time"`
+	TaskID      string                    `json:"task_id"`
+	Status      string                    `json:"status"`
+	Truncated   bool                      `json:"truncated"`
+	Columns     []connectors.ResultColumn `json:"columns,omitempty"`
+	Queries     []GeneratedQuery          `json:"queries,omitempty"`
+	Filters     []models.Filter           `json:"filters,omitempty"`
+	ParsedQuery *models.ParsedQuery       `json:"parsed_query,omitempty"`
+	PlanID      string                    `json:"plan_id,omitempty"`
 }
 
 func NewAnalyticsService(agentManager *agent.Manager, enhancedAnalytics *EnhancedAnalyticsService, connectorService *ConnectorService, llmConn *connectors.OllamaConnector, logger *slog.Logger) *AnalyticsService {
@@ -74,6 +81,29 @@ func (as *AnalyticsService) SetCache(cache *cache.RedisCache) {
 }
 
 func (as *AnalyticsService) ProcessQuery(ctx context.Context, query string) (*AnalyticsResponse, error) {
+	return as.ProcessQueryWithOptions(ctx, query, QueryOptions{})
+}
+
+// QueryOptions adjusts how a text query is answered
+type QueryOptions struct {
+	// Filters, when set, replace the filters recognized in the question
+	Filters *[]models.Filter
+	// UserID owns the plan stored for the question
+	UserID string
+	// OnEvent, when set, is told about each step of answering the question
+	OnEvent QueryEventFunc
+}
+
+func (o QueryOptions) emit(eventType QueryEventType, data interface{}) {
+	if o.OnEvent != nil {
+		o.OnEvent(eventType, data)
+	}
+}
+
+// ProcessQueryWithOptions answers a text query. Corrected filters are only understood
+// by the enhanced analytics path, so such queries are not routed to the Superset agent.
+func (as *AnalyticsService) ProcessQueryWithOptions(ctx context.Context, query string, opts QueryOptions) (*AnalyticsResponse, error) {
+	filters := opts.Filters
 	as.logger.Info("Processing text query", "query", query)
 
 	// Use RAG intent classification if available, otherwise fallback to legacy parsing
@@ -111,9 +141,14 @@ func (as *AnalyticsService) ProcessQuery(ctx context.Context, query string) (*An
 	}
 
 	as.logger.Info("Intent analysis complete", "intent", intentStr, "confidence", confidence, "use_superset", shouldUseSuperset)
+	opts.emit(QueryEventIntent, map[string]interface{}{
+		"intent":       intentStr,
+		"confidence":   confidence,
+		"use_superset": shouldUseSuperset && filters == nil,
+	})
 
 	// Check if this should be routed to Superset agent
-	if shouldUseSuperset {
+	if shouldUseSuperset && filters == nil {
 		as.logger.Info("📊 ROUTING TO SUPERSET AGENT", "intent", intentStr, "confidence", confidence, "query", query)
 		return as.processSupersetQuery(ctx, query)
 	}
@@ -122,11 +157,11 @@ func (as *AnalyticsService) ProcessQuery(ctx context.Context, query string) (*An
 
 	// Use enhanced analytics service if available
 	if as.enhancedAnalytics != nil {
-		req := &EnhancedAnalyticsRequest{Query: query}
+		req := &EnhancedAnalyticsRequest{Query: query, Filters: filters, UserID: opts.UserID, OnEvent: opts.OnEvent}
 		enhancedResponse, err := as.enhancedAnalytics.ProcessQuery(ctx, req)
 		if err == nil {
 			// Convert enhanced response to standard response format
-			return &AnalyticsResponse{
+			response := &AnalyticsResponse{
 				Query:       enhancedResponse.Query,
 				Data:        enhancedResponse.Data,
 				Insights:    enhancedResponse.Analysis,
@@ -134,7 +169,19 @@ func (as *AnalyticsService) ProcessQuery(ctx context.Context, query string) (*An
 				ProcessTime: mustParseDuration(enhancedResponse.ProcessTime),
 				TaskID:      enhancedResponse.TaskID,
 				Status:      enhancedResponse.Status,
-			}, nil
+				Truncated:   enhancedResponse.Truncated,
+				Columns:     enhancedResponse.Columns,
+				Queries:     enhancedResponse.Queries,
+				Filters:     enhancedResponse.Filters,
+				PlanID:      enhancedResponse.PlanID,
+			}
+			if enhancedResponse.Intent != nil {
+				response.ParsedQuery = &enhancedResponse.Intent.ParsedQuery
+			}
+			return response, nil
+		}
+		if errors.Is(err, ErrPlanCancelled) {
+			return nil, err
 		}
 		as.logger.Warn("Enhanced analytics failed, falling back to agent system", "error", err)
 	}
@@ -150,6 +197,7 @@ func (as *AnalyticsService) ProcessQuery(ctx context.Context, query string) (*An
 		ID:      taskID,
 		Type:    "text_query",
 		AgentID: "analytics-1", // Target our analytics agent
+		UserID:  opts.UserID,
 		Payload: map[string]interface{}{
 			"query": query,
 		},
@@ -182,7 +230,7 @@ func (as *AnalyticsService) ProcessQuery(ctx context.Context, query string) (*An
 
 	if result.Status == agent.TaskStatusCompleted {
 		// Extract data from result
-		if data, ok := result.Result["data"].([]map[string]interface{}); ok {
+		if data, ok := taskRows(result.Result["data"]); ok {
 			response.Data = data
 		}
 		if insights, ok := result.Result["insights"].(string); ok {
@@ -201,7 +249,9 @@ func (as *AnalyticsService) ProcessQuery(ctx context.Context, query string) (*An
 	return response, nil
 }
 
-func (as *AnalyticsService) ExecuteCustomSQL(ctx context.Context, sql, question string) (*AnalyticsResponse, error) {
+// ExecuteCustomSQL answers a question with the given SQL. The agent task is queued
+// under userID, so it counts towards that user's share of the queue.
+func (as *AnalyticsService) ExecuteCustomSQL(ctx context.Context, userID, sql, question string) (*AnalyticsResponse, error) {
 	as.logger.Info("Processing SQL query with enhanced analytics", "sql_length", len(sql), "question", question)
 
 	// Use enhanced analytics service if available
@@ -217,6 +267,9 @@ func (as *AnalyticsService) ExecuteCustomSQL(ctx context.Context, sql, question
 				ProcessTime: mustParseDuration(enhancedResponse.ProcessTime),
 				TaskID:      enhancedResponse.TaskID,
 				Status:      enhancedResponse.Status,
+				Truncated:   enhancedResponse.Truncated,
+				Columns:     enhancedResponse.Columns,
+				Queries:     enhancedResponse.Queries,
 			}, nil
 		}
 		as.logger.Warn("Enhanced SQL analytics failed, falling back to agent system", "error", err)
@@ -236,6 +289,7 @@ func (as *AnalyticsService) ExecuteCustomSQL(ctx context.Context, sql, question
 		ID:      taskID,
 		Type:    "sql_query",
 		AgentID: "analytics-1",
+		UserID:  userID,
 		Payload: map[string]interface{}{
 			"sql":      sql,
 			"question": question,
@@ -267,7 +321,7 @@ func (as *AnalyticsService) ExecuteCustomSQL(ctx context.Context, sql, question
 	}
 
 	if result.Status == agent.TaskStatusCompleted {
-		if data, ok := result.Result["data"].([]map[string]interface{}); ok {
+		if data, ok := taskRows(result.Result["data"]); ok {
 			response.Data = data
 		}
 		if insights, ok := result.Result["insights"].(string); ok {
@@ -292,6 +346,7 @@ func (as *AnalyticsService) GetAgentStatus() map[string]interface{} {
 
 	return map[string]interface{}{
 		"agents":       status,
+		"queue":        as.agentManager.QueueMetrics(),
 		"timestamp":    time.Now(),
 		"total_agents": len(status),
 	}
@@ -299,30 +354,34 @@ func (as *AnalyticsService) GetAgentStatus() map[string]interface{} {
 
 // Helper function to wait for task results
 func (as *AnalyticsService) waitForResult(ctx context.Context, taskID string, timeout time.Duration) (*agent.TaskResult, error) {
-	// Get the result from agent manager
-	result := as.agentManager.GetTaskResult(taskID)
-	if result != nil {
-		return result, nil
-	}
-
-	// Wait for the task to complete with polling
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	ticker := time.NewTicker(500 * time.Millisecond)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return nil, fmt.Errorf("task timeout: %s", taskID)
-		case <-ticker.C:
-			result := as.agentManager.GetTaskResult(taskID)
-			if result != nil {
-				return result, nil
+	result, err := as.agentManager.WaitForResult(ctx, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("task timeout: %s: %w", taskID, err)
+	}
+	return result, nil
+}
+
+// taskRows returns the rows of a task result, also when the result was loaded
+// from a shared store and its rows decoded as generic JSON
+func taskRows(value interface{}) ([]map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case []map[string]interface{}:
+		return v, true
+	case []interface{}:
+		rows := make([]map[string]interface{}, 0, len(v))
+		for _, item := range v {
+			row, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, false
 			}
+			rows = append(rows, row)
 		}
+		return rows, true
 	}
+	return nil, false
 }
 
 // Generate unique task ID
@@ -2,36 +2,77 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
-	"strings"
 	"time"
 
 	"insightiq/backend/internal/connectors"
 	"insightiq/backend/internal/models"
 	"insightiq/backend/internal/repository"
+	"insightiq/backend/internal/secrets"
 )
 
+// Connection test results kept per connector
+const healthHistorySize = 500
+
+// ErrInvalidConnectorQuery is returned when a connector query is refused before it
+// runs: SQL that is missing, not read-only or too expensive
+var ErrInvalidConnectorQuery = errors.New("invalid connector query")
+
 type ConnectorService struct {
-	repo   *repository.ConnectorRepository
-	logger *slog.Logger
+	repo      *repository.ConnectorRepository
+	drivers   *connectors.Registry
+	fileStore *connectors.FileStore
+	keyring   *secrets.Keyring
+	logger    *slog.Logger
 }
 
-func NewConnectorService(repo *repository.ConnectorRepository, logger *slog.Logger) *ConnectorService {
+func NewConnectorService(repo *repository.ConnectorRepository, drivers *connectors.Registry, logger *slog.Logger) *ConnectorService {
 	return &ConnectorService{
-		repo:   repo,
-		logger: logger.With("service", "connector"),
+		repo:    repo,
+		drivers: drivers,
+		logger:  logger.With("service", "connector"),
 	}
 }
 
+// Drivers returns the registry of connector drivers
+func (s *ConnectorService) Drivers() *connectors.Registry {
+	return s.drivers
+}
+
+// SetFileStore sets the storage used by file upload connectors
+func (s *ConnectorService) SetFileStore(fileStore *connectors.FileStore) {
+	s.fileStore = fileStore
+}
+
+// SetKeyring sets the keys used to encrypt sensitive config fields at rest.
+// Without a keyring secrets are stored in plaintext.
+func (s *ConnectorService) SetKeyring(keyring *secrets.Keyring) {
+	s.keyring = keyring
+}
+
+// FileStore returns the storage used by file upload connectors, or nil when not configured
+func (s *ConnectorService) FileStore() *connectors.FileStore {
+	return s.fileStore
+}
+
 // GetConnectors retrieves all connectors
 func (s *ConnectorService) GetConnectors(ctx context.Context) ([]*models.DataConnector, error) {
-	return s.repo.GetAll(ctx)
+	connectors, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.openConnectors(connectors), nil
 }
 
 // GetConnector retrieves a specific connector by ID
 func (s *ConnectorService) GetConnector(ctx context.Context, id string) (*models.DataConnector, error) {
-	return s.repo.GetByID(ctx, id)
+	connector, err := s.repo.GetByID(ctx, id)
+	if err != nil || connector == nil {
+		return connector, err
+	}
+	return connector, s.openConnector(connector)
 }
 
 // CreateConnector creates a new data connector
@@ -44,16 +85,22 @@ func (s *ConnectorService) CreateConnector(ctx context.Context, req *models.Crea
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
+	sealed, err := s.sealConfig(req.Type, req.Config)
+	if err != nil {
+		return nil, err
+	}
+
 	connector := &models.DataConnector{
 		Name:   req.Name,
 		Type:   req.Type,
-		Config: req.Config,
+		Config: sealed,
 	}
 
 	if err := s.repo.Create(ctx, connector); err != nil {
 		s.logger.Error("Failed to create connector", "error", err)
 		return nil, fmt.Errorf("failed to create connector: %w", err)
 	}
+	connector.Config = req.Config
 
 	s.logger.Info("Connector created successfully", "id", connector.ID, "name", connector.Name)
 	return connector, nil
@@ -64,7 +111,7 @@ func (s *ConnectorService) UpdateConnector(ctx context.Context, id string, req *
 	s.logger.Info("Updating connector", "id", id)
 
 	// Check if connector exists
-	existing, err := s.repo.GetByID(ctx, id)
+	existing, err := s.GetConnector(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get connector: %w", err)
 	}
@@ -74,10 +121,20 @@ func (s *ConnectorService) UpdateConnector(ctx context.Context, id string, req *
 
 	// Validate configuration if provided
 	if req.Config != nil {
-		if err := s.validateConnectorConfig(existing.Type, req.Config); err != nil {
+		// Secrets sent back masked keep their stored values
+		config := models.ConnectorConfig(secrets.RestoreMasked(req.Config, existing.Config, s.sensitiveFields(existing.Type)))
+		if err := s.validateConnectorConfig(existing.Type, config); err != nil {
 			s.logger.Error("Invalid connector configuration", "error", err)
 			return nil, fmt.Errorf("invalid configuration: %w", err)
 		}
+
+		sealed, err := s.sealConfig(existing.Type, config)
+		if err != nil {
+			return nil, err
+		}
+		sealedReq := *req
+		sealedReq.Config = sealed
+		req = &sealedReq
 	}
 
 	updated, err := s.repo.Update(ctx, id, req)
@@ -85,6 +142,9 @@ func (s *ConnectorService) UpdateConnector(ctx context.Context, id string, req *
 		s.logger.Error("Failed to update connector", "error", err)
 		return nil, fmt.Errorf("failed to update connector: %w", err)
 	}
+	if err := s.openConnector(updated); err != nil {
+		return nil, err
+	}
 
 	s.logger.Info("Connector updated successfully", "id", id)
 	return updated, nil
@@ -94,11 +154,23 @@ func (s *ConnectorService) UpdateConnector(ctx context.Context, id string, req *
 func (s *ConnectorService) DeleteConnector(ctx context.Context, id string) error {
 	s.logger.Info("Deleting connector", "id", id)
 
+	existing, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get connector: %w", err)
+	}
+
 	if err := s.repo.Delete(ctx, id); err != nil {
 		s.logger.Error("Failed to delete connector", "error", err)
 		return fmt.Errorf("failed to delete connector: %w", err)
 	}
 
+	// Remove uploaded files along with the connector
+	if existing != nil && existing.Type == models.ConnectorTypeFile && s.fileStore != nil {
+		if err := s.fileStore.DeleteAll(id); err != nil {
+			s.logger.Warn("Failed to remove uploaded files", "id", id, "error", err)
+		}
+	}
+
 	s.logger.Info("Connector deleted successfully", "id", id)
 	return nil
 }
@@ -107,7 +179,7 @@ func (s *ConnectorService) DeleteConnector(ctx context.Context, id string) error
 func (s *ConnectorService) TestConnector(ctx context.Context, id string) (*models.ConnectorTestResult, error) {
 	s.logger.Info("Testing connector", "id", id)
 
-	connector, err := s.repo.GetByID(ctx, id)
+	connector, err := s.GetConnector(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get connector: %w", err)
 	}
@@ -115,11 +187,14 @@ func (s *ConnectorService) TestConnector(ctx context.Context, id string) (*model
 		return nil, fmt.Errorf("connector not found")
 	}
 
-	// Update status to testing
-	s.repo.UpdateStatus(ctx, id, models.ConnectorStatusTesting)
+	// Update status to testing; connected connectors stay routable while they are re-checked
+	if connector.Status != models.ConnectorStatusConnected {
+		s.repo.UpdateStatus(ctx, id, models.ConnectorStatusTesting)
+	}
 
 	// Test the connection
-	result := s.testConnectorConfig(ctx, connector.Type, connector.Config)
+	checkedAt := time.Now()
+	result := s.testConnectorConfig(ctx, connector.ID, connector.Type, connector.Config)
 
 	// Update status based on test result
 	newStatus := models.ConnectorStatusError
@@ -128,10 +203,67 @@ func (s *ConnectorService) TestConnector(ctx context.Context, id string) (*model
 	}
 	s.repo.UpdateStatus(ctx, id, newStatus)
 
+	check := &models.ConnectorHealthCheck{
+		ConnectorID:  id,
+		CheckedAt:    checkedAt,
+		Success:      result.Success,
+		ResponseTime: result.ResponseTime,
+		Error:        result.Error,
+	}
+	if err := s.repo.RecordHealthCheck(ctx, check, healthHistorySize); err != nil {
+		s.logger.Warn("Failed to record health check", "id", id, "error", err)
+	}
+
 	s.logger.Info("Connector test completed", "id", id, "success", result.Success)
 	return result, nil
 }
 
+// QueryConnector runs a query against a connector and returns its rows as an iterator.
+// SQL connectors run sqlQuery, which must be a read-only query in their dialect; other
+// connectors answer question. Callers must close the iterator.
+func (s *ConnectorService) QueryConnector(ctx context.Context, connector *models.DataConnector, question, sqlQuery string) (connectors.RowIterator, error) {
+	driver, err := s.drivers.Driver(connector.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	dialect := driver.Capabilities().SQLDialect
+	if dialect != "" && sqlQuery == "" {
+		return nil, fmt.Errorf("%w: %s connectors need a sql parameter", ErrInvalidConnectorQuery, driver.Name())
+	}
+	if dialect == "" && sqlQuery != "" {
+		return nil, fmt.Errorf("%w: %s connectors do not run SQL; ask a question instead", ErrInvalidConnectorQuery, driver.Name())
+	}
+
+	s.logger.Info("Querying connector", "id", connector.ID, "type", connector.Type)
+
+	it, err := connectors.StreamQuery(ctx, driver, connectors.QueryRequest{
+		Connection: connectors.Connection{
+			ID:     connector.ID,
+			Name:   connector.Name,
+			Config: connector.Config,
+		},
+		Question: question,
+		SQL:      sqlQuery,
+	})
+	if err != nil {
+		if errors.Is(err, connectors.ErrInvalidQuery) || errors.Is(err, connectors.ErrDangerousQuery) ||
+			errors.Is(err, connectors.ErrQueryTooLong) || errors.Is(err, connectors.ErrQueryTooExpensive) {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidConnectorQuery, err)
+		}
+		return nil, fmt.Errorf("failed to query connector %s: %w", connector.Name, err)
+	}
+	return it, nil
+}
+
+// GetHealthHistory retrieves the most recent connection test results of a connector, newest first
+func (s *ConnectorService) GetHealthHistory(ctx context.Context, id string, limit int) ([]*models.ConnectorHealthCheck, error) {
+	if limit <= 0 || limit > healthHistorySize {
+		limit = healthHistorySize
+	}
+	return s.repo.GetHealthHistory(ctx, id, limit)
+}
+
 // TestConnectorConfig tests a connector configuration without saving it
 func (s *ConnectorService) TestConnectorConfig(ctx context.Context, req *models.TestConnectorConfigRequest) (*models.ConnectorTestResult, error) {
 	s.logger.Info("Testing connector configuration", "type", req.Type)
@@ -145,154 +277,181 @@ func (s *ConnectorService) TestConnectorConfig(ctx context.Context, req *models.
 		}, nil
 	}
 
-	result := s.testConnectorConfig(ctx, req.Type, req.Config)
+	result := s.testConnectorConfig(ctx, "", req.Type, req.Config)
 	s.logger.Info("Configuration test completed", "type", req.Type, "success", result.Success)
 	return result, nil
 }
 
 // GetActiveConnectors retrieves all active connectors
 func (s *ConnectorService) GetActiveConnectors(ctx context.Context) ([]*models.DataConnector, error) {
-	return s.repo.GetActive(ctx)
+	connectors, err := s.repo.GetActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.openConnectors(connectors), nil
 }
 
 // GetConnectorsByType retrieves connectors by type
 func (s *ConnectorService) GetConnectorsByType(ctx context.Context, connectorType models.ConnectorType) ([]*models.DataConnector, error) {
-	return s.repo.GetByType(ctx, connectorType)
+	connectors, err := s.repo.GetByType(ctx, connectorType)
+	if err != nil {
+		return nil, err
+	}
+	return s.openConnectors(connectors), nil
 }
 
-// validateConnectorConfig validates the configuration based on connector type
-func (s *ConnectorService) validateConnectorConfig(connectorType models.ConnectorType, config models.ConnectorConfig) error {
-	switch connectorType {
-	case models.ConnectorTypeSuperset:
-		return s.validateSupersetConfig(config)
-	case models.ConnectorTypePostgres:
-		return s.validatePostgresConfig(config)
-	default:
-		return fmt.Errorf("unsupported connector type: %s", connectorType)
+// RedactConnector returns a copy of the connector with its sensitive config fields masked,
+// for use in API responses
+func (s *ConnectorService) RedactConnector(connector *models.DataConnector) *models.DataConnector {
+	if connector == nil {
+		return nil
 	}
+	redacted := *connector
+	redacted.Config = secrets.MaskFields(connector.Config, s.sensitiveFields(connector.Type))
+	return &redacted
 }
 
-// validateSupersetConfig validates Superset connector configuration
-func (s *ConnectorService) validateSupersetConfig(config models.ConnectorConfig) error {
-	url, ok := config["url"].(string)
-	if !ok || url == "" {
-		return fmt.Errorf("url is required for Superset connector")
+// RedactConnectors masks the sensitive config fields of every connector
+func (s *ConnectorService) RedactConnectors(connectors []*models.DataConnector) []*models.DataConnector {
+	redacted := make([]*models.DataConnector, len(connectors))
+	for i, connector := range connectors {
+		redacted[i] = s.RedactConnector(connector)
 	}
+	return redacted
+}
 
-	// Basic URL validation
-	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
-		return fmt.Errorf("url must start with http:// or https://")
+// ReencryptSecrets seals plaintext secrets and re-wraps secrets sealed with an older
+// key under the primary key. It returns the number of connectors rewritten.
+func (s *ConnectorService) ReencryptSecrets(ctx context.Context) (int, error) {
+	if s.keyring == nil {
+		return 0, fmt.Errorf("no encryption keys configured")
 	}
 
-	// Check if either username/password or bearer_token is provided
-	username, hasUsername := config["username"].(string)
-	password, hasPassword := config["password"].(string)
-	token, hasToken := config["bearer_token"].(string)
-
-	if (!hasUsername || !hasPassword || username == "" || password == "") && (!hasToken || token == "") {
-		return fmt.Errorf("either username/password or bearer_token is required for Superset connector")
+	stored, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list connectors: %w", err)
 	}
 
-	return nil
-}
+	rewritten := 0
+	for _, connector := range stored {
+		fields := s.sensitiveFields(connector.Type)
+		if !s.keyring.FieldsNeedRotation(connector.Config, fields) {
+			continue
+		}
+
+		plaintext, err := s.keyring.OpenFields(connector.Config, fields)
+		if err != nil {
+			return rewritten, fmt.Errorf("connector %s: %w", connector.ID, err)
+		}
+		sealed, err := s.keyring.SealFields(plaintext, fields)
+		if err != nil {
+			return rewritten, fmt.Errorf("connector %s: %w", connector.ID, err)
+		}
 
-// validatePostgresConfig validates PostgreSQL connector configuration
-func (s *ConnectorService) validatePostgresConfig(config models.ConnectorConfig) error {
-	url, ok := config["url"].(string)
-	if !ok || url == "" {
-		return fmt.Errorf("url is required for PostgreSQL connector")
+		if _, err := s.repo.Update(ctx, connector.ID, &models.UpdateConnectorRequest{Config: sealed}); err != nil {
+			return rewritten, fmt.Errorf("failed to update connector %s: %w", connector.ID, err)
+		}
+		rewritten++
+		s.logger.Info("Re-encrypted connector secrets", "id", connector.ID, "key_id", s.keyring.PrimaryKeyID())
 	}
 
-	return nil
+	return rewritten, nil
 }
 
-// testConnectorConfig tests the actual connection to the data source
-func (s *ConnectorService) testConnectorConfig(ctx context.Context, connectorType models.ConnectorType, config models.ConnectorConfig) *models.ConnectorTestResult {
-	start := time.Now()
-
-	switch connectorType {
-	case models.ConnectorTypeSuperset:
-		return s.testSupersetConnection(ctx, config, start)
-	case models.ConnectorTypePostgres:
-		return s.testPostgresConnection(ctx, config, start)
-	default:
-		return &models.ConnectorTestResult{
-			Success: false,
-			Message: "Unsupported connector type",
-			Error:   fmt.Sprintf("connector type %s is not supported", connectorType),
-		}
+// sensitiveFields returns the config fields of a connector type that hold secrets
+func (s *ConnectorService) sensitiveFields(connectorType models.ConnectorType) []string {
+	driver, err := s.drivers.Driver(connectorType)
+	if err != nil {
+		return nil
 	}
+	return connectors.SensitiveFields(driver)
 }
 
-// testSupersetConnection tests connection to Superset
-func (s *ConnectorService) testSupersetConnection(ctx context.Context, config models.ConnectorConfig, start time.Time) *models.ConnectorTestResult {
-	url, _ := config["url"].(string)
-	username, _ := config["username"].(string)
-	password, _ := config["password"].(string)
-	bearerToken, _ := config["bearer_token"].(string)
-
-	var supersetConn *connectors.SuperSetConnector
-
-	// Use bearer token if provided, otherwise use username/password
-	if bearerToken != "" {
-		supersetConn = connectors.NewSuperSetConnectorWithToken(url, bearerToken, s.logger)
-	} else {
-		supersetConn = connectors.NewSuperSetConnector(url, username, password, s.logger)
+// sealConfig returns a copy of config with its sensitive fields encrypted for storage
+func (s *ConnectorService) sealConfig(connectorType models.ConnectorType, config models.ConnectorConfig) (models.ConnectorConfig, error) {
+	if s.keyring == nil || config == nil {
+		return config, nil
 	}
+	sealed, err := s.keyring.SealFields(config, s.sensitiveFields(connectorType))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt connector secrets: %w", err)
+	}
+	return sealed, nil
+}
 
-	// Test connection with a simple health check or login attempt
-	err := supersetConn.TestConnection(ctx)
-	responseTime := time.Since(start).Milliseconds()
-
+// openConnector decrypts the sensitive config fields of a stored connector in place
+func (s *ConnectorService) openConnector(connector *models.DataConnector) error {
+	if s.keyring == nil || connector == nil {
+		return nil
+	}
+	config, err := s.keyring.OpenFields(connector.Config, s.sensitiveFields(connector.Type))
 	if err != nil {
-		s.logger.Error("Superset connection test failed", "error", err, "url", url)
-		return &models.ConnectorTestResult{
-			Success:      false,
-			Message:      "Failed to connect to Superset",
-			ResponseTime: &responseTime,
-			Error:        err.Error(),
+		return fmt.Errorf("failed to decrypt secrets of connector %s: %w", connector.ID, err)
+	}
+	connector.Config = config
+	return nil
+}
+
+// openConnectors decrypts stored connectors, leaving out the ones whose secrets
+// cannot be decrypted (e.g. after a key rotation dropped their key) so they
+// don't take the others down with them
+func (s *ConnectorService) openConnectors(connectors []*models.DataConnector) []*models.DataConnector {
+	opened := connectors[:0]
+	for _, connector := range connectors {
+		if err := s.openConnector(connector); err != nil {
+			s.logger.Error("Skipping connector with undecryptable secrets", "error", err, "id", connector.ID, "name", connector.Name)
+			continue
 		}
+		opened = append(opened, connector)
 	}
+	return opened
+}
 
-	return &models.ConnectorTestResult{
-		Success:      true,
-		Message:      "Successfully connected to Superset",
-		ResponseTime: &responseTime,
+// validateConnectorConfig validates the configuration with the driver for the connector type
+func (s *ConnectorService) validateConnectorConfig(connectorType models.ConnectorType, config models.ConnectorConfig) error {
+	driver, err := s.drivers.Driver(connectorType)
+	if err != nil {
+		return err
 	}
+	return driver.ValidateConfig(config)
 }
 
-// testPostgresConnection tests connection to PostgreSQL
-func (s *ConnectorService) testPostgresConnection(ctx context.Context, config models.ConnectorConfig, start time.Time) *models.ConnectorTestResult {
-	url, _ := config["url"].(string)
+// testConnectorConfig tests the actual connection to the data source.
+// connectorID is empty when testing a configuration that has not been saved.
+func (s *ConnectorService) testConnectorConfig(ctx context.Context, connectorID string, connectorType models.ConnectorType, config models.ConnectorConfig) *models.ConnectorTestResult {
+	start := time.Now()
 
-	postgresConn, err := connectors.NewPostgresConnector(url, s.logger)
+	driver, err := s.drivers.Driver(connectorType)
 	if err != nil {
-		responseTime := time.Since(start).Milliseconds()
 		return &models.ConnectorTestResult{
-			Success:      false,
-			Message:      "Failed to create PostgreSQL connection",
-			ResponseTime: &responseTime,
-			Error:        err.Error(),
+			Success: false,
+			Message: "Unsupported connector type",
+			Error:   err.Error(),
 		}
 	}
-	defer postgresConn.Close()
 
-	// Test with a simple query
-	_, err = postgresConn.TestConnection(ctx)
+	report, err := driver.Test(ctx, connectors.Connection{ID: connectorID, Config: config})
 	responseTime := time.Since(start).Milliseconds()
 
 	if err != nil {
+		s.logger.Error("Connection test failed", "type", connectorType, "error", err)
 		return &models.ConnectorTestResult{
 			Success:      false,
-			Message:      "Failed to connect to PostgreSQL",
+			Message:      fmt.Sprintf("Failed to connect to %s", driver.Name()),
 			ResponseTime: &responseTime,
 			Error:        err.Error(),
 		}
 	}
 
+	message := report.Message
+	if message == "" {
+		message = fmt.Sprintf("Successfully connected to %s", driver.Name())
+	}
+
 	return &models.ConnectorTestResult{
-		Success:      true,
-		Message:      "Successfully connected to PostgreSQL",
-		ResponseTime: &responseTime,
+		Success:           true,
+		Message:           message,
+		ResponseTime:      &responseTime,
+		AvailableDatasets: report.Datasets,
 	}
-}
\ No newline at end of file
+}
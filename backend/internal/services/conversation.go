@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"insightiq/backend/internal/models"
+	"insightiq/backend/internal/repository"
+)
+
+// Bounds of what a turn stores about its answer
+const (
+	maxSessionTitleLength = 200
+	maxResultSummaryRows  = 3
+	maxResultSummaryValue = 80
+)
+
+// ConversationService answers questions within server-side sessions, so follow-ups
+// such as "now break that down by region" are read with the earlier questions
+type ConversationService struct {
+	repo      *repository.ConversationRepository
+	planner   *PlannerService
+	analytics *AnalyticsService
+	logger    *slog.Logger
+}
+
+// ConversationReply is the answer to a question of a session
+type ConversationReply struct {
+	*AnalyticsResponse
+	SessionID string `json:"session_id"`
+	// ResolvedQuestion is the question that was answered, with the context of the
+	// session filled in
+	ResolvedQuestion string `json:"resolved_question"`
+}
+
+func NewConversationService(repo *repository.ConversationRepository, planner *PlannerService, analytics *AnalyticsService, logger *slog.Logger) *ConversationService {
+	return &ConversationService{
+		repo:      repo,
+		planner:   planner,
+		analytics: analytics,
+		logger:    logger.With("service", "conversation"),
+	}
+}
+
+// Ask answers a question in a session of a user, starting a new session when
+// sessionID is empty. The question is answered as the user, whatever opts.UserID is.
+func (s *ConversationService) Ask(ctx context.Context, userID, sessionID, question string, opts QueryOptions) (*ConversationReply, error) {
+	session, err := s.session(ctx, userID, sessionID, question)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := s.planner.ResolveFollowUp(ctx, question, session.Turns)
+	turn := &models.ConversationTurn{
+		SessionID:        session.ID,
+		Question:         question,
+		ResolvedQuestion: resolved,
+		Status:           "success",
+	}
+
+	opts.UserID = userID
+	response, err := s.analytics.ProcessQueryWithOptions(ctx, resolved, opts)
+	if err != nil {
+		turn.Status = "error"
+		turn.ErrorMessage = err.Error()
+		s.recordTurn(ctx, turn)
+		return nil, err
+	}
+
+	turn.ParsedQuery = response.ParsedQuery
+	turn.SQL = responseSQL(response)
+	turn.ResultSummary = summarizeResult(response)
+	turn.RowCount = len(response.Data)
+	s.recordTurn(ctx, turn)
+
+	return &ConversationReply{
+		AnalyticsResponse: response,
+		SessionID:         session.ID,
+		ResolvedQuestion:  resolved,
+	}, nil
+}
+
+// session loads a session of a user with its turns, or starts one titled after
+// its first question
+func (s *ConversationService) session(ctx context.Context, userID, sessionID, question string) (*models.ConversationSession, error) {
+	if sessionID != "" {
+		return s.repo.GetSession(ctx, sessionID, userID)
+	}
+
+	session := &models.ConversationSession{UserID: userID, Title: truncate(question, maxSessionTitleLength)}
+	if err := s.repo.CreateSession(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to create conversation session: %w", err)
+	}
+	s.logger.Info("Started conversation session", "session_id", session.ID, "user_id", userID)
+	return session, nil
+}
+
+// recordTurn stores a turn; the answer is returned even when it cannot be stored
+func (s *ConversationService) recordTurn(ctx context.Context, turn *models.ConversationTurn) {
+	if err := s.repo.AddTurn(ctx, turn); err != nil {
+		s.logger.Warn("Failed to record conversation turn", "session_id", turn.SessionID, "error", err)
+	}
+}
+
+// ListSessions retrieves the sessions of a user, most recently active first
+func (s *ConversationService) ListSessions(ctx context.Context, userID string, limit, offset int) ([]models.ConversationSession, error) {
+	return s.repo.ListSessions(ctx, userID, limit, offset)
+}
+
+// GetSession retrieves a session of a user with its turns, to resume it
+func (s *ConversationService) GetSession(ctx context.Context, userID, sessionID string) (*models.ConversationSession, error) {
+	return s.repo.GetSession(ctx, sessionID, userID)
+}
+
+// DeleteSession removes a session of a user
+func (s *ConversationService) DeleteSession(ctx context.Context, userID, sessionID string) error {
+	return s.repo.DeleteSession(ctx, sessionID, userID)
+}
+
+// responseSQL joins the SQL that answered a question on each source
+func responseSQL(response *AnalyticsResponse) string {
+	statements := make([]string, 0, len(response.Queries))
+	for _, q := range response.Queries {
+		if q.SQL != "" {
+			statements = append(statements, q.SQL)
+		}
+	}
+	return strings.Join(statements, ";\n")
+}
+
+// summarizeResult describes an answer briefly enough to give as context for
+// follow-ups: its size, its columns and its first rows
+func summarizeResult(response *AnalyticsResponse) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d rows", len(response.Data))
+	if response.Truncated {
+		b.WriteString(" (truncated)")
+	}
+
+	var columns []string
+	for _, col := range response.Columns {
+		columns = append(columns, col.Name)
+	}
+	if len(columns) > 0 {
+		fmt.Fprintf(&b, "; columns: %s", strings.Join(columns, ", "))
+	}
+
+	for i, row := range response.Data {
+		if i == maxResultSummaryRows {
+			break
+		}
+		names := columns
+		if len(names) == 0 {
+			for name := range row {
+				names = append(names, name)
+			}
+			slices.Sort(names)
+		}
+		values := make([]string, 0, len(names))
+		for _, name := range names {
+			values = append(values, fmt.Sprintf("%s=%s", name, truncate(fmt.Sprint(row[name]), maxResultSummaryValue)))
+		}
+		fmt.Fprintf(&b, "; row %d: %s", i+1, strings.Join(values, ", "))
+	}
+	return b.String()
+}
+
+// truncate shortens s to at most n bytes without splitting a character
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "…"
+}
@@ -2,15 +2,35 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
+	"sync"
 	"time"
 
 	"insightiq/backend/internal/connectors"
+	"insightiq/backend/internal/executor"
 	"insightiq/backend/internal/models"
+	"insightiq/backend/internal/schema"
+	"insightiq/backend/internal/sqlgen"
+	"insightiq/backend/internal/timeexpr"
 )
 
+// How long a scanned schema is reused for SQL generation before rescanning
+const schemaCacheTTL = 10 * time.Minute
+
+// SchemaScanner provides the scanned schema of a connector
+type SchemaScanner interface {
+	ScanDataSource(ctx context.Context, connectorID string) (*schema.SchemaContext, error)
+}
+
+type cachedSchema struct {
+	context   *schema.SchemaContext
+	scannedAt time.Time
+	values    *schema.ValueIndex // built on first use
+}
+
 // EnhancedAnalyticsService provides intelligent data source routing and RAG capabilities
 type EnhancedAnalyticsService struct {
 	connectorService *ConnectorService
@@ -18,6 +38,11 @@ type EnhancedAnalyticsService struct {
 	llmConn          *connectors.OllamaConnector
 	fallbackPostgres *connectors.PostgresConnector
 	fallbackSuperset *connectors.SuperSetConnector
+	schemaScanner    SchemaScanner
+	schemaCache      map[string]cachedSchema
+	sqlGenerator     *sqlgen.Generator
+	planService      *PlanService
+	schemaMu         sync.Mutex
 	logger           *slog.Logger
 }
 
@@ -27,6 +52,30 @@ type EnhancedAnalyticsRequest struct {
 	ConnectorIDs []string          `json:"connector_ids,omitempty"`
 	SQL          string            `json:"sql,omitempty"`
 	Metadata     map[string]string `json:"metadata,omitempty"`
+	// Filters replace the filters recognized in the question, so users can correct
+	// them; an empty list removes them all
+	Filters *[]models.Filter `json:"filters,omitempty"`
+	// UserID owns the plan stored for the question
+	UserID string `json:"-"`
+	// OnEvent, when set, is told about each step of answering the question
+	OnEvent QueryEventFunc `json:"-"`
+}
+
+func (req *EnhancedAnalyticsRequest) emit(eventType QueryEventType, data interface{}) {
+	if req.OnEvent != nil {
+		req.OnEvent(eventType, data)
+	}
+}
+
+// insightTokens returns the function streaming the tokens of the insights as
+// events, or nil when nobody listens
+func (req *EnhancedAnalyticsRequest) insightTokens() func(token string) {
+	if req.OnEvent == nil {
+		return nil
+	}
+	return func(token string) {
+		req.OnEvent(QueryEventInsightToken, map[string]string{"token": token})
+	}
 }
 
 // EnhancedAnalyticsResponse represents the response with data and insights
@@ -43,6 +92,27 @@ type EnhancedAnalyticsResponse struct {
 	Intent       *models.Intent           `json:"intent,omitempty"`
 	TaskGraph    *models.TaskGraph        `json:"task_graph,omitempty"`
 	PlanningTime string                   `json:"planning_time,omitempty"`
+	// ExecutionTime is how long running the task graph took
+	ExecutionTime string `json:"execution_time,omitempty"`
+	// Truncated is set when a source returned more rows than its max_rows
+	Truncated bool `json:"truncated"`
+	// Columns describes the columns of Data, in the order the sources returned them
+	Columns []connectors.ResultColumn `json:"columns,omitempty"`
+	// Queries lists the SQL generated for each source that answered with SQL
+	Queries []GeneratedQuery `json:"queries,omitempty"`
+	// Filters are the filters applied, including values recognized in the question
+	Filters []models.Filter `json:"filters,omitempty"`
+	// Visualization is the chart proposed by a visualization step of the plan
+	Visualization *ChartSuggestion `json:"visualization,omitempty"`
+	// PlanID identifies the stored plan, whose progress and result can be fetched later
+	PlanID string `json:"plan_id,omitempty"`
+}
+
+// GeneratedQuery is the SQL that answered the question on one source
+type GeneratedQuery struct {
+	Source   string           `json:"source"`
+	SQL      string           `json:"sql"`
+	Attempts []sqlgen.Attempt `json:"attempts"`
 }
 
 func NewEnhancedAnalyticsService(
@@ -57,17 +127,83 @@ func NewEnhancedAnalyticsService(
 		llmConn:          llm,
 		fallbackPostgres: fallbackPostgres,
 		fallbackSuperset: fallbackSuperset,
+		schemaCache:      make(map[string]cachedSchema),
 		logger:           logger.With("service", "enhanced_analytics"),
 	}
 
 	// Initialize planner service
 	eas.plannerService = NewPlannerService(llm, connectorService, logger)
+	eas.plannerService.SetValueLinker(eas)
+
+	// A nil *OllamaConnector must not become a non-nil sqlgen.LLM
+	var sqlLLM sqlgen.LLM
+	if llm != nil {
+		sqlLLM = llm
+	}
+	eas.sqlGenerator = sqlgen.NewGenerator(sqlLLM, logger)
 
 	return eas
 }
 
-// ProcessQuery intelligently routes queries to appropriate data sources with RAG
+// SetSchemaScanner sets the scanner used to ground SQL generation in a connector's real schema
+func (eas *EnhancedAnalyticsService) SetSchemaScanner(scanner SchemaScanner) {
+	eas.schemaScanner = scanner
+}
+
+// SetDomainRetriever sets where SQL generation finds the business domains of a connector
+func (eas *EnhancedAnalyticsService) SetDomainRetriever(retriever sqlgen.DomainRetriever) {
+	eas.sqlGenerator.SetDomainRetriever(retriever)
+}
+
+// SetTimeResolver sets the time zone and fiscal calendar relative dates in questions resolve in
+func (eas *EnhancedAnalyticsService) SetTimeResolver(resolver *timeexpr.Resolver) {
+	eas.plannerService.SetTimeResolver(resolver)
+}
+
+// SetDefinitionSource sets where SQL generation loads the current semantic definitions of a connector
+func (eas *EnhancedAnalyticsService) SetDefinitionSource(definitions sqlgen.DefinitionSource) {
+	eas.sqlGenerator.SetDefinitionSource(definitions)
+}
+
+// SetPlanService sets where task graphs are stored while they run, so they can be
+// followed and cancelled
+func (eas *EnhancedAnalyticsService) SetPlanService(plans *PlanService) {
+	eas.planService = plans
+}
+
+// ProcessQuery intelligently routes queries to appropriate data sources with RAG.
+// With a plan service the question is answered as a stored plan, which keeps
+// running when ctx is cancelled and stops when the plan is cancelled instead.
 func (eas *EnhancedAnalyticsService) ProcessQuery(ctx context.Context, req *EnhancedAnalyticsRequest) (*EnhancedAnalyticsResponse, error) {
+	if eas.planService == nil {
+		return eas.processQuery(ctx, req, nil)
+	}
+	run, err := eas.planService.Start(ctx, req.UserID, req.Query)
+	if err != nil {
+		eas.logger.Warn("Failed to store plan, answering without one", "error", err)
+		return eas.processQuery(ctx, req, nil)
+	}
+
+	req.emit(QueryEventPlan, map[string]string{"plan_id": run.ID})
+	response, err := eas.processQuery(run.Context(), req, run)
+	if err != nil {
+		run.Finish(nil, err)
+		if run.Cancelled() {
+			return nil, ErrPlanCancelled
+		}
+		return nil, err
+	}
+
+	// The graph is stored with the plan already
+	response.PlanID = run.ID
+	stored := *response
+	stored.TaskGraph = nil
+	run.Finish(&stored, nil)
+	return response, nil
+}
+
+// processQuery answers a question, recording the task graph with run when set
+func (eas *EnhancedAnalyticsService) processQuery(ctx context.Context, req *EnhancedAnalyticsRequest, run *PlanRun) (*EnhancedAnalyticsResponse, error) {
 	start := time.Now()
 	eas.logger.Info("Processing enhanced analytics query with planner", "query", req.Query)
 
@@ -91,67 +227,89 @@ func (eas *EnhancedAnalyticsService) ProcessQuery(ctx context.Context, req *Enha
 		return eas.processWithBasicRouting(ctx, req, dataSources, start, nil, "")
 	}
 
+	// Filters sent with the request correct the ones recognized in the question
+	if req.Filters != nil {
+		plannerResponse.Intent.ParsedQuery.Filters = *req.Filters
+	}
+
 	eas.logger.Info("Intent parsed successfully",
 		"intent_type", plannerResponse.Intent.Type,
 		"confidence", plannerResponse.Confidence,
 		"steps", len(plannerResponse.TaskGraph.Steps),
 		"planning_time", planningTime)
 
-	// 2. Execute task graph based on parsed intent
+	// 2. Select data sources based on parsed intent
 	dataSources := eas.selectDataSourcesFromIntent(ctx, plannerResponse.Intent, req.ConnectorIDs)
 	eas.logger.Info("Data sources selected for query", "count", len(dataSources), "query", req.Query)
 	for i, ds := range dataSources {
 		eas.logger.Info("Selected data source", "index", i, "name", ds.Name, "type", ds.Type, "status", ds.Status)
 	}
+	req.emit(QueryEventSources, map[string]interface{}{"sources": eas.getSourceNames(dataSources)})
 
-	// 2. Retrieve data from multiple sources
-	allData := make(map[string]interface{})
-	var combinedData []map[string]interface{}
-
-	for _, source := range dataSources {
-		eas.logger.Info("Attempting to fetch data from source", "source", source.Name, "type", source.Type)
-		data, err := eas.fetchDataFromSource(ctx, source, req.Query)
-		if err != nil {
-			eas.logger.Error("Failed to fetch from source", "source", source.Name, "type", source.Type, "error", err)
-			continue
-		}
-
-		if len(data) > 0 {
-			allData[source.Name] = data
-			combinedData = append(combinedData, data...)
-			eas.logger.Info("Retrieved data from source", "source", source.Name, "rows", len(data))
-		} else {
-			eas.logger.Warn("No data returned from source", "source", source.Name, "type", source.Type)
-		}
+	// 3. Execute the task graph: sources are queried in parallel, then the planned
+	// transformations and analysis run on the rows they returned
+	graph := &plannerResponse.TaskGraph
+	eas.prepareTaskGraph(graph, dataSources)
+	execution := eas.newPlanExecution(req, &plannerResponse.Intent, dataSources)
+	exec := executor.New(execution.handlers(), eas.logger)
+	if run != nil {
+		run.SetGraph(graph)
+		exec.SetProgressFunc(run.Progress)
 	}
+	outputs, execErr := exec.Execute(ctx, graph)
 
-	// 3. Check if any data was retrieved from connectors
-	if len(combinedData) == 0 {
-		return nil, fmt.Errorf("no data available from configured connectors. Please check your connector configuration and ensure they contain the requested data")
+	// 4. Check if any data was retrieved from connectors
+	retrievedRows, queries, truncated := execution.retrieved()
+	if retrievedRows == 0 {
+		return nil, noDataError(sourceErrors(graph))
 	}
-
-	// 4. Generate comprehensive analysis with enhanced RAG context using intent
-	analysis, err := eas.generateAnalysisWithIntentRAG(ctx, combinedData, allData, req.Query, plannerResponse.Intent)
-	if err != nil {
-		return nil, fmt.Errorf("failed to analyze data: %w", err)
+	if execErr != nil {
+		return nil, fmt.Errorf("failed to execute query plan: %w", execErr)
 	}
 
 	return &EnhancedAnalyticsResponse{
-		Query:        req.Query,
-		Data:         combinedData,
-		Sources:      allData,
-		Analysis:     analysis,
-		DataSources:  eas.getSourceNames(dataSources),
-		Timestamp:    time.Now(),
-		ProcessTime:  time.Since(start).String(),
-		TaskID:       fmt.Sprintf("task_%d", start.Unix()),
-		Status:       "completed",
-		Intent:       &plannerResponse.Intent,
-		TaskGraph:    &plannerResponse.TaskGraph,
-		PlanningTime: planningTime.String(),
+		Query:         req.Query,
+		Data:          resultRows(graph, outputs),
+		Sources:       execution.sourceData(),
+		Analysis:      execution.analysis,
+		DataSources:   eas.getSourceNames(dataSources),
+		Timestamp:     time.Now(),
+		ProcessTime:   time.Since(start).String(),
+		TaskID:        fmt.Sprintf("task_%d", start.Unix()),
+		Status:        "completed",
+		Intent:        &plannerResponse.Intent,
+		TaskGraph:     graph,
+		PlanningTime:  planningTime.String(),
+		ExecutionTime: graph.ExecutionTime.String(),
+		Truncated:     truncated,
+		Columns:       execution.columns(),
+		Queries:       queries,
+		Filters:       plannerResponse.Intent.ParsedQuery.Filters,
+		Visualization: execution.chart,
 	}, nil
 }
 
+// LinkValues finds the dimension values a question names in the connectors it
+// would be routed to, using the value index of their scanned schemas
+func (eas *EnhancedAnalyticsService) LinkValues(ctx context.Context, connectorIDs []string, question string) []LinkedValue {
+	if eas.schemaScanner == nil {
+		return nil
+	}
+
+	var linked []LinkedValue
+	for _, connector := range eas.analyzeQueryForDataSources(ctx, question, connectorIDs) {
+		index, err := eas.connectorValueIndex(ctx, connector.ID)
+		if err != nil {
+			eas.logger.Warn("Failed to load dimension values", "connector", connector.Name, "error", err)
+			continue
+		}
+		for _, match := range index.Match(question) {
+			linked = append(linked, LinkedValue{ConnectorID: connector.ID, ValueMatch: match})
+		}
+	}
+	return linked
+}
+
 // ExecuteCustomSQL is disabled to prevent direct SQL execution on internal databases
 func (eas *EnhancedAnalyticsService) ExecuteCustomSQL(ctx context.Context, sql, question string) (*EnhancedAnalyticsResponse, error) {
 	eas.logger.Info("Custom SQL execution disabled - use configured external connectors only")
@@ -167,7 +325,7 @@ func (eas *EnhancedAnalyticsService) analyzeQueryForDataSources(ctx context.Cont
 		var requestedConnectors []*models.DataConnector
 		for _, id := range connectorIDs {
 			connector, err := eas.connectorService.GetConnector(ctx, id)
-			if err == nil && connector != nil && connector.Status == models.ConnectorStatusConnected {
+			if err == nil && eas.isRoutable(connector) {
 				requestedConnectors = append(requestedConnectors, connector)
 			}
 		}
@@ -188,19 +346,13 @@ func (eas *EnhancedAnalyticsService) analyzeQueryForDataSources(ctx context.Cont
 	var relevantSources []*models.DataConnector
 
 	for _, connector := range activeConnectors {
-		switch connector.Type {
-		case models.ConnectorTypeSuperset:
-			// Superset is good for dashboard queries, analytics, trends
-			if containsAny(queryLower, []string{"dashboard", "chart", "trend", "analytics", "visualization"}) {
-				relevantSources = append(relevantSources, connector)
-			}
-		case models.ConnectorTypePostgres:
-			// PostgreSQL is good for detailed data queries, transactions
-			if containsAny(queryLower, []string{"sales", "customers", "orders", "revenue", "data", "records"}) {
-				relevantSources = append(relevantSources, connector)
-			}
-		default:
-			// Include all other types for now
+		driver, err := eas.connectorService.Drivers().Driver(connector.Type)
+		if err != nil {
+			continue
+		}
+		// Sources declaring routing keywords only handle matching questions
+		keywords := driver.Capabilities().RoutingKeywords
+		if len(keywords) == 0 || containsAny(queryLower, keywords) {
 			relevantSources = append(relevantSources, connector)
 		}
 	}
@@ -214,85 +366,178 @@ func (eas *EnhancedAnalyticsService) analyzeQueryForDataSources(ctx context.Cont
 	return relevantSources
 }
 
-// fetchDataFromSource retrieves data from a specific connector
-func (eas *EnhancedAnalyticsService) fetchDataFromSource(ctx context.Context, connector *models.DataConnector, query string) ([]map[string]interface{}, error) {
-	switch connector.Type {
-	case models.ConnectorTypeSuperset:
-		return eas.fetchFromSuperset(ctx, connector, query)
-	case models.ConnectorTypePostgres:
-		return eas.fetchFromPostgres(ctx, connector, query)
-	default:
-		return nil, fmt.Errorf("unsupported connector type: %s", connector.Type)
+// fetchDataFromSource retrieves data from a specific connector through its driver.
+// parsedQuery may be nil when the planner could not parse the question. The generated
+// query is returned when the source was answered with SQL.
+func (eas *EnhancedAnalyticsService) fetchDataFromSource(ctx context.Context, connector *models.DataConnector, query string, parsedQuery *models.ParsedQuery) (*connectors.QueryResult, *sqlgen.Result, error) {
+	driver, err := eas.connectorService.Drivers().Driver(connector.Type)
+	if err != nil {
+		return nil, nil, err
 	}
-}
 
-// fetchFromSuperset retrieves data from a Superset connector
-func (eas *EnhancedAnalyticsService) fetchFromSuperset(ctx context.Context, connector *models.DataConnector, query string) ([]map[string]interface{}, error) {
-	config := connector.Config
-	url, _ := config["url"].(string)
-	username, _ := config["username"].(string)
-	password, _ := config["password"].(string)
-	bearerToken, _ := config["bearer_token"].(string)
+	req := connectors.QueryRequest{
+		Connection: connectors.Connection{
+			ID:     connector.ID,
+			Name:   connector.Name,
+			Config: connector.Config,
+		},
+		Question:    query,
+		ParsedQuery: parsedQueryForConnector(parsedQuery, connector.ID),
+	}
 
-	var supersetConn *connectors.SuperSetConnector
+	caps := driver.Capabilities()
 
-	// Use bearer token if provided, otherwise use username/password
-	if bearerToken != "" {
-		supersetConn = connectors.NewSuperSetConnectorWithToken(url, bearerToken, eas.logger)
-	} else {
-		supersetConn = connectors.NewSuperSetConnector(url, username, password, eas.logger)
+	// Drivers with a SQL dialect run SQL generated from the connector's scanned schema
+	if caps.SQLDialect != "" {
+		return eas.generateAndRunSQL(ctx, driver, connector, req, caps.SQLDialect)
 	}
 
-	// Test connection first
-	if err := supersetConn.TestConnection(ctx); err != nil {
-		return nil, fmt.Errorf("superset connection failed: %w", err)
+	result, err := driver.ExecuteQuery(ctx, req)
+	if caps.SQLFallback && errors.Is(err, connectors.ErrNoMatchingData) {
+		eas.logger.Info("No curated data matches, generating SQL", "source", connector.Name, "reason", err)
+		return eas.generateAndRunSQL(ctx, driver, connector, req, connectors.FallbackSQLDialect(connector.Config))
 	}
+	return result, nil, err
+}
 
-	eas.logger.Info("🔍 Fetching data from Superset based on user query", "query", query)
+// generateAndRunSQL writes SQL for the question from the connector's scanned schema
+// and runs it, repairing queries the connector rejects
+func (eas *EnhancedAnalyticsService) generateAndRunSQL(ctx context.Context, driver connectors.Driver, connector *models.DataConnector, req connectors.QueryRequest, dialect string) (*connectors.QueryResult, *sqlgen.Result, error) {
+	schemaCtx, err := eas.connectorSchema(ctx, connector.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to scan %s schema: %w", driver.Name(), err)
+	}
+	if len(schemaCtx.Tables) == 0 {
+		return nil, nil, fmt.Errorf("no tables found in %s connector %s", driver.Name(), connector.Name)
+	}
 
-	// Try to find relevant dataset based on query
-	dashboard, err := supersetConn.FindRelevantDataset(ctx, query)
+	generated, err := eas.sqlGenerator.Generate(ctx, sqlgen.Request{
+		Question:    req.Question,
+		Dialect:     dialect,
+		Schema:      schemaCtx,
+		ParsedQuery: req.ParsedQuery,
+		Executor:    &driverExecutor{driver: driver, req: req},
+	})
 	if err != nil {
-		eas.logger.Warn("❌ No matching dashboard found, trying QueryDataset fallback", "error", err)
-		// Fallback to QueryDataset which has its own heuristics
-		result, err := supersetConn.QueryDataset(ctx, query)
-		if err != nil {
-			eas.logger.Error("Query failed", "error", err)
-			return nil, fmt.Errorf("no matching dataset found and query failed: %w. Available dashboards may not match query keywords.", err)
+		return nil, generated, err
+	}
+	return generated.Data, generated, nil
+}
+
+// driverExecutor runs generated SQL through a connector's driver
+type driverExecutor struct {
+	driver connectors.Driver
+	req    connectors.QueryRequest
+}
+
+func (e *driverExecutor) Explain(ctx context.Context, sql string) error {
+	explainer, ok := e.driver.(connectors.QueryExplainer)
+	if !ok {
+		return nil
+	}
+	req := e.req
+	req.SQL = sql
+	return explainer.ExplainQuery(ctx, req)
+}
+
+func (e *driverExecutor) Execute(ctx context.Context, sql string) (*connectors.QueryResult, error) {
+	req := e.req
+	req.SQL = sql
+	return e.driver.ExecuteQuery(ctx, req)
+}
+
+// mergeResultColumns appends the columns of another source that are not yet described
+func mergeResultColumns(columns, more []connectors.ResultColumn) []connectors.ResultColumn {
+	for _, col := range more {
+		known := false
+		for _, existing := range columns {
+			if existing.Name == col.Name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			columns = append(columns, col)
 		}
-		eas.logger.Info("✅ Query executed using fallback method", "rows", len(result.Data))
-		return result.Data, nil
+	}
+	return columns
+}
+
+// noDataError explains why no connector returned data, including the reasons
+// sources failed, such as a query refused by the cost guard
+func noDataError(sourceErrors []string) error {
+	if len(sourceErrors) == 0 {
+		return fmt.Errorf("no data available from configured connectors. Please check your connector configuration and ensure they contain the requested data")
+	}
+	return fmt.Errorf("no data available from configured connectors: %s", strings.Join(sourceErrors, "; "))
+}
+
+// connectorSchema returns the scanned schema of a connector, reusing recent scans
+func (eas *EnhancedAnalyticsService) connectorSchema(ctx context.Context, connectorID string) (*schema.SchemaContext, error) {
+	if eas.schemaScanner == nil {
+		return nil, fmt.Errorf("schema scanner not configured")
 	}
 
-	// Extract dashboard ID and title
-	dashboardID := 0
-	if id, ok := dashboard["id"].(float64); ok {
-		dashboardID = int(id)
+	eas.schemaMu.Lock()
+	cached, ok := eas.schemaCache[connectorID]
+	eas.schemaMu.Unlock()
+	if ok && time.Since(cached.scannedAt) < schemaCacheTTL {
+		return cached.context, nil
 	}
 
-	dashTitle := ""
-	if title, ok := dashboard["dashboard_title"].(string); ok {
-		dashTitle = title
+	schemaCtx, err := eas.schemaScanner.ScanDataSource(ctx, connectorID)
+	if err != nil {
+		return nil, err
 	}
 
-	eas.logger.Info("✅ Found matching dashboard", "dashboard", dashTitle, "id", dashboardID)
+	eas.schemaMu.Lock()
+	eas.schemaCache[connectorID] = cachedSchema{context: schemaCtx, scannedAt: time.Now()}
+	eas.schemaMu.Unlock()
+
+	return schemaCtx, nil
+}
 
-	// Query data from the discovered dashboard
-	result, err := supersetConn.QueryDashboardData(ctx, dashboardID, query)
+// connectorValueIndex returns the dimension value index of a connector's scanned
+// schema, built once per scan
+func (eas *EnhancedAnalyticsService) connectorValueIndex(ctx context.Context, connectorID string) (*schema.ValueIndex, error) {
+	schemaCtx, err := eas.connectorSchema(ctx, connectorID)
 	if err != nil {
-		eas.logger.Error("Failed to query dashboard", "dashboard", dashTitle, "error", err)
-		return nil, fmt.Errorf("failed to query dashboard '%s' (id: %d): %w", dashTitle, dashboardID, err)
+		return nil, err
 	}
 
-	eas.logger.Info("✅ Successfully retrieved data from Superset", "dashboard", dashTitle, "rows", len(result.Data))
-	return result.Data, nil
+	eas.schemaMu.Lock()
+	defer eas.schemaMu.Unlock()
+
+	cached, ok := eas.schemaCache[connectorID]
+	if ok && cached.context == schemaCtx && cached.values != nil {
+		return cached.values, nil
+	}
+	index := schema.NewValueIndex(schemaCtx)
+	if ok && cached.context == schemaCtx {
+		cached.values = index
+		eas.schemaCache[connectorID] = cached
+	}
+	return index, nil
 }
 
-// fetchFromPostgres retrieves data from a PostgreSQL connector
-func (eas *EnhancedAnalyticsService) fetchFromPostgres(ctx context.Context, connector *models.DataConnector, query string) ([]map[string]interface{}, error) {
-	// PostgreSQL connectors are disabled to prevent fallback to internal database
-	// All data should come from configured external connectors (Superset, etc.)
-	return nil, fmt.Errorf("PostgreSQL connectors are disabled. Please use configured external connectors (Superset, etc.) for data retrieval")
+// parsedQueryForConnector leaves out filters recognized in the data of other connectors
+func parsedQueryForConnector(pq *models.ParsedQuery, connectorID string) *models.ParsedQuery {
+	if pq == nil {
+		return nil
+	}
+
+	filters := make([]models.Filter, 0, len(pq.Filters))
+	for _, f := range pq.Filters {
+		if f.ConnectorID == "" || f.ConnectorID == connectorID {
+			filters = append(filters, f)
+		}
+	}
+	if len(filters) == len(pq.Filters) {
+		return pq
+	}
+	scoped := *pq
+	scoped.Filters = filters
+	return &scoped
 }
 
 // fetchFromFallbackSources is disabled to prevent any fallback to internal databases
@@ -304,7 +549,7 @@ func (eas *EnhancedAnalyticsService) fetchFromFallbackSources(ctx context.Contex
 }
 
 // generateAnalysisWithRAG creates comprehensive analysis using RAG (Retrieval Augmented Generation)
-func (eas *EnhancedAnalyticsService) generateAnalysisWithRAG(ctx context.Context, combinedData []map[string]interface{}, sourceData map[string]interface{}, query string) (string, error) {
+func (eas *EnhancedAnalyticsService) generateAnalysisWithRAG(ctx context.Context, combinedData []map[string]interface{}, sourceData map[string]interface{}, query string, onToken func(token string)) (string, error) {
 	// Build context from multiple sources
 	contextBuilder := strings.Builder{}
 	contextBuilder.WriteString("Data Analysis Context:\n")
@@ -340,7 +585,7 @@ Please provide a comprehensive analysis that includes:
 
 Original Query: %s`, contextBuilder.String(), query)
 
-	return eas.llmConn.AnalyzeData(ctx, combinedData, enhancedQuery)
+	return eas.llmConn.AnalyzeDataStream(ctx, combinedData, enhancedQuery, onToken)
 }
 
 // Helper functions
@@ -377,7 +622,7 @@ func (eas *EnhancedAnalyticsService) selectDataSourcesFromIntent(
 		var requestedConnectors []*models.DataConnector
 		for _, id := range requestedConnectorIDs {
 			connector, err := eas.connectorService.GetConnector(ctx, id)
-			if err == nil && connector != nil && connector.Status == models.ConnectorStatusConnected {
+			if err == nil && eas.isRoutable(connector) {
 				requestedConnectors = append(requestedConnectors, connector)
 			}
 		}
@@ -400,21 +645,21 @@ func (eas *EnhancedAnalyticsService) selectDataSourcesFromIntent(
 	case models.IntentTypeVisualization:
 		// Prefer Superset for visualization queries
 		for _, connector := range activeConnectors {
-			if connector.Type == models.ConnectorTypeSuperset {
+			if eas.connectorCapabilities(connector).Dashboards {
 				relevantSources = append(relevantSources, connector)
 			}
 		}
 		// Add other sources as backup
 		for _, connector := range activeConnectors {
-			if connector.Type != models.ConnectorTypeSuperset {
+			if !eas.connectorCapabilities(connector).Dashboards {
 				relevantSources = append(relevantSources, connector)
 			}
 		}
 
 	case models.IntentTypeSQL:
-		// Prefer PostgreSQL for direct SQL queries
+		// Prefer SQL databases for direct SQL queries
 		for _, connector := range activeConnectors {
-			if connector.Type == models.ConnectorTypePostgres {
+			if eas.connectorCapabilities(connector).SQL {
 				relevantSources = append(relevantSources, connector)
 			}
 		}
@@ -429,14 +674,14 @@ func (eas *EnhancedAnalyticsService) selectDataSourcesFromIntent(
 		if containsAny(queryLower, []string{"dashboard", "chart"}) {
 			// Prefer Superset
 			for _, connector := range activeConnectors {
-				if connector.Type == models.ConnectorTypeSuperset {
+				if eas.connectorCapabilities(connector).Dashboards {
 					relevantSources = append(relevantSources, connector)
 				}
 			}
 		} else {
-			// Use PostgreSQL for detailed analytics
+			// Use SQL databases and sources that aggregate parsed queries for detailed analytics
 			for _, connector := range activeConnectors {
-				if connector.Type == models.ConnectorTypePostgres {
+				if caps := eas.connectorCapabilities(connector); caps.SQL || caps.Aggregation {
 					relevantSources = append(relevantSources, connector)
 				}
 			}
@@ -459,6 +704,28 @@ func (eas *EnhancedAnalyticsService) selectDataSourcesFromIntent(
 	return relevantSources
 }
 
+// isRoutable reports whether queries may be sent to a connector. Connectors whose
+// last health check failed are skipped rather than left to time out.
+func (eas *EnhancedAnalyticsService) isRoutable(connector *models.DataConnector) bool {
+	if connector == nil {
+		return false
+	}
+	if connector.Status == models.ConnectorStatusError {
+		eas.logger.Warn("Skipping unhealthy connector", "connector", connector.Name, "last_tested", connector.LastTested)
+		return false
+	}
+	return connector.Status == models.ConnectorStatusConnected
+}
+
+// connectorCapabilities returns what the driver of a connector supports
+func (eas *EnhancedAnalyticsService) connectorCapabilities(connector *models.DataConnector) connectors.Capabilities {
+	driver, err := eas.connectorService.Drivers().Driver(connector.Type)
+	if err != nil {
+		return connectors.Capabilities{}
+	}
+	return driver.Capabilities()
+}
+
 // processWithBasicRouting handles fallback to original routing logic
 func (eas *EnhancedAnalyticsService) processWithBasicRouting(
 	ctx context.Context,
@@ -471,15 +738,39 @@ func (eas *EnhancedAnalyticsService) processWithBasicRouting(
 	// Use original logic for data retrieval and analysis
 	allData := make(map[string]interface{})
 	var combinedData []map[string]interface{}
+	var sourceErrors []string
+	var columns []connectors.ResultColumn
+	var queries []GeneratedQuery
+	truncated := false
 
+	var parsedQuery *models.ParsedQuery
+	if intent != nil {
+		parsedQuery = &intent.ParsedQuery
+	}
+
+	req.emit(QueryEventSources, map[string]interface{}{"sources": eas.getSourceNames(dataSources)})
 	for _, source := range dataSources {
-		data, err := eas.fetchDataFromSource(ctx, source, req.Query)
+		result, generated, err := eas.fetchDataFromSource(ctx, source, req.Query, parsedQuery)
 		if err != nil {
 			eas.logger.Warn("Failed to fetch from source", "source", source.Name, "error", err)
+			sourceErrors = append(sourceErrors, fmt.Sprintf("%s: %v", source.Name, err))
 			continue
 		}
 
+		if generated != nil {
+			queries = append(queries, GeneratedQuery{Source: source.Name, SQL: generated.SQL, Attempts: generated.Attempts})
+			req.emit(QueryEventSQL, map[string]string{"source": source.Name, "sql": generated.SQL})
+		}
+		req.emit(QueryEventRows, map[string]interface{}{
+			"source":    source.Name,
+			"rows":      len(result.Data),
+			"truncated": result.Truncated,
+		})
+
+		data := result.Data
+		truncated = truncated || result.Truncated
 		if len(data) > 0 {
+			columns = mergeResultColumns(columns, result.Columns)
 			allData[source.Name] = data
 			combinedData = append(combinedData, data...)
 			eas.logger.Info("Retrieved data from source", "source", source.Name, "rows", len(data))
@@ -488,7 +779,7 @@ func (eas *EnhancedAnalyticsService) processWithBasicRouting(
 
 	// Check if any data was retrieved from connectors
 	if len(combinedData) == 0 {
-		return nil, fmt.Errorf("no data available from configured connectors. Please check your connector configuration and ensure they contain the requested data")
+		return nil, noDataError(sourceErrors)
 	}
 
 	// Generate analysis using basic or intent-based RAG
@@ -496,9 +787,9 @@ func (eas *EnhancedAnalyticsService) processWithBasicRouting(
 	var err error
 
 	if intent != nil {
-		analysis, err = eas.generateAnalysisWithIntentRAG(ctx, combinedData, allData, req.Query, *intent)
+		analysis, err = eas.generateAnalysisWithIntentRAG(ctx, combinedData, allData, req.Query, *intent, req.insightTokens())
 	} else {
-		analysis, err = eas.generateAnalysisWithRAG(ctx, combinedData, allData, req.Query)
+		analysis, err = eas.generateAnalysisWithRAG(ctx, combinedData, allData, req.Query, req.insightTokens())
 	}
 
 	if err != nil {
@@ -515,6 +806,9 @@ func (eas *EnhancedAnalyticsService) processWithBasicRouting(
 		ProcessTime: time.Since(start).String(),
 		TaskID:      fmt.Sprintf("task_%d", start.Unix()),
 		Status:      "completed",
+		Truncated:   truncated,
+		Columns:     columns,
+		Queries:     queries,
 	}
 
 	if intent != nil {
@@ -532,6 +826,7 @@ func (eas *EnhancedAnalyticsService) generateAnalysisWithIntentRAG(
 	sourceData map[string]interface{},
 	query string,
 	intent models.Intent,
+	onToken func(token string),
 ) (string, error) {
 	// Build enhanced context with intent information
 	contextBuilder := strings.Builder{}
@@ -549,7 +844,7 @@ func (eas *EnhancedAnalyticsService) generateAnalysisWithIntentRAG(
 		contextBuilder.WriteString(fmt.Sprintf("Identified Dimensions: %s\n", strings.Join(intent.ParsedQuery.Dimensions, ", ")))
 	}
 	if intent.ParsedQuery.TimeRange != nil {
-		contextBuilder.WriteString(fmt.Sprintf("Time Range: %s\n", intent.ParsedQuery.TimeRange.Period))
+		contextBuilder.WriteString(fmt.Sprintf("Time Range: %s\n", intent.ParsedQuery.TimeRange.Describe()))
 	}
 
 	// Add source information
@@ -620,5 +915,5 @@ func (eas *EnhancedAnalyticsService) generateAnalysisWithIntentRAG(
 
 Original Query: %s`, contextBuilder.String(), analysisPrompt, query)
 
-	return eas.llmConn.AnalyzeData(ctx, combinedData, enhancedQuery)
+	return eas.llmConn.AnalyzeDataStream(ctx, combinedData, enhancedQuery, onToken)
 }
\ No newline at end of file
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"insightiq/backend/internal/models"
+	"insightiq/backend/internal/timeexpr"
+)
+
+// followUpHistory is how many earlier turns are shown to the LLM when rewriting
+const followUpHistory = 3
+
+// followUpFragmentWords is the longest question read as a fragment of an earlier one
+const followUpFragmentWords = 4
+
+var (
+	// followUpLead matches the openings of questions that continue the previous one
+	followUpLead = regexp.MustCompile(`(?i)^(?:(?:ok(?:ay)?|so|and|now|then|but|also|great|thanks)[,.]?\s+)*(?:(?:what|how) about|(?:the )?same (?:thing )?(?:for|but)|do the same for|(?:and )?for|in|during|only|just|excluding|except|without)?\b\s*`)
+	// followUpBreakdown matches requests to split the previous answer by a dimension
+	followUpBreakdown = regexp.MustCompile(`(?i)^(?:(?:break|split|group|segment)(?: (?:that|it|this|those|them|these|the results?))?(?: down| up| out)? )?by (.+)$`)
+	// followUpOpening matches openings that continue the previous question at any length
+	followUpOpening = regexp.MustCompile(`^(?:and|now|then|but|also|what about|how about|same|do the same|(?:break|split|group|segment)(?: (?:that|it|this|those|them|these|the results?))?(?: down| up| out)? by)\b`)
+	// followUpFragment matches the openings of fragments such as "only in europe",
+	// which also start standalone questions like "in which region ..."
+	followUpFragment = regexp.MustCompile(`^(?:by|for|in|during|only|just|excluding|except|without)\b`)
+	// followUpReference matches words that refer to an earlier question in a fragment
+	// such as "only those"; longer questions use the same words on their own
+	followUpReference = regexp.MustCompile(`\b(?:that|those|these|it|them|same|instead|previous|above)\b`)
+)
+
+// ResolveFollowUp rewrites a question that continues an earlier one, such as
+// "now break that down by region" or "what about last year?", into a fully
+// specified question using the conversation so far. Questions that stand on their
+// own are returned unchanged. The LLM rewrites when it is available; simple
+// follow-ups are also rewritten without it.
+func (ps *PlannerService) ResolveFollowUp(ctx context.Context, question string, history []models.ConversationTurn) string {
+	previous := lastResolvedQuestion(history)
+	if previous == "" || !isFollowUp(question, ps.timeResolver) {
+		return question
+	}
+
+	if ps.llmConn != nil {
+		rewritten, err := ps.rewriteFollowUpWithLLM(ctx, question, history)
+		if err == nil && rewritten != "" {
+			ps.logger.Info("Rewrote follow-up question", "question", question, "resolved", rewritten)
+			return rewritten
+		}
+		ps.logger.Warn("LLM follow-up rewrite failed, using rules", "error", err)
+	}
+
+	rewritten := rewriteFollowUp(previous, question, ps.timeResolver)
+	ps.logger.Info("Rewrote follow-up question", "question", question, "resolved", rewritten)
+	return rewritten
+}
+
+// rewriteFollowUpWithLLM asks the LLM for a standalone version of the question
+func (ps *PlannerService) rewriteFollowUpWithLLM(ctx context.Context, question string, history []models.ConversationTurn) (string, error) {
+	var conversation strings.Builder
+	start := max(len(history)-followUpHistory, 0)
+	for _, turn := range history[start:] {
+		conversation.WriteString(fmt.Sprintf("Question: %s\n", turn.ResolvedQuestion))
+		if turn.SQL != "" {
+			conversation.WriteString(fmt.Sprintf("SQL: %s\n", turn.SQL))
+		}
+		if turn.ResultSummary != "" {
+			conversation.WriteString(fmt.Sprintf("Result: %s\n", turn.ResultSummary))
+		}
+		conversation.WriteString("\n")
+	}
+
+	prompt := fmt.Sprintf(`Rewrite the follow-up question so it can be understood without the conversation.
+Keep the metrics, filters, breakdowns and time period of the earlier questions unless the follow-up changes them.
+Return ONLY the rewritten question on one line.
+
+Conversation:
+%s
+Follow-up: %s`, conversation.String(), question)
+
+	response, err := ps.llmConn.GenerateResponse(ctx, prompt)
+	if err != nil {
+		return "", fmt.Errorf("failed to rewrite follow-up: %w", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(response), "\n")
+	rewritten := strings.TrimSpace(lines[0])
+	rewritten = strings.TrimPrefix(rewritten, "Rewritten question:")
+	rewritten = strings.Trim(strings.TrimSpace(rewritten), `"'`)
+	if len(rewritten) < len(question)/2 {
+		return "", fmt.Errorf("unusable rewrite: %q", response)
+	}
+	return rewritten, nil
+}
+
+// lastResolvedQuestion returns the latest question of the conversation that was answered
+func lastResolvedQuestion(history []models.ConversationTurn) string {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Status != "error" && history[i].ResolvedQuestion != "" {
+			return history[i].ResolvedQuestion
+		}
+	}
+	return ""
+}
+
+// isFollowUp reports whether a question needs an earlier one to be understood:
+// it opens like a continuation, or is a short fragment that refers back, narrows
+// or names a time period on its own
+func isFollowUp(question string, resolver *timeexpr.Resolver) bool {
+	q := strings.ToLower(normalizeQuestion(question))
+	if q == "" {
+		return false
+	}
+	if followUpOpening.MatchString(q) {
+		return true
+	}
+	if len(strings.Fields(q)) > followUpFragmentWords {
+		return false
+	}
+	if followUpFragment.MatchString(q) || followUpReference.MatchString(q) {
+		return true
+	}
+	// "last year?" names nothing but a period
+	if r, ok := resolver.Resolve(q); ok {
+		return len(strings.Fields(strings.Replace(q, r.Text, "", 1))) <= 1
+	}
+	return false
+}
+
+// rewriteFollowUp combines a follow-up with the previous question without the LLM:
+// a new time period replaces the previous one, "by X" adds a breakdown, and
+// anything else narrows the previous question. The follow-up keeps its casing.
+func rewriteFollowUp(previous, question string, resolver *timeexpr.Resolver) string {
+	previous = strings.TrimRight(strings.Join(strings.Fields(previous), " "), "?.! ")
+	q := normalizeQuestion(question)
+	rest := stripFollowUpLead(q)
+	if rest == "" {
+		return previous
+	}
+
+	if m := followUpBreakdown.FindStringSubmatch(rest); m != nil {
+		return previous + " broken down by " + m[1]
+	}
+
+	if r, ok := resolver.Resolve(q); ok {
+		period := r.Text
+		if i := indexFold(q, r.Text); i >= 0 {
+			period = q[i : i+len(r.Text)]
+		}
+		remaining := strings.Fields(stripFollowUpLead(strings.Replace(q, period, "", 1)))
+		if len(remaining) <= 1 {
+			return replaceTimeExpression(previous, period, resolver)
+		}
+	}
+
+	return previous + " for " + rest
+}
+
+// stripFollowUpLead removes the words that only mark a question as a follow-up
+func stripFollowUpLead(q string) string {
+	q = strings.TrimSpace(followUpLead.ReplaceAllString(strings.TrimSpace(q), ""))
+	if strings.HasSuffix(strings.ToLower(q), "instead") {
+		q = q[:len(q)-len("instead")]
+	}
+	return strings.TrimSpace(q)
+}
+
+// replaceTimeExpression swaps the time period of a question for another one, or
+// adds it when the question had none
+func replaceTimeExpression(question, period string, resolver *timeexpr.Resolver) string {
+	if r, ok := resolver.Resolve(question); ok {
+		if i := indexFold(question, r.Text); i >= 0 {
+			return question[:i] + period + question[i+len(r.Text):]
+		}
+	}
+	return question + " for " + period
+}
+
+// indexFold returns the index of the lower-cased text sub in s, or -1. The resolver
+// matches lower-cased text; the offsets only hold for questions whose length does
+// not change when lower-cased.
+func indexFold(s, sub string) int {
+	lower := strings.ToLower(s)
+	if len(lower) != len(s) {
+		return -1
+	}
+	return strings.Index(lower, sub)
+}
+
+// normalizeQuestion collapses the whitespace of a question and drops its closing
+// punctuation
+func normalizeQuestion(question string) string {
+	q := strings.Join(strings.Fields(question), " ")
+	return strings.TrimSpace(strings.TrimRight(q, "?.! "))
+}
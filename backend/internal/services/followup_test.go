@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"insightiq/backend/internal/models"
+	"insightiq/backend/internal/timeexpr"
+)
+
+func TestRewriteFollowUp(t *testing.T) {
+	resolver := timeexpr.NewResolver(timeexpr.Config{})
+	previous := "What was total revenue by month last quarter?"
+
+	tests := []struct {
+		question string
+		followUp bool
+		want     string
+	}{
+		{"now break that down by region", true, "What was total revenue by month last quarter broken down by region"},
+		{"What about last year?", true, "What was total revenue by month last year"},
+		{"and in 2023?", true, "What was total revenue by month in 2023"},
+		{"how about California", true, "What was total revenue by month last quarter for California"},
+		{"What about Q3 2024?", true, "What was total revenue by month Q3 2024"},
+		{"only those in Europe", true, "What was total revenue by month last quarter for those in Europe"},
+		{"by product category", true, "What was total revenue by month last quarter broken down by product category"},
+		{"How many customers signed up in March?", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.question, func(t *testing.T) {
+			if got := isFollowUp(tt.question, resolver); got != tt.followUp {
+				t.Fatalf("isFollowUp = %v, want %v", got, tt.followUp)
+			}
+			if !tt.followUp {
+				return
+			}
+			if got := rewriteFollowUp(previous, tt.question, resolver); got != tt.want {
+				t.Errorf("rewriteFollowUp = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveFollowUpKeepsStandaloneQuestions(t *testing.T) {
+	ps := &PlannerService{
+		logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+		timeResolver: timeexpr.NewResolver(timeexpr.Config{}),
+	}
+	history := []models.ConversationTurn{{ResolvedQuestion: "Total revenue last month", Status: "success"}}
+
+	for _, question := range []string{
+		"Show orders that shipped late in March",
+		"In which region are sales highest?",
+		"Is it true revenue grew in Q3 2024?",
+	} {
+		t.Run(question, func(t *testing.T) {
+			if got := ps.ResolveFollowUp(context.Background(), question, history); got != question {
+				t.Errorf("ResolveFollowUp = %q, want the question unchanged", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+const (
+	// Default time between checks of the same connector
+	DefaultHealthCheckInterval = 5 * time.Minute
+	// Upper bound for a single connection test
+	healthCheckTimeout = 30 * time.Second
+	// Connection tests run at the same time
+	healthCheckConcurrency = 4
+	// Each interval is randomly stretched or shortened by up to this fraction,
+	// so connectors created together do not keep hitting their sources in lockstep
+	healthCheckJitter = 0.2
+)
+
+// HealthMonitor periodically tests every connector so that statuses, last_tested
+// and the health history stay current without anyone pressing "test"
+type HealthMonitor struct {
+	connectorService *ConnectorService
+	interval         time.Duration
+	logger           *slog.Logger
+
+	mu      sync.Mutex
+	nextRun map[string]time.Time
+}
+
+// NewHealthMonitor creates a monitor that tests each connector about once per interval
+func NewHealthMonitor(connectorService *ConnectorService, interval time.Duration, logger *slog.Logger) *HealthMonitor {
+	if interval <= 0 {
+		interval = DefaultHealthCheckInterval
+	}
+	return &HealthMonitor{
+		connectorService: connectorService,
+		interval:         interval,
+		nextRun:          make(map[string]time.Time),
+		logger:           logger.With("service", "health_monitor"),
+	}
+}
+
+// Start runs the monitor until ctx is cancelled
+func (hm *HealthMonitor) Start(ctx context.Context) {
+	hm.logger.Info("Starting connector health monitor", "interval", hm.interval)
+	go hm.run(ctx)
+}
+
+func (hm *HealthMonitor) run(ctx context.Context) {
+	sem := make(chan struct{}, healthCheckConcurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		for _, id := range hm.dueConnectors(ctx) {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			wg.Add(1)
+			go func(id string) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				hm.check(ctx, id)
+			}(id)
+		}
+
+		timer := time.NewTimer(hm.untilNextRun())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			hm.logger.Info("Connector health monitor stopping")
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// dueConnectors lists connectors whose next check time has passed and schedules
+// their following check. New connectors get a random first check within one interval.
+func (hm *HealthMonitor) dueConnectors(ctx context.Context) []string {
+	connectors, err := hm.connectorService.GetConnectors(ctx)
+	if err != nil {
+		hm.logger.Error("Failed to list connectors for health checks", "error", err)
+		return nil
+	}
+
+	now := time.Now()
+	hm.mu.Lock()
+	defer hm.mu.Unlock()
+
+	seen := make(map[string]bool, len(connectors))
+	var due []string
+	for _, connector := range connectors {
+		seen[connector.ID] = true
+
+		next, scheduled := hm.nextRun[connector.ID]
+		if !scheduled {
+			hm.nextRun[connector.ID] = now.Add(time.Duration(rand.Int64N(int64(hm.interval))))
+			continue
+		}
+		if now.Before(next) {
+			continue
+		}
+
+		due = append(due, connector.ID)
+		hm.nextRun[connector.ID] = now.Add(hm.jitteredInterval())
+	}
+
+	// Forget deleted connectors
+	for id := range hm.nextRun {
+		if !seen[id] {
+			delete(hm.nextRun, id)
+		}
+	}
+
+	return due
+}
+
+// untilNextRun returns how long to sleep before the earliest scheduled check.
+// The connector list is re-read at least once per interval to pick up new connectors.
+func (hm *HealthMonitor) untilNextRun() time.Duration {
+	hm.mu.Lock()
+	defer hm.mu.Unlock()
+
+	wait := hm.interval
+	now := time.Now()
+	for _, next := range hm.nextRun {
+		if d := next.Sub(now); d < wait {
+			wait = d
+		}
+	}
+	return max(wait, time.Second)
+}
+
+func (hm *HealthMonitor) jitteredInterval() time.Duration {
+	factor := 1 + healthCheckJitter*(2*rand.Float64()-1)
+	return time.Duration(float64(hm.interval) * factor)
+}
+
+func (hm *HealthMonitor) check(ctx context.Context, connectorID string) {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	result, err := hm.connectorService.TestConnector(ctx, connectorID)
+	if err != nil {
+		hm.logger.Warn("Health check failed to run", "connector_id", connectorID, "error", err)
+		return
+	}
+	if !result.Success {
+		hm.logger.Warn("Connector unhealthy", "connector_id", connectorID, "error", result.Error)
+	}
+}
@@ -0,0 +1,364 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"insightiq/backend/internal/connectors"
+	"insightiq/backend/internal/executor"
+	"insightiq/backend/internal/models"
+	"insightiq/backend/internal/sqlparse"
+)
+
+// Step actions with special handling
+const (
+	actionDiscoverSources = "discover_sources"
+	actionFetchData       = "fetch_data"
+)
+
+// errNoRows is returned by analysis steps given nothing to analyze
+var errNoRows = errors.New("no rows to analyze")
+
+// ChartSuggestion is how the visualization step proposes to chart an answer
+type ChartSuggestion struct {
+	Type   string   `json:"type"` // "line", "bar", "table"
+	X      string   `json:"x,omitempty"`
+	Y      []string `json:"y,omitempty"`
+	Reason string   `json:"reason"`
+}
+
+// planExecution is the state shared by the steps of one question's task graph
+type planExecution struct {
+	eas     *EnhancedAnalyticsService
+	req     *EnhancedAnalyticsRequest
+	intent  *models.Intent
+	sources []*models.DataConnector
+
+	mu       sync.Mutex
+	results  map[string]*sourceResult // by connector ID
+	analysis string
+	chart    *ChartSuggestion
+}
+
+// sourceResult is what one source answered
+type sourceResult struct {
+	result    *connectors.QueryResult
+	generated *GeneratedQuery
+}
+
+func (eas *EnhancedAnalyticsService) newPlanExecution(req *EnhancedAnalyticsRequest, intent *models.Intent, sources []*models.DataConnector) *planExecution {
+	return &planExecution{
+		eas:     eas,
+		req:     req,
+		intent:  intent,
+		sources: sources,
+		results: make(map[string]*sourceResult),
+	}
+}
+
+// handlers maps every step type to the code running it
+func (pe *planExecution) handlers() executor.Handlers {
+	handlers := executor.DataHandlers()
+	handlers[models.TaskStepTypeDataRetrieval] = pe.retrieve
+	handlers[models.TaskStepTypeValidation] = pe.validate
+	handlers[models.TaskStepTypeAnalysis] = pe.analyze
+	handlers[models.TaskStepTypeVisualization] = pe.visualize
+	return handlers
+}
+
+// prepareTaskGraph makes a planned graph executable for the selected sources.
+// Graphs without a retrieval step get one before their first steps, and every
+// retrieval step is split into one optional step per source, so sources are
+// queried in parallel and one failing source does not stop the others.
+func (eas *EnhancedAnalyticsService) prepareTaskGraph(graph *models.TaskGraph, sources []*models.DataConnector) {
+	hasFetch := slices.ContainsFunc(graph.Steps, func(step models.TaskStep) bool {
+		return step.Type == models.TaskStepTypeDataRetrieval && step.Action != actionDiscoverSources
+	})
+	if !hasFetch {
+		fetch := models.TaskStep{
+			ID:            "data_retrieval",
+			Type:          models.TaskStepTypeDataRetrieval,
+			Description:   "Retrieve data from identified sources",
+			Action:        actionFetchData,
+			EstimatedTime: 5 * time.Second,
+			Retry:         defaultRetrievalRetry(),
+		}
+		for i, step := range graph.Steps {
+			if len(step.Dependencies) == 0 && step.Action != actionDiscoverSources {
+				graph.Steps[i].Dependencies = []string{fetch.ID}
+			}
+		}
+		graph.Steps = append([]models.TaskStep{fetch}, graph.Steps...)
+	}
+
+	if len(sources) > 0 {
+		var steps []models.TaskStep
+		replaced := make(map[string][]string)
+		for _, step := range graph.Steps {
+			if step.Type != models.TaskStepTypeDataRetrieval || step.Action == actionDiscoverSources {
+				steps = append(steps, step)
+				continue
+			}
+			for _, source := range sources {
+				perSource := step
+				perSource.ID = step.ID + ":" + source.ID
+				perSource.Description = fmt.Sprintf("%s (%s)", step.Description, source.Name)
+				perSource.Optional = true
+				perSource.Parameters = make(map[string]interface{}, len(step.Parameters)+2)
+				for k, v := range step.Parameters {
+					perSource.Parameters[k] = v
+				}
+				perSource.Parameters["connector_id"] = source.ID
+				perSource.Parameters["connector_name"] = source.Name
+				steps = append(steps, perSource)
+				replaced[step.ID] = append(replaced[step.ID], perSource.ID)
+			}
+		}
+		for i := range steps {
+			var deps []string
+			for _, dep := range steps[i].Dependencies {
+				if ids, ok := replaced[dep]; ok {
+					deps = append(deps, ids...)
+				} else {
+					deps = append(deps, dep)
+				}
+			}
+			steps[i].Dependencies = deps
+		}
+		graph.Steps = steps
+	}
+
+	eas.plannerService.updateDependencies(graph)
+	graph.EstimatedTime = eas.plannerService.calculateEstimatedTime(graph.Steps)
+}
+
+// retrieve runs a retrieval step: discovery reports the selected sources, and
+// fetching queries the source of the step
+func (pe *planExecution) retrieve(ctx context.Context, step models.TaskStep, inputs []executor.Input) (*executor.Output, error) {
+	if step.Action == actionDiscoverSources {
+		return &executor.Output{Value: pe.eas.getSourceNames(pe.sources)}, nil
+	}
+
+	var connectorID string
+	if _, err := executor.Param(step, "connector_id", &connectorID); err != nil {
+		return nil, err
+	}
+	var source *models.DataConnector
+	for _, s := range pe.sources {
+		if s.ID == connectorID {
+			source = s
+		}
+	}
+	if source == nil {
+		return nil, fmt.Errorf("no data source selected for step %s", step.ID)
+	}
+
+	pe.eas.logger.Info("Attempting to fetch data from source", "source", source.Name, "type", source.Type, "step", step.ID)
+	result, generated, err := pe.eas.fetchDataFromSource(ctx, source, pe.req.Query, &pe.intent.ParsedQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &sourceResult{result: result}
+	if generated != nil {
+		res.generated = &GeneratedQuery{Source: source.Name, SQL: generated.SQL, Attempts: generated.Attempts}
+		pe.req.emit(QueryEventSQL, map[string]string{"source": source.Name, "sql": generated.SQL})
+	}
+	pe.req.emit(QueryEventRows, map[string]interface{}{
+		"source":    source.Name,
+		"rows":      len(result.Data),
+		"truncated": result.Truncated,
+	})
+	pe.mu.Lock()
+	pe.results[source.ID] = res
+	pe.mu.Unlock()
+
+	if len(result.Data) == 0 {
+		pe.eas.logger.Warn("No data returned from source", "source", source.Name, "type", source.Type)
+	} else {
+		pe.eas.logger.Info("Retrieved data from source", "source", source.Name, "rows", len(result.Data))
+	}
+	return &executor.Output{Data: result.Data}, nil
+}
+
+// validate checks SQL sent with the request is read-only and that there is a
+// source to run it on
+func (pe *planExecution) validate(_ context.Context, step models.TaskStep, inputs []executor.Input) (*executor.Output, error) {
+	if pe.req.SQL != "" {
+		if _, err := sqlparse.ValidateReadOnlyDialect(pe.req.SQL, sqlparse.DialectAny); err != nil {
+			return nil, fmt.Errorf("invalid SQL: %w", err)
+		}
+	}
+	if len(pe.sources) == 0 {
+		return nil, fmt.Errorf("no data sources available")
+	}
+	return &executor.Output{Data: executor.Rows(inputs)}, nil
+}
+
+// analyze writes the analysis of the rows of its inputs
+func (pe *planExecution) analyze(ctx context.Context, step models.TaskStep, inputs []executor.Input) (*executor.Output, error) {
+	rows := executor.Rows(inputs)
+	if len(rows) == 0 {
+		return nil, errNoRows
+	}
+
+	analysis, err := pe.eas.generateAnalysisWithIntentRAG(ctx, rows, pe.sourceData(), pe.req.Query, *pe.intent, pe.req.insightTokens())
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze data: %w", err)
+	}
+
+	pe.mu.Lock()
+	pe.analysis = analysis
+	pe.mu.Unlock()
+	return &executor.Output{Data: rows, Value: analysis}, nil
+}
+
+// visualize proposes a chart for the rows of its inputs
+func (pe *planExecution) visualize(_ context.Context, step models.TaskStep, inputs []executor.Input) (*executor.Output, error) {
+	rows := executor.Rows(inputs)
+	chart := suggestChart(rows, pe.columns())
+
+	pe.mu.Lock()
+	pe.chart = chart
+	pe.mu.Unlock()
+	return &executor.Output{Data: rows, Value: chart}, nil
+}
+
+// sourceData returns the rows of each source that returned any, by source name
+func (pe *planExecution) sourceData() map[string]interface{} {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	data := make(map[string]interface{})
+	for _, source := range pe.sources {
+		if res, ok := pe.results[source.ID]; ok && len(res.result.Data) > 0 {
+			data[source.Name] = res.result.Data
+		}
+	}
+	return data
+}
+
+// columns describes the columns of the rows retrieved, in source order
+func (pe *planExecution) columns() []connectors.ResultColumn {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	var columns []connectors.ResultColumn
+	for _, source := range pe.sources {
+		if res, ok := pe.results[source.ID]; ok && len(res.result.Data) > 0 {
+			columns = mergeResultColumns(columns, res.result.Columns)
+		}
+	}
+	return columns
+}
+
+// retrieved returns the number of rows the sources returned, the SQL generated
+// for them and whether any source truncated its rows, in source order
+func (pe *planExecution) retrieved() (int, []GeneratedQuery, bool) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	rows, truncated := 0, false
+	var queries []GeneratedQuery
+	for _, source := range pe.sources {
+		res, ok := pe.results[source.ID]
+		if !ok {
+			continue
+		}
+		rows += len(res.result.Data)
+		truncated = truncated || res.result.Truncated
+		if res.generated != nil {
+			queries = append(queries, *res.generated)
+		}
+	}
+	return rows, queries, truncated
+}
+
+// sourceErrors lists why retrieval steps failed, by source
+func sourceErrors(graph *models.TaskGraph) []string {
+	var errs []string
+	for _, step := range graph.Steps {
+		if step.Type != models.TaskStepTypeDataRetrieval || step.Status != models.TaskStatusFailed {
+			continue
+		}
+		name, _ := step.Parameters["connector_name"].(string)
+		if name == "" {
+			name = step.ID
+		}
+		errs = append(errs, fmt.Sprintf("%s: %s", name, step.Error))
+	}
+	return errs
+}
+
+// resultRows returns the rows answering the question: the output of the first
+// step nothing depends on that produced rows
+func resultRows(graph *models.TaskGraph, outputs map[string]*executor.Output) []map[string]interface{} {
+	dependedOn := make(map[string]bool)
+	for _, step := range graph.Steps {
+		for _, dep := range step.Dependencies {
+			dependedOn[dep] = true
+		}
+	}
+	var rows []map[string]interface{}
+	for _, step := range graph.Steps {
+		if out, ok := outputs[step.ID]; ok && !dependedOn[step.ID] && out.Data != nil {
+			return out.Data
+		}
+		if out, ok := outputs[step.ID]; ok && step.Type == models.TaskStepTypeDataRetrieval {
+			rows = append(rows, out.Data...)
+		}
+	}
+	return rows
+}
+
+// suggestChart picks a chart for rows: a line over a time column, bars for a text
+// column against numbers, or a table
+func suggestChart(rows []map[string]interface{}, columns []connectors.ResultColumn) *ChartSuggestion {
+	if len(rows) == 0 {
+		return &ChartSuggestion{Type: "table", Reason: "no rows"}
+	}
+
+	names := make([]string, 0, len(columns))
+	for _, col := range columns {
+		names = append(names, col.Name)
+	}
+	if len(names) == 0 {
+		for name := range rows[0] {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+	}
+
+	var timeColumn, textColumn string
+	var numeric []string
+	for _, name := range names {
+		switch value := rows[0][name].(type) {
+		case time.Time:
+			if timeColumn == "" {
+				timeColumn = name
+			}
+		case int, int32, int64, float32, float64:
+			numeric = append(numeric, name)
+		case string:
+			if _, err := time.Parse("2006-01-02", value[:min(len(value), 10)]); err == nil && timeColumn == "" {
+				timeColumn = name
+			} else if textColumn == "" {
+				textColumn = name
+			}
+		}
+	}
+
+	switch {
+	case len(numeric) == 0:
+		return &ChartSuggestion{Type: "table", Reason: "no numeric columns"}
+	case timeColumn != "":
+		return &ChartSuggestion{Type: "line", X: timeColumn, Y: numeric, Reason: "values over time"}
+	case textColumn != "":
+		return &ChartSuggestion{Type: "bar", X: textColumn, Y: numeric, Reason: "values by category"}
+	}
+	return &ChartSuggestion{Type: "table", Reason: "no category or time column"}
+}
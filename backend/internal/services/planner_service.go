@@ -11,6 +11,8 @@ import (
 
 	"insightiq/backend/internal/connectors"
 	"insightiq/backend/internal/models"
+	"insightiq/backend/internal/schema"
+	"insightiq/backend/internal/timeexpr"
 )
 
 // PlannerService handles intent parsing and task graph generation
@@ -20,6 +22,19 @@ type PlannerService struct {
 	logger           *slog.Logger
 	intentPatterns   map[models.IntentType][]string
 	entityExtractor  *EntityExtractor
+	timeResolver     *timeexpr.Resolver
+	valueLinker      ValueLinker
+}
+
+// ValueLinker finds the dimension values a question names in the data of connectors
+type ValueLinker interface {
+	LinkValues(ctx context.Context, connectorIDs []string, question string) []LinkedValue
+}
+
+// LinkedValue is a dimension value named in a question, found in a connector's data
+type LinkedValue struct {
+	ConnectorID string `json:"connector_id"`
+	schema.ValueMatch
 }
 
 // EntityExtractor handles entity extraction from queries
@@ -42,11 +57,24 @@ func NewPlannerService(
 		logger:           logger.With("service", "planner"),
 		intentPatterns:   initializeIntentPatterns(),
 		entityExtractor:  initializeEntityExtractor(),
+		timeResolver:     timeexpr.NewResolver(timeexpr.Config{}),
 	}
 
 	return ps
 }
 
+// SetValueLinker sets how values named in questions, such as "California", are
+// recognized in connector data and turned into filters
+func (ps *PlannerService) SetValueLinker(linker ValueLinker) {
+	ps.valueLinker = linker
+}
+
+// SetTimeResolver sets the time zone and fiscal calendar relative time expressions
+// are resolved in; UTC with fiscal years starting in January by default
+func (ps *PlannerService) SetTimeResolver(resolver *timeexpr.Resolver) {
+	ps.timeResolver = resolver
+}
+
 // ParseIntent analyzes user input and determines intent with confidence
 func (ps *PlannerService) ParseIntent(ctx context.Context, req *models.PlannerRequest) (*models.PlannerResponse, error) {
 	start := time.Now()
@@ -67,9 +95,25 @@ func (ps *PlannerService) ParseIntent(ctx context.Context, req *models.PlannerRe
 		}
 	}
 
+	// Resolve time expressions such as "last 7 days" or "Q3 2024" into absolute bounds
+	if tr := primaryIntent.ParsedQuery.TimeRange; tr == nil || (tr.Start == nil && tr.End == nil) {
+		if resolved := ps.resolveTimeRange(req.Query); resolved != nil {
+			primaryIntent.ParsedQuery.TimeRange = resolved
+		}
+	}
+
+	// Recognized dimension values become filters, e.g. "California" as region = 'CA'
+	var linked []LinkedValue
+	if ps.valueLinker != nil {
+		linked = ps.valueLinker.LinkValues(ctx, requestConnectorIDs(req), req.Query)
+		primaryIntent.ParsedQuery.Filters = append(primaryIntent.ParsedQuery.Filters, linkedFilters(linked)...)
+	}
+
 	// Step 2: Extract entities and parameters
 	entities := ps.entityExtractor.ExtractEntities(req.Query)
 	parsedQuery := ps.parseQueryStructure(req.Query, entities)
+	parsedQuery.TimeRange = primaryIntent.ParsedQuery.TimeRange
+	parsedQuery.Filters = primaryIntent.ParsedQuery.Filters
 
 	// Step 3: Generate task graph
 	taskGraph, err := ps.generateTaskGraph(ctx, primaryIntent, parsedQuery, req)
@@ -86,6 +130,7 @@ func (ps *PlannerService) ParseIntent(ctx context.Context, req *models.PlannerRe
 		Metadata: map[string]interface{}{
 			"entities_found": len(entities),
 			"steps_planned":  len(taskGraph.Steps),
+			"linked_values":  linked,
 		},
 	}
 
@@ -288,6 +333,90 @@ func (ps *PlannerService) enhanceParsedQueryFromPatterns(queryLower string, inte
 	return pq
 }
 
+// resolveTimeRange returns the absolute range of the first time expression in the
+// query, or nil when it has none
+func (ps *PlannerService) resolveTimeRange(query string) *models.TimeRange {
+	resolved, ok := ps.timeResolver.Resolve(query)
+	if !ok {
+		return nil
+	}
+	ps.logger.Debug("Resolved time expression",
+		"expression", resolved.Text,
+		"start", resolved.Start,
+		"end", resolved.End,
+		"granularity", resolved.Granularity)
+	return timeRangeFromExpr(resolved)
+}
+
+// linkedFilters turns linked values into equality filters, one per column, with
+// several values of a column combined into IN
+func linkedFilters(linked []LinkedValue) []models.Filter {
+	var filters []models.Filter
+	var values [][]string
+	positions := make(map[[3]string]int)
+	for _, v := range linked {
+		key := [3]string{v.ConnectorID, v.Table, v.Column}
+		i, ok := positions[key]
+		if !ok {
+			i = len(filters)
+			positions[key] = i
+			filters = append(filters, models.Filter{
+				Field:       v.Column,
+				Operator:    "=",
+				Condition:   "AND",
+				Table:       v.Table,
+				ConnectorID: v.ConnectorID,
+				Text:        v.Text,
+			})
+			values = append(values, nil)
+		} else {
+			filters[i].Text += ", " + v.Text
+		}
+		values[i] = append(values[i], v.Value)
+	}
+
+	for i := range filters {
+		if len(values[i]) == 1 {
+			filters[i].Value = values[i][0]
+		} else {
+			filters[i].Operator = "IN"
+			filters[i].Value = values[i]
+		}
+	}
+	return filters
+}
+
+// requestConnectorIDs returns the connectors a planner request is restricted to
+func requestConnectorIDs(req *models.PlannerRequest) []string {
+	switch ids := req.Context["connector_ids"].(type) {
+	case []string:
+		return ids
+	case []interface{}:
+		var result []string
+		for _, id := range ids {
+			if s, ok := id.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+func timeRangeFromExpr(r *timeexpr.Range) *models.TimeRange {
+	start, end := r.Start, r.End
+	tr := &models.TimeRange{
+		Start:       &start,
+		End:         &end,
+		Relative:    r.Text,
+		Granularity: string(r.Granularity),
+	}
+	if r.Comparison != nil {
+		tr.Comparison = timeRangeFromExpr(r.Comparison)
+	}
+	return tr
+}
+
 // generateTaskGraph creates an execution plan based on intent and parsed query
 func (ps *PlannerService) generateTaskGraph(
 	ctx context.Context,
@@ -347,6 +476,7 @@ func (ps *PlannerService) addAnalyticsSteps(taskGraph *models.TaskGraph, parsedQ
 			Dependencies: []string{"data_discovery"},
 			Priority:    2,
 			EstimatedTime: 5 * time.Second,
+			Retry:       defaultRetrievalRetry(),
 		},
 		{
 			ID:          "data_analysis",
@@ -381,6 +511,7 @@ func (ps *PlannerService) addSQLSteps(taskGraph *models.TaskGraph, parsedQuery m
 			Dependencies: []string{"sql_validation"},
 			Priority:    2,
 			EstimatedTime: 10 * time.Second,
+			Retry:       defaultRetrievalRetry(),
 		},
 	}
 
@@ -422,6 +553,7 @@ func (ps *PlannerService) addComparisonSteps(taskGraph *models.TaskGraph, parsed
 			Action:      "fetch_comparison_data",
 			Priority:    1,
 			EstimatedTime: 7 * time.Second,
+			Retry:       defaultRetrievalRetry(),
 		},
 		{
 			ID:          "data_alignment",
@@ -456,6 +588,7 @@ func (ps *PlannerService) addTrendSteps(taskGraph *models.TaskGraph, parsedQuery
 			Action:      "fetch_time_series",
 			Priority:    1,
 			EstimatedTime: 5 * time.Second,
+			Retry:       defaultRetrievalRetry(),
 		},
 		{
 			ID:          "trend_analysis",
@@ -498,6 +631,12 @@ func (ps *PlannerService) updateDependencies(taskGraph *models.TaskGraph) {
 	}
 }
 
+// defaultRetrievalRetry is how failed retrievals are retried, since sources fail
+// transiently, e.g. on timeouts
+func defaultRetrievalRetry() *models.RetryConfig {
+	return &models.RetryConfig{MaxRetries: 1, Delay: time.Second, Backoff: "exponential"}
+}
+
 func (ps *PlannerService) calculateEstimatedTime(steps []models.TaskStep) time.Duration {
 	var totalTime time.Duration
 	for _, step := range steps {
@@ -0,0 +1,275 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"insightiq/backend/internal/models"
+	"insightiq/backend/internal/repository"
+)
+
+var (
+	// ErrPlanNotRunning is returned when cancelling a plan that finished or does not run on this server
+	ErrPlanNotRunning = errors.New("plan is not running")
+	// ErrPlanCancelled is returned for questions whose plan was cancelled while it ran
+	ErrPlanCancelled = errors.New("plan cancelled")
+)
+
+const (
+	// planSaveTimeout bounds each write of a plan's state, which happen even after
+	// the request that started the plan went away
+	planSaveTimeout = 5 * time.Second
+	// planHeartbeatInterval is the longest a running plan goes without updating its
+	// row, so other servers can tell it from one whose server stopped
+	planHeartbeatInterval = 30 * time.Second
+	// planStaleAfter is how long an unfinished plan may go without an update before
+	// it is considered abandoned
+	planStaleAfter = 4 * planHeartbeatInterval
+)
+
+// PlanService stores the task graphs questions are answered with, so their
+// progress can be followed and their runs cancelled from any request
+type PlanService struct {
+	repo   *repository.PlanRepository
+	mu     sync.Mutex
+	cancel map[string]context.CancelFunc // of the plans running on this server, by ID
+	logger *slog.Logger
+}
+
+func NewPlanService(repo *repository.PlanRepository, logger *slog.Logger) *PlanService {
+	return &PlanService{
+		repo:   repo,
+		cancel: make(map[string]context.CancelFunc),
+		logger: logger.With("service", "plans"),
+	}
+}
+
+// PlanRun is a plan being run. Its methods must be called from the goroutine
+// answering the question.
+type PlanRun struct {
+	ID      string
+	ctx     context.Context
+	service *PlanService
+	graph   *models.TaskGraph
+	updates chan *models.TaskGraph // latest snapshot not yet saved
+	saved   chan struct{}          // closed once all snapshots were saved
+}
+
+// Start stores a new plan for a question and returns its run. The run has its own
+// context: it outlives the request starting it, so a client that went away can
+// reattach, and ends only when the plan finishes or is cancelled.
+func (s *PlanService) Start(ctx context.Context, userID, query string) (*PlanRun, error) {
+	plan := &models.Plan{
+		ID:     uuid.New().String(),
+		UserID: userID,
+		Query:  query,
+		Status: models.TaskStatusExecuting,
+	}
+	if err := s.repo.Create(ctx, plan); err != nil {
+		return nil, err
+	}
+
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	s.mu.Lock()
+	s.cancel[plan.ID] = cancel
+	s.mu.Unlock()
+
+	run := &PlanRun{
+		ID:      plan.ID,
+		ctx:     runCtx,
+		service: s,
+		updates: make(chan *models.TaskGraph, 1),
+		saved:   make(chan struct{}),
+	}
+	go run.saveProgress()
+	return run, nil
+}
+
+// Context is cancelled when the plan is
+func (r *PlanRun) Context() context.Context {
+	return r.ctx
+}
+
+// Cancelled reports whether the plan was cancelled
+func (r *PlanRun) Cancelled() bool {
+	return r.ctx.Err() != nil
+}
+
+// SetGraph sets the task graph the plan runs and saves it
+func (r *PlanRun) SetGraph(graph *models.TaskGraph) {
+	graph.ID = r.ID
+	r.graph = graph
+	r.Progress(graph)
+}
+
+// Progress saves the state of the steps of the graph in the background. Only the
+// latest state is kept when saving falls behind.
+func (r *PlanRun) Progress(graph *models.TaskGraph) {
+	// The executor only assigns step fields, so copying the steps is a snapshot
+	snapshot := *graph
+	snapshot.Steps = slices.Clone(graph.Steps)
+
+	select {
+	case <-r.updates:
+	default:
+	}
+	r.updates <- &snapshot
+}
+
+// saveProgress saves the snapshots sent by Progress and, between them, keeps the
+// plan's row fresh so the plan is not taken for abandoned
+func (r *PlanRun) saveProgress() {
+	defer close(r.saved)
+	heartbeat := time.NewTicker(planHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case graph, ok := <-r.updates:
+			if !ok {
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(r.ctx), planSaveTimeout)
+			if err := r.service.repo.UpdateProgress(ctx, r.ID, models.TaskStatusExecuting, graph); err != nil {
+				r.service.logger.Warn("Failed to save plan progress", "plan", r.ID, "error", err)
+			}
+			cancel()
+			heartbeat.Reset(planHeartbeatInterval)
+		case <-heartbeat.C:
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(r.ctx), planSaveTimeout)
+			if err := r.service.repo.Touch(ctx, r.ID); err != nil {
+				r.service.logger.Warn("Failed to refresh running plan", "plan", r.ID, "error", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// Finish saves the outcome of the plan, the result answering its question or the
+// error it failed with, and ends the run
+func (r *PlanRun) Finish(result interface{}, err error) {
+	close(r.updates)
+	<-r.saved
+
+	status := models.TaskStatusCompleted
+	var errorMessage string
+	var resultJSON json.RawMessage
+	switch {
+	case err != nil && r.Cancelled():
+		status = models.TaskStatusCancelled
+		errorMessage = ErrPlanCancelled.Error()
+	case err != nil:
+		status = models.TaskStatusFailed
+		errorMessage = err.Error()
+	default:
+		if resultJSON, err = json.Marshal(result); err != nil {
+			status = models.TaskStatusFailed
+			errorMessage = "failed to encode result: " + err.Error()
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.ctx), planSaveTimeout)
+	defer cancel()
+	if err := r.service.repo.Finish(ctx, r.ID, status, r.graph, resultJSON, errorMessage); err != nil {
+		r.service.logger.Error("Failed to save plan outcome", "plan", r.ID, "status", status, "error", err)
+	}
+	r.service.release(r.ID)
+}
+
+// release forgets a run and frees its context
+func (s *PlanService) release(id string) {
+	s.mu.Lock()
+	cancel := s.cancel[id]
+	delete(s.cancel, id)
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// Get returns a plan of a user with its progress
+func (s *PlanService) Get(ctx context.Context, userID, id string) (*models.Plan, error) {
+	plan, err := s.repo.GetByID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if plan.Graph != nil {
+		plan.Progress = plan.Graph.Progress()
+	}
+	return plan, nil
+}
+
+// List returns the plans of a user, newest first; an empty status lists all
+func (s *PlanService) List(ctx context.Context, userID string, status models.TaskStatus, limit, offset int) ([]models.Plan, error) {
+	plans, err := s.repo.ListByUser(ctx, userID, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	for i := range plans {
+		if plans[i].Graph != nil {
+			plans[i].Progress = plans[i].Graph.Progress()
+		}
+	}
+	return plans, nil
+}
+
+// Cancel cancels a running plan of a user. Running connector queries and LLM calls
+// are cancelled through the context of the run, and no further steps start.
+func (s *PlanService) Cancel(ctx context.Context, userID, id string) error {
+	plan, err := s.repo.GetByID(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if plan.FinishedAt != nil {
+		return ErrPlanNotRunning
+	}
+
+	s.mu.Lock()
+	cancel, ok := s.cancel[id]
+	s.mu.Unlock()
+	if !ok {
+		return ErrPlanNotRunning
+	}
+	cancel()
+	s.logger.Info("Plan cancelled", "plan", id, "user_id", userID)
+	return nil
+}
+
+// AbandonUnfinished marks plans whose server stopped as failed, as nothing will
+// finish them. Running plans refresh their row every planHeartbeatInterval, so plans
+// of other servers sharing the database are left alone while those servers run.
+func (s *PlanService) AbandonUnfinished(ctx context.Context) error {
+	n, err := s.repo.AbandonUnfinished(ctx, "server stopped before the plan finished", time.Now().Add(-planStaleAfter))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		s.logger.Warn("Marked unfinished plans as failed", "count", n)
+	}
+	return nil
+}
+
+// SweepAbandoned runs AbandonUnfinished now and then every planStaleAfter until ctx
+// is cancelled, so plans of a server that stopped are failed while others keep running
+func (s *PlanService) SweepAbandoned(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(planStaleAfter)
+		defer ticker.Stop()
+		for {
+			if err := s.AbandonUnfinished(ctx); err != nil {
+				s.logger.Warn("Failed to fail plans left unfinished", "error", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
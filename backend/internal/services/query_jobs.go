@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"insightiq/backend/internal/models"
+)
+
+// ErrQueryJobNotFound is returned for jobs that do not exist, belong to another
+// user or were forgotten after queryJobRetention
+var ErrQueryJobNotFound = errors.New("query job not found")
+
+// Bounds of query jobs
+const (
+	// queryJobTimeout bounds how long answering one question may take
+	queryJobTimeout = 10 * time.Minute
+	// queryJobRetention is how long finished jobs stay available
+	queryJobRetention = 30 * time.Minute
+)
+
+// QueryEventType is what a query event reports
+type QueryEventType string
+
+const (
+	QueryEventIntent       QueryEventType = "intent"        // question classified
+	QueryEventPlan         QueryEventType = "plan"          // task graph planned and stored
+	QueryEventSources      QueryEventType = "sources"       // data sources selected
+	QueryEventSQL          QueryEventType = "sql"           // SQL generated for a source
+	QueryEventRows         QueryEventType = "rows"          // rows fetched from a source
+	QueryEventInsightToken QueryEventType = "insight_token" // token of the insights being written
+	QueryEventDone         QueryEventType = "done"          // answered, with the result
+	QueryEventError        QueryEventType = "error"         // failed, with the error
+)
+
+// QueryEvent is a step of answering a question, as streamed to clients
+type QueryEvent struct {
+	ID   int            `json:"id"` // increasing from 1 within a job
+	Type QueryEventType `json:"type"`
+	Data interface{}    `json:"data,omitempty"`
+	Time time.Time      `json:"time"`
+}
+
+// QueryEventFunc receives the events of answering a question. It may be called
+// from several goroutines at once.
+type QueryEventFunc func(eventType QueryEventType, data interface{})
+
+// QueryJob is a question answered in the background
+type QueryJob struct {
+	ID        string
+	UserID    string
+	Query     string
+	CreatedAt time.Time
+
+	mu         sync.Mutex
+	status     models.TaskStatus
+	result     interface{}
+	err        string
+	finishedAt *time.Time
+	events     []QueryEvent
+	changed    chan struct{} // closed and replaced whenever an event is added
+}
+
+// QueryJobSnapshot is the state of a job with the events after a given one
+type QueryJobSnapshot struct {
+	ID         string            `json:"id"`
+	Query      string            `json:"query"`
+	Status     models.TaskStatus `json:"status"`
+	Result     interface{}       `json:"result,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+	Events     []QueryEvent      `json:"events"`
+}
+
+// emit adds an event and wakes up the clients waiting for it
+func (j *QueryJob) emit(eventType QueryEventType, data interface{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.appendEvent(eventType, data)
+}
+
+func (j *QueryJob) appendEvent(eventType QueryEventType, data interface{}) {
+	j.events = append(j.events, QueryEvent{
+		ID:   len(j.events) + 1,
+		Type: eventType,
+		Data: data,
+		Time: time.Now(),
+	})
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// finish records the outcome of the job along with its last event
+func (j *QueryJob) finish(result interface{}, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	j.finishedAt = &now
+	switch {
+	case err == nil:
+		j.status = models.TaskStatusCompleted
+		j.result = result
+		j.appendEvent(QueryEventDone, result)
+	case errors.Is(err, ErrPlanCancelled):
+		j.status = models.TaskStatusCancelled
+		j.err = err.Error()
+		j.appendEvent(QueryEventError, map[string]string{"error": j.err})
+	default:
+		j.status = models.TaskStatusFailed
+		j.err = err.Error()
+		j.appendEvent(QueryEventError, map[string]string{"error": j.err})
+	}
+}
+
+// EventsSince returns the events after the one with ID after, whether the job
+// finished, and a channel closed when there are more events
+func (j *QueryJob) EventsSince(after int) ([]QueryEvent, bool, <-chan struct{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	after = min(max(after, 0), len(j.events))
+	events := append([]QueryEvent(nil), j.events[after:]...)
+	return events, j.finishedAt != nil, j.changed
+}
+
+// Snapshot returns the state of the job with the events after the one with ID after
+func (j *QueryJob) Snapshot(after int) *QueryJobSnapshot {
+	events, _, _ := j.EventsSince(after)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return &QueryJobSnapshot{
+		ID:         j.ID,
+		Query:      j.Query,
+		Status:     j.status,
+		Result:     j.result,
+		Error:      j.err,
+		CreatedAt:  j.CreatedAt,
+		FinishedAt: j.finishedAt,
+		Events:     events,
+	}
+}
+
+// QueryJobService answers questions in the background, so clients are not held
+// up by planning, fetching and writing insights and can follow along instead
+type QueryJobService struct {
+	analytics     *AnalyticsService
+	conversations *ConversationService
+	mu            sync.Mutex
+	jobs          map[string]*QueryJob
+	logger        *slog.Logger
+}
+
+func NewQueryJobService(analytics *AnalyticsService, logger *slog.Logger) *QueryJobService {
+	return &QueryJobService{
+		analytics: analytics,
+		jobs:      make(map[string]*QueryJob),
+		logger:    logger.With("service", "query_jobs"),
+	}
+}
+
+// SetConversationService answers the questions of signed-in users within their
+// conversation sessions
+func (s *QueryJobService) SetConversationService(conversations *ConversationService) {
+	s.conversations = conversations
+}
+
+// Submit starts answering a question of a user and returns its job. The job does
+// not stop when ctx is cancelled. sessionID continues a conversation session; a new
+// one is started when it is empty and the user is signed in.
+func (s *QueryJobService) Submit(ctx context.Context, userID, sessionID, query string, filters *[]models.Filter) *QueryJob {
+	job := &QueryJob{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Query:     query,
+		CreatedAt: time.Now(),
+		status:    models.TaskStatusExecuting,
+		changed:   make(chan struct{}),
+	}
+
+	s.mu.Lock()
+	s.pruneLocked()
+	s.jobs[job.ID] = job
+	s.mu.Unlock()
+
+	go s.run(context.WithoutCancel(ctx), job, sessionID, filters)
+	s.logger.Info("Query job submitted", "job", job.ID, "user_id", userID)
+	return job
+}
+
+func (s *QueryJobService) run(ctx context.Context, job *QueryJob, sessionID string, filters *[]models.Filter) {
+	ctx, cancel := context.WithTimeout(ctx, queryJobTimeout)
+	defer cancel()
+
+	opts := QueryOptions{Filters: filters, UserID: job.UserID, OnEvent: job.emit}
+	var result interface{}
+	var err error
+	if s.conversations != nil && job.UserID != "" {
+		result, err = s.conversations.Ask(ctx, job.UserID, sessionID, job.Query, opts)
+	} else {
+		result, err = s.analytics.ProcessQueryWithOptions(ctx, job.Query, opts)
+	}
+
+	if err != nil {
+		s.logger.Warn("Query job failed", "job", job.ID, "error", err)
+	}
+	job.finish(result, err)
+}
+
+// Get returns a job of a user
+func (s *QueryJobService) Get(userID, id string) (*QueryJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok || job.UserID != userID {
+		return nil, ErrQueryJobNotFound
+	}
+	return job, nil
+}
+
+// pruneLocked forgets jobs that finished more than queryJobRetention ago
+func (s *QueryJobService) pruneLocked() {
+	cutoff := time.Now().Add(-queryJobRetention)
+	for id, job := range s.jobs {
+		job.mu.Lock()
+		expired := job.finishedAt != nil && job.finishedAt.Before(cutoff)
+		job.mu.Unlock()
+		if expired {
+			delete(s.jobs, id)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"insightiq/backend/internal/models"
+)
+
+func TestQueryJobEvents(t *testing.T) {
+	job := &QueryJob{ID: "job", status: models.TaskStatusExecuting, changed: make(chan struct{})}
+	job.emit(QueryEventIntent, nil)
+
+	events, finished, changed := job.EventsSince(0)
+	if len(events) != 1 || events[0].ID != 1 || finished {
+		t.Fatalf("events = %v, finished = %v", events, finished)
+	}
+
+	job.emit(QueryEventSources, nil)
+	select {
+	case <-changed:
+	default:
+		t.Fatal("waiters not woken up by a new event")
+	}
+
+	job.finish(nil, errors.New("boom"))
+	events, finished, _ = job.EventsSince(1)
+	if len(events) != 2 || events[0].Type != QueryEventSources || events[1].Type != QueryEventError || !finished {
+		t.Fatalf("events = %v, finished = %v", events, finished)
+	}
+	if snapshot := job.Snapshot(3); snapshot.Status != models.TaskStatusFailed || len(snapshot.Events) != 0 {
+		t.Errorf("snapshot = %+v", snapshot)
+	}
+}
@@ -0,0 +1,307 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"insightiq/backend/internal/models"
+	"insightiq/backend/internal/repository"
+	"insightiq/backend/internal/sqlparse"
+)
+
+// semanticModelFileVersion is the version written to YAML exports
+const semanticModelFileVersion = 1
+
+var (
+	// ErrInvalidDefinition is returned when a metric or dimension definition is incomplete or not valid SQL
+	ErrInvalidDefinition = errors.New("invalid semantic definition")
+	// ErrConnectorNotFound is returned when definitions are requested for an unknown connector
+	ErrConnectorNotFound = errors.New("connector not found")
+)
+
+// Aggregations a metric definition may apply to its expression
+var definitionAggregations = map[string]bool{
+	"":               true,
+	"sum":            true,
+	"count":          true,
+	"count_distinct": true,
+	"avg":            true,
+	"min":            true,
+	"max":            true,
+}
+
+// SemanticService manages the user-maintained metric and dimension definitions of
+// connectors
+type SemanticService struct {
+	repo             *repository.SemanticRepository
+	connectorService *ConnectorService
+	logger           *slog.Logger
+}
+
+func NewSemanticService(repo *repository.SemanticRepository, connectorService *ConnectorService, logger *slog.Logger) *SemanticService {
+	return &SemanticService{
+		repo:             repo,
+		connectorService: connectorService,
+		logger:           logger.With("service", "semantic"),
+	}
+}
+
+// ListDefinitions retrieves the definitions of a connector, certified ones first
+func (s *SemanticService) ListDefinitions(ctx context.Context, connectorID string) ([]*models.SemanticDefinition, error) {
+	if err := s.checkConnector(ctx, connectorID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByConnector(ctx, connectorID)
+}
+
+// ConnectorDefinitions returns the current definitions of a connector for schema
+// analysis and SQL generation
+func (s *SemanticService) ConnectorDefinitions(ctx context.Context, connectorID string) ([]models.SemanticDefinition, error) {
+	defs, err := s.repo.ListByConnector(ctx, connectorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list semantic definitions: %w", err)
+	}
+
+	result := make([]models.SemanticDefinition, len(defs))
+	for i, def := range defs {
+		result[i] = *def
+	}
+	return result, nil
+}
+
+// GetDefinition retrieves a definition of a connector
+func (s *SemanticService) GetDefinition(ctx context.Context, connectorID, id string) (*models.SemanticDefinition, error) {
+	return s.repo.GetByID(ctx, connectorID, id)
+}
+
+// DefinitionVersions retrieves the history of a definition, newest first
+func (s *SemanticService) DefinitionVersions(ctx context.Context, connectorID, id string) ([]*models.SemanticDefinitionVersion, error) {
+	if _, err := s.repo.GetByID(ctx, connectorID, id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetVersions(ctx, id)
+}
+
+// CreateDefinition adds a metric or dimension to a connector. The owner defaults to
+// the creating user.
+func (s *SemanticService) CreateDefinition(ctx context.Context, connectorID string, req *models.SemanticDefinitionRequest, user string) (*models.SemanticDefinition, error) {
+	if err := s.checkConnector(ctx, connectorID); err != nil {
+		return nil, err
+	}
+	if err := validateDefinition(req.Kind, &req.SemanticDefinitionSpec); err != nil {
+		return nil, err
+	}
+
+	def := &models.SemanticDefinition{
+		ConnectorID:            connectorID,
+		Kind:                   req.Kind,
+		SemanticDefinitionSpec: req.SemanticDefinitionSpec,
+		UpdatedBy:              user,
+	}
+	if def.Owner == "" {
+		def.Owner = user
+	}
+
+	if err := s.repo.Create(ctx, def); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Created semantic definition", "connector_id", connectorID, "kind", def.Kind, "name", def.Name)
+	return def, nil
+}
+
+// UpdateDefinition replaces a definition. When expectedVersion is not zero the
+// update fails with repository.ErrDefinitionVersionConflict if the definition has
+// changed since that version.
+func (s *SemanticService) UpdateDefinition(ctx context.Context, connectorID, id string, req *models.SemanticDefinitionRequest, expectedVersion int, user string) (*models.SemanticDefinition, error) {
+	existing, err := s.repo.GetByID(ctx, connectorID, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Kind != "" && req.Kind != existing.Kind {
+		return nil, fmt.Errorf("%w: the kind of a definition cannot be changed", ErrInvalidDefinition)
+	}
+	if err := validateDefinition(existing.Kind, &req.SemanticDefinitionSpec); err != nil {
+		return nil, err
+	}
+
+	def := &models.SemanticDefinition{
+		ID:                     id,
+		ConnectorID:            connectorID,
+		SemanticDefinitionSpec: req.SemanticDefinitionSpec,
+		UpdatedBy:              user,
+	}
+	if def.Owner == "" {
+		def.Owner = existing.Owner
+	}
+
+	if err := s.repo.Update(ctx, def, expectedVersion); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Updated semantic definition", "connector_id", connectorID, "name", def.Name, "version", def.Version)
+	return def, nil
+}
+
+// DeleteDefinition removes a definition and its history
+func (s *SemanticService) DeleteDefinition(ctx context.Context, connectorID, id string) error {
+	if err := s.repo.Delete(ctx, connectorID, id); err != nil {
+		return err
+	}
+
+	s.logger.Info("Deleted semantic definition", "connector_id", connectorID, "id", id)
+	return nil
+}
+
+// ExportYAML writes the definitions of a connector as a semantic model file
+func (s *SemanticService) ExportYAML(ctx context.Context, connectorID string) ([]byte, error) {
+	defs, err := s.ListDefinitions(ctx, connectorID)
+	if err != nil {
+		return nil, err
+	}
+
+	file := models.SemanticModelFile{Version: semanticModelFileVersion}
+	for _, def := range defs {
+		switch def.Kind {
+		case models.DefinitionKindMetric:
+			file.Metrics = append(file.Metrics, def.SemanticDefinitionSpec)
+		case models.DefinitionKindDimension:
+			file.Dimensions = append(file.Dimensions, def.SemanticDefinitionSpec)
+		}
+	}
+
+	data, err := yaml.Marshal(&file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode semantic model: %w", err)
+	}
+	return data, nil
+}
+
+// ImportYAML creates or updates definitions from a semantic model file, matching
+// existing ones by kind and name. Every entry is validated before anything is saved;
+// definitions missing from the file are kept.
+func (s *SemanticService) ImportYAML(ctx context.Context, connectorID string, data []byte, user string) (*models.SemanticImportResult, error) {
+	if err := s.checkConnector(ctx, connectorID); err != nil {
+		return nil, err
+	}
+
+	var file models.SemanticModelFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%w: failed to parse YAML: %v", ErrInvalidDefinition, err)
+	}
+	if file.Version > semanticModelFileVersion {
+		return nil, fmt.Errorf("%w: unsupported semantic model version %d", ErrInvalidDefinition, file.Version)
+	}
+
+	var requests []*models.SemanticDefinitionRequest
+	seen := make(map[string]bool)
+	add := func(kind models.DefinitionKind, specs []models.SemanticDefinitionSpec) error {
+		for _, spec := range specs {
+			req := &models.SemanticDefinitionRequest{Kind: kind, SemanticDefinitionSpec: spec}
+			if err := validateDefinition(kind, &req.SemanticDefinitionSpec); err != nil {
+				return err
+			}
+			key := string(kind) + ":" + strings.ToLower(req.Name)
+			if seen[key] {
+				return fmt.Errorf("%w: %s %q is defined more than once", ErrInvalidDefinition, kind, req.Name)
+			}
+			seen[key] = true
+			requests = append(requests, req)
+		}
+		return nil
+	}
+	if err := add(models.DefinitionKindMetric, file.Metrics); err != nil {
+		return nil, err
+	}
+	if err := add(models.DefinitionKindDimension, file.Dimensions); err != nil {
+		return nil, err
+	}
+
+	result := &models.SemanticImportResult{}
+	for _, req := range requests {
+		existing, err := s.repo.GetByName(ctx, connectorID, req.Kind, req.Name)
+		if errors.Is(err, repository.ErrDefinitionNotFound) {
+			if _, err := s.CreateDefinition(ctx, connectorID, req, user); err != nil {
+				return result, fmt.Errorf("failed to create %s %q: %w", req.Kind, req.Name, err)
+			}
+			result.Created++
+			continue
+		}
+		if err != nil {
+			return result, fmt.Errorf("failed to look up %s %q: %w", req.Kind, req.Name, err)
+		}
+
+		if req.Owner == "" {
+			req.Owner = existing.Owner
+		}
+		if specEqual(existing.SemanticDefinitionSpec, req.SemanticDefinitionSpec) {
+			result.Unchanged++
+			continue
+		}
+		if _, err := s.UpdateDefinition(ctx, connectorID, existing.ID, req, existing.Version, user); err != nil {
+			return result, fmt.Errorf("failed to update %s %q: %w", req.Kind, req.Name, err)
+		}
+		result.Updated++
+	}
+
+	s.logger.Info("Imported semantic model", "connector_id", connectorID,
+		"created", result.Created, "updated", result.Updated, "unchanged", result.Unchanged)
+	return result, nil
+}
+
+// checkConnector returns ErrConnectorNotFound when the connector doesn't exist
+func (s *SemanticService) checkConnector(ctx context.Context, connectorID string) error {
+	connector, err := s.connectorService.GetConnector(ctx, connectorID)
+	if err != nil {
+		return err
+	}
+	if connector == nil {
+		return ErrConnectorNotFound
+	}
+	return nil
+}
+
+// validateDefinition trims and checks a definition. Expressions must parse as
+// read-only SQL so they can be placed into generated queries.
+func validateDefinition(kind models.DefinitionKind, spec *models.SemanticDefinitionSpec) error {
+	spec.Name = strings.TrimSpace(spec.Name)
+	spec.Table = strings.TrimSpace(spec.Table)
+	spec.Expression = strings.TrimSpace(spec.Expression)
+	spec.Aggregation = strings.ToLower(strings.TrimSpace(spec.Aggregation))
+
+	switch {
+	case kind != models.DefinitionKindMetric && kind != models.DefinitionKindDimension:
+		return fmt.Errorf("%w: kind must be %q or %q", ErrInvalidDefinition, models.DefinitionKindMetric, models.DefinitionKindDimension)
+	case spec.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidDefinition)
+	case spec.Table == "":
+		return fmt.Errorf("%w: table is required for %q", ErrInvalidDefinition, spec.Name)
+	case spec.Expression == "":
+		return fmt.Errorf("%w: expression is required for %q", ErrInvalidDefinition, spec.Name)
+	case !definitionAggregations[spec.Aggregation]:
+		return fmt.Errorf("%w: unsupported aggregation %q for %q", ErrInvalidDefinition, spec.Aggregation, spec.Name)
+	case kind == models.DefinitionKindDimension && spec.Aggregation != "":
+		return fmt.Errorf("%w: dimension %q cannot have an aggregation", ErrInvalidDefinition, spec.Name)
+	}
+
+	if _, err := sqlparse.ValidateReadOnlyDialect("SELECT "+spec.Expression+" FROM t", sqlparse.DialectAny); err != nil {
+		return fmt.Errorf("%w: expression of %q: %v", ErrInvalidDefinition, spec.Name, err)
+	}
+	return nil
+}
+
+// specEqual compares definitions, treating missing and empty synonyms alike
+func specEqual(a, b models.SemanticDefinitionSpec) bool {
+	if !slices.Equal(a.Synonyms, b.Synonyms) {
+		return false
+	}
+	a.Synonyms, b.Synonyms = nil, nil
+	return reflect.DeepEqual(a, b)
+}
@@ -3,6 +3,7 @@ package services
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"log/slog"
 	"time"
@@ -38,7 +39,9 @@ func NewVoiceService(agentManager *agent.Manager, logger *slog.Logger) *VoiceSer
 	}
 }
 
-func (vs *VoiceService) ProcessVoiceQuery(ctx context.Context, audioData []byte, format string) (*VoiceResponse, error) {
+// ProcessVoiceQuery transcribes and answers a spoken question. The agent task is
+// queued under userID, so it counts towards that user's share of the queue.
+func (vs *VoiceService) ProcessVoiceQuery(ctx context.Context, userID string, audioData []byte, format string) (*VoiceResponse, error) {
 	start := time.Now()
 	taskID := generateTaskID()
 
@@ -61,6 +64,7 @@ func (vs *VoiceService) ProcessVoiceQuery(ctx context.Context, audioData []byte,
 		ID:      taskID,
 		Type:    "voice_query",
 		AgentID: "voice-1",
+		UserID:  userID,
 		Payload: map[string]interface{}{
 			"audio_data": audioData,
 			"format":     format,
@@ -108,14 +112,20 @@ func (vs *VoiceService) ProcessVoiceQuery(ctx context.Context, audioData []byte,
 				Status:      "completed",
 			}
 
-			if data, ok := analyticsResult["data"].([]map[string]interface{}); ok {
+			if data, ok := taskRows(analyticsResult["data"]); ok {
 				response.Response.Data = data
 			}
 		}
 
 		// Extract audio reply if available
-		if audioReply, ok := result.Result["audio_reply"].([]byte); ok {
+		switch audioReply := result.Result["audio_reply"].(type) {
+		case []byte:
 			response.AudioReply = audioReply
+		case string:
+			// Results loaded from a shared store hold bytes as base64
+			if decoded, err := base64.StdEncoding.DecodeString(audioReply); err == nil {
+				response.AudioReply = decoded
+			}
 		}
 	} else {
 		vs.logger.Error("Voice task failed", "task_id", taskID, "status", result.Status, "error", result.Error)
@@ -130,7 +140,7 @@ func (vs *VoiceService) ProcessVoiceQuery(ctx context.Context, audioData []byte,
 	return response, nil
 }
 
-func (vs *VoiceService) ProcessAudioFile(ctx context.Context, audioData []byte, filename string) (*VoiceResponse, error) {
+func (vs *VoiceService) ProcessAudioFile(ctx context.Context, userID string, audioData []byte, filename string) (*VoiceResponse, error) {
 	// Extract format from filename
 	format := "wav"
 	if len(filename) > 4 {
@@ -141,7 +151,7 @@ func (vs *VoiceService) ProcessAudioFile(ctx context.Context, audioData []byte,
 		}
 	}
 
-	return vs.ProcessVoiceQuery(ctx, audioData, format)
+	return vs.ProcessVoiceQuery(ctx, userID, audioData, format)
 }
 
 func (vs *VoiceService) GetSupportedFormats() []string {
@@ -173,30 +183,14 @@ func (vs *VoiceService) HealthCheck(ctx context.Context) map[string]interface{}
 
 // Helper function to wait for voice task results
 func (vs *VoiceService) waitForVoiceResult(ctx context.Context, taskID string, timeout time.Duration) (*agent.TaskResult, error) {
-	// Get the result from agent manager
-	result := vs.agentManager.GetTaskResult(taskID)
-	if result != nil {
-		return result, nil
-	}
-
-	// Wait for the task to complete with polling
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	ticker := time.NewTicker(1 * time.Second) // Check every second for voice tasks
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return nil, fmt.Errorf("voice task timeout: %s", taskID)
-		case <-ticker.C:
-			result := vs.agentManager.GetTaskResult(taskID)
-			if result != nil {
-				return result, nil
-			}
-		}
+	result, err := vs.agentManager.WaitForResult(ctx, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("voice task timeout: %s: %w", taskID, err)
 	}
+	return result, nil
 }
 
 // Helper function to safely extract string from map
@@ -0,0 +1,293 @@
+// Package sqlgen turns questions into read-only SQL grounded in a connector's
+// scanned schema. Generated queries are validated, dry-run with EXPLAIN and run;
+// errors along the way are fed back to the LLM for a bounded number of repairs.
+package sqlgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"insightiq/backend/internal/connectors"
+	"insightiq/backend/internal/models"
+	"insightiq/backend/internal/schema"
+	"insightiq/backend/internal/semantic"
+	"insightiq/backend/internal/sqlparse"
+)
+
+const (
+	// DefaultMaxRepairs is how many times a failing query is sent back to the LLM
+	DefaultMaxRepairs = 2
+	// Domain contexts retrieved from the vector store per question
+	domainContextLimit = 3
+)
+
+var (
+	// ErrNoLLM is returned when the generator has no LLM to prompt
+	ErrNoLLM = errors.New("LLM not configured for SQL generation")
+	// ErrGenerationFailed is returned when no attempt produced a query that runs
+	ErrGenerationFailed = errors.New("failed to generate a working SQL query")
+)
+
+// Stages an attempt can fail at
+const (
+	StageExtract  = "extract"
+	StageValidate = "validate"
+	StageExplain  = "explain"
+	StageExecute  = "execute"
+)
+
+// LLM completes prompts
+type LLM interface {
+	GenerateResponse(ctx context.Context, prompt string) (string, error)
+}
+
+// DomainRetriever finds the business domains of a connector that relate to a question
+type DomainRetriever interface {
+	SearchConnectorDomains(ctx context.Context, connectorID, question string, limit int) ([]schema.DomainContext, error)
+}
+
+// DefinitionSource provides the current user-maintained definitions of a connector
+type DefinitionSource interface {
+	ConnectorDefinitions(ctx context.Context, connectorID string) ([]models.SemanticDefinition, error)
+}
+
+// Executor runs SQL against the connector the question is asked of. Errors caused by
+// the query itself should wrap connectors.ErrQueryRejected, or one of the connectors
+// validation and cost errors, so they can be repaired; other errors end generation.
+type Executor interface {
+	// Explain dry-runs sql without reading data; executors that cannot return nil
+	Explain(ctx context.Context, sql string) error
+	Execute(ctx context.Context, sql string) (*connectors.QueryResult, error)
+}
+
+// Request is a question to answer with SQL
+type Request struct {
+	Question    string
+	Dialect     string
+	Schema      *schema.SchemaContext
+	ParsedQuery *models.ParsedQuery // nil when the planner could not parse the question
+	Executor    Executor
+}
+
+// Attempt records one generated query and why it failed, if it did
+type Attempt struct {
+	SQL   string `json:"sql"`
+	Stage string `json:"stage,omitempty"`
+	Error string `json:"error,omitempty"`
+	// Compiled is set when the query was compiled from the semantic model rather than written by the LLM
+	Compiled bool `json:"compiled,omitempty"`
+}
+
+// Result is the query that answered the question and the data it returned
+type Result struct {
+	SQL  string                  `json:"sql"`
+	Data *connectors.QueryResult `json:"-"`
+	// Attempts lists every query tried, the last one being SQL
+	Attempts []Attempt `json:"attempts"`
+}
+
+// Generator writes SQL for questions with an LLM
+type Generator struct {
+	llm         LLM
+	domains     DomainRetriever
+	definitions DefinitionSource
+	maxRepairs  int
+	logger      *slog.Logger
+}
+
+// NewGenerator creates a generator; llm may be nil, in which case Generate fails with ErrNoLLM
+func NewGenerator(llm LLM, logger *slog.Logger) *Generator {
+	return &Generator{
+		llm:        llm,
+		maxRepairs: DefaultMaxRepairs,
+		logger:     logger.With("service", "sqlgen"),
+	}
+}
+
+// SetDomainRetriever sets where domain contexts for the prompt come from
+func (g *Generator) SetDomainRetriever(domains DomainRetriever) {
+	g.domains = domains
+}
+
+// SetDefinitionSource sets where current definitions come from. Without one, the
+// definitions attached to the scanned schema are used.
+func (g *Generator) SetDefinitionSource(definitions DefinitionSource) {
+	g.definitions = definitions
+}
+
+// SetMaxRepairs sets how many times a failing query is sent back for correction
+func (g *Generator) SetMaxRepairs(n int) {
+	if n >= 0 {
+		g.maxRepairs = n
+	}
+}
+
+// Generate writes SQL for the question, checks it and runs it through the executor.
+// Parsed queries the semantic model can express are compiled without the LLM. Otherwise,
+// or when the compiled query fails, parse, validation, planning and execution errors
+// are fed back to the LLM until a query runs or the repair budget is spent. Certified
+// definitions take precedence in both the compiled query and the prompt.
+func (g *Generator) Generate(ctx context.Context, req Request) (*Result, error) {
+	result := &Result{}
+	defs := g.connectorDefinitions(ctx, req)
+
+	if compiled, ok := g.compile(req, defs); ok {
+		attempt := Attempt{SQL: compiled, Compiled: true}
+		data, err := g.check(ctx, req, &attempt)
+		result.Attempts = append(result.Attempts, attempt)
+		if err != nil {
+			return nil, err
+		}
+		if attempt.Error == "" {
+			result.SQL = attempt.SQL
+			result.Data = data
+			g.logger.Info("Compiled SQL answered the question", "rows", len(data.Data))
+			return result, nil
+		}
+		g.logger.Warn("Compiled SQL failed", "stage", attempt.Stage, "error", attempt.Error, "sql", attempt.SQL)
+	}
+
+	if g.llm == nil {
+		return nil, ErrNoLLM
+	}
+
+	prompt := buildPrompt(req, g.domainContexts(ctx, req), defs)
+
+	for i := 0; i <= g.maxRepairs; i++ {
+		attemptPrompt := prompt + "SQL:"
+		if len(result.Attempts) > 0 {
+			attemptPrompt = buildRepairPrompt(prompt, result.Attempts)
+		}
+
+		response, err := g.llm.GenerateResponse(ctx, attemptPrompt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate SQL: %w", err)
+		}
+
+		attempt := Attempt{SQL: ExtractSQL(response)}
+		data, err := g.check(ctx, req, &attempt)
+		result.Attempts = append(result.Attempts, attempt)
+		if err != nil {
+			return nil, err
+		}
+		if attempt.Error == "" {
+			result.SQL = attempt.SQL
+			result.Data = data
+			g.logger.Info("Generated SQL answered the question", "attempts", len(result.Attempts), "rows", len(data.Data))
+			return result, nil
+		}
+
+		g.logger.Warn("Generated SQL failed",
+			"attempt", i+1,
+			"stage", attempt.Stage,
+			"error", attempt.Error,
+			"sql", attempt.SQL)
+	}
+
+	last := result.Attempts[len(result.Attempts)-1]
+	return result, fmt.Errorf("%w after %d attempts: %s", ErrGenerationFailed, len(result.Attempts), last.Error)
+}
+
+// compile turns the parsed query into SQL through the semantic model derived from
+// the scanned schema. Questions the model cannot express are left to the LLM.
+func (g *Generator) compile(req Request, defs []models.SemanticDefinition) (string, bool) {
+	if req.ParsedQuery == nil || req.Schema == nil {
+		return "", false
+	}
+	dialect, err := semantic.DialectFor(req.Dialect)
+	if err != nil {
+		return "", false
+	}
+	model := semantic.ModelFromSchema(req.Schema)
+	model.ApplyDefinitions(defs)
+	query, err := semantic.Compile(model, req.ParsedQuery, dialect)
+	if err != nil {
+		g.logger.Debug("Parsed query not compilable, generating SQL with the LLM", "error", err)
+		return "", false
+	}
+	return query.SQL, true
+}
+
+// check validates, explains and runs an attempt. Failures the LLM can fix are
+// recorded on the attempt; other failures are returned.
+func (g *Generator) check(ctx context.Context, req Request, attempt *Attempt) (*connectors.QueryResult, error) {
+	if attempt.SQL == "" {
+		attempt.Stage = StageExtract
+		attempt.Error = "the response did not contain a SELECT statement"
+		return nil, nil
+	}
+
+	if _, err := sqlparse.ValidateReadOnlyDialect(attempt.SQL, sqlparse.DialectNamed(req.Dialect)); err != nil {
+		attempt.Stage = StageValidate
+		attempt.Error = err.Error()
+		return nil, nil
+	}
+
+	if req.Executor == nil {
+		return &connectors.QueryResult{}, nil
+	}
+
+	if err := req.Executor.Explain(ctx, attempt.SQL); err != nil {
+		if !repairable(err) {
+			return nil, err
+		}
+		attempt.Stage = StageExplain
+		attempt.Error = err.Error()
+		return nil, nil
+	}
+
+	data, err := req.Executor.Execute(ctx, attempt.SQL)
+	if err != nil {
+		if !repairable(err) {
+			return nil, err
+		}
+		attempt.Stage = StageExecute
+		attempt.Error = err.Error()
+		return nil, nil
+	}
+	return data, nil
+}
+
+// connectorDefinitions returns the connector's current definitions, falling back to
+// those attached to the scanned schema, which may be older
+func (g *Generator) connectorDefinitions(ctx context.Context, req Request) []models.SemanticDefinition {
+	if req.Schema == nil {
+		return nil
+	}
+	if g.definitions == nil || req.Schema.ConnectorID == "" {
+		return req.Schema.Definitions
+	}
+	defs, err := g.definitions.ConnectorDefinitions(ctx, req.Schema.ConnectorID)
+	if err != nil {
+		g.logger.Warn("Failed to load semantic definitions", "connector_id", req.Schema.ConnectorID, "error", err)
+		return req.Schema.Definitions
+	}
+	return defs
+}
+
+// domainContexts retrieves the domains of the connector closest to the question.
+// Retrieval is best effort: the schema alone is enough to generate SQL.
+func (g *Generator) domainContexts(ctx context.Context, req Request) []schema.DomainContext {
+	if g.domains == nil || req.Schema == nil || req.Schema.ConnectorID == "" {
+		return nil
+	}
+	domains, err := g.domains.SearchConnectorDomains(ctx, req.Schema.ConnectorID, req.Question, domainContextLimit)
+	if err != nil {
+		g.logger.Warn("Failed to retrieve domain contexts", "connector_id", req.Schema.ConnectorID, "error", err)
+		return nil
+	}
+	return domains
+}
+
+// repairable reports whether an error is about the query rather than the connection
+func repairable(err error) bool {
+	var parseErr *sqlparse.Error
+	return errors.As(err, &parseErr) ||
+		errors.Is(err, connectors.ErrQueryRejected) ||
+		errors.Is(err, connectors.ErrInvalidQuery) ||
+		errors.Is(err, connectors.ErrDangerousQuery) ||
+		errors.Is(err, connectors.ErrQueryTooLong) ||
+		errors.Is(err, connectors.ErrQueryTooExpensive)
+}
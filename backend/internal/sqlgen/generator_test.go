@@ -0,0 +1,89 @@
+package sqlgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"insightiq/backend/internal/connectors"
+)
+
+type scriptedLLM struct {
+	responses []string
+	prompts   []string
+}
+
+func (l *scriptedLLM) GenerateResponse(ctx context.Context, prompt string) (string, error) {
+	l.prompts = append(l.prompts, prompt)
+	response := l.responses[0]
+	l.responses = l.responses[1:]
+	return response, nil
+}
+
+type fakeExecutor struct {
+	explainErr map[string]error
+	executed   []string
+}
+
+func (e *fakeExecutor) Explain(ctx context.Context, sql string) error {
+	return e.explainErr[sql]
+}
+
+func (e *fakeExecutor) Execute(ctx context.Context, sql string) (*connectors.QueryResult, error) {
+	e.executed = append(e.executed, sql)
+	return &connectors.QueryResult{Data: []map[string]interface{}{{"total": 1}}}, nil
+}
+
+func TestGenerateRepairsRejectedQuery(t *testing.T) {
+	llm := &scriptedLLM{responses: []string{
+		"DELETE FROM orders",
+		"```sql\nSELECT sum(amout) FROM orders;\n```",
+		"SELECT sum(amount) AS total FROM orders",
+	}}
+	exec := &fakeExecutor{explainErr: map[string]error{
+		"SELECT sum(amout) FROM orders": fmt.Errorf("%w: column \"amout\" does not exist", connectors.ErrQueryRejected),
+	}}
+
+	g := NewGenerator(llm, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	result, err := g.Generate(context.Background(), Request{Question: "total revenue", Dialect: "PostgreSQL", Executor: exec})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if result.SQL != "SELECT sum(amount) AS total FROM orders" {
+		t.Errorf("SQL = %q", result.SQL)
+	}
+	stages := []string{StageExtract, StageExplain, ""}
+	if len(result.Attempts) != len(stages) {
+		t.Fatalf("got %d attempts, want %d", len(result.Attempts), len(stages))
+	}
+	for i, stage := range stages {
+		if result.Attempts[i].Stage != stage {
+			t.Errorf("attempt %d stage = %q, want %q", i+1, result.Attempts[i].Stage, stage)
+		}
+	}
+	if len(exec.executed) != 1 {
+		t.Errorf("executed %d queries, want 1", len(exec.executed))
+	}
+	if !strings.Contains(llm.prompts[2], `column "amout" does not exist`) {
+		t.Error("repair prompt does not include the database error")
+	}
+}
+
+func TestGenerateStopsAfterMaxRepairs(t *testing.T) {
+	llm := &scriptedLLM{responses: []string{"no idea", "still no idea"}}
+
+	g := NewGenerator(llm, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	g.SetMaxRepairs(1)
+	result, err := g.Generate(context.Background(), Request{Question: "total revenue", Dialect: "PostgreSQL"})
+	if !errors.Is(err, ErrGenerationFailed) {
+		t.Fatalf("Generate() error = %v, want ErrGenerationFailed", err)
+	}
+	if len(result.Attempts) != 2 {
+		t.Errorf("got %d attempts, want 2", len(result.Attempts))
+	}
+}
@@ -0,0 +1,246 @@
+package sqlgen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"insightiq/backend/internal/models"
+	"insightiq/backend/internal/schema"
+	"insightiq/backend/internal/semantic"
+)
+
+// buildPrompt describes the scanned schema, the agreed definitions, the related
+// business domains and the question for text-to-SQL generation. It ends with the
+// question; callers append what they ask for.
+func buildPrompt(req Request, domains []schema.DomainContext, defs []models.SemanticDefinition) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("You are an expert %s analyst. Write one read-only SQL query that answers the question.\n\n", req.Dialect))
+
+	if req.Schema != nil {
+		writeSchema(&b, req.Schema, defs)
+	}
+	writeDefinitions(&b, defs)
+
+	if len(domains) > 0 {
+		b.WriteString("\nBusiness domains of this data:\n")
+		for _, domain := range domains {
+			b.WriteString(fmt.Sprintf("- %s", domain.Domain))
+			if domain.Description != "" {
+				b.WriteString(fmt.Sprintf(": %s", domain.Description))
+			}
+			b.WriteString("\n")
+			if len(domain.Metrics) > 0 {
+				b.WriteString(fmt.Sprintf("  metrics: %s\n", strings.Join(domain.Metrics, ", ")))
+			}
+			if len(domain.Dimensions) > 0 {
+				b.WriteString(fmt.Sprintf("  dimensions: %s\n", strings.Join(domain.Dimensions, ", ")))
+			}
+		}
+	}
+
+	if pq := req.ParsedQuery; pq != nil {
+		if len(pq.Metrics) > 0 {
+			b.WriteString(fmt.Sprintf("\nRequested metrics: %s\n", strings.Join(pq.Metrics, ", ")))
+		}
+		if len(pq.Dimensions) > 0 {
+			b.WriteString(fmt.Sprintf("Requested dimensions: %s\n", strings.Join(pq.Dimensions, ", ")))
+		}
+		if len(pq.Filters) > 0 {
+			b.WriteString("Filters:\n")
+			for _, f := range pq.Filters {
+				b.WriteString(fmt.Sprintf("- %s\n", describeFilter(f)))
+			}
+		}
+		if pq.TimeRange != nil {
+			if described := pq.TimeRange.Describe(); described != "" {
+				b.WriteString(fmt.Sprintf("Time range: %s\n", described))
+			}
+			if pq.TimeRange.Start != nil || pq.TimeRange.End != nil {
+				b.WriteString("Filter on exactly these bounds rather than computing dates relative to the current date\n")
+			}
+		}
+	}
+
+	b.WriteString(fmt.Sprintf(`
+Rules:
+- Use only the tables and columns listed above
+- Write a single read-only SELECT statement in %s syntax; WITH clauses are allowed, semicolons are not
+- Never modify data
+- Add LIMIT 100 unless the query aggregates to fewer rows
+- Return only the SQL, without explanation
+
+Question: %s
+`, req.Dialect, req.Question))
+
+	return b.String()
+}
+
+// describeFilter renders a filter for the prompt, e.g. customers.segment = 'Premium'
+// (from "premium")
+func describeFilter(f models.Filter) string {
+	field := f.Field
+	if f.Table != "" {
+		field = f.Table + "." + f.Field
+	}
+
+	var value string
+	switch v := f.Value.(type) {
+	case string:
+		value = "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	case []string:
+		quoted := make([]string, len(v))
+		for i, s := range v {
+			quoted[i] = "'" + strings.ReplaceAll(s, "'", "''") + "'"
+		}
+		value = "(" + strings.Join(quoted, ", ") + ")"
+	default:
+		value = fmt.Sprint(v)
+	}
+
+	described := fmt.Sprintf("%s %s %s", field, f.Operator, value)
+	if f.Text != "" {
+		described += fmt.Sprintf(" (from %q)", f.Text)
+	}
+	return described
+}
+
+func writeSchema(b *strings.Builder, schemaCtx *schema.SchemaContext, defs []models.SemanticDefinition) {
+	b.WriteString("Database schema:\n")
+
+	for _, table := range schemaCtx.Tables {
+		name := table.TableName
+		if table.Schema != "" {
+			name = table.Schema + "." + table.TableName
+		}
+		b.WriteString(fmt.Sprintf("Table %s", name))
+		if table.Description != "" {
+			b.WriteString(fmt.Sprintf(" -- %s", table.Description))
+		}
+		b.WriteString("\n")
+
+		for _, col := range table.Columns {
+			var roles []string
+			if col.IsID {
+				roles = append(roles, "id")
+			}
+			if col.IsMetric {
+				roles = append(roles, "metric")
+			}
+			if col.IsDimension {
+				roles = append(roles, "dimension")
+			}
+			if col.IsDatetime {
+				roles = append(roles, "datetime")
+			}
+			b.WriteString(fmt.Sprintf("  - %s %s", col.Name, col.DataType))
+			if len(roles) > 0 {
+				b.WriteString(fmt.Sprintf(" [%s]", strings.Join(roles, ", ")))
+			}
+			if len(col.SampleValues) > 0 {
+				b.WriteString(fmt.Sprintf(" e.g. %s", strings.Join(col.SampleValues[:min(3, len(col.SampleValues))], ", ")))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if len(schemaCtx.Relationships) > 0 {
+		b.WriteString("\nJoin paths (foreign keys):\n")
+		for _, rel := range schemaCtx.Relationships {
+			b.WriteString(fmt.Sprintf("- %s.%s -> %s.%s\n", rel.FromTable, rel.FromColumn, rel.ToTable, rel.ToColumn))
+		}
+	}
+
+	if len(schemaCtx.BusinessMetrics) > 0 {
+		b.WriteString("\nKnown business metrics:\n")
+		for _, metric := range schemaCtx.BusinessMetrics {
+			if isDefined(defs, metric.Name) {
+				continue
+			}
+			if metric.Expression != "" {
+				b.WriteString(fmt.Sprintf("- %s: %s on %s\n", metric.Name, metric.Expression, metric.Table))
+			} else {
+				b.WriteString(fmt.Sprintf("- %s: %s(%s.%s)\n", metric.Name, metric.Type, metric.Table, metric.Column))
+			}
+		}
+	}
+}
+
+// writeDefinitions lists the user-maintained metrics and dimensions, which replace
+// any derived metric of the same name
+func writeDefinitions(b *strings.Builder, defs []models.SemanticDefinition) {
+	if len(defs) == 0 {
+		return
+	}
+
+	b.WriteString("\nAgreed definitions (use these expressions exactly; certified definitions are authoritative):\n")
+	for _, def := range defs {
+		expr := def.Expression
+		if def.Kind == models.DefinitionKindMetric {
+			expr = semantic.MetricExpression(def)
+		}
+		b.WriteString(fmt.Sprintf("- %s (%s", def.Name, def.Kind))
+		if def.Certified {
+			b.WriteString(", certified")
+		}
+		b.WriteString(fmt.Sprintf("): %s on %s", expr, def.Table))
+		if def.Description != "" {
+			b.WriteString(fmt.Sprintf(" -- %s", def.Description))
+		}
+		if len(def.Synonyms) > 0 {
+			b.WriteString(fmt.Sprintf("; also called %s", strings.Join(def.Synonyms, ", ")))
+		}
+		b.WriteString("\n")
+	}
+}
+
+func isDefined(defs []models.SemanticDefinition, name string) bool {
+	for _, def := range defs {
+		if strings.EqualFold(def.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildRepairPrompt asks for a corrected query, showing every failed attempt so the
+// LLM does not repeat one
+func buildRepairPrompt(prompt string, attempts []Attempt) string {
+	var b strings.Builder
+	b.WriteString(prompt)
+	b.WriteString("\nEarlier queries for this question failed:\n")
+	for i, attempt := range attempts {
+		sql := attempt.SQL
+		if sql == "" {
+			sql = "(no SQL found in the response)"
+		}
+		b.WriteString(fmt.Sprintf("\nAttempt %d:\n%s\nError: %s\n", i+1, sql, attempt.Error))
+	}
+	b.WriteString("\nFix the error and write the corrected query. Return only the SQL.\nSQL:")
+	return b.String()
+}
+
+var (
+	sqlFencePattern = regexp.MustCompile("(?s)```(?:sql)?\\s*(.*?)```")
+	sqlStartPattern = regexp.MustCompile(`(?ims)^\s*(?:SELECT|WITH)\b.*`)
+)
+
+// ExtractSQL pulls the first SQL statement out of an LLM response, dropping
+// markdown fences, surrounding prose and a trailing semicolon
+func ExtractSQL(response string) string {
+	text := response
+	if m := sqlFencePattern.FindStringSubmatch(text); m != nil {
+		text = m[1]
+	}
+
+	text = sqlStartPattern.FindString(text)
+	if text == "" {
+		return ""
+	}
+
+	if idx := strings.Index(text, ";"); idx >= 0 {
+		text = text[:idx]
+	}
+
+	return strings.TrimSpace(text)
+}
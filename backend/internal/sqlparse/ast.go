@@ -0,0 +1,430 @@
+package sqlparse
+
+import "strings"
+
+// Node is implemented by every syntax tree node. Pos is the byte offset of
+// the node in the parsed source.
+type Node interface {
+	Pos() int
+}
+
+// Statement is a top-level statement: *Query, *Explain or *OtherStatement
+type Statement interface {
+	Node
+	statementNode()
+}
+
+// QueryBody is the part of a query between its WITH clause and its ORDER BY:
+// *Select, *SetOperation, *Values, *TableStmt or a parenthesized *Query
+type QueryBody interface {
+	Node
+	queryBodyNode()
+}
+
+// Expr is a value expression
+type Expr interface {
+	Node
+	exprNode()
+}
+
+// TableExpr is an item of a FROM clause: *TableName, *DerivedTable, *TableFunction or *Join
+type TableExpr interface {
+	Node
+	tableExprNode()
+}
+
+// ObjectName is a possibly qualified name such as schema.table
+type ObjectName []string
+
+func (n ObjectName) String() string {
+	return strings.Join(n, ".")
+}
+
+// Base returns the unqualified name, lowercased
+func (n ObjectName) Base() string {
+	if len(n) == 0 {
+		return ""
+	}
+	return strings.ToLower(n[len(n)-1])
+}
+
+// Schema returns the lowercased qualifier of a schema.name pair, or "" when unqualified
+func (n ObjectName) Schema() string {
+	if len(n) < 2 {
+		return ""
+	}
+	return strings.ToLower(n[len(n)-2])
+}
+
+// Query is a full query: WITH ... body ORDER BY ... LIMIT ... OFFSET ... FOR UPDATE
+type Query struct {
+	With    *With
+	Body    QueryBody
+	OrderBy []*OrderItem
+	Limit   Expr
+	Offset  Expr
+	// Row locking clause such as "FOR UPDATE", empty when absent
+	Locking string
+	pos     int
+}
+
+// With is a WITH clause
+type With struct {
+	Recursive bool
+	CTEs      []*CTE
+	pos       int
+}
+
+// CTE is a common table expression. Stmt is a *Query for read-only CTEs and an
+// *OtherStatement for data-modifying ones.
+type CTE struct {
+	Name    string
+	Columns []string
+	Stmt    Statement
+	pos     int
+}
+
+// Select is a single SELECT ... FROM ... WHERE ... GROUP BY ... HAVING block
+type Select struct {
+	Distinct   bool
+	DistinctOn []Expr
+	Columns    []*SelectItem
+	// Target of SELECT ... INTO, which creates a table
+	Into    ObjectName
+	From    []TableExpr
+	Where   Expr
+	GroupBy []Expr
+	Having  Expr
+	Windows []*NamedWindow
+	pos     int
+}
+
+// SelectItem is an output column. Expr is nil for a bare "*".
+type SelectItem struct {
+	Expr  Expr
+	Alias string
+	pos   int
+}
+
+// SetOperation combines two queries with UNION, INTERSECT or EXCEPT
+type SetOperation struct {
+	Op    string
+	All   bool
+	Left  QueryBody
+	Right QueryBody
+	pos   int
+}
+
+// Values is a VALUES list
+type Values struct {
+	Rows [][]Expr
+	pos  int
+}
+
+// TableStmt is the "TABLE name" shorthand for SELECT * FROM name
+type TableStmt struct {
+	Name ObjectName
+	pos  int
+}
+
+// Explain is an EXPLAIN statement
+type Explain struct {
+	Options []string
+	Stmt    Statement
+	pos     int
+}
+
+// OtherStatement is any statement that is recognized but not modelled, such as
+// INSERT or DROP. Verb is its uppercased leading keyword.
+type OtherStatement struct {
+	Verb string
+	With *With
+	pos  int
+}
+
+// OrderItem is an ORDER BY entry
+type OrderItem struct {
+	Expr       Expr
+	Desc       bool
+	NullsFirst *bool
+	pos        int
+}
+
+// NamedWindow is an entry of a WINDOW clause
+type NamedWindow struct {
+	Name string
+	Spec *WindowSpec
+	pos  int
+}
+
+// WindowSpec is the window of an OVER clause
+type WindowSpec struct {
+	Name        string
+	PartitionBy []Expr
+	OrderBy     []*OrderItem
+	// Frame offsets such as "3 PRECEDING"; the frame mode itself is not kept
+	FrameBounds []Expr
+	pos         int
+}
+
+// Alias names a FROM item and optionally its columns
+type Alias struct {
+	Name    string
+	Columns []string
+}
+
+// TableName is a table or view reference
+type TableName struct {
+	Name  ObjectName
+	Alias *Alias
+	pos   int
+}
+
+// DerivedTable is a subquery in FROM
+type DerivedTable struct {
+	Lateral bool
+	Query   *Query
+	Alias   *Alias
+	pos     int
+}
+
+// TableFunction is a set-returning function in FROM, such as generate_series
+type TableFunction struct {
+	Lateral bool
+	Func    *FuncCall
+	Alias   *Alias
+	pos     int
+}
+
+// Join joins two FROM items
+type Join struct {
+	// INNER, LEFT, RIGHT, FULL or CROSS
+	Type    string
+	Natural bool
+	Left    TableExpr
+	Right   TableExpr
+	On      Expr
+	Using   []string
+	pos     int
+}
+
+// Literal is a number, string, boolean or NULL constant
+type Literal struct {
+	Kind  LiteralKind
+	Value string
+	pos   int
+}
+
+// LiteralKind tells literals apart
+type LiteralKind int
+
+const (
+	NumberLiteral LiteralKind = iota
+	StringLiteral
+	BoolLiteral
+	NullLiteral
+)
+
+// TypedLiteral is a constant with a type prefix, such as DATE '2024-01-01' or INTERVAL '7 days'
+type TypedLiteral struct {
+	Type  string
+	Value Expr
+	// Interval unit, as in INTERVAL '1' DAY or MySQL's INTERVAL 7 DAY
+	Unit string
+	pos  int
+}
+
+// ColumnRef is a possibly qualified column name, or a qualified star such as t.*
+type ColumnRef struct {
+	Name ObjectName
+	Star bool
+	pos  int
+}
+
+// Param is a positional parameter such as $1
+type Param struct {
+	Name string
+	pos  int
+}
+
+// FuncCall is a function or aggregate call
+type FuncCall struct {
+	Name     ObjectName
+	Distinct bool
+	// count(*)
+	Star    bool
+	Args    []Expr
+	OrderBy []*OrderItem
+	// WITHIN GROUP (ORDER BY ...)
+	WithinGroup []*OrderItem
+	Filter      Expr
+	Over        *WindowSpec
+	pos         int
+}
+
+// UnaryExpr is a prefix operator such as NOT or unary minus
+type UnaryExpr struct {
+	Op  string
+	X   Expr
+	pos int
+}
+
+// BinaryExpr covers AND, OR, comparisons, arithmetic, LIKE and other infix operators.
+// Op is uppercased for keyword operators, e.g. "NOT LIKE" or "IS DISTINCT FROM".
+type BinaryExpr struct {
+	Op  string
+	X   Expr
+	Y   Expr
+	pos int
+}
+
+// IsExpr is "x IS [NOT] NULL/TRUE/FALSE/UNKNOWN"
+type IsExpr struct {
+	X    Expr
+	Not  bool
+	What string
+	pos  int
+}
+
+// InExpr is "x [NOT] IN (list)" or "x [NOT] IN (subquery)"
+type InExpr struct {
+	X     Expr
+	Not   bool
+	List  []Expr
+	Query *Query
+	pos   int
+}
+
+// BetweenExpr is "x [NOT] BETWEEN low AND high"
+type BetweenExpr struct {
+	X    Expr
+	Not  bool
+	Low  Expr
+	High Expr
+	pos  int
+}
+
+// SubqueryExpr is a scalar subquery, or EXISTS (subquery) when Exists is set
+type SubqueryExpr struct {
+	Exists bool
+	Query  *Query
+	pos    int
+}
+
+// CaseExpr is a CASE expression; Operand is nil for the searched form
+type CaseExpr struct {
+	Operand Expr
+	Whens   []*When
+	Else    Expr
+	pos     int
+}
+
+// When is a WHEN ... THEN ... arm of a CASE expression
+type When struct {
+	Cond   Expr
+	Result Expr
+}
+
+// CastExpr is CAST(x AS type) or x::type
+type CastExpr struct {
+	X    Expr
+	Type string
+	pos  int
+}
+
+// CollateExpr is "x COLLATE name"
+type CollateExpr struct {
+	X         Expr
+	Collation ObjectName
+	pos       int
+}
+
+// ArrayExpr is ARRAY[...] or ARRAY(subquery)
+type ArrayExpr struct {
+	Elems []Expr
+	Query *Query
+	pos   int
+}
+
+// RowExpr is a row constructor: ROW(a, b) or (a, b)
+type RowExpr struct {
+	Elems []Expr
+	pos   int
+}
+
+// IndexExpr is an array subscript x[i] or slice x[lo:hi]
+type IndexExpr struct {
+	X     Expr
+	Index Expr
+	Upper Expr
+	Slice bool
+	pos   int
+}
+
+func (n *Query) Pos() int          { return n.pos }
+func (n *With) Pos() int           { return n.pos }
+func (n *CTE) Pos() int            { return n.pos }
+func (n *Select) Pos() int         { return n.pos }
+func (n *SelectItem) Pos() int     { return n.pos }
+func (n *SetOperation) Pos() int   { return n.pos }
+func (n *Values) Pos() int         { return n.pos }
+func (n *TableStmt) Pos() int      { return n.pos }
+func (n *Explain) Pos() int        { return n.pos }
+func (n *OtherStatement) Pos() int { return n.pos }
+func (n *OrderItem) Pos() int      { return n.pos }
+func (n *NamedWindow) Pos() int    { return n.pos }
+func (n *WindowSpec) Pos() int     { return n.pos }
+func (n *TableName) Pos() int      { return n.pos }
+func (n *DerivedTable) Pos() int   { return n.pos }
+func (n *TableFunction) Pos() int  { return n.pos }
+func (n *Join) Pos() int           { return n.pos }
+func (n *Literal) Pos() int        { return n.pos }
+func (n *TypedLiteral) Pos() int   { return n.pos }
+func (n *ColumnRef) Pos() int      { return n.pos }
+func (n *Param) Pos() int          { return n.pos }
+func (n *FuncCall) Pos() int       { return n.pos }
+func (n *UnaryExpr) Pos() int      { return n.pos }
+func (n *BinaryExpr) Pos() int     { return n.pos }
+func (n *IsExpr) Pos() int         { return n.pos }
+func (n *InExpr) Pos() int         { return n.pos }
+func (n *BetweenExpr) Pos() int    { return n.pos }
+func (n *SubqueryExpr) Pos() int   { return n.pos }
+func (n *CaseExpr) Pos() int       { return n.pos }
+func (n *CastExpr) Pos() int       { return n.pos }
+func (n *CollateExpr) Pos() int    { return n.pos }
+func (n *ArrayExpr) Pos() int      { return n.pos }
+func (n *RowExpr) Pos() int        { return n.pos }
+func (n *IndexExpr) Pos() int      { return n.pos }
+
+func (*Query) statementNode()          {}
+func (*Explain) statementNode()        {}
+func (*OtherStatement) statementNode() {}
+
+func (*Query) queryBodyNode()        {}
+func (*Select) queryBodyNode()       {}
+func (*SetOperation) queryBodyNode() {}
+func (*Values) queryBodyNode()       {}
+func (*TableStmt) queryBodyNode()    {}
+
+func (*TableName) tableExprNode()     {}
+func (*DerivedTable) tableExprNode()  {}
+func (*TableFunction) tableExprNode() {}
+func (*Join) tableExprNode()          {}
+
+func (*Literal) exprNode()      {}
+func (*TypedLiteral) exprNode() {}
+func (*ColumnRef) exprNode()    {}
+func (*Param) exprNode()        {}
+func (*FuncCall) exprNode()     {}
+func (*UnaryExpr) exprNode()    {}
+func (*BinaryExpr) exprNode()   {}
+func (*IsExpr) exprNode()       {}
+func (*InExpr) exprNode()       {}
+func (*BetweenExpr) exprNode()  {}
+func (*SubqueryExpr) exprNode() {}
+func (*CaseExpr) exprNode()     {}
+func (*CastExpr) exprNode()     {}
+func (*CollateExpr) exprNode()  {}
+func (*ArrayExpr) exprNode()    {}
+func (*RowExpr) exprNode()      {}
+func (*IndexExpr) exprNode()    {}
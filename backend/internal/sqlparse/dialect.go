@@ -0,0 +1,46 @@
+package sqlparse
+
+import "strings"
+
+// Dialect selects the rules SQL text is split into comments, literals and code
+// with. Engines disagree on some of them, and a query checked with rules other
+// than the ones of the engine that runs it can hide code in what the checks took
+// for a comment or a string.
+type Dialect int
+
+const (
+	// DialectPostgres reads SQL as PostgreSQL does: block comments nest, "--"
+	// always starts a comment and backslashes only escape in E'' strings
+	DialectPostgres Dialect = iota
+	// DialectMySQL reads SQL as MySQL does: "#" and "-- " start comments, block
+	// comments don't nest, backslashes escape in strings and "" quotes strings.
+	// Executable /*! */ comments are rejected.
+	DialectMySQL
+	// DialectAny accepts only SQL that PostgreSQL and MySQL read the same way, for
+	// queries whose engine is not known, such as the ones run through Superset
+	DialectAny
+)
+
+// DialectNamed returns the dialect for a connector's SQL dialect name, such as the
+// SQLDialect of a driver, or DialectAny for names it doesn't know
+func DialectNamed(name string) Dialect {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "postgresql", "postgres":
+		return DialectPostgres
+	case "mysql", "mariadb":
+		return DialectMySQL
+	default:
+		return DialectAny
+	}
+}
+
+func (d Dialect) String() string {
+	switch d {
+	case DialectPostgres:
+		return "PostgreSQL"
+	case DialectMySQL:
+		return "MySQL"
+	default:
+		return "any dialect"
+	}
+}
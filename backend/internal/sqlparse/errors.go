@@ -0,0 +1,64 @@
+package sqlparse
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrSyntax is returned when a query cannot be parsed
+	ErrSyntax = errors.New("syntax error")
+	// ErrMultipleStatements is returned when more than one statement is submitted at once
+	ErrMultipleStatements = errors.New("multiple statements are not allowed")
+	// ErrNotReadOnly is returned for statements and clauses that modify data or take
+	// locks, and for EXPLAIN ANALYZE, which runs the query it explains
+	ErrNotReadOnly = errors.New("statement is not read-only")
+	// ErrDeniedFunction is returned when a query calls a function on the denylist
+	ErrDeniedFunction = errors.New("function is not allowed")
+	// ErrDeniedRelation is returned when a query reads from a system catalog
+	ErrDeniedRelation = errors.New("relation is not allowed")
+	// ErrAmbiguousSyntax is returned for comments and literals that the database could
+	// read differently than the checks did, which would let them hide code
+	ErrAmbiguousSyntax = errors.New("ambiguous syntax")
+)
+
+// Error describes why a query was rejected. Reason is meant to be shown to users;
+// errors.Is matches Kind, which is one of the sentinel errors above.
+type Error struct {
+	Kind   error
+	Reason string
+	// 1-based position of the offending token, zero when not tied to a position
+	Line   int
+	Column int
+}
+
+func (e *Error) Error() string {
+	if e.Line == 0 {
+		return e.Reason
+	}
+	return fmt.Sprintf("%s (line %d, column %d)", e.Reason, e.Line, e.Column)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+func newError(kind error, src string, pos int, format string, args ...interface{}) *Error {
+	line, column := position(src, pos)
+	return &Error{
+		Kind:   kind,
+		Reason: fmt.Sprintf(format, args...),
+		Line:   line,
+		Column: column,
+	}
+}
+
+// position converts a byte offset into a 1-based line and column
+func position(src string, pos int) (int, int) {
+	pos = min(max(pos, 0), len(src))
+	before := src[:pos]
+	line := strings.Count(before, "\n") + 1
+	column := len([]rune(before[strings.LastIndexByte(before, '\n')+1:])) + 1
+	return line, column
+}
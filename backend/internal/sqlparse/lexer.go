@@ -0,0 +1,343 @@
+package sqlparse
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokQuotedIdent
+	tokString
+	tokNumber
+	tokParam
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	// Identifier as written, decoded string literal value, or operator text
+	text string
+	pos  int
+}
+
+// Multi-character operators, longest first so the lexer can take the first match
+var multiCharOps = []string{
+	"<=>", "->>", "#>>", "!~~*", "!~~", "!~*", "~~*",
+	"::", "<=", ">=", "<>", "!=", "||", "->", "#>", "@>", "<@", "?|", "?&", "&&",
+	"~~", "~*", "!~", "<<", ">>", "@@",
+}
+
+const singleCharOps = "+-*/%^<>=~!@#&|?()[],;.:"
+
+// lex splits sql into tokens with the rules of dialect. Comments and whitespace are dropped.
+func lex(src string, dialect Dialect) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+			i++
+
+		case strings.HasPrefix(src[i:], "--") && dialect == DialectPostgres,
+			strings.HasPrefix(src[i:], "--") && (i+2 == len(src) || src[i+2] <= ' '),
+			c == '#' && dialect == DialectMySQL:
+			// MySQL only takes "--" followed by whitespace for a comment
+			i = skipLineComment(src, i)
+
+		case strings.HasPrefix(src[i:], "--") && dialect == DialectAny:
+			return nil, newError(ErrAmbiguousSyntax, src, i, `"--" must be followed by a space, as MySQL does not read it as a comment otherwise`)
+
+		case c == '#' && dialect == DialectAny:
+			return nil, newError(ErrAmbiguousSyntax, src, i, `"#" starts a comment in MySQL but is an operator in PostgreSQL`)
+
+		case strings.HasPrefix(src[i:], "/*"):
+			end, err := skipBlockComment(src, i, dialect)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+
+		case c == '\'':
+			value, end, err := lexDialectString(src, i, i+1, c, dialect)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokString, text: value, pos: i})
+			i = end
+
+		case (c == 'E' || c == 'e') && i+1 < len(src) && src[i+1] == '\'':
+			value, end, err := lexString(src, i, i+2, '\'', true)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokString, text: value, pos: i})
+			i = end
+
+		case (c == 'N' || c == 'n' || c == 'B' || c == 'b' || c == 'X' || c == 'x') && i+1 < len(src) && src[i+1] == '\'':
+			// National, bit and hex string constants; the prefix does not matter here
+			value, end, err := lexDialectString(src, i, i+2, '\'', dialect)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokString, text: value, pos: i})
+			i = end
+
+		case c == '"' && dialect == DialectMySQL:
+			value, end, err := lexString(src, i, i+1, '"', true)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokString, text: value, pos: i})
+			i = end
+
+		case c == '"' && dialect == DialectAny:
+			// An identifier in PostgreSQL and a string with backslash escapes in MySQL
+			value, end, err := lexQuotedIdent(src, i, c)
+			if err != nil {
+				return nil, err
+			}
+			if _, mysqlEnd, err := lexString(src, i, i+1, '"', true); err != nil || mysqlEnd != end {
+				return nil, newError(ErrAmbiguousSyntax, src, i, "backslashes in double-quoted names end them differently in MySQL")
+			}
+			tokens = append(tokens, token{kind: tokQuotedIdent, text: value, pos: i})
+			i = end
+
+		case c == '"' || c == '`':
+			value, end, err := lexQuotedIdent(src, i, c)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokQuotedIdent, text: value, pos: i})
+			i = end
+
+		case c == '$':
+			if j := i + 1; j < len(src) && src[j] >= '0' && src[j] <= '9' {
+				for j < len(src) && src[j] >= '0' && src[j] <= '9' {
+					j++
+				}
+				tokens = append(tokens, token{kind: tokParam, text: src[i:j], pos: i})
+				i = j
+				continue
+			}
+			if dialect != DialectPostgres {
+				// MySQL reads $tag$ as a name, and what follows it as code
+				return nil, newError(ErrAmbiguousSyntax, src, i, "dollar-quoted strings are only supported by PostgreSQL")
+			}
+			value, end, err := lexDollarString(src, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokString, text: value, pos: i})
+			i = end
+
+		case isDigit(c) || (c == '.' && i+1 < len(src) && isDigit(src[i+1])):
+			end := lexNumber(src, i)
+			tokens = append(tokens, token{kind: tokNumber, text: src[i:end], pos: i})
+			i = end
+
+		case isIdentStart(src, i):
+			end := i
+			for end < len(src) && isIdentPart(src, end) {
+				_, size := utf8.DecodeRuneInString(src[end:])
+				end += size
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: src[i:end], pos: i})
+			i = end
+
+		default:
+			op := ""
+			for _, candidate := range multiCharOps {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" && strings.IndexByte(singleCharOps, c) >= 0 {
+				op = src[i : i+1]
+			}
+			if op == "" {
+				r, _ := utf8.DecodeRuneInString(src[i:])
+				return nil, newError(ErrSyntax, src, i, "unexpected character %q", r)
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	tokens = append(tokens, token{kind: tokEOF, pos: len(src)})
+	return tokens, nil
+}
+
+// skipLineComment returns the offset after the comment starting at start, which
+// runs to the end of the line
+func skipLineComment(src string, start int) int {
+	end := strings.IndexByte(src[start:], '\n')
+	if end < 0 {
+		return len(src)
+	}
+	return start + end + 1
+}
+
+// skipBlockComment returns the offset after the comment starting at start.
+// Block comments nest in PostgreSQL but not in MySQL, which runs the content of
+// /*! */ and /*M! */ comments as code.
+func skipBlockComment(src string, start int, dialect Dialect) (int, error) {
+	if dialect != DialectPostgres {
+		if rest := src[start+2:]; strings.HasPrefix(rest, "!") || strings.HasPrefix(rest, "M!") {
+			return 0, newError(ErrAmbiguousSyntax, src, start, "executable comments are not allowed")
+		}
+	}
+
+	depth := 0
+	for i := start; i < len(src)-1; {
+		switch {
+		case src[i] == '/' && src[i+1] == '*' && dialect == DialectMySQL && depth > 0:
+			i++
+		case src[i] == '/' && src[i+1] == '*' && dialect == DialectAny && depth > 0:
+			return 0, newError(ErrAmbiguousSyntax, src, i, "nested comments end at a different place in MySQL than in PostgreSQL")
+		case src[i] == '/' && src[i+1] == '*':
+			depth++
+			i += 2
+		case src[i] == '*' && src[i+1] == '/':
+			depth--
+			i += 2
+			if depth == 0 {
+				return i, nil
+			}
+		default:
+			i++
+		}
+	}
+	return 0, newError(ErrSyntax, src, start, "unterminated comment")
+}
+
+// lexDialectString reads a string quoted with quote whose body starts at bodyStart,
+// with backslash escapes in MySQL. In DialectAny the string must end at the same
+// place either way.
+func lexDialectString(src string, start, bodyStart int, quote byte, dialect Dialect) (string, int, error) {
+	value, end, err := lexString(src, start, bodyStart, quote, dialect == DialectMySQL)
+	if err != nil || dialect != DialectAny {
+		return value, end, err
+	}
+	if _, mysqlEnd, err := lexString(src, start, bodyStart, quote, true); err != nil || mysqlEnd != end {
+		return "", 0, newError(ErrAmbiguousSyntax, src, start, "a backslash before a quote ends this string at a different place in MySQL")
+	}
+	return value, end, nil
+}
+
+// lexString reads a string quoted with quote whose body starts at bodyStart.
+// Quotes are escaped by doubling them; backslash escapes apply when backslashEscapes
+// is set, as for escape strings (E'...') and MySQL strings.
+func lexString(src string, start, bodyStart int, quote byte, backslashEscapes bool) (string, int, error) {
+	var b strings.Builder
+	for i := bodyStart; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '\\' && backslashEscapes && i+1 < len(src):
+			i++
+			switch src[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(src[i])
+			}
+		case c == quote:
+			if i+1 < len(src) && src[i+1] == quote {
+				b.WriteByte(quote)
+				i++
+				continue
+			}
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, newError(ErrSyntax, src, start, "unterminated string literal")
+}
+
+func lexQuotedIdent(src string, start int, quote byte) (string, int, error) {
+	var b strings.Builder
+	for i := start + 1; i < len(src); i++ {
+		if src[i] == quote {
+			if i+1 < len(src) && src[i+1] == quote {
+				b.WriteByte(quote)
+				i++
+				continue
+			}
+			if b.Len() == 0 {
+				return "", 0, newError(ErrSyntax, src, start, "empty quoted identifier")
+			}
+			return b.String(), i + 1, nil
+		}
+		b.WriteByte(src[i])
+	}
+	return "", 0, newError(ErrSyntax, src, start, "unterminated quoted identifier")
+}
+
+// lexDollarString reads a PostgreSQL dollar-quoted string such as $$text$$ or $tag$text$tag$
+func lexDollarString(src string, start int) (string, int, error) {
+	end := start + 1
+	for end < len(src) && src[end] != '$' && isIdentPart(src, end) {
+		end++
+	}
+	if end >= len(src) || src[end] != '$' {
+		return "", 0, newError(ErrSyntax, src, start, "unexpected character '$'")
+	}
+	tag := src[start : end+1]
+	body := end + 1
+	closing := strings.Index(src[body:], tag)
+	if closing < 0 {
+		return "", 0, newError(ErrSyntax, src, start, "unterminated dollar-quoted string")
+	}
+	return src[body : body+closing], body + closing + len(tag), nil
+}
+
+func lexNumber(src string, start int) int {
+	i := start
+	for i < len(src) && isDigit(src[i]) {
+		i++
+	}
+	// A second dot belongs to the next token, as in 1..2 or t.1
+	if i < len(src) && src[i] == '.' && !(i+1 < len(src) && src[i+1] == '.') {
+		i++
+		for i < len(src) && isDigit(src[i]) {
+			i++
+		}
+	}
+	if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+		j := i + 1
+		if j < len(src) && (src[j] == '+' || src[j] == '-') {
+			j++
+		}
+		if j < len(src) && isDigit(src[j]) {
+			i = j
+			for i < len(src) && isDigit(src[i]) {
+				i++
+			}
+		}
+	}
+	return i
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(src string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(src[i:])
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isIdentPart(src string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(src[i:])
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,1388 @@
+// Package sqlparse parses SQL queries into a syntax tree so they can be checked
+// before they run against a customer database.
+//
+// The grammar covers the query language of PostgreSQL with the MySQL extensions
+// that generated SQL tends to use (backquoted identifiers, LIMIT offset, count and
+// INTERVAL n DAY). Statements other than queries are recognized by their leading
+// keyword but not parsed further.
+package sqlparse
+
+import (
+	"strings"
+)
+
+// Parse parses sql into its statements with the rules of PostgreSQL
+func Parse(sql string) ([]Statement, error) {
+	return ParseDialect(sql, DialectPostgres)
+}
+
+// ParseDialect parses sql into its statements with the rules of dialect
+func ParseDialect(sql string, dialect Dialect) ([]Statement, error) {
+	tokens, err := lex(sql, dialect)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: sql, tokens: tokens}
+	return p.parseStatements()
+}
+
+// Keywords that end an expression or a FROM item, so they are never taken as an
+// implicit alias or a column name
+var reservedKeywords = map[string]bool{
+	"ALL": true, "AND": true, "ANY": true, "AS": true, "ASC": true, "BETWEEN": true,
+	"BY": true, "CASE": true, "COLLATE": true, "CROSS": true, "DESC": true,
+	"DISTINCT": true, "ELSE": true, "END": true, "EXCEPT": true, "FETCH": true,
+	"FOR": true, "FROM": true, "FULL": true, "GROUP": true, "HAVING": true,
+	"ILIKE": true, "IN": true, "INNER": true, "INTERSECT": true, "INTO": true,
+	"IS": true, "ISNULL": true, "JOIN": true, "LATERAL": true, "LEFT": true,
+	"LIKE": true, "LIMIT": true, "NATURAL": true, "NOT": true, "NOTNULL": true,
+	"OFFSET": true, "ON": true, "OR": true, "ORDER": true, "OUTER": true,
+	"RIGHT": true, "SELECT": true, "SIMILAR": true, "SOME": true, "THEN": true,
+	"UNION": true, "USING": true, "VALUES": true, "WHEN": true, "WHERE": true,
+	"WINDOW": true, "WITH": true,
+}
+
+// Reserved keywords that are also function names, as in left(name, 3)
+var reservedFunctionNames = map[string]bool{
+	"LEFT": true, "RIGHT": true, "ANY": true, "SOME": true, "ALL": true,
+}
+
+// Functions whose arguments are separated by keywords instead of, or as well as, commas
+var keywordArgFunctions = map[string][]string{
+	"extract":      {"FROM"},
+	"substring":    {"FROM", "FOR"},
+	"substr":       {"FROM", "FOR"},
+	"trim":         {"FROM"},
+	"position":     {"IN"},
+	"overlay":      {"PLACING", "FROM", "FOR"},
+	"group_concat": {"SEPARATOR"},
+}
+
+// Statements that modify data and may appear inside a WITH clause
+var dataModifyingVerbs = map[string]bool{
+	"INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true,
+}
+
+// Binding power of infix operators, lowest first
+const (
+	precOr = iota + 1
+	precAnd
+	precNot
+	precIs
+	precCompare
+	precLike
+	precOther
+	precAdd
+	precMul
+	precExp
+	precAt
+	precUnary
+)
+
+// bailout carries a parse error up the recursive descent; Parse recovers it
+type bailout struct {
+	err *Error
+}
+
+type parser struct {
+	src    string
+	tokens []token
+	i      int
+}
+
+func (p *parser) parseStatements() (stmts []Statement, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			b, ok := r.(bailout)
+			if !ok {
+				panic(r)
+			}
+			stmts, err = nil, b.err
+		}
+	}()
+
+	for {
+		for p.acceptOp(";") {
+		}
+		if p.tok().kind == tokEOF {
+			return stmts, nil
+		}
+		stmts = append(stmts, p.parseStatement())
+		if p.tok().kind != tokEOF && !p.isOp(";") {
+			p.unexpected()
+		}
+	}
+}
+
+// Token helpers
+
+func (p *parser) tok() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) peek(n int) token {
+	if p.i+n < len(p.tokens) {
+		return p.tokens[p.i+n]
+	}
+	return p.tokens[len(p.tokens)-1]
+}
+
+func (p *parser) advance() token {
+	t := p.tokens[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func isKeyword(t token, kw string) bool {
+	return t.kind == tokIdent && strings.EqualFold(t.text, kw)
+}
+
+func (p *parser) isKeyword(kws ...string) bool {
+	for _, kw := range kws {
+		if isKeyword(p.tok(), kw) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) acceptKeyword(kw string) bool {
+	if p.isKeyword(kw) {
+		p.advance()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectKeyword(kw string) {
+	if !p.acceptKeyword(kw) {
+		p.failf(p.tok().pos, "expected %s but found %s", kw, describe(p.tok()))
+	}
+}
+
+func (p *parser) isOp(op string) bool {
+	return p.tok().kind == tokOp && p.tok().text == op
+}
+
+func (p *parser) acceptOp(op string) bool {
+	if p.isOp(op) {
+		p.advance()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectOp(op string) {
+	if !p.acceptOp(op) {
+		p.failf(p.tok().pos, "expected %q but found %s", op, describe(p.tok()))
+	}
+}
+
+func (p *parser) failf(pos int, format string, args ...interface{}) {
+	panic(bailout{newError(ErrSyntax, p.src, pos, format, args...)})
+}
+
+func (p *parser) unexpected() {
+	p.failf(p.tok().pos, "unexpected %s", describe(p.tok()))
+}
+
+// upperKeyword returns the uppercased text of an unquoted identifier, or ""
+func upperKeyword(t token) string {
+	if t.kind != tokIdent {
+		return ""
+	}
+	return strings.ToUpper(t.text)
+}
+
+func describe(t token) string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokString:
+		return "string literal"
+	case tokIdent:
+		if reservedKeywords[strings.ToUpper(t.text)] {
+			return "keyword " + strings.ToUpper(t.text)
+		}
+		return "\"" + t.text + "\""
+	default:
+		return "\"" + t.text + "\""
+	}
+}
+
+// isName reports whether t can be used as an identifier
+func isName(t token) bool {
+	return t.kind == tokQuotedIdent || (t.kind == tokIdent && !reservedKeywords[strings.ToUpper(t.text)])
+}
+
+func (p *parser) parseName() string {
+	t := p.tok()
+	if !isName(t) {
+		p.failf(t.pos, "expected a name but found %s", describe(t))
+	}
+	p.advance()
+	return t.text
+}
+
+func (p *parser) parseObjectName() ObjectName {
+	name := ObjectName{p.parseName()}
+	for p.isOp(".") && isName(p.peek(1)) {
+		p.advance()
+		name = append(name, p.parseName())
+	}
+	return name
+}
+
+func (p *parser) parseNameList() []string {
+	p.expectOp("(")
+	var names []string
+	for {
+		names = append(names, p.parseName())
+		if !p.acceptOp(",") {
+			break
+		}
+	}
+	p.expectOp(")")
+	return names
+}
+
+// Statements
+
+func (p *parser) parseStatement() Statement {
+	switch {
+	case p.isKeyword("EXPLAIN"):
+		return p.parseExplain()
+	case p.isKeyword("SELECT", "WITH", "VALUES", "TABLE") || p.isOp("("):
+		return p.parseQueryStatement()
+	default:
+		return p.parseOtherStatement(nil)
+	}
+}
+
+// parseQueryStatement parses a query, or a data-modifying statement that
+// follows a WITH clause
+func (p *parser) parseQueryStatement() Statement {
+	pos := p.tok().pos
+	var with *With
+	if p.isKeyword("WITH") {
+		with = p.parseWith()
+	}
+	if dataModifyingVerbs[upperKeyword(p.tok())] {
+		return p.parseOtherStatement(with)
+	}
+	q := p.parseQueryAfterWith(with)
+	q.pos = pos
+	return q
+}
+
+// parseOtherStatement records the verb of a statement that is not a query and
+// skips to its end: a top-level semicolon, or the parenthesis closing a CTE
+func (p *parser) parseOtherStatement(with *With) *OtherStatement {
+	t := p.tok()
+	if t.kind != tokIdent {
+		p.unexpected()
+	}
+	stmt := &OtherStatement{Verb: strings.ToUpper(t.text), With: with, pos: t.pos}
+	depth := 0
+	for {
+		switch {
+		case p.tok().kind == tokEOF:
+			return stmt
+		case p.isOp("(") || p.isOp("["):
+			depth++
+		case p.isOp(")") || p.isOp("]"):
+			if depth == 0 {
+				return stmt
+			}
+			depth--
+		case p.isOp(";") && depth == 0:
+			return stmt
+		}
+		p.advance()
+	}
+}
+
+func (p *parser) parseExplain() *Explain {
+	explain := &Explain{pos: p.advance().pos}
+	if p.isOp("(") {
+		p.advance()
+		for {
+			option := strings.ToUpper(p.parseName())
+			if !p.isOp(",") && !p.isOp(")") {
+				// Option value such as TRUE, OFF or JSON
+				option += " " + strings.ToUpper(p.advance().text)
+			}
+			explain.Options = append(explain.Options, option)
+			if !p.acceptOp(",") {
+				break
+			}
+		}
+		p.expectOp(")")
+	} else {
+		for p.isKeyword("ANALYZE", "ANALYSE", "VERBOSE") {
+			explain.Options = append(explain.Options, strings.ToUpper(p.advance().text))
+		}
+	}
+	explain.Stmt = p.parseStatement()
+	return explain
+}
+
+func (p *parser) parseWith() *With {
+	with := &With{pos: p.advance().pos}
+	with.Recursive = p.acceptKeyword("RECURSIVE")
+	for {
+		cte := &CTE{pos: p.tok().pos, Name: p.parseName()}
+		if p.isOp("(") {
+			cte.Columns = p.parseNameList()
+		}
+		p.expectKeyword("AS")
+		if p.acceptKeyword("NOT") {
+			p.expectKeyword("MATERIALIZED")
+		} else {
+			p.acceptKeyword("MATERIALIZED")
+		}
+		p.expectOp("(")
+		if dataModifyingVerbs[upperKeyword(p.tok())] {
+			cte.Stmt = p.parseOtherStatement(nil)
+		} else {
+			cte.Stmt = p.parseQuery()
+		}
+		p.expectOp(")")
+		with.CTEs = append(with.CTEs, cte)
+		if !p.acceptOp(",") {
+			break
+		}
+	}
+	return with
+}
+
+// Queries
+
+func (p *parser) parseQuery() *Query {
+	pos := p.tok().pos
+	var with *With
+	if p.isKeyword("WITH") {
+		with = p.parseWith()
+	}
+	q := p.parseQueryAfterWith(with)
+	q.pos = pos
+	return q
+}
+
+func (p *parser) parseQueryAfterWith(with *With) *Query {
+	q := &Query{With: with, pos: p.tok().pos}
+	q.Body = p.parseSetOperations(0)
+
+	if p.isKeyword("ORDER") {
+		p.advance()
+		p.expectKeyword("BY")
+		q.OrderBy = p.parseOrderItems()
+	}
+
+	for {
+		switch {
+		case p.isKeyword("LIMIT"):
+			p.advance()
+			if p.acceptKeyword("ALL") {
+				continue
+			}
+			q.Limit = p.parseExpr()
+			if p.acceptOp(",") {
+				// MySQL: LIMIT offset, count
+				q.Offset = q.Limit
+				q.Limit = p.parseExpr()
+			}
+		case p.isKeyword("OFFSET"):
+			p.advance()
+			q.Offset = p.parseExpr()
+			if !p.acceptKeyword("ROWS") {
+				p.acceptKeyword("ROW")
+			}
+		case p.isKeyword("FETCH"):
+			p.advance()
+			if !p.acceptKeyword("FIRST") {
+				p.expectKeyword("NEXT")
+			}
+			if p.isKeyword("ROW", "ROWS") {
+				q.Limit = &Literal{Kind: NumberLiteral, Value: "1", pos: p.tok().pos}
+			} else {
+				q.Limit = p.parsePrimary()
+			}
+			if !p.acceptKeyword("ROWS") {
+				p.expectKeyword("ROW")
+			}
+			if p.acceptKeyword("WITH") {
+				p.expectKeyword("TIES")
+			} else {
+				p.expectKeyword("ONLY")
+			}
+		case p.isKeyword("FOR"):
+			q.Locking = p.parseLockingClause()
+		default:
+			return q
+		}
+	}
+}
+
+// parseLockingClause parses FOR UPDATE/NO KEY UPDATE/SHARE/KEY SHARE [OF ...] [NOWAIT | SKIP LOCKED]
+func (p *parser) parseLockingClause() string {
+	p.expectKeyword("FOR")
+	var words []string
+	for p.isKeyword("UPDATE", "NO", "KEY", "SHARE") {
+		words = append(words, strings.ToUpper(p.advance().text))
+	}
+	if len(words) == 0 {
+		p.failf(p.tok().pos, "expected UPDATE or SHARE after FOR but found %s", describe(p.tok()))
+	}
+	if p.acceptKeyword("OF") {
+		p.parseObjectName()
+		for p.acceptOp(",") {
+			p.parseObjectName()
+		}
+	}
+	if !p.acceptKeyword("NOWAIT") && p.acceptKeyword("SKIP") {
+		p.expectKeyword("LOCKED")
+	}
+	return "FOR " + strings.Join(words, " ")
+}
+
+// parseSetOperations parses UNION and EXCEPT, which bind looser than INTERSECT
+func (p *parser) parseSetOperations(level int) QueryBody {
+	if level == 0 {
+		left := p.parseSetOperations(1)
+		for p.isKeyword("UNION", "EXCEPT") {
+			left = p.parseSetOperationRight(left, 1)
+		}
+		return left
+	}
+	left := p.parseQueryTerm()
+	for p.isKeyword("INTERSECT") {
+		left = p.parseSetOperationRight(left, 2)
+	}
+	return left
+}
+
+func (p *parser) parseSetOperationRight(left QueryBody, level int) QueryBody {
+	t := p.advance()
+	op := &SetOperation{Op: strings.ToUpper(t.text), Left: left, pos: t.pos}
+	if p.acceptKeyword("ALL") {
+		op.All = true
+	} else {
+		p.acceptKeyword("DISTINCT")
+	}
+	if level == 1 {
+		op.Right = p.parseSetOperations(1)
+	} else {
+		op.Right = p.parseQueryTerm()
+	}
+	return op
+}
+
+func (p *parser) parseQueryTerm() QueryBody {
+	t := p.tok()
+	switch {
+	case p.isKeyword("SELECT"):
+		return p.parseSelect()
+	case p.isKeyword("VALUES"):
+		return p.parseValues()
+	case p.isKeyword("TABLE"):
+		p.advance()
+		return &TableStmt{Name: p.parseObjectName(), pos: t.pos}
+	case p.isOp("("):
+		p.advance()
+		q := p.parseQuery()
+		p.expectOp(")")
+		return q
+	}
+	if dataModifyingVerbs[upperKeyword(t)] {
+		p.failf(t.pos, "%s is only allowed as a statement or in a WITH clause", strings.ToUpper(t.text))
+	}
+	p.failf(t.pos, "expected SELECT or VALUES but found %s", describe(t))
+	return nil
+}
+
+func (p *parser) parseValues() *Values {
+	values := &Values{pos: p.advance().pos}
+	for {
+		p.expectOp("(")
+		values.Rows = append(values.Rows, p.parseExprList())
+		p.expectOp(")")
+		if !p.acceptOp(",") {
+			return values
+		}
+	}
+}
+
+func (p *parser) parseSelect() *Select {
+	sel := &Select{pos: p.advance().pos}
+
+	if p.acceptKeyword("DISTINCT") {
+		sel.Distinct = true
+		if p.acceptKeyword("ON") {
+			p.expectOp("(")
+			sel.DistinctOn = p.parseExprList()
+			p.expectOp(")")
+		}
+	} else {
+		p.acceptKeyword("ALL")
+	}
+
+	for {
+		sel.Columns = append(sel.Columns, p.parseSelectItem())
+		if !p.acceptOp(",") {
+			break
+		}
+	}
+
+	if p.acceptKeyword("INTO") {
+		p.acceptKeyword("TEMPORARY")
+		p.acceptKeyword("TEMP")
+		p.acceptKeyword("UNLOGGED")
+		p.acceptKeyword("TABLE")
+		sel.Into = p.parseObjectName()
+	}
+
+	if p.acceptKeyword("FROM") {
+		for {
+			sel.From = append(sel.From, p.parseTableRef())
+			if !p.acceptOp(",") {
+				break
+			}
+		}
+	}
+
+	if p.acceptKeyword("WHERE") {
+		sel.Where = p.parseExpr()
+	}
+
+	if p.isKeyword("GROUP") {
+		p.advance()
+		p.expectKeyword("BY")
+		if !p.acceptKeyword("ALL") {
+			p.acceptKeyword("DISTINCT")
+		}
+		for {
+			sel.GroupBy = append(sel.GroupBy, p.parseGroupingElement())
+			if !p.acceptOp(",") {
+				break
+			}
+		}
+		// MySQL rollup modifier
+		if p.isKeyword("WITH") && isKeyword(p.peek(1), "ROLLUP") {
+			p.advance()
+			p.advance()
+		}
+	}
+
+	if p.acceptKeyword("HAVING") {
+		sel.Having = p.parseExpr()
+	}
+
+	if p.acceptKeyword("WINDOW") {
+		for {
+			window := &NamedWindow{pos: p.tok().pos, Name: p.parseName()}
+			p.expectKeyword("AS")
+			window.Spec = p.parseWindowSpec()
+			sel.Windows = append(sel.Windows, window)
+			if !p.acceptOp(",") {
+				break
+			}
+		}
+	}
+
+	return sel
+}
+
+func (p *parser) parseSelectItem() *SelectItem {
+	item := &SelectItem{pos: p.tok().pos}
+	if p.acceptOp("*") {
+		return item
+	}
+	item.Expr = p.parseExpr()
+	if p.acceptKeyword("AS") {
+		item.Alias = p.parseAliasName()
+	} else if isName(p.tok()) {
+		item.Alias = p.parseName()
+	}
+	return item
+}
+
+// parseAliasName accepts any identifier after AS, including keywords such as "AS count"
+func (p *parser) parseAliasName() string {
+	t := p.tok()
+	if t.kind != tokIdent && t.kind != tokQuotedIdent {
+		p.failf(t.pos, "expected an alias but found %s", describe(t))
+	}
+	p.advance()
+	return t.text
+}
+
+// parseGroupingElement parses a GROUP BY entry, including GROUPING SETS ((a, b), ())
+func (p *parser) parseGroupingElement() Expr {
+	if p.isOp("(") && p.peek(1).kind == tokOp && p.peek(1).text == ")" {
+		pos := p.advance().pos
+		p.advance()
+		return &RowExpr{pos: pos}
+	}
+	if p.isKeyword("GROUPING") && isKeyword(p.peek(1), "SETS") {
+		pos := p.advance().pos
+		p.advance()
+		p.expectOp("(")
+		call := &FuncCall{Name: ObjectName{"grouping sets"}, pos: pos}
+		for {
+			call.Args = append(call.Args, p.parseGroupingElement())
+			if !p.acceptOp(",") {
+				break
+			}
+		}
+		p.expectOp(")")
+		return call
+	}
+	return p.parseExpr()
+}
+
+func (p *parser) parseOrderItems() []*OrderItem {
+	var items []*OrderItem
+	for {
+		item := &OrderItem{pos: p.tok().pos, Expr: p.parseExpr()}
+		if p.acceptKeyword("DESC") {
+			item.Desc = true
+		} else if !p.acceptKeyword("ASC") && p.acceptKeyword("USING") {
+			p.advance()
+		}
+		if p.acceptKeyword("NULLS") {
+			first := p.acceptKeyword("FIRST")
+			if !first {
+				p.expectKeyword("LAST")
+			}
+			item.NullsFirst = &first
+		}
+		items = append(items, item)
+		if !p.acceptOp(",") {
+			return items
+		}
+	}
+}
+
+// FROM clause
+
+func (p *parser) parseTableRef() TableExpr {
+	left := p.parseTablePrimary()
+	for {
+		t := p.tok()
+		join := &Join{Left: left, pos: t.pos}
+		if p.acceptKeyword("NATURAL") {
+			join.Natural = true
+		}
+		switch {
+		case p.acceptKeyword("CROSS"):
+			join.Type = "CROSS"
+		case p.acceptKeyword("INNER"):
+			join.Type = "INNER"
+		case p.isKeyword("LEFT", "RIGHT", "FULL"):
+			join.Type = strings.ToUpper(p.advance().text)
+			p.acceptKeyword("OUTER")
+		case p.isKeyword("JOIN"):
+			join.Type = "INNER"
+		default:
+			if join.Natural {
+				p.failf(p.tok().pos, "expected JOIN after NATURAL but found %s", describe(p.tok()))
+			}
+			return left
+		}
+		p.expectKeyword("JOIN")
+		join.Right = p.parseTablePrimary()
+
+		if join.Type != "CROSS" && !join.Natural {
+			switch {
+			case p.acceptKeyword("ON"):
+				join.On = p.parseExpr()
+			case p.acceptKeyword("USING"):
+				join.Using = p.parseNameList()
+			default:
+				p.failf(p.tok().pos, "expected ON or USING after %s JOIN but found %s", join.Type, describe(p.tok()))
+			}
+		}
+		left = join
+	}
+}
+
+func (p *parser) parseTablePrimary() TableExpr {
+	pos := p.tok().pos
+	lateral := p.acceptKeyword("LATERAL")
+
+	if p.isOp("(") {
+		next := p.peek(1)
+		if isKeyword(next, "SELECT") || isKeyword(next, "WITH") || isKeyword(next, "VALUES") || isKeyword(next, "TABLE") ||
+			(next.kind == tokOp && next.text == "(") {
+			p.advance()
+			q := p.parseQuery()
+			p.expectOp(")")
+			return &DerivedTable{Lateral: lateral, Query: q, Alias: p.parseOptionalAlias(), pos: pos}
+		}
+		p.advance()
+		ref := p.parseTableRef()
+		p.expectOp(")")
+		return ref
+	}
+
+	if !lateral {
+		p.acceptKeyword("ONLY")
+	}
+	name := p.parseObjectName()
+	if p.isOp("(") {
+		call := p.parseFuncCall(name, pos)
+		if p.isKeyword("WITH") && isKeyword(p.peek(1), "ORDINALITY") {
+			p.advance()
+			p.advance()
+		}
+		return &TableFunction{Lateral: lateral, Func: call, Alias: p.parseOptionalAlias(), pos: pos}
+	}
+	if lateral {
+		p.failf(pos, "LATERAL must be followed by a subquery or a function call")
+	}
+	return &TableName{Name: name, Alias: p.parseOptionalAlias(), pos: pos}
+}
+
+func (p *parser) parseOptionalAlias() *Alias {
+	var alias *Alias
+	if p.acceptKeyword("AS") {
+		alias = &Alias{Name: p.parseName()}
+	} else if isName(p.tok()) {
+		alias = &Alias{Name: p.parseName()}
+	} else {
+		return nil
+	}
+	if p.isOp("(") {
+		alias.Columns = p.parseNameList()
+	}
+	return alias
+}
+
+// Expressions
+
+func (p *parser) parseExpr() Expr {
+	return p.parseBinary(precOr)
+}
+
+func (p *parser) parseExprList() []Expr {
+	var exprs []Expr
+	for {
+		exprs = append(exprs, p.parseExpr())
+		if !p.acceptOp(",") {
+			return exprs
+		}
+	}
+}
+
+var comparisonOps = map[string]bool{
+	"=": true, "<>": true, "!=": true, "<": true, ">": true, "<=": true, ">=": true, "<=>": true,
+}
+
+var otherOps = map[string]bool{
+	"||": true, "->": true, "->>": true, "#>": true, "#>>": true, "@>": true, "<@": true,
+	"?": true, "?|": true, "?&": true, "&&": true, "~": true, "~*": true, "!~": true,
+	"!~*": true, "~~": true, "~~*": true, "!~~": true, "!~~*": true, "<<": true, ">>": true,
+	"&": true, "|": true, "#": true, "@@": true,
+}
+
+// parseBinary parses an expression whose infix operators bind at least as tightly as minPrec
+func (p *parser) parseBinary(minPrec int) Expr {
+	left := p.parsePrefix()
+	for {
+		t := p.tok()
+		kw := upperKeyword(t)
+
+		// NOT IN, NOT LIKE, NOT BETWEEN ...
+		not := false
+		if kw == "NOT" {
+			switch upperKeyword(p.peek(1)) {
+			case "IN", "LIKE", "ILIKE", "BETWEEN", "SIMILAR":
+				not = true
+				kw = upperKeyword(p.peek(1))
+			}
+		}
+
+		switch {
+		case kw == "OR" && minPrec <= precOr:
+			p.advance()
+			left = &BinaryExpr{Op: "OR", X: left, Y: p.parseBinary(precOr + 1), pos: t.pos}
+		case kw == "AND" && minPrec <= precAnd:
+			p.advance()
+			left = &BinaryExpr{Op: "AND", X: left, Y: p.parseBinary(precAnd + 1), pos: t.pos}
+		case kw == "IS" && minPrec <= precIs:
+			left = p.parseIs(left)
+		case (kw == "ISNULL" || kw == "NOTNULL") && minPrec <= precIs:
+			p.advance()
+			left = &IsExpr{X: left, Not: kw == "NOTNULL", What: "NULL", pos: t.pos}
+		case t.kind == tokOp && comparisonOps[t.text] && minPrec <= precCompare:
+			p.advance()
+			left = &BinaryExpr{Op: t.text, X: left, Y: p.parseBinary(precCompare + 1), pos: t.pos}
+		case (kw == "IN" || kw == "LIKE" || kw == "ILIKE" || kw == "BETWEEN" || kw == "SIMILAR") && minPrec <= precLike:
+			if not {
+				p.advance()
+			}
+			left = p.parsePredicate(left, kw, not, t.pos)
+		case t.kind == tokOp && otherOps[t.text] && minPrec <= precOther:
+			p.advance()
+			left = &BinaryExpr{Op: t.text, X: left, Y: p.parseBinary(precOther + 1), pos: t.pos}
+		case t.kind == tokOp && (t.text == "+" || t.text == "-") && minPrec <= precAdd:
+			p.advance()
+			left = &BinaryExpr{Op: t.text, X: left, Y: p.parseBinary(precAdd + 1), pos: t.pos}
+		case t.kind == tokOp && (t.text == "*" || t.text == "/" || t.text == "%") && minPrec <= precMul:
+			p.advance()
+			left = &BinaryExpr{Op: t.text, X: left, Y: p.parseBinary(precMul + 1), pos: t.pos}
+		case (kw == "DIV" || kw == "MOD") && startsOperand(p.peek(1)) && minPrec <= precMul:
+			// MySQL integer division and modulo
+			p.advance()
+			left = &BinaryExpr{Op: kw, X: left, Y: p.parseBinary(precMul + 1), pos: t.pos}
+		case t.kind == tokOp && t.text == "^" && minPrec <= precExp:
+			p.advance()
+			left = &BinaryExpr{Op: "^", X: left, Y: p.parseBinary(precExp + 1), pos: t.pos}
+		case kw == "AT" && isKeyword(p.peek(1), "TIME") && minPrec <= precAt:
+			p.advance()
+			p.advance()
+			p.expectKeyword("ZONE")
+			left = &BinaryExpr{Op: "AT TIME ZONE", X: left, Y: p.parseBinary(precAt + 1), pos: t.pos}
+		default:
+			return left
+		}
+	}
+}
+
+// startsOperand reports whether t can begin the right operand of a keyword operator,
+// so "SELECT a mod FROM t" still reads mod as an alias
+func startsOperand(t token) bool {
+	switch t.kind {
+	case tokNumber, tokString, tokParam, tokQuotedIdent:
+		return true
+	case tokIdent:
+		return !reservedKeywords[strings.ToUpper(t.text)]
+	case tokOp:
+		return t.text == "(" || t.text == "-"
+	}
+	return false
+}
+
+func (p *parser) parseIs(left Expr) Expr {
+	pos := p.advance().pos
+	not := p.acceptKeyword("NOT")
+	if p.acceptKeyword("DISTINCT") {
+		p.expectKeyword("FROM")
+		op := "IS DISTINCT FROM"
+		if not {
+			op = "IS NOT DISTINCT FROM"
+		}
+		return &BinaryExpr{Op: op, X: left, Y: p.parseBinary(precIs + 1), pos: pos}
+	}
+	t := p.tok()
+	switch kw := upperKeyword(t); kw {
+	case "NULL", "TRUE", "FALSE", "UNKNOWN", "DOCUMENT":
+		p.advance()
+		return &IsExpr{X: left, Not: not, What: kw, pos: pos}
+	}
+	p.failf(t.pos, "expected NULL, TRUE, FALSE or DISTINCT FROM after IS but found %s", describe(t))
+	return nil
+}
+
+// parsePredicate parses IN, LIKE, ILIKE, SIMILAR TO and BETWEEN after the left operand
+func (p *parser) parsePredicate(left Expr, kw string, not bool, pos int) Expr {
+	p.advance()
+	switch kw {
+	case "IN":
+		in := &InExpr{X: left, Not: not, pos: pos}
+		p.expectOp("(")
+		if p.isKeyword("SELECT", "WITH", "VALUES") {
+			in.Query = p.parseQuery()
+		} else {
+			in.List = p.parseExprList()
+		}
+		p.expectOp(")")
+		return in
+	case "BETWEEN":
+		p.acceptKeyword("SYMMETRIC")
+		between := &BetweenExpr{X: left, Not: not, pos: pos}
+		between.Low = p.parseBinary(precLike + 1)
+		p.expectKeyword("AND")
+		between.High = p.parseBinary(precLike + 1)
+		return between
+	case "SIMILAR":
+		p.expectKeyword("TO")
+		kw = "SIMILAR TO"
+	}
+	op := kw
+	if not {
+		op = "NOT " + kw
+	}
+	expr := &BinaryExpr{Op: op, X: left, Y: p.parseBinary(precLike + 1), pos: pos}
+	if p.acceptKeyword("ESCAPE") {
+		p.parseBinary(precLike + 1)
+	}
+	return expr
+}
+
+func (p *parser) parsePrefix() Expr {
+	t := p.tok()
+	switch {
+	case isKeyword(t, "NOT"):
+		p.advance()
+		return &UnaryExpr{Op: "NOT", X: p.parseBinary(precNot), pos: t.pos}
+	case t.kind == tokOp && (t.text == "-" || t.text == "+" || t.text == "~"):
+		p.advance()
+		return &UnaryExpr{Op: t.text, X: p.parseBinary(precUnary), pos: t.pos}
+	}
+	return p.parsePostfix(p.parsePrimary())
+}
+
+// parsePostfix parses casts, subscripts and COLLATE after a primary expression
+func (p *parser) parsePostfix(expr Expr) Expr {
+	for {
+		t := p.tok()
+		switch {
+		case p.isOp("::"):
+			p.advance()
+			expr = &CastExpr{X: expr, Type: p.parseTypeName(), pos: t.pos}
+		case p.isOp("["):
+			p.advance()
+			index := &IndexExpr{X: expr, pos: t.pos}
+			if !p.isOp(":") {
+				index.Index = p.parseExpr()
+			}
+			if p.acceptOp(":") {
+				index.Slice = true
+				if !p.isOp("]") {
+					index.Upper = p.parseExpr()
+				}
+			}
+			p.expectOp("]")
+			expr = index
+		case p.isKeyword("COLLATE"):
+			p.advance()
+			expr = &CollateExpr{X: expr, Collation: p.parseObjectName(), pos: t.pos}
+		case p.isOp(".") && p.peek(1).kind != tokEOF:
+			// Field selection from a composite value, as in (row_value).field
+			p.advance()
+			if p.acceptOp("*") {
+				continue
+			}
+			p.parseName()
+		default:
+			return expr
+		}
+	}
+}
+
+func (p *parser) parsePrimary() Expr {
+	t := p.tok()
+	switch t.kind {
+	case tokNumber:
+		p.advance()
+		return &Literal{Kind: NumberLiteral, Value: t.text, pos: t.pos}
+	case tokString:
+		p.advance()
+		return &Literal{Kind: StringLiteral, Value: t.text, pos: t.pos}
+	case tokParam:
+		p.advance()
+		return &Param{Name: t.text, pos: t.pos}
+	case tokQuotedIdent:
+		return p.parseNameExpr()
+	case tokOp:
+		if t.text == "(" {
+			return p.parseParenExpr()
+		}
+		p.unexpected()
+	case tokEOF:
+		p.failf(t.pos, "unexpected end of query, expected an expression")
+	}
+
+	switch kw := strings.ToUpper(t.text); kw {
+	case "NULL":
+		p.advance()
+		return &Literal{Kind: NullLiteral, Value: "NULL", pos: t.pos}
+	case "TRUE", "FALSE":
+		p.advance()
+		return &Literal{Kind: BoolLiteral, Value: kw, pos: t.pos}
+	case "CASE":
+		return p.parseCase()
+	case "CAST", "TRY_CAST":
+		p.advance()
+		p.expectOp("(")
+		x := p.parseExpr()
+		p.expectKeyword("AS")
+		typ := p.parseTypeName()
+		p.expectOp(")")
+		return &CastExpr{X: x, Type: typ, pos: t.pos}
+	case "EXISTS":
+		p.advance()
+		p.expectOp("(")
+		q := p.parseQuery()
+		p.expectOp(")")
+		return &SubqueryExpr{Exists: true, Query: q, pos: t.pos}
+	case "ARRAY":
+		if next := p.peek(1); next.kind == tokOp && (next.text == "[" || next.text == "(") {
+			return p.parseArray()
+		}
+	case "ROW":
+		if next := p.peek(1); next.kind == tokOp && next.text == "(" {
+			p.advance()
+			p.advance()
+			row := &RowExpr{pos: t.pos}
+			if !p.isOp(")") {
+				row.Elems = p.parseExprList()
+			}
+			p.expectOp(")")
+			return row
+		}
+	case "INTERVAL":
+		return p.parseInterval()
+	}
+
+	if reservedKeywords[strings.ToUpper(t.text)] {
+		if next := p.peek(1); !(reservedFunctionNames[strings.ToUpper(t.text)] && next.kind == tokOp && next.text == "(") {
+			p.failf(t.pos, "unexpected keyword %s", strings.ToUpper(t.text))
+		}
+		p.advance()
+		return p.parseFuncCall(ObjectName{t.text}, t.pos)
+	}
+
+	// Typed literal such as DATE '2024-01-01' or TIMESTAMP '2024-01-01 00:00'
+	if next := p.peek(1); next.kind == tokString {
+		p.advance()
+		p.advance()
+		return &TypedLiteral{Type: strings.ToUpper(t.text), Value: &Literal{Kind: StringLiteral, Value: next.text, pos: next.pos}, pos: t.pos}
+	}
+
+	return p.parseNameExpr()
+}
+
+// parseNameExpr parses a column reference, qualified star or function call
+func (p *parser) parseNameExpr() Expr {
+	pos := p.tok().pos
+	name := ObjectName{p.parseName()}
+	for p.isOp(".") {
+		next := p.peek(1)
+		if next.kind == tokOp && next.text == "*" {
+			p.advance()
+			p.advance()
+			return &ColumnRef{Name: name, Star: true, pos: pos}
+		}
+		if next.kind != tokIdent && next.kind != tokQuotedIdent {
+			break
+		}
+		p.advance()
+		name = append(name, p.advance().text)
+	}
+	if p.isOp("(") {
+		return p.parseFuncCall(name, pos)
+	}
+	return &ColumnRef{Name: name, pos: pos}
+}
+
+func (p *parser) parseFuncCall(name ObjectName, pos int) *FuncCall {
+	call := &FuncCall{Name: name, pos: pos}
+	p.expectOp("(")
+
+	switch {
+	case p.isOp(")"):
+	case p.isOp("*"):
+		p.advance()
+		call.Star = true
+	case p.isKeyword("SELECT", "WITH", "VALUES"):
+		// ANY (subquery), ALL (subquery)
+		q := p.parseQuery()
+		call.Args = []Expr{&SubqueryExpr{Query: q, pos: q.pos}}
+	default:
+		if p.acceptKeyword("DISTINCT") {
+			call.Distinct = true
+		} else {
+			p.acceptKeyword("ALL")
+		}
+		call.Args = p.parseFuncArgs(name.Base())
+	}
+
+	if p.isKeyword("ORDER") {
+		p.advance()
+		p.expectKeyword("BY")
+		call.OrderBy = p.parseOrderItems()
+	}
+	p.expectOp(")")
+
+	if p.isKeyword("WITHIN") {
+		p.advance()
+		p.expectKeyword("GROUP")
+		p.expectOp("(")
+		p.expectKeyword("ORDER")
+		p.expectKeyword("BY")
+		call.WithinGroup = p.parseOrderItems()
+		p.expectOp(")")
+	}
+	if p.isKeyword("FILTER") && p.peek(1).kind == tokOp && p.peek(1).text == "(" {
+		p.advance()
+		p.advance()
+		p.expectKeyword("WHERE")
+		call.Filter = p.parseExpr()
+		p.expectOp(")")
+	}
+	if p.acceptKeyword("OVER") {
+		if p.isOp("(") {
+			call.Over = p.parseWindowSpec()
+		} else {
+			call.Over = &WindowSpec{Name: p.parseName(), pos: p.tok().pos}
+		}
+	}
+	return call
+}
+
+// parseFuncArgs parses call arguments, allowing the keyword separators of
+// EXTRACT(field FROM x), SUBSTRING(x FROM 1 FOR 2), TRIM(BOTH ' ' FROM x) and friends
+func (p *parser) parseFuncArgs(name string) []Expr {
+	separators := keywordArgFunctions[name]
+	isSeparator := func() bool {
+		for _, kw := range separators {
+			if p.isKeyword(kw) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if name == "trim" && p.isKeyword("BOTH", "LEADING", "TRAILING") {
+		p.advance()
+		if p.acceptKeyword("FROM") {
+			return p.parseExprList()
+		}
+	}
+
+	var args []Expr
+	for {
+		if name == "trim" && isSeparator() && len(args) == 0 {
+			p.advance()
+			continue
+		}
+		minPrec := precOr
+		if name == "position" && len(args) == 0 {
+			// The IN of POSITION(a IN b) is not a membership test
+			minPrec = precLike + 1
+		}
+		args = append(args, p.parseBinary(minPrec))
+		if p.acceptOp(",") {
+			continue
+		}
+		if isSeparator() {
+			p.advance()
+			continue
+		}
+		return args
+	}
+}
+
+func (p *parser) parseWindowSpec() *WindowSpec {
+	spec := &WindowSpec{pos: p.tok().pos}
+	p.expectOp("(")
+	if isName(p.tok()) && !p.isKeyword("PARTITION", "ORDER", "ROWS", "RANGE", "GROUPS") {
+		spec.Name = p.parseName()
+	}
+	if p.isKeyword("PARTITION") {
+		p.advance()
+		p.expectKeyword("BY")
+		spec.PartitionBy = p.parseExprList()
+	}
+	if p.isKeyword("ORDER") {
+		p.advance()
+		p.expectKeyword("BY")
+		spec.OrderBy = p.parseOrderItems()
+	}
+	if p.isKeyword("ROWS", "RANGE", "GROUPS") {
+		p.advance()
+		if p.acceptKeyword("BETWEEN") {
+			p.parseFrameBound(spec)
+			p.expectKeyword("AND")
+		}
+		p.parseFrameBound(spec)
+		if p.acceptKeyword("EXCLUDE") {
+			switch {
+			case p.acceptKeyword("CURRENT"):
+				p.expectKeyword("ROW")
+			case p.acceptKeyword("NO"):
+				p.expectKeyword("OTHERS")
+			case p.acceptKeyword("GROUP"), p.acceptKeyword("TIES"):
+			default:
+				p.unexpected()
+			}
+		}
+	}
+	p.expectOp(")")
+	return spec
+}
+
+func (p *parser) parseFrameBound(spec *WindowSpec) {
+	switch {
+	case p.acceptKeyword("UNBOUNDED"):
+	case p.acceptKeyword("CURRENT"):
+		p.expectKeyword("ROW")
+		return
+	default:
+		spec.FrameBounds = append(spec.FrameBounds, p.parseBinary(precAdd))
+	}
+	if !p.acceptKeyword("PRECEDING") {
+		p.expectKeyword("FOLLOWING")
+	}
+}
+
+func (p *parser) parseParenExpr() Expr {
+	pos := p.advance().pos
+	if p.isKeyword("SELECT", "WITH", "VALUES") {
+		q := p.parseQuery()
+		p.expectOp(")")
+		return &SubqueryExpr{Query: q, pos: pos}
+	}
+	exprs := p.parseExprList()
+	p.expectOp(")")
+	if len(exprs) == 1 {
+		return exprs[0]
+	}
+	return &RowExpr{Elems: exprs, pos: pos}
+}
+
+func (p *parser) parseCase() Expr {
+	c := &CaseExpr{pos: p.advance().pos}
+	if !p.isKeyword("WHEN") {
+		c.Operand = p.parseExpr()
+	}
+	for p.acceptKeyword("WHEN") {
+		when := &When{Cond: p.parseExpr()}
+		p.expectKeyword("THEN")
+		when.Result = p.parseExpr()
+		c.Whens = append(c.Whens, when)
+	}
+	if len(c.Whens) == 0 {
+		p.failf(p.tok().pos, "expected WHEN in CASE expression but found %s", describe(p.tok()))
+	}
+	if p.acceptKeyword("ELSE") {
+		c.Else = p.parseExpr()
+	}
+	p.expectKeyword("END")
+	return c
+}
+
+func (p *parser) parseArray() Expr {
+	array := &ArrayExpr{pos: p.advance().pos}
+	if p.acceptOp("(") {
+		array.Query = p.parseQuery()
+		p.expectOp(")")
+		return array
+	}
+	p.expectOp("[")
+	if !p.isOp("]") {
+		for {
+			if p.isOp("[") {
+				// Nested array literal ARRAY[[1, 2], [3, 4]]
+				inner := &ArrayExpr{pos: p.tok().pos}
+				p.advance()
+				if !p.isOp("]") {
+					inner.Elems = p.parseExprList()
+				}
+				p.expectOp("]")
+				array.Elems = append(array.Elems, inner)
+			} else {
+				array.Elems = append(array.Elems, p.parseExpr())
+			}
+			if !p.acceptOp(",") {
+				break
+			}
+		}
+	}
+	p.expectOp("]")
+	return array
+}
+
+var intervalUnits = map[string]bool{
+	"YEAR": true, "QUARTER": true, "MONTH": true, "WEEK": true, "DAY": true,
+	"HOUR": true, "MINUTE": true, "SECOND": true, "MICROSECOND": true,
+	"YEARS": true, "MONTHS": true, "WEEKS": true, "DAYS": true,
+	"HOURS": true, "MINUTES": true, "SECONDS": true,
+}
+
+// parseInterval parses INTERVAL '1 day', INTERVAL '1' DAY and MySQL's INTERVAL 7 DAY
+func (p *parser) parseInterval() Expr {
+	pos := p.advance().pos
+	lit := &TypedLiteral{Type: "INTERVAL", pos: pos}
+	if p.tok().kind == tokString {
+		t := p.advance()
+		lit.Value = &Literal{Kind: StringLiteral, Value: t.text, pos: t.pos}
+	} else {
+		lit.Value = p.parseBinary(precUnary)
+	}
+	if intervalUnits[upperKeyword(p.tok())] {
+		lit.Unit = strings.ToUpper(p.advance().text)
+		if p.acceptKeyword("TO") {
+			lit.Unit += " TO " + strings.ToUpper(p.advance().text)
+		}
+	} else if _, ok := lit.Value.(*Literal); !ok {
+		p.failf(p.tok().pos, "expected an interval unit such as DAY but found %s", describe(p.tok()))
+	}
+	return lit
+}
+
+// parseTypeName parses a type in a cast, such as numeric(10, 2), double precision,
+// timestamp with time zone or text[]
+func (p *parser) parseTypeName() string {
+	t := p.tok()
+	if t.kind != tokIdent && t.kind != tokQuotedIdent {
+		p.failf(t.pos, "expected a type name but found %s", describe(t))
+	}
+	words := []string{p.advance().text}
+	for p.isOp(".") {
+		p.advance()
+		words[len(words)-1] += "." + p.parseName()
+	}
+
+	switch strings.ToUpper(words[0]) {
+	case "DOUBLE":
+		if p.isKeyword("PRECISION") {
+			words = append(words, p.advance().text)
+		}
+	case "CHARACTER", "CHAR", "BIT", "NATIONAL":
+		for p.isKeyword("VARYING", "CHARACTER", "CHAR") {
+			words = append(words, p.advance().text)
+		}
+	case "UNSIGNED", "SIGNED":
+		// MySQL CAST(x AS UNSIGNED INTEGER)
+		if p.isKeyword("INTEGER", "INT") {
+			words = append(words, p.advance().text)
+		}
+	}
+
+	name := strings.Join(words, " ")
+	if p.acceptOp("(") {
+		var params []string
+		for !p.isOp(")") {
+			t := p.advance()
+			if t.kind == tokEOF {
+				p.failf(t.pos, "unterminated type modifier")
+			}
+			params = append(params, t.text)
+		}
+		p.advance()
+		name += "(" + strings.Join(params, "") + ")"
+	}
+
+	if p.isKeyword("WITH", "WITHOUT") && isKeyword(p.peek(1), "TIME") {
+		name += " " + strings.ToUpper(p.advance().text) + " TIME"
+		p.advance()
+		p.expectKeyword("ZONE")
+		name += " ZONE"
+	}
+
+	for p.isOp("[") {
+		p.advance()
+		if p.tok().kind == tokNumber {
+			p.advance()
+		}
+		p.expectOp("]")
+		name += "[]"
+	}
+	return name
+}
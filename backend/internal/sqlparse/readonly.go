@@ -0,0 +1,182 @@
+package sqlparse
+
+import (
+	"strings"
+)
+
+// Functions that sleep, touch the server's file system, signal other backends,
+// change settings or sequences, take advisory locks, or run SQL given as a string
+var deniedFunctions = map[string]bool{
+	"pg_sleep": true, "pg_sleep_for": true, "pg_sleep_until": true,
+	"sleep": true, "benchmark": true, "waitfor": true,
+	"pg_terminate_backend": true, "pg_cancel_backend": true, "pg_reload_conf": true,
+	"pg_rotate_logfile": true, "pg_switch_wal": true, "pg_promote": true,
+	"pg_stat_file": true, "pg_notify": true, "pg_logical_emit_message": true,
+	"set_config": true, "nextval": true, "setval": true,
+	"query_to_xml": true, "query_to_xmlschema": true, "query_to_xml_and_xmlschema": true,
+	"cursor_to_xml": true, "cursor_to_xmlschema": true,
+	"load_file": true, "get_lock": true, "release_lock": true, "release_all_locks": true,
+	"sys_exec": true, "sys_eval": true,
+}
+
+// Prefixes of function families that are denied as a whole
+var deniedFunctionPrefixes = []string{
+	"pg_read_", "pg_ls_", "pg_file_", "pg_advisory_", "pg_try_advisory_",
+	"pg_create_", "pg_drop_", "pg_replication_", "pg_stat_reset",
+	"lo_", "dblink", "xp_", "sp_",
+}
+
+// System schemas that expose the catalog, server settings or other tenants' objects
+var deniedSchemas = map[string]bool{
+	"information_schema": true, "pg_catalog": true, "pg_toast": true,
+	"mysql": true, "performance_schema": true, "sys": true,
+}
+
+// ValidateReadOnly parses sql with the rules of PostgreSQL and checks that it is a
+// single read-only query: SELECT, WITH, VALUES, TABLE or EXPLAIN of one of those.
+// The returned error is an *Error whose Reason can be shown to the user as is.
+func ValidateReadOnly(sql string) (Statement, error) {
+	return ValidateReadOnlyDialect(sql, DialectPostgres)
+}
+
+// ValidateReadOnlyDialect is ValidateReadOnly for SQL that runs on an engine of
+// dialect. Use DialectAny when the engine is not known.
+func ValidateReadOnlyDialect(sql string, dialect Dialect) (Statement, error) {
+	if strings.TrimSpace(sql) == "" {
+		return nil, &Error{Kind: ErrSyntax, Reason: "query is empty"}
+	}
+
+	stmts, err := ParseDialect(sql, dialect)
+	if err != nil {
+		return nil, err
+	}
+	if len(stmts) == 0 {
+		return nil, &Error{Kind: ErrSyntax, Reason: "query contains no statement"}
+	}
+	if len(stmts) > 1 {
+		return nil, newError(ErrMultipleStatements, sql, stmts[1].Pos(),
+			"only one statement can run at a time, but the query contains %d", len(stmts))
+	}
+
+	if err := CheckReadOnly(sql, stmts[0]); err != nil {
+		return nil, err
+	}
+	return stmts[0], nil
+}
+
+// CheckReadOnly checks a parsed statement against the read-only rules. src is the
+// text it was parsed from and is only used to report positions.
+func CheckReadOnly(src string, stmt Statement) error {
+	if explain, ok := stmt.(*Explain); ok {
+		if _, ok := explain.Stmt.(*Query); !ok {
+			return notReadOnly(src, explain.Stmt, "EXPLAIN")
+		}
+		if explainAnalyzes(explain) {
+			return newError(ErrNotReadOnly, src, explain.Pos(),
+				"EXPLAIN ANALYZE is not allowed: it runs the query without the row limit and cost checks; use EXPLAIN without ANALYZE")
+		}
+	}
+	if other, ok := stmt.(*OtherStatement); ok && other.With == nil {
+		return notReadOnly(src, other, "")
+	}
+
+	var err error
+	Walk(stmt, func(node Node) bool {
+		if err != nil {
+			return false
+		}
+		switch n := node.(type) {
+		case *OtherStatement:
+			// Data-modifying statement attached to or inside a WITH clause
+			err = newError(ErrNotReadOnly, src, n.Pos(),
+				"%s is not allowed in a WITH query: only read-only queries can run", n.Verb)
+		case *CTE:
+			if other, ok := n.Stmt.(*OtherStatement); ok {
+				err = newError(ErrNotReadOnly, src, other.Pos(),
+					"WITH clause %q contains a %s statement, which modifies data", n.Name, other.Verb)
+			}
+		case *Select:
+			if len(n.Into) > 0 {
+				err = newError(ErrNotReadOnly, src, n.Pos(),
+					"SELECT INTO is not allowed because it creates the table %s", n.Into)
+			}
+		case *Query:
+			if n.Locking != "" {
+				err = newError(ErrNotReadOnly, src, n.Pos(),
+					"%s is not allowed because it locks rows", n.Locking)
+			}
+		case *FuncCall:
+			if isDeniedFunction(n.Name) {
+				err = newError(ErrDeniedFunction, src, n.Pos(),
+					"function %s is not allowed", n.Name)
+			}
+		case *TableName:
+			if isDeniedRelation(n.Name) {
+				err = newError(ErrDeniedRelation, src, n.Pos(),
+					"reading from %s is not allowed: system catalogs cannot be queried", n.Name)
+			}
+		case *TableStmt:
+			if isDeniedRelation(n.Name) {
+				err = newError(ErrDeniedRelation, src, n.Pos(),
+					"reading from %s is not allowed: system catalogs cannot be queried", n.Name)
+			}
+		}
+		return err == nil
+	})
+	return err
+}
+
+// explainAnalyzes reports whether an EXPLAIN runs the statement it explains, as
+// ANALYZE does unless it is turned off with a value such as FALSE or OFF
+func explainAnalyzes(explain *Explain) bool {
+	for _, option := range explain.Options {
+		fields := strings.Fields(option)
+		if fields[0] != "ANALYZE" && fields[0] != "ANALYSE" {
+			continue
+		}
+		if len(fields) == 1 {
+			return true
+		}
+		switch fields[1] {
+		case "FALSE", "OFF", "0":
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+func notReadOnly(src string, stmt Statement, context string) error {
+	verb := "this statement"
+	if other, ok := stmt.(*OtherStatement); ok {
+		verb = other.Verb
+	}
+	if context != "" {
+		return newError(ErrNotReadOnly, src, stmt.Pos(),
+			"%s %s is not allowed: only SELECT, WITH and VALUES queries can be explained", context, verb)
+	}
+	return newError(ErrNotReadOnly, src, stmt.Pos(),
+		"%s statements are not allowed: only read-only queries (SELECT, WITH, VALUES, EXPLAIN) can run", verb)
+}
+
+func isDeniedFunction(name ObjectName) bool {
+	base := name.Base()
+	if deniedFunctions[base] {
+		return true
+	}
+	for _, prefix := range deniedFunctionPrefixes {
+		if strings.HasPrefix(base, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDeniedRelation rejects tables in system schemas, and unqualified pg_ names,
+// which resolve to pg_catalog through the search path
+func isDeniedRelation(name ObjectName) bool {
+	if schema := name.Schema(); schema != "" {
+		return deniedSchemas[schema]
+	}
+	return strings.HasPrefix(name.Base(), "pg_")
+}
@@ -0,0 +1,176 @@
+package sqlparse
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateReadOnlyAccepts(t *testing.T) {
+	queries := []string{
+		"SELECT 1",
+		"SELECT id, created_at, updated_by, last_update FROM orders WHERE deleted = false",
+		"select u.id, count(*) as total from users u left join orders o on o.user_id = u.id group by u.id having count(*) > 1 order by 2 desc nulls last limit 10 offset 5;",
+		"WITH recent AS (SELECT * FROM orders WHERE created_at > now() - interval '7 days') SELECT count(*) FROM recent",
+		"WITH RECURSIVE t(n) AS (VALUES (1) UNION ALL SELECT n + 1 FROM t WHERE n < 10) SELECT sum(n) FROM t",
+		"VALUES (1, 'a'), (2, 'b')",
+		"EXPLAIN SELECT * FROM users",
+		"EXPLAIN (FORMAT JSON, COSTS true) SELECT * FROM users",
+		"EXPLAIN (ANALYZE false) SELECT * FROM users",
+		"SELECT region, sum(revenue) FILTER (WHERE status = 'paid'), rank() OVER (PARTITION BY region ORDER BY sum(revenue) DESC) FROM sales GROUP BY ROLLUP (region)",
+		"SELECT avg(x) OVER (ORDER BY day ROWS BETWEEN 6 PRECEDING AND CURRENT ROW) FROM metrics",
+		"SELECT CASE WHEN amount >= 100 THEN 'large' ELSE 'small' END, amount::numeric(10, 2), CAST(d AS timestamp with time zone) FROM t",
+		"SELECT date_trunc('month', created_at) AS month, extract(year FROM created_at), substring(name FROM 1 FOR 3) FROM t",
+		"SELECT * FROM a WHERE id IN (SELECT a_id FROM b) AND NOT EXISTS (SELECT 1 FROM c WHERE c.id = a.id) AND x NOT BETWEEN 1 AND 2",
+		"SELECT data->>'name', tags @> ARRAY['x'], name ILIKE '%foo%' FROM docs WHERE id = ANY($1)",
+		"SELECT * FROM generate_series(1, 10) AS g(n) CROSS JOIN LATERAL (SELECT n * 2 AS m) sub",
+		"SELECT `order`.id FROM `order` WHERE created_at > DATE_SUB(NOW(), INTERVAL 7 DAY) LIMIT 5, 10",
+		"(SELECT 1) UNION (SELECT 2) INTERSECT SELECT 3 EXCEPT SELECT 4",
+		"SELECT DISTINCT ON (user_id) user_id, created_at FROM events ORDER BY user_id, created_at DESC",
+		"SELECT percentile_cont(0.5) WITHIN GROUP (ORDER BY amount) FROM payments",
+		"SELECT id FROM t -- trailing comment\nWHERE id = 1 /* block */",
+		"SELECT 'it''s; not a statement', E'tab\\t', $$dollar ; quoted$$",
+		"SELECT * FROM t FETCH FIRST 10 ROWS ONLY",
+		"TABLE users",
+	}
+
+	for _, query := range queries {
+		t.Run(query, func(t *testing.T) {
+			if _, err := ValidateReadOnly(query); err != nil {
+				t.Errorf("ValidateReadOnly() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateReadOnlyRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		kind   error
+		reason string
+	}{
+		{"empty", "  ", ErrSyntax, "query is empty"},
+		{"delete", "DELETE FROM users", ErrNotReadOnly, "DELETE statements are not allowed"},
+		{"drop", "drop table users", ErrNotReadOnly, "DROP statements are not allowed"},
+		{"stacked statements", "SELECT * FROM users; DROP TABLE users;--", ErrMultipleStatements, "contains 2"},
+		{"modifying CTE", "WITH gone AS (DELETE FROM users RETURNING *) SELECT * FROM gone", ErrNotReadOnly, `WITH clause "gone" contains a DELETE statement`},
+		{"CTE feeding an update", "WITH ids AS (SELECT 1) UPDATE users SET role = 'admin'", ErrNotReadOnly, "UPDATE is not allowed in a WITH query"},
+		{"select into", "SELECT * INTO backup FROM users", ErrNotReadOnly, "SELECT INTO"},
+		{"row locks", "SELECT * FROM users FOR UPDATE", ErrNotReadOnly, "FOR UPDATE"},
+		{"explain delete", "EXPLAIN ANALYZE DELETE FROM users", ErrNotReadOnly, "EXPLAIN DELETE"},
+		{"explain analyze", "EXPLAIN ANALYZE SELECT * FROM orders", ErrNotReadOnly, "EXPLAIN ANALYZE is not allowed"},
+		{"explain analyze option", "EXPLAIN (FORMAT JSON, ANALYZE true) SELECT * FROM orders", ErrNotReadOnly, "EXPLAIN ANALYZE is not allowed"},
+		{"sleep", "SELECT pg_sleep(10)", ErrDeniedFunction, "function pg_sleep is not allowed"},
+		{"nested denied function", "SELECT id FROM t WHERE id IN (SELECT lo_import('/etc/passwd'))", ErrDeniedFunction, "lo_import"},
+		{"qualified denied function", "SELECT * FROM pg_catalog.pg_read_file('x')", ErrDeniedFunction, "pg_catalog.pg_read_file"},
+		{"catalog", "SELECT * FROM information_schema.tables", ErrDeniedRelation, "information_schema.tables"},
+		{"pg table", "SELECT usename, passwd FROM pg_shadow", ErrDeniedRelation, "pg_shadow"},
+		{"injected quote", "SELECT * FROM users WHERE id = 1' OR '1'='1", ErrSyntax, "unexpected string literal"},
+		{"unterminated string", "SELECT * FROM users WHERE name = 'x", ErrSyntax, "unterminated string literal"},
+		{"bad syntax", "SELECT FROM WHERE", ErrSyntax, "unexpected keyword FROM"},
+		{"missing join condition", "SELECT * FROM a JOIN b", ErrSyntax, "expected ON or USING"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateReadOnly(tt.query)
+			if err == nil {
+				t.Fatalf("ValidateReadOnly(%q) expected error", tt.query)
+			}
+			if !errors.Is(err, tt.kind) {
+				t.Errorf("error = %v, want kind %v", err, tt.kind)
+			}
+			if !strings.Contains(err.Error(), tt.reason) {
+				t.Errorf("error = %q, want it to mention %q", err.Error(), tt.reason)
+			}
+		})
+	}
+}
+
+func TestValidateReadOnlyDialects(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		dialect Dialect
+		kind    error // nil when the query is accepted
+	}{
+		{"nested comment in PostgreSQL", "SELECT 1 /* /* */, SLEEP(30) -- */", DialectPostgres, nil},
+		{"nested comment in MySQL", "SELECT 1 /* /* */, SLEEP(30) -- */", DialectMySQL, ErrDeniedFunction},
+		{"nested comment in any dialect", "SELECT 1 /* /* */, SLEEP(30) -- */", DialectAny, ErrAmbiguousSyntax},
+		{"executable comment in PostgreSQL", "SELECT 1 /*!50000 , SLEEP(30) */", DialectPostgres, nil},
+		{"executable comment in MySQL", "SELECT 1 /*!50000 , SLEEP(30) */", DialectMySQL, ErrAmbiguousSyntax},
+		{"executable comment in any dialect", "SELECT 1 /*!50000 , SLEEP(30) */", DialectAny, ErrAmbiguousSyntax},
+		{"MariaDB executable comment", "SELECT 1 /*M!100000 , LOAD_FILE('/etc/passwd') */", DialectMySQL, ErrAmbiguousSyntax},
+		{"dashes without space in MySQL", "SELECT 1 --1, SLEEP(30)", DialectMySQL, ErrDeniedFunction},
+		{"dashes without space in any dialect", "SELECT 1 --1, SLEEP(30)", DialectAny, ErrAmbiguousSyntax},
+		{"hash comment in MySQL", "SELECT 1 # 2 /*\n, SLEEP(30) -- */", DialectMySQL, ErrDeniedFunction},
+		{"hash in any dialect", "SELECT 1 # 2 /*\n, SLEEP(30) -- */", DialectAny, ErrAmbiguousSyntax},
+		{"escaped quote in PostgreSQL", `SELECT 'x\', 1 -- ', SLEEP(30)`, DialectPostgres, nil},
+		{"escaped quote in MySQL", `SELECT 'x\', 1 -- ', SLEEP(30)`, DialectMySQL, ErrDeniedFunction},
+		{"escaped quote in any dialect", `SELECT 'x\', 1 -- ', SLEEP(30)`, DialectAny, ErrAmbiguousSyntax},
+		{"escaped double quote in MySQL", `SELECT "x\", 1 -- ", SLEEP(30)`, DialectMySQL, ErrDeniedFunction},
+		{"escaped double quote in any dialect", `SELECT "x\", 1 -- ", SLEEP(30)`, DialectAny, ErrAmbiguousSyntax},
+		{"dollar quotes in any dialect", "SELECT $a$, SLEEP(30), $a$", DialectAny, ErrAmbiguousSyntax},
+		{"MySQL query", "SELECT /*+ MAX_EXECUTION_TIME(1000) */ `order`.id, 'it\\'s', \"x\" FROM `order` # note\nWHERE note LIKE '%\\_%' -- note", DialectMySQL, nil},
+		{"portable query", `SELECT 'C:\temp', "name" FROM t WHERE note LIKE '%\_%' -- note`, DialectAny, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateReadOnlyDialect(tt.query, tt.dialect)
+			if tt.kind == nil && err != nil {
+				t.Errorf("ValidateReadOnlyDialect(%q, %v) unexpected error: %v", tt.query, tt.dialect, err)
+			}
+			if tt.kind != nil && !errors.Is(err, tt.kind) {
+				t.Errorf("ValidateReadOnlyDialect(%q, %v) = %v, want %v", tt.query, tt.dialect, err, tt.kind)
+			}
+		})
+	}
+}
+
+func TestErrorPosition(t *testing.T) {
+	_, err := ValidateReadOnly("SELECT id\nFROM users\nWHERE pg_sleep(1) IS NULL")
+	var perr *Error
+	if !errors.As(err, &perr) {
+		t.Fatalf("expected *Error, got %v", err)
+	}
+	if perr.Line != 3 || perr.Column != 7 {
+		t.Errorf("position = %d:%d, want 3:7", perr.Line, perr.Column)
+	}
+}
+
+func TestParseTree(t *testing.T) {
+	stmts, err := Parse("SELECT a.id, sum(b.amount) AS total FROM a JOIN b USING (id) WHERE a.x = 1 OR a.y = 2 AND a.z = 3")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	q := stmts[0].(*Query)
+	sel := q.Body.(*Select)
+	if len(sel.Columns) != 2 || sel.Columns[1].Alias != "total" {
+		t.Errorf("unexpected select list: %+v", sel.Columns)
+	}
+	join, ok := sel.From[0].(*Join)
+	if !ok || join.Type != "INNER" || len(join.Using) != 1 {
+		t.Errorf("unexpected FROM: %+v", sel.From[0])
+	}
+	// AND binds tighter than OR
+	or, ok := sel.Where.(*BinaryExpr)
+	if !ok || or.Op != "OR" {
+		t.Fatalf("WHERE root = %+v, want OR", sel.Where)
+	}
+	if and, ok := or.Y.(*BinaryExpr); !ok || and.Op != "AND" {
+		t.Errorf("OR right operand = %+v, want AND", or.Y)
+	}
+
+	var tables []string
+	Walk(q, func(n Node) bool {
+		if table, ok := n.(*TableName); ok {
+			tables = append(tables, table.Name.String())
+		}
+		return true
+	})
+	if strings.Join(tables, ",") != "a,b" {
+		t.Errorf("tables = %v", tables)
+	}
+}
@@ -0,0 +1,50 @@
+package sqlparse
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// WithRowLimit rewrites sql, whose parsed form with the rules of dialect is stmt,
+// so that it returns at most limit rows. It reports whether the query was changed: queries whose own LIMIT is a
+// constant no larger than limit, and EXPLAIN statements, are returned as they are.
+//
+// A LIMIT is appended to queries that have none, which keeps duplicate column names
+// working on MySQL; other queries are wrapped in a subquery.
+func WithRowLimit(sql string, stmt Statement, limit int, dialect Dialect) (string, bool) {
+	q, ok := stmt.(*Query)
+	if !ok {
+		return sql, false
+	}
+
+	if lit, ok := q.Limit.(*Literal); ok && lit.Kind == NumberLiteral {
+		if n, err := strconv.ParseInt(lit.Value, 10, 64); err == nil && n <= int64(limit) {
+			return sql, false
+		}
+	}
+
+	body := TrimSemicolons(sql, dialect)
+	// The newline ends any trailing "--" comment
+	if q.Limit == nil && q.Locking == "" {
+		return fmt.Sprintf("%s\nLIMIT %d", body, limit), true
+	}
+	return fmt.Sprintf("SELECT * FROM (\n%s\n) AS limited_result LIMIT %d", body, limit), true
+}
+
+// TrimSemicolons removes the semicolons that end sql, read with the rules of
+// dialect, along with any comments after them
+func TrimSemicolons(sql string, dialect Dialect) string {
+	tokens, err := lex(sql, dialect)
+	if err != nil || len(tokens) < 2 {
+		return sql
+	}
+
+	end := len(tokens) - 1 // EOF
+	for end > 0 && tokens[end-1].kind == tokOp && tokens[end-1].text == ";" {
+		end--
+	}
+	if end == len(tokens)-1 {
+		return sql
+	}
+	return sql[:tokens[end].pos]
+}
@@ -0,0 +1,34 @@
+package sqlparse
+
+import "testing"
+
+func TestWithRowLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    string
+		changed bool
+	}{
+		{"no limit", "SELECT * FROM t;", "SELECT * FROM t\nLIMIT 100", true},
+		{"trailing comment", "SELECT * FROM t -- all rows", "SELECT * FROM t -- all rows\nLIMIT 100", true},
+		{"smaller limit kept", "SELECT * FROM t LIMIT 10", "SELECT * FROM t LIMIT 10", false},
+		{"larger limit wrapped", "SELECT * FROM t LIMIT 500", "SELECT * FROM (\nSELECT * FROM t LIMIT 500\n) AS limited_result LIMIT 100", true},
+		{"explain untouched", "EXPLAIN SELECT * FROM t", "EXPLAIN SELECT * FROM t", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := ValidateReadOnly(tt.query)
+			if err != nil {
+				t.Fatalf("ValidateReadOnly: %v", err)
+			}
+			got, changed := WithRowLimit(tt.query, stmt, 100, DialectPostgres)
+			if got != tt.want || changed != tt.changed {
+				t.Errorf("WithRowLimit() = %q, %v; want %q, %v", got, changed, tt.want, tt.changed)
+			}
+			if _, err := ValidateReadOnly(got); err != nil {
+				t.Errorf("rewritten query does not parse: %v", err)
+			}
+		})
+	}
+}
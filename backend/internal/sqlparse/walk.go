@@ -0,0 +1,142 @@
+package sqlparse
+
+// Walk traverses the tree rooted at node in depth-first order. It calls fn for
+// each node and descends into its children only when fn returns true.
+func Walk(node Node, fn func(Node) bool) {
+	if isNil(node) || !fn(node) {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Query:
+		Walk(n.With, fn)
+		Walk(n.Body, fn)
+		walkOrder(n.OrderBy, fn)
+		Walk(n.Limit, fn)
+		Walk(n.Offset, fn)
+	case *With:
+		for _, cte := range n.CTEs {
+			Walk(cte, fn)
+		}
+	case *CTE:
+		Walk(n.Stmt, fn)
+	case *Select:
+		walkExprs(n.DistinctOn, fn)
+		for _, item := range n.Columns {
+			Walk(item, fn)
+		}
+		for _, from := range n.From {
+			Walk(from, fn)
+		}
+		Walk(n.Where, fn)
+		walkExprs(n.GroupBy, fn)
+		Walk(n.Having, fn)
+		for _, window := range n.Windows {
+			Walk(window, fn)
+		}
+	case *SelectItem:
+		Walk(n.Expr, fn)
+	case *SetOperation:
+		Walk(n.Left, fn)
+		Walk(n.Right, fn)
+	case *Values:
+		for _, row := range n.Rows {
+			walkExprs(row, fn)
+		}
+	case *Explain:
+		Walk(n.Stmt, fn)
+	case *OtherStatement:
+		Walk(n.With, fn)
+	case *OrderItem:
+		Walk(n.Expr, fn)
+	case *NamedWindow:
+		Walk(n.Spec, fn)
+	case *WindowSpec:
+		walkExprs(n.PartitionBy, fn)
+		walkOrder(n.OrderBy, fn)
+		walkExprs(n.FrameBounds, fn)
+	case *DerivedTable:
+		Walk(n.Query, fn)
+	case *TableFunction:
+		Walk(n.Func, fn)
+	case *Join:
+		Walk(n.Left, fn)
+		Walk(n.Right, fn)
+		Walk(n.On, fn)
+	case *TypedLiteral:
+		Walk(n.Value, fn)
+	case *FuncCall:
+		walkExprs(n.Args, fn)
+		walkOrder(n.OrderBy, fn)
+		walkOrder(n.WithinGroup, fn)
+		Walk(n.Filter, fn)
+		Walk(n.Over, fn)
+	case *UnaryExpr:
+		Walk(n.X, fn)
+	case *BinaryExpr:
+		Walk(n.X, fn)
+		Walk(n.Y, fn)
+	case *IsExpr:
+		Walk(n.X, fn)
+	case *InExpr:
+		Walk(n.X, fn)
+		walkExprs(n.List, fn)
+		Walk(n.Query, fn)
+	case *BetweenExpr:
+		Walk(n.X, fn)
+		Walk(n.Low, fn)
+		Walk(n.High, fn)
+	case *SubqueryExpr:
+		Walk(n.Query, fn)
+	case *CaseExpr:
+		Walk(n.Operand, fn)
+		for _, when := range n.Whens {
+			Walk(when.Cond, fn)
+			Walk(when.Result, fn)
+		}
+		Walk(n.Else, fn)
+	case *CastExpr:
+		Walk(n.X, fn)
+	case *CollateExpr:
+		Walk(n.X, fn)
+	case *ArrayExpr:
+		walkExprs(n.Elems, fn)
+		Walk(n.Query, fn)
+	case *RowExpr:
+		walkExprs(n.Elems, fn)
+	case *IndexExpr:
+		Walk(n.X, fn)
+		Walk(n.Index, fn)
+		Walk(n.Upper, fn)
+	}
+}
+
+func walkExprs(exprs []Expr, fn func(Node) bool) {
+	for _, expr := range exprs {
+		Walk(expr, fn)
+	}
+}
+
+func walkOrder(items []*OrderItem, fn func(Node) bool) {
+	for _, item := range items {
+		Walk(item, fn)
+	}
+}
+
+// isNil reports whether node is nil, including typed nil pointers stored in an interface
+func isNil(node Node) bool {
+	if node == nil {
+		return true
+	}
+	switch n := node.(type) {
+	case *Query:
+		return n == nil
+	case *With:
+		return n == nil
+	case *WindowSpec:
+		return n == nil
+	case *FuncCall:
+		return n == nil
+	}
+	return false
+}
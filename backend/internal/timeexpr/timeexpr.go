@@ -0,0 +1,575 @@
+// Package timeexpr resolves the time expressions of questions, such as "last 7
+// days", "Q3 2024", "YTD" or "past 2 fiscal quarters", into absolute date ranges in
+// a configured time zone and fiscal calendar.
+package timeexpr
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Granularity is the calendar unit an expression counts in
+type Granularity string
+
+const (
+	GranularityHour    Granularity = "hour"
+	GranularityDay     Granularity = "day"
+	GranularityWeek    Granularity = "week"
+	GranularityMonth   Granularity = "month"
+	GranularityQuarter Granularity = "quarter"
+	GranularityYear    Granularity = "year"
+)
+
+// Config sets the calendar expressions are resolved in. Weeks start on Monday.
+type Config struct {
+	// Location is the time zone days start in; UTC when nil
+	Location *time.Location
+	// FiscalYearStart is the first month of the fiscal year; January when zero.
+	// Fiscal years are named after the calendar year they end in, so with an
+	// October start FY2024 runs from October 2023 to September 2024.
+	FiscalYearStart time.Month
+}
+
+// ParseConfig builds a Config from a time zone name such as "Europe/Berlin" and
+// the number (1-12) of the month fiscal years start in. Empty values keep the
+// defaults.
+func ParseConfig(timezone string, fiscalYearStart string) (Config, error) {
+	var cfg Config
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return cfg, fmt.Errorf("failed to load time zone: %w", err)
+		}
+		cfg.Location = loc
+	}
+	if fiscalYearStart != "" {
+		month, err := strconv.Atoi(fiscalYearStart)
+		if err != nil || month < 1 || month > 12 {
+			return cfg, fmt.Errorf("fiscal year start must be a month number from 1 to 12, got %q", fiscalYearStart)
+		}
+		cfg.FiscalYearStart = time.Month(month)
+	}
+	return cfg, nil
+}
+
+// Range is a resolved time expression. End is exclusive.
+type Range struct {
+	Start       time.Time
+	End         time.Time
+	Granularity Granularity
+	// Text is the expression as found in the question, lower case
+	Text string
+	// Comparison is the period the range is compared with, e.g. for "same period
+	// last year"
+	Comparison *Range
+}
+
+// Resolver finds and resolves time expressions
+type Resolver struct {
+	loc         *time.Location
+	fiscalStart time.Month
+}
+
+func NewResolver(cfg Config) *Resolver {
+	r := &Resolver{loc: cfg.Location, fiscalStart: cfg.FiscalYearStart}
+	if r.loc == nil {
+		r.loc = time.UTC
+	}
+	if r.fiscalStart == 0 {
+		r.fiscalStart = time.January
+	}
+	return r
+}
+
+// Location returns the time zone ranges are resolved in
+func (r *Resolver) Location() *time.Location {
+	return r.loc
+}
+
+// Resolve finds the first time expression in text and resolves it relative to now
+func (r *Resolver) Resolve(text string) (*Range, bool) {
+	return r.ResolveAt(text, time.Now())
+}
+
+// ResolveAt finds the first time expression in text and resolves it relative to
+// now. Relative periods such as "last month" or "past 2 quarters" are complete
+// calendar periods before the current one; "this" periods and "to date" periods
+// include the current one.
+func (r *Resolver) ResolveAt(text string, now time.Time) (*Range, bool) {
+	text = strings.Join(strings.Fields(strings.ToLower(text)), " ")
+	now = now.In(r.loc)
+
+	// Comparisons are found first and blanked out, since "same period last year"
+	// would otherwise read as "last year"
+	var compare func(*Range) *Range
+	var compareText string
+	for _, c := range comparisons {
+		if loc := c.pattern.FindStringIndex(text); loc != nil {
+			compare = c.shift
+			compareText = text[loc[0]:loc[1]]
+			text = text[:loc[0]] + strings.Repeat(" ", loc[1]-loc[0]) + text[loc[1]:]
+			break
+		}
+	}
+
+	primary, ok := r.findPrimary(text, now)
+	if compare == nil {
+		return primary, ok
+	}
+	if !ok {
+		// On its own, "same period last year" is this year so far, a year earlier
+		ytd := &Range{
+			Start:       r.periodStart(now, GranularityYear, false),
+			End:         r.add(r.periodStart(now, GranularityDay, false), GranularityDay, 1),
+			Granularity: GranularityYear,
+		}
+		shifted := compare(ytd)
+		shifted.Text = compareText
+		return shifted, true
+	}
+	primary.Comparison = compare(primary)
+	primary.Comparison.Text = compareText
+	return primary, true
+}
+
+// findPrimary resolves the leftmost expression, preferring the longest one where
+// several start at the same place
+func (r *Resolver) findPrimary(text string, now time.Time) (*Range, bool) {
+	var best *Range
+	bestStart, bestEnd := len(text)+1, 0
+	for _, rule := range rules {
+		for _, loc := range rule.pattern.FindAllStringSubmatchIndex(text, -1) {
+			start, end := loc[0], loc[1]
+			if start > bestStart || (start == bestStart && end <= bestEnd) {
+				continue
+			}
+			groups := make([]string, len(loc)/2)
+			for i := range groups {
+				if loc[2*i] >= 0 {
+					groups[i] = text[loc[2*i]:loc[2*i+1]]
+				}
+			}
+			if resolved, ok := rule.resolve(r, groups, now); ok {
+				resolved.Text = strings.TrimSpace(text[start:end])
+				best, bestStart, bestEnd = resolved, start, end
+			}
+		}
+	}
+	return best, best != nil
+}
+
+const (
+	numberExpr = `(\d+|one|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve)`
+	unitExpr   = `(hour|day|week|month|quarter|year)s?`
+	monthExpr  = `(jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|june?|july?|aug(?:ust)?|sep(?:t(?:ember)?)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?)`
+	// pointExpr is a single date, month, quarter or year that bounds a range
+	pointExpr = `(\d{4}-\d{2}-\d{2}|(?:fiscal )?q[1-4](?: (?:fy ?)?\d{4})?|(?:jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|june?|july?|aug(?:ust)?|sep(?:t(?:ember)?)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?)(?: \d{4})?|(?:fy ?)?\d{4})`
+)
+
+// maxRelativeYears bounds relative expressions such as "last 30 years" or "200
+// months ago"; larger offsets are not read as time expressions
+const maxRelativeYears = 100
+
+var numberWords = map[string]int{
+	"one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+	"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12,
+	"a": 1, "an": 1,
+}
+
+type rule struct {
+	pattern *regexp.Regexp
+	resolve func(r *Resolver, groups []string, now time.Time) (*Range, bool)
+}
+
+var rules = []rule{
+	// last 7 days, past 2 fiscal quarters, previous month
+	{regexp.MustCompile(`\b(?:last|past|previous|prior|trailing) (?:` + numberExpr + ` )?(fiscal )?` + unitExpr + `\b`),
+		func(r *Resolver, g []string, now time.Time) (*Range, bool) {
+			n := parseNumber(g[1], 1)
+			unit := Granularity(g[3])
+			if !withinRelativeBound(n, unit) {
+				return nil, false
+			}
+			end := r.periodStart(now, unit, g[2] != "")
+			return &Range{Start: r.add(end, unit, -n), End: end, Granularity: unit}, true
+		}},
+	// this month, current fiscal year
+	{regexp.MustCompile(`\b(?:this|current) (fiscal )?(day|week|month|quarter|year)\b`),
+		func(r *Resolver, g []string, now time.Time) (*Range, bool) {
+			unit := Granularity(g[2])
+			start := r.periodStart(now, unit, g[1] != "")
+			return &Range{Start: start, End: r.add(start, unit, 1), Granularity: unit}, true
+		}},
+	// YTD, quarter to date, fiscal year-to-date
+	{regexp.MustCompile(`\b(fiscal )?(?:(year|quarter|month|week)[ -]to[ -]date|(f?ytd|f?qtd|mtd|wtd))\b`),
+		func(r *Resolver, g []string, now time.Time) (*Range, bool) {
+			unit, fiscal := Granularity(g[2]), g[1] != ""
+			if abbreviation := g[3]; abbreviation != "" {
+				fiscal = fiscal || strings.HasPrefix(abbreviation, "f")
+				unit = map[byte]Granularity{'y': GranularityYear, 'q': GranularityQuarter, 'm': GranularityMonth, 'w': GranularityWeek}[strings.TrimPrefix(abbreviation, "f")[0]]
+			}
+			return &Range{
+				Start:       r.periodStart(now, unit, fiscal),
+				End:         r.add(r.periodStart(now, GranularityDay, false), GranularityDay, 1),
+				Granularity: unit,
+			}, true
+		}},
+	// 3 days ago, a year ago
+	{regexp.MustCompile(`\b(` + strings.Trim(numberExpr, "()") + `|a|an) ` + unitExpr + ` ago\b`),
+		func(r *Resolver, g []string, now time.Time) (*Range, bool) {
+			n := parseNumber(g[1], 1)
+			unit := Granularity(g[2])
+			if !withinRelativeBound(n, unit) {
+				return nil, false
+			}
+			start := r.periodStart(r.add(now, unit, -n), unit, false)
+			return &Range{Start: start, End: r.add(start, unit, 1), Granularity: unit}, true
+		}},
+	{regexp.MustCompile(`\b(today|yesterday|tomorrow)\b`),
+		func(r *Resolver, g []string, now time.Time) (*Range, bool) {
+			start := r.periodStart(now, GranularityDay, false)
+			start = r.add(start, GranularityDay, map[string]int{"today": 0, "yesterday": -1, "tomorrow": 1}[g[1]])
+			return &Range{Start: start, End: r.add(start, GranularityDay, 1), Granularity: GranularityDay}, true
+		}},
+	// Q3, Q3 2024, fiscal Q3, Q3 FY24
+	{regexp.MustCompile(`\b(fiscal )?q([1-4])(?: (?:of )?(fy ?|fiscal year )?('?\d{2}|\d{4}))?\b`),
+		func(r *Resolver, g []string, now time.Time) (*Range, bool) {
+			quarter, _ := strconv.Atoi(g[2])
+			fiscal := g[1] != "" || g[3] != ""
+			if g[4] != "" && g[3] == "" && len(g[4]) != 4 {
+				return nil, false
+			}
+			return r.quarter(quarter, parseYear(g[4]), fiscal, now), true
+		}},
+	// 2024 Q3, FY24 Q3
+	{regexp.MustCompile(`\b(fy ?|fiscal year )?('?\d{2}|\d{4}) q([1-4])\b`),
+		func(r *Resolver, g []string, now time.Time) (*Range, bool) {
+			if g[1] == "" && len(g[2]) != 4 {
+				return nil, false
+			}
+			quarter, _ := strconv.Atoi(g[3])
+			return r.quarter(quarter, parseYear(g[2]), g[1] != "", now), true
+		}},
+	// FY2024, FY24, fiscal year 2024
+	{regexp.MustCompile(`\b(?:fy ?|fiscal year )('?\d{2}|\d{4})\b`),
+		func(r *Resolver, g []string, now time.Time) (*Range, bool) {
+			start := r.fiscalYear(parseYear(g[1]))
+			return &Range{Start: start, End: r.add(start, GranularityYear, 1), Granularity: GranularityYear}, true
+		}},
+	// March 2024, in March
+	{regexp.MustCompile(`\b(?:(?:in|during|for|of) ` + monthExpr + `(?: (\d{4}))?|` + monthExpr + ` (\d{4}))\b`),
+		func(r *Resolver, g []string, now time.Time) (*Range, bool) {
+			name, year := g[1], g[2]
+			if name == "" {
+				name, year = g[3], g[4]
+			}
+			return r.month(parseMonth(name), parseYear(year), now), true
+		}},
+	// in 2024, for 2024 Q3
+	{regexp.MustCompile(`\b(?:in|during|for|of|calendar year) (\d{4})(?: q([1-4]))?\b`),
+		func(r *Resolver, g []string, now time.Time) (*Range, bool) {
+			year := parseYear(g[1])
+			if year < 1900 || year > 2200 {
+				return nil, false
+			}
+			if g[2] != "" {
+				quarter, _ := strconv.Atoi(g[2])
+				return r.quarter(quarter, year, false, now), true
+			}
+			start := time.Date(year, time.January, 1, 0, 0, 0, 0, r.loc)
+			return &Range{Start: start, End: start.AddDate(1, 0, 0), Granularity: GranularityYear}, true
+		}},
+	{regexp.MustCompile(`\b(\d{4}-\d{2}-\d{2})\b`),
+		func(r *Resolver, g []string, now time.Time) (*Range, bool) {
+			return r.point(g[1], now)
+		}},
+	// between March and June, from 2024-01-01 to 2024-03-31
+	{regexp.MustCompile(`\b(?:between|from) ` + pointExpr + ` (?:and|to|until|through|thru|-) ` + pointExpr + `\b`),
+		func(r *Resolver, g []string, now time.Time) (*Range, bool) {
+			from, ok := r.point(g[1], now)
+			if !ok {
+				return nil, false
+			}
+			to, ok := r.point(g[2], now)
+			if !ok {
+				return nil, false
+			}
+			fromYear, toYear := hasYear(g[1]), hasYear(g[2])
+			switch {
+			case toYear && !fromYear:
+				from = r.point2(g[1], to.Start.Year(), now)
+				if !from.Start.Before(to.Start) {
+					from = shiftYears(from, -1)
+				}
+			case fromYear && !toYear:
+				to = r.point2(g[2], from.Start.Year(), now)
+				if to.Start.Before(from.Start) {
+					to = shiftYears(to, 1)
+				}
+			case !fromYear && !toYear && from.Start.After(to.Start):
+				from = shiftYears(from, -1)
+			}
+			if !from.Start.Before(to.End) {
+				return nil, false
+			}
+			return &Range{Start: from.Start, End: to.End, Granularity: from.Granularity}, true
+		}},
+	// since March, since 2024-01-01
+	{regexp.MustCompile(`\bsince ` + pointExpr + `\b`),
+		func(r *Resolver, g []string, now time.Time) (*Range, bool) {
+			from, ok := r.point(g[1], now)
+			if !ok {
+				return nil, false
+			}
+			end := r.add(r.periodStart(now, GranularityDay, false), GranularityDay, 1)
+			if !from.Start.Before(end) {
+				return nil, false
+			}
+			return &Range{Start: from.Start, End: end, Granularity: from.Granularity}, true
+		}},
+}
+
+type comparison struct {
+	pattern *regexp.Regexp
+	shift   func(*Range) *Range
+}
+
+var comparisons = []comparison{
+	{regexp.MustCompile(`\b(?:(?:the )?same (?:period|time|range|days?|weeks?|months?|quarters?) (?:last|previous|prior|a) year|(?:the )?same (?:period|time) a year (?:ago|earlier)|year[ -]over[ -]year|yoy)\b`),
+		func(r *Range) *Range { return shiftYears(r, -1) }},
+	{regexp.MustCompile(`\b(?:(?:the )?(?:previous|prior|preceding) period|period[ -]over[ -]period)\b`),
+		previousPeriod},
+}
+
+var (
+	pointDatePattern    = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	pointQuarterPattern = regexp.MustCompile(`^(fiscal )?q([1-4])(?: (fy ?)?(\d{4}))?$`)
+	pointMonthPattern   = regexp.MustCompile(`^` + monthExpr + `(?: (\d{4}))?$`)
+	pointYearPattern    = regexp.MustCompile(`^(fy ?)?(\d{4})$`)
+	yearPattern         = regexp.MustCompile(`\d{4}`)
+)
+
+// point resolves a range bound, inferring a missing year as the most recent one
+func (r *Resolver) point(text string, now time.Time) (*Range, bool) {
+	switch {
+	case pointDatePattern.MatchString(text):
+		day, err := time.ParseInLocation("2006-01-02", text, r.loc)
+		if err != nil {
+			return nil, false
+		}
+		return &Range{Start: day, End: day.AddDate(0, 0, 1), Granularity: GranularityDay}, true
+	case pointQuarterPattern.MatchString(text):
+		m := pointQuarterPattern.FindStringSubmatch(text)
+		quarter, _ := strconv.Atoi(m[2])
+		return r.quarter(quarter, parseYear(m[4]), m[1] != "" || m[3] != "", now), true
+	case pointMonthPattern.MatchString(text):
+		m := pointMonthPattern.FindStringSubmatch(text)
+		return r.month(parseMonth(m[1]), parseYear(m[2]), now), true
+	case pointYearPattern.MatchString(text):
+		m := pointYearPattern.FindStringSubmatch(text)
+		year := parseYear(m[2])
+		if m[1] != "" {
+			start := r.fiscalYear(year)
+			return &Range{Start: start, End: start.AddDate(1, 0, 0), Granularity: GranularityYear}, true
+		}
+		start := time.Date(year, time.January, 1, 0, 0, 0, 0, r.loc)
+		return &Range{Start: start, End: start.AddDate(1, 0, 0), Granularity: GranularityYear}, true
+	}
+	return nil, false
+}
+
+// point2 resolves a range bound without a year of its own in the given year
+func (r *Resolver) point2(text string, year int, now time.Time) *Range {
+	resolved, _ := r.point(text+" "+strconv.Itoa(year), now)
+	return resolved
+}
+
+// quarter resolves a calendar or fiscal quarter. Without a year it is the most
+// recent such quarter that has started.
+func (r *Resolver) quarter(quarter, year int, fiscal bool, now time.Time) *Range {
+	start := func(year int) time.Time {
+		if fiscal {
+			return r.fiscalYear(year).AddDate(0, 3*(quarter-1), 0)
+		}
+		return time.Date(year, time.Month(3*(quarter-1)+1), 1, 0, 0, 0, 0, r.loc)
+	}
+
+	if year == 0 {
+		year = now.Year()
+		if fiscal {
+			year = r.fiscalYearName(now)
+		}
+		if start(year).After(now) {
+			year--
+		}
+	}
+	s := start(year)
+	return &Range{Start: s, End: s.AddDate(0, 3, 0), Granularity: GranularityQuarter}
+}
+
+// month resolves a month. Without a year it is the most recent such month that has started.
+func (r *Resolver) month(month time.Month, year int, now time.Time) *Range {
+	if year == 0 {
+		year = now.Year()
+		if month > now.Month() {
+			year--
+		}
+	}
+	start := time.Date(year, month, 1, 0, 0, 0, 0, r.loc)
+	return &Range{Start: start, End: start.AddDate(0, 1, 0), Granularity: GranularityMonth}
+}
+
+// periodStart returns the start of the calendar or fiscal period containing t
+func (r *Resolver) periodStart(t time.Time, unit Granularity, fiscal bool) time.Time {
+	t = t.In(r.loc)
+	y, m, d := t.Date()
+	switch unit {
+	case GranularityHour:
+		return time.Date(y, m, d, t.Hour(), 0, 0, 0, r.loc)
+	case GranularityWeek:
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(y, m, d-offset, 0, 0, 0, 0, r.loc)
+	case GranularityMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, r.loc)
+	case GranularityQuarter:
+		if fiscal {
+			yearStart := r.fiscalYearStart(t)
+			months := (y-yearStart.Year())*12 + int(m) - int(yearStart.Month())
+			return yearStart.AddDate(0, months/3*3, 0)
+		}
+		return time.Date(y, (m-1)/3*3+1, 1, 0, 0, 0, 0, r.loc)
+	case GranularityYear:
+		if fiscal {
+			return r.fiscalYearStart(t)
+		}
+		return time.Date(y, time.January, 1, 0, 0, 0, 0, r.loc)
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, r.loc)
+	}
+}
+
+// add moves t by n units, keeping the wall clock time across DST changes
+func (r *Resolver) add(t time.Time, unit Granularity, n int) time.Time {
+	switch unit {
+	case GranularityHour:
+		return t.Add(time.Duration(n) * time.Hour)
+	case GranularityWeek:
+		return t.AddDate(0, 0, 7*n)
+	case GranularityMonth:
+		return t.AddDate(0, n, 0)
+	case GranularityQuarter:
+		return t.AddDate(0, 3*n, 0)
+	case GranularityYear:
+		return t.AddDate(n, 0, 0)
+	default:
+		return t.AddDate(0, 0, n)
+	}
+}
+
+// fiscalYearStart returns the start of the fiscal year containing t
+func (r *Resolver) fiscalYearStart(t time.Time) time.Time {
+	y, m, _ := t.In(r.loc).Date()
+	if m < r.fiscalStart {
+		y--
+	}
+	return time.Date(y, r.fiscalStart, 1, 0, 0, 0, 0, r.loc)
+}
+
+// fiscalYear returns the start of the fiscal year named year
+func (r *Resolver) fiscalYear(year int) time.Time {
+	if r.fiscalStart == time.January {
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, r.loc)
+	}
+	return time.Date(year-1, r.fiscalStart, 1, 0, 0, 0, 0, r.loc)
+}
+
+// fiscalYearName returns the name of the fiscal year containing t
+func (r *Resolver) fiscalYearName(t time.Time) int {
+	start := r.fiscalYearStart(t)
+	if r.fiscalStart == time.January {
+		return start.Year()
+	}
+	return start.Year() + 1
+}
+
+func shiftYears(r *Range, years int) *Range {
+	return &Range{Start: r.Start.AddDate(years, 0, 0), End: r.End.AddDate(years, 0, 0), Granularity: r.Granularity}
+}
+
+// previousPeriod returns the period of the same length just before r, in whole
+// months when r spans whole months
+func previousPeriod(r *Range) *Range {
+	if isMonthStart(r.Start) && isMonthStart(r.End) {
+		months := (r.End.Year()-r.Start.Year())*12 + int(r.End.Month()) - int(r.Start.Month())
+		return &Range{Start: r.Start.AddDate(0, -months, 0), End: r.Start, Granularity: r.Granularity}
+	}
+	if isMidnight(r.Start) && isMidnight(r.End) {
+		days := int(time.Date(r.End.Year(), r.End.Month(), r.End.Day(), 0, 0, 0, 0, time.UTC).
+			Sub(time.Date(r.Start.Year(), r.Start.Month(), r.Start.Day(), 0, 0, 0, 0, time.UTC)).Hours() / 24)
+		return &Range{Start: r.Start.AddDate(0, 0, -days), End: r.Start, Granularity: r.Granularity}
+	}
+	return &Range{Start: r.Start.Add(-r.End.Sub(r.Start)), End: r.Start, Granularity: r.Granularity}
+}
+
+func isMidnight(t time.Time) bool {
+	return t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0
+}
+
+func isMonthStart(t time.Time) bool {
+	return t.Day() == 1 && isMidnight(t)
+}
+
+func hasYear(text string) bool {
+	return yearPattern.MatchString(text)
+}
+
+// withinRelativeBound reports whether n units back is a usable offset: positive and
+// at most maxRelativeYears, past which dates leave what SQL engines can compare
+func withinRelativeBound(n int, unit Granularity) bool {
+	perYear := map[Granularity]int{
+		GranularityHour: 366 * 24, GranularityDay: 366, GranularityWeek: 53,
+		GranularityMonth: 12, GranularityQuarter: 4, GranularityYear: 1,
+	}
+	return n > 0 && n <= maxRelativeYears*perYear[unit]
+}
+
+// parseNumber reads a number in digits or words; fallback when text is empty and
+// zero when it is not a number that fits an int
+func parseNumber(text string, fallback int) int {
+	if text == "" {
+		return fallback
+	}
+	if n, ok := numberWords[text]; ok {
+		return n
+	}
+	n, err := strconv.Atoi(text)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// parseYear reads 2024, 24 or '24; zero when empty
+func parseYear(text string) int {
+	text = strings.TrimPrefix(text, "'")
+	year, err := strconv.Atoi(text)
+	if err != nil {
+		return 0
+	}
+	if len(text) == 2 {
+		year += 2000
+	}
+	return year
+}
+
+func parseMonth(name string) time.Month {
+	for month := time.January; month <= time.December; month++ {
+		if strings.HasPrefix(strings.ToLower(month.String()), name[:3]) {
+			return month
+		}
+	}
+	return 0
+}
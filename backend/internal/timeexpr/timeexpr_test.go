@@ -0,0 +1,124 @@
+package timeexpr
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResolveAt(t *testing.T) {
+	// Wednesday
+	now := time.Date(2024, time.August, 14, 10, 30, 0, 0, time.UTC)
+	calendar := NewResolver(Config{})
+	fiscal := NewResolver(Config{FiscalYearStart: time.October})
+
+	tests := []struct {
+		resolver    *Resolver
+		text        string
+		start, end  string
+		granularity Granularity
+	}{
+		{calendar, "revenue for the last 7 days", "2024-08-07", "2024-08-14", GranularityDay},
+		{calendar, "orders last month by region", "2024-07-01", "2024-08-01", GranularityMonth},
+		{calendar, "signups this quarter", "2024-07-01", "2024-10-01", GranularityQuarter},
+		{calendar, "sales past two weeks", "2024-07-29", "2024-08-12", GranularityWeek},
+		{calendar, "revenue in Q3 2023", "2023-07-01", "2023-10-01", GranularityQuarter},
+		{calendar, "revenue in Q4", "2023-10-01", "2024-01-01", GranularityQuarter},
+		{calendar, "revenue for 2023 q2", "2023-04-01", "2023-07-01", GranularityQuarter},
+		{calendar, "YTD revenue", "2024-01-01", "2024-08-15", GranularityYear},
+		{calendar, "month-to-date signups", "2024-08-01", "2024-08-15", GranularityMonth},
+		{calendar, "orders yesterday", "2024-08-13", "2024-08-14", GranularityDay},
+		{calendar, "orders between March and June", "2024-03-01", "2024-07-01", GranularityMonth},
+		{calendar, "orders between November and February", "2023-11-01", "2024-03-01", GranularityMonth},
+		{calendar, "orders from 2024-01-15 to 2024-02-15", "2024-01-15", "2024-02-16", GranularityDay},
+		{calendar, "orders since March 2023", "2023-03-01", "2024-08-15", GranularityMonth},
+		{calendar, "churn in 2022", "2022-01-01", "2023-01-01", GranularityYear},
+		{calendar, "churn 3 months ago", "2024-05-01", "2024-06-01", GranularityMonth},
+		{calendar, "signups over the last 100 years", "1924-01-01", "2024-01-01", GranularityYear},
+		{calendar, "revenue in December", "2023-12-01", "2024-01-01", GranularityMonth},
+		{fiscal, "revenue past 2 fiscal quarters", "2024-01-01", "2024-07-01", GranularityQuarter},
+		{fiscal, "revenue this fiscal year", "2023-10-01", "2024-10-01", GranularityYear},
+		{fiscal, "revenue for FY2024", "2023-10-01", "2024-10-01", GranularityYear},
+		{fiscal, "revenue in fiscal Q1", "2023-10-01", "2024-01-01", GranularityQuarter},
+		{fiscal, "revenue in Q2 FY25", "2025-01-01", "2025-04-01", GranularityQuarter},
+		{fiscal, "fiscal ytd bookings", "2023-10-01", "2024-08-15", GranularityYear},
+		{fiscal, "revenue last quarter", "2024-04-01", "2024-07-01", GranularityQuarter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, ok := tt.resolver.ResolveAt(tt.text, now)
+			if !ok {
+				t.Fatal("no time expression found")
+			}
+			if day(got.Start) != tt.start || day(got.End) != tt.end || got.Granularity != tt.granularity {
+				t.Errorf("got [%s, %s) by %s, want [%s, %s) by %s",
+					day(got.Start), day(got.End), got.Granularity, tt.start, tt.end, tt.granularity)
+			}
+		})
+	}
+}
+
+func TestResolveAtComparison(t *testing.T) {
+	now := time.Date(2024, time.August, 14, 10, 30, 0, 0, time.UTC)
+	resolver := NewResolver(Config{})
+
+	got, ok := resolver.ResolveAt("revenue last month vs the same period last year", now)
+	if !ok || got.Comparison == nil {
+		t.Fatalf("got %+v, want a range with a comparison", got)
+	}
+	if day(got.Start) != "2024-07-01" || day(got.Comparison.Start) != "2023-07-01" || day(got.Comparison.End) != "2023-08-01" {
+		t.Errorf("got %s compared with [%s, %s)", day(got.Start), day(got.Comparison.Start), day(got.Comparison.End))
+	}
+
+	got, _ = resolver.ResolveAt("signups last 30 days compared to the previous period", now)
+	if got.Comparison == nil || day(got.Comparison.Start) != "2024-06-15" || day(got.Comparison.End) != "2024-07-15" {
+		t.Errorf("previous period = %+v, want the 30 days before", got.Comparison)
+	}
+
+	got, _ = resolver.ResolveAt("revenue same period last year", now)
+	if day(got.Start) != "2023-01-01" || day(got.End) != "2023-08-15" {
+		t.Errorf("got [%s, %s), want last year to date", day(got.Start), day(got.End))
+	}
+}
+
+func TestResolveAtTimeZone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip("time zone data unavailable")
+	}
+	resolver := NewResolver(Config{Location: tokyo})
+
+	// Already the 1st of September in Tokyo
+	got, _ := resolver.ResolveAt("orders last month", time.Date(2024, time.August, 31, 20, 0, 0, 0, time.UTC))
+	want := time.Date(2024, time.August, 1, 0, 0, 0, 0, tokyo)
+	if !got.Start.Equal(want) {
+		t.Errorf("start = %v, want %v", got.Start, want)
+	}
+}
+
+func TestResolveAtNoExpression(t *testing.T) {
+	for _, text := range []string{
+		"top 10 customers by revenue",
+		"revenue for the last 100000 years",
+		"orders 5000 years ago",
+		"signups in the last 99999999999999999999 days",
+	} {
+		if got, ok := NewResolver(Config{}).ResolveAt(text, time.Now()); ok {
+			t.Errorf("ResolveAt(%q) = [%s, %s), want no time expression", text, day(got.Start), day(got.End))
+		}
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig("", "10")
+	if err != nil || cfg.FiscalYearStart != time.October || cfg.Location != nil {
+		t.Errorf("got %+v, %v", cfg, err)
+	}
+	if _, err := ParseConfig("", "13"); err == nil {
+		t.Error("month 13 should be rejected")
+	}
+}
+
+func day(t time.Time) string {
+	return t.Format("2006-01-02")
+}
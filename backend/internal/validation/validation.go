@@ -2,8 +2,11 @@ package validation
 
 import (
 	"errors"
+	"fmt"
 	"regexp"
 	"strings"
+
+	"insightiq/backend/internal/sqlparse"
 )
 
 var (
@@ -57,7 +60,8 @@ func ValidateTextQuery(query string) error {
 	return nil
 }
 
-// ValidateSQL validates SQL queries (more restrictive)
+// ValidateSQL validates SQL queries (more restrictive). Queries are parsed and must be a
+// single read-only statement; the returned error wraps the parser's user-facing reason.
 func ValidateSQL(sql string) error {
 	if sql == "" {
 		return ErrInvalidInput
@@ -68,23 +72,11 @@ func ValidateSQL(sql string) error {
 		return ErrInputTooLong
 	}
 
-	// Only allow SELECT statements
-	sqlTrimmed := strings.TrimSpace(strings.ToUpper(sql))
-	if !strings.HasPrefix(sqlTrimmed, "SELECT") {
-		return ErrInvalidFormat
-	}
-
-	// Disallow dangerous keywords
-	dangerousKeywords := []string{
-		"DROP", "DELETE", "INSERT", "UPDATE", "ALTER", "CREATE",
-		"EXEC", "EXECUTE", "XP_", "SP_", "SHUTDOWN", "GRANT", "REVOKE",
-	}
-
-	sqlUpper := strings.ToUpper(sql)
-	for _, keyword := range dangerousKeywords {
-		if strings.Contains(sqlUpper, keyword) {
-			return ErrSuspiciousInput
+	if _, err := sqlparse.ValidateReadOnlyDialect(sql, sqlparse.DialectAny); err != nil {
+		if errors.Is(err, sqlparse.ErrSyntax) {
+			return fmt.Errorf("%w: %w", ErrInvalidFormat, err)
 		}
+		return fmt.Errorf("%w: %w", ErrSuspiciousInput, err)
 	}
 
 	return nil
@@ -128,6 +120,28 @@ func ValidateFileUpload(filename string, size int64) error {
 	return nil
 }
 
+// MaxDatasetUploadSize is the largest dataset file accepted by file connectors (50MB)
+const MaxDatasetUploadSize = 50 << 20
+
+// ValidateDatasetUpload validates dataset files uploaded to file connectors
+func ValidateDatasetUpload(filename string, size int64) error {
+	if size > MaxDatasetUploadSize {
+		return ErrInputTooLong
+	}
+
+	// Allowed extensions for tabular datasets
+	allowedExtensions := []string{".csv", ".tsv", ".jsonl", ".ndjson", ".xlsx"}
+	filename = strings.ToLower(filename)
+
+	for _, ext := range allowedExtensions {
+		if strings.HasSuffix(filename, ext) {
+			return nil
+		}
+	}
+
+	return ErrInvalidFormat
+}
+
 // ValidateConnectorName validates connector names
 func ValidateConnectorName(name string) error {
 	if name == "" {